@@ -0,0 +1,48 @@
+package common
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yzx9/otodo/model/dto"
+	"github.com/yzx9/otodo/util"
+)
+
+const ETagHeaderKey = "ETag"
+const IfMatchHeaderKey = "If-Match"
+
+// Set entity tag by version of resource
+func SetETag(c *gin.Context, version int64) {
+	c.Header(ETagHeaderKey, fmt.Sprintf(`"%v"`, version))
+}
+
+// Parse If-Match header, e.g. `"1"`, `"1", W/"2"` or `*`. Weak tags never
+// match, since If-Match requires strong comparison, see RFC 7232
+func GetIfMatch(c *gin.Context) (dto.IfMatch, error) {
+	header := strings.TrimSpace(c.GetHeader(IfMatchHeaderKey))
+	if header == "" {
+		return dto.IfMatch{}, nil
+	}
+
+	if header == "*" {
+		return dto.IfMatch{Present: true, Any: true}, nil
+	}
+
+	ifMatch := dto.IfMatch{Present: true}
+	for _, tag := range strings.Split(header, ",") {
+		tag = strings.TrimSpace(tag)
+		weak := strings.HasPrefix(tag, "W/")
+		version, err := strconv.ParseInt(strings.Trim(strings.TrimPrefix(tag, "W/"), `"`), 10, 64)
+		if err != nil {
+			return dto.IfMatch{}, util.NewErrorWithBadRequest("invalid entity tag: %v", tag)
+		}
+
+		if !weak {
+			ifMatch.Versions = append(ifMatch.Versions, version)
+		}
+	}
+
+	return ifMatch, nil
+}
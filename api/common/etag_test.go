@@ -0,0 +1,46 @@
+package common
+
+import (
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yzx9/otodo/model/dto"
+)
+
+func TestGetIfMatch(t *testing.T) {
+	tests := []struct {
+		name    string
+		header  string
+		want    dto.IfMatch
+		wantErr bool
+	}{
+		{"absent", "", dto.IfMatch{}, false},
+		{"any", "*", dto.IfMatch{Present: true, Any: true}, false},
+		{"strong", `"1"`, dto.IfMatch{Present: true, Versions: []int64{1}}, false},
+		{"multiple", `"1", "2"`, dto.IfMatch{Present: true, Versions: []int64{1, 2}}, false},
+		{"weak never matches", `W/"3"`, dto.IfMatch{Present: true}, false},
+		{"strong and weak", `"1", W/"2"`, dto.IfMatch{Present: true, Versions: []int64{1}}, false},
+		{"invalid", `"a"`, dto.IfMatch{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			c, _ := gin.CreateTestContext(httptest.NewRecorder())
+			c.Request = httptest.NewRequest("DELETE", "/", nil)
+			if tt.header != "" {
+				c.Request.Header.Set(IfMatchHeaderKey, tt.header)
+			}
+
+			got, err := GetIfMatch(c)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("GetIfMatch() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("GetIfMatch() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,145 @@
+package common
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yzx9/otodo/model/dto"
+	"github.com/yzx9/otodo/util"
+)
+
+const NextCursorHeaderKey = "X-Next-Cursor"
+const MaxTodoPageSize = 100
+
+var todoSorts = map[string]dto.TodoSort{
+	"":           dto.TodoSortID,
+	"deadline":   dto.TodoSortDeadline,
+	"createdAt":  dto.TodoSortCreatedAt,
+	"title":      dto.TodoSortTitle,
+	"importance": dto.TodoSortImportance,
+	"notifyAt":   dto.TodoSortNotifyAt,
+	"rank":       dto.TodoSortRank,
+}
+
+// Parse todo query from query string, e.g.
+// `?limit=20&cursor=xxx&sort=deadline&order=desc&done=false&tag=work`
+func GetTodoQuery(c *gin.Context) (dto.TodoQuery, error) {
+	write := func(err error) (dto.TodoQuery, error) {
+		return dto.TodoQuery{}, err
+	}
+
+	var query dto.TodoQuery
+	var err error
+
+	sort, ok := todoSorts[c.Query("sort")]
+	if !ok {
+		return write(util.NewErrorWithBadRequest("invalid sort: %v", c.Query("sort")))
+	}
+	query.Sort = sort
+
+	switch order := c.Query("order"); order {
+	case "", "asc":
+	case "desc":
+		query.Desc = true
+	default:
+		return write(util.NewErrorWithBadRequest("invalid order: %v", order))
+	}
+
+	if limit := c.Query("limit"); limit != "" {
+		query.Limit, err = strconv.Atoi(limit)
+		if err != nil || query.Limit <= 0 {
+			return write(util.NewErrorWithBadRequest("invalid limit: %v", limit))
+		}
+		if query.Limit > MaxTodoPageSize {
+			query.Limit = MaxTodoPageSize
+		}
+	}
+
+	if cursor := c.Query("cursor"); cursor != "" {
+		if query.After, err = decodeTodoCursor(cursor); err != nil {
+			return write(util.NewErrorWithBadRequest("invalid cursor"))
+		}
+	}
+
+	for key, value := range map[string]**bool{
+		"done":       &query.Done,
+		"importance": &query.Importance,
+		"hasFiles":   &query.HasFiles,
+	} {
+		if *value, err = GetQueryBool(c, key); err != nil {
+			return write(err)
+		}
+	}
+
+	for key, value := range map[string]**time.Time{
+		"deadlineFrom": &query.DeadlineFrom,
+		"deadlineTo":   &query.DeadlineTo,
+	} {
+		if *value, err = getQueryTime(c, key); err != nil {
+			return write(err)
+		}
+	}
+
+	query.Tag = c.Query("tag")
+	return query, nil
+}
+
+// Set cursor of next page, nothing to do if no more page
+func SetNextCursor(c *gin.Context, cursor *dto.TodoCursor) {
+	if cursor == nil {
+		return
+	}
+
+	data, err := json.Marshal(cursor)
+	if err != nil {
+		return
+	}
+
+	c.Header(NextCursorHeaderKey, base64.RawURLEncoding.EncodeToString(data))
+}
+
+func decodeTodoCursor(cursor string) (*dto.TodoCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	var after dto.TodoCursor
+	if err := json.Unmarshal(data, &after); err != nil {
+		return nil, err
+	}
+
+	return &after, nil
+}
+
+// Get optional bool of query, nil if not exists
+func GetQueryBool(c *gin.Context, key string) (*bool, error) {
+	value, ok := c.GetQuery(key)
+	if !ok {
+		return nil, nil
+	}
+
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		return nil, util.NewErrorWithBadRequest("invalid %v: %v", key, value)
+	}
+
+	return &b, nil
+}
+
+func getQueryTime(c *gin.Context, key string) (*time.Time, error) {
+	value, ok := c.GetQuery(key)
+	if !ok {
+		return nil, nil
+	}
+
+	t, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return nil, util.NewErrorWithBadRequest("invalid %v: %v", key, value)
+	}
+
+	return &t, nil
+}
@@ -20,12 +20,14 @@ func SetConfig(config *viper.Viper) {
 	{
 		c := config.Sub("database")
 		otodo.Conf.Database = otodo.ConfigDatabase{
+			Driver:       c.GetString("driver"),
 			Host:         c.GetString("host"),
 			Port:         c.GetInt("port"),
 			UserName:     c.GetString("username"),
 			Password:     c.GetString("password"),
 			Protocol:     c.GetString("protocol"),
 			DatabaseName: c.GetString("dbname"),
+			AutoMigrate:  c.GetBool("auto_migrate"),
 		}
 	}
 
@@ -56,4 +58,30 @@ func SetConfig(config *viper.Viper) {
 			OAuthStateExpiresIn: c.GetInt("oauth_state_exp"),
 		}
 	}
+
+	{
+		c := config.Sub("trash")
+		otodo.Conf.Trash = otodo.ConfigTrash{
+			Retention:     c.GetInt("retention"),
+			PurgeInterval: c.GetInt("purge_interval"),
+		}
+	}
+
+	{
+		c := config.Sub("reminder")
+		otodo.Conf.Reminder = otodo.ConfigReminder{
+			Interval: c.GetInt("interval"),
+			SMTP: otodo.ConfigSMTP{
+				Host:     c.GetString("smtp.host"),
+				Port:     c.GetInt("smtp.port"),
+				UserName: c.GetString("smtp.username"),
+				Password: c.GetString("smtp.password"),
+				From:     c.GetString("smtp.from"),
+			},
+			Webhook: otodo.ConfigWebhook{
+				URL:    c.GetString("webhook.url"),
+				Secret: c.GetString("webhook.secret"),
+			},
+		}
+	}
 }
@@ -2,13 +2,20 @@ package handler
 
 import (
 	"net/http"
+	"strconv"
+	"strings"
 
 	"github.com/gin-gonic/gin"
 	"github.com/yzx9/otodo/api/common"
 	"github.com/yzx9/otodo/bll"
-	"github.com/yzx9/otodo/model/entity"
+	"github.com/yzx9/otodo/model/dto"
+	"github.com/yzx9/otodo/otodo"
+	"github.com/yzx9/otodo/util"
 )
 
+const defaultSearchLimit = 50
+const maxSearchLimit = 100
+
 // Get current user
 func GetCurrentUserHandler(c *gin.Context) {
 	userID := common.MustGetAccessUserID(c)
@@ -45,6 +52,18 @@ func GetCurrentUserTodoListsHandler(c *gin.Context) {
 	c.JSON(http.StatusOK, todos)
 }
 
+// Get tags with count of todos for current user
+func GetCurrentUserTagsHandler(c *gin.Context) {
+	userID := common.MustGetAccessUserID(c)
+	tags, err := bll.GetTags(userID)
+	if err != nil {
+		common.AbortWithError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, tags)
+}
+
 // Get basic todo list todos for current user
 func GetCurrentUserBasicTodoListTodosHandler(c *gin.Context) {
 	userID := common.MustGetAccessUserID(c)
@@ -54,18 +73,90 @@ func GetCurrentUserBasicTodoListTodosHandler(c *gin.Context) {
 		return
 	}
 
-	todos, err := bll.ForceGetTodos(user.BasicTodoListID)
+	query, err := common.GetTodoQuery(c)
 	if err != nil {
 		common.AbortWithError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, todos)
+	page, err := bll.ForceGetTodos(userID, user.BasicTodoListID, query)
+	if err != nil {
+		common.AbortWithError(c, err)
+		return
+	}
+
+	common.SetNextCursor(c, page.Next)
+	c.JSON(http.StatusOK, page.Todos)
+}
+
+// Update timezone for current user, "My Day" resets at local midnight
+func PutCurrentUserTimezoneHandler(c *gin.Context) {
+	payload := dto.UserTimezoneDTO{}
+	if err := c.ShouldBind(&payload); err != nil {
+		common.AbortWithError(c, util.NewError(otodo.ErrPreconditionRequired, "timezone required"))
+		return
+	}
+
+	userID := common.MustGetAccessUserID(c)
+	user, err := bll.UpdateUserTimezone(userID, payload.Timezone)
+	if err != nil {
+		common.AbortWithError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, user)
 }
 
 // Get daily todos for current user
 func GetCurrentUserDailyTodosHandler(c *gin.Context) {
-	c.Status(http.StatusNotImplemented)
+	handleGetCurrentUserTodos(c, bll.GetDailyTodos)
+}
+
+// Get suggestions of daily todos for current user
+func GetCurrentUserDailyTodoSuggestionsHandler(c *gin.Context) {
+	userID := common.MustGetAccessUserID(c)
+	suggestions, err := bll.GetDailyTodoSuggestions(userID)
+	if err != nil {
+		common.AbortWithError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, suggestions)
+}
+
+// Add todo to daily todos for current user
+func PutCurrentUserDailyTodoHandler(c *gin.Context) {
+	todoID, err := common.GetRequiredParamID(c, "todo-id")
+	if err != nil {
+		common.AbortWithError(c, err)
+		return
+	}
+
+	userID := common.MustGetAccessUserID(c)
+	todo, err := bll.AddDailyTodo(userID, todoID)
+	if err != nil {
+		common.AbortWithError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, todo)
+}
+
+// Remove todo from daily todos for current user
+func DeleteCurrentUserDailyTodoHandler(c *gin.Context) {
+	todoID, err := common.GetRequiredParamID(c, "todo-id")
+	if err != nil {
+		common.AbortWithError(c, err)
+		return
+	}
+
+	userID := common.MustGetAccessUserID(c)
+	if err := bll.RemoveDailyTodo(userID, todoID); err != nil {
+		common.AbortWithError(c, err)
+		return
+	}
+
+	c.Status(http.StatusOK)
 }
 
 // Get planned todos for current user
@@ -78,20 +169,59 @@ func GetCurrentUserImportantTodosHandler(c *gin.Context) {
 	handleGetCurrentUserTodos(c, bll.GetImportantTodos)
 }
 
+// Get todos shared with current user alone, not in shared todo lists
+func GetCurrentUserSharedTodosHandler(c *gin.Context) {
+	handleGetCurrentUserTodos(c, bll.GetSharedTodos)
+}
+
 // Get not-notified todos for current user
 func GetCurrentUserNotNotifiedTodosHandler(c *gin.Context) {
 	handleGetCurrentUserTodos(c, bll.GetNotNotifiedTodos)
 }
 
-func handleGetCurrentUserTodos(c *gin.Context, getTodos func(userID int64) ([]entity.Todo, error)) {
+func handleGetCurrentUserTodos(c *gin.Context, getTodos func(userID int64, query dto.TodoQuery) (dto.TodoPage, error)) {
+	query, err := common.GetTodoQuery(c)
+	if err != nil {
+		common.AbortWithError(c, err)
+		return
+	}
+
 	userID := common.MustGetAccessUserID(c)
-	todos, err := getTodos(userID)
+	page, err := getTodos(userID, query)
 	if err != nil {
 		common.AbortWithError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, todos)
+	common.SetNextCursor(c, page.Next)
+	c.JSON(http.StatusOK, page.Todos)
+}
+
+// Search todos for current user, e.g. `?q=milk #shopping is:undone`
+func GetCurrentUserSearchTodosHandler(c *gin.Context) {
+	q := strings.TrimSpace(c.Query("q"))
+	if q == "" {
+		common.AbortWithError(c, util.NewError(otodo.ErrPreconditionRequired, "q required"))
+		return
+	}
+
+	limit := defaultSearchLimit
+	if value := c.Query("limit"); value != "" {
+		var err error
+		if limit, err = strconv.Atoi(value); err != nil || limit <= 0 || limit > maxSearchLimit {
+			common.AbortWithError(c, util.NewErrorWithBadRequest("invalid limit: %v", value))
+			return
+		}
+	}
+
+	userID := common.MustGetAccessUserID(c)
+	results, err := bll.SearchTodos(userID, q, limit)
+	if err != nil {
+		common.AbortWithError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, results)
 }
 
 // Get todo list folders for current user
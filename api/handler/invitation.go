@@ -0,0 +1,55 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yzx9/otodo/api/common"
+	"github.com/yzx9/otodo/bll"
+)
+
+// Get pending todo list invitations of current user
+func GetCurrentUserInvitationsHandler(c *gin.Context) {
+	userID := common.MustGetAccessUserID(c)
+	invitations, err := bll.GetUserTodoListInvitations(userID)
+	if err != nil {
+		common.AbortWithError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, invitations)
+}
+
+// Accept invitation and join todo list
+func PostCurrentUserInvitationAcceptHandler(c *gin.Context) {
+	id, err := common.GetRequiredParamID(c, "id")
+	if err != nil {
+		common.AbortWithError(c, err)
+		return
+	}
+
+	userID := common.MustGetAccessUserID(c)
+	if err := bll.AcceptTodoListInvitation(userID, id); err != nil {
+		common.AbortWithError(c, err)
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
+
+// Decline invitation
+func PostCurrentUserInvitationDeclineHandler(c *gin.Context) {
+	id, err := common.GetRequiredParamID(c, "id")
+	if err != nil {
+		common.AbortWithError(c, err)
+		return
+	}
+
+	userID := common.MustGetAccessUserID(c)
+	if err := bll.DeclineTodoListInvitation(userID, id); err != nil {
+		common.AbortWithError(c, err)
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
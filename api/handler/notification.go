@@ -0,0 +1,49 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yzx9/otodo/api/common"
+	"github.com/yzx9/otodo/bll"
+)
+
+// Get in-app notifications of current user
+func GetCurrentUserNotificationsHandler(c *gin.Context) {
+	userID := common.MustGetAccessUserID(c)
+	notifications, err := bll.GetNotifications(userID)
+	if err != nil {
+		common.AbortWithError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, notifications)
+}
+
+// Mark notification as read
+func PostCurrentUserNotificationReadHandler(c *gin.Context) {
+	id, err := common.GetRequiredParamID(c, "id")
+	if err != nil {
+		common.AbortWithError(c, err)
+		return
+	}
+
+	userID := common.MustGetAccessUserID(c)
+	if err := bll.ReadNotification(userID, id); err != nil {
+		common.AbortWithError(c, err)
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
+
+// Mark all notifications of current user as read
+func PostCurrentUserNotificationsReadHandler(c *gin.Context) {
+	userID := common.MustGetAccessUserID(c)
+	if err := bll.ReadAllNotifications(userID); err != nil {
+		common.AbortWithError(c, err)
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
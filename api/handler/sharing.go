@@ -1,14 +1,60 @@
 package handler
 
 import (
+	"fmt"
+	"html/template"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+	"github.com/gin-gonic/gin/render"
 	"github.com/yzx9/otodo/api/common"
 	"github.com/yzx9/otodo/bll"
 	"github.com/yzx9/otodo/model/dto"
 )
 
+// Published todo list may be cached by browsers and proxies for a while
+const publishedTodoListMaxAge = 60 // 1 min
+
+var publishedTodoListTemplate = template.Must(template.New("todo-list").Parse(`<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<title>{{.TodoListName}}</title>
+</head>
+<body>
+<h1>{{.TodoListName}}</h1>
+<p>Shared by {{.UserNickname}}</p>
+<ul>
+{{- range .Todos}}
+<li>
+<input type="checkbox" disabled{{if .Done}} checked{{end}}>
+{{if .Importance}}<strong>{{.Title}}</strong>{{else}}{{.Title}}{{end}}
+{{- if .Deadline}} <small>{{.Deadline.Format "2006-01-02"}}</small>{{end}}
+{{- if .Steps}}
+<ul>
+{{- range .Steps}}
+<li><input type="checkbox" disabled{{if .Done}} checked{{end}}> {{.Name}}</li>
+{{- end}}
+</ul>
+{{- end}}
+{{- if .Memo}}
+<p>{{.Memo}}</p>
+{{- end}}
+{{- if .Files}}
+<ul>
+{{- range .Files}}
+<li><a href="../../../files/{{.FileID}}">{{.FileName}}</a></li>
+{{- end}}
+</ul>
+{{- end}}
+</li>
+{{- end}}
+</ul>
+</body>
+</html>
+`))
+
 // Get sharing info, only support todo list now
 func GetSharingHandler(c *gin.Context) {
 	token, err := common.GetRequiredParam(c, "token")
@@ -23,11 +69,7 @@ func GetSharingHandler(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, dto.SharingToken{
-		Token:     sharing.Token,
-		Type:      sharing.Type,
-		CreatedAt: sharing.CreatedAt,
-	})
+	c.JSON(http.StatusOK, dto.NewSharingToken(sharing))
 }
 
 // Get todo list info by share token
@@ -61,3 +103,43 @@ func GetSharingTodoListHandler(c *gin.Context) {
 		TodoListName: list.Name,
 	})
 }
+
+// Get todos of published todo list, no account required
+func GetSharingTodoListTodosHandler(c *gin.Context) {
+	token, err := common.GetRequiredParam(c, "token")
+	if err != nil {
+		common.AbortWithError(c, err)
+		return
+	}
+
+	list, err := bll.GetPublishedTodoList(token)
+	if err != nil {
+		common.AbortWithError(c, err)
+		return
+	}
+
+	setPublishedTodoListCache(c)
+	c.JSON(http.StatusOK, list)
+}
+
+// Get published todo list as web page, no account required
+func GetSharingTodoListPageHandler(c *gin.Context) {
+	token, err := common.GetRequiredParam(c, "token")
+	if err != nil {
+		common.AbortWithError(c, err)
+		return
+	}
+
+	list, err := bll.GetPublishedTodoList(token)
+	if err != nil {
+		common.AbortWithError(c, err)
+		return
+	}
+
+	setPublishedTodoListCache(c)
+	c.Render(http.StatusOK, render.HTML{Template: publishedTodoListTemplate, Data: list})
+}
+
+func setPublishedTodoListCache(c *gin.Context) {
+	c.Header("Cache-Control", fmt.Sprintf("public, max-age=%v", publishedTodoListMaxAge))
+}
@@ -0,0 +1,95 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yzx9/otodo/api/common"
+	"github.com/yzx9/otodo/bll"
+	"github.com/yzx9/otodo/model/dto"
+)
+
+// Get tag
+func GetTagHandler(c *gin.Context) {
+	userID := common.MustGetAccessUserID(c)
+	tag, err := bll.GetTag(userID, c.Param("name"))
+	if err != nil {
+		common.AbortWithError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, tag)
+}
+
+// Get todos with tag
+func GetTagTodosHandler(c *gin.Context) {
+	query, err := common.GetTodoQuery(c)
+	if err != nil {
+		common.AbortWithError(c, err)
+		return
+	}
+
+	userID := common.MustGetAccessUserID(c)
+	page, err := bll.GetTagTodos(userID, c.Param("name"), query)
+	if err != nil {
+		common.AbortWithError(c, err)
+		return
+	}
+
+	common.SetNextCursor(c, page.Next)
+	c.JSON(http.StatusOK, page.Todos)
+}
+
+// Update tag partial, rename or set color
+func PatchTagHandler(c *gin.Context) {
+	name := c.Param("name")
+	userID := common.MustGetAccessUserID(c)
+	tag, err := bll.GetTag(userID, name)
+	if err != nil {
+		common.AbortWithError(c, err)
+		return
+	}
+
+	// Decoding json into the current tag only overwrites present fields
+	if err := c.ShouldBindJSON(&tag); err != nil {
+		common.AbortWithError(c, err)
+		return
+	}
+
+	if err := bll.UpdateTag(userID, name, &tag); err != nil {
+		common.AbortWithError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, tag)
+}
+
+// Merge tag into another one
+func PostTagMergeHandler(c *gin.Context) {
+	payload := dto.TagMergeDTO{}
+	if err := c.ShouldBind(&payload); err != nil {
+		common.AbortWithError(c, err)
+		return
+	}
+
+	userID := common.MustGetAccessUserID(c)
+	tag, err := bll.MergeTag(userID, c.Param("name"), payload.Into)
+	if err != nil {
+		common.AbortWithError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, tag)
+}
+
+// Delete tag, and remove it from todos
+func DeleteTagHandler(c *gin.Context) {
+	userID := common.MustGetAccessUserID(c)
+	tag, err := bll.DeleteTag(userID, c.Param("name"))
+	if err != nil {
+		common.AbortWithError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, tag)
+}
@@ -2,10 +2,12 @@ package handler
 
 import (
 	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/yzx9/otodo/api/common"
 	"github.com/yzx9/otodo/bll"
+	"github.com/yzx9/otodo/model/dto"
 	"github.com/yzx9/otodo/model/entity"
 )
 
@@ -41,32 +43,76 @@ func GetTodoHandler(c *gin.Context) {
 		return
 	}
 
+	common.SetETag(c, todo.Version)
 	c.JSON(http.StatusOK, todo)
 }
 
 // Update todo fully
 func PutTodoHandler(c *gin.Context) {
-	todo := entity.Todo{}
-	err := c.ShouldBind(&todo)
+	todoID, err := common.GetRequiredParamID(c, "id")
+	if err != nil {
+		common.AbortWithError(c, err)
+		return
+	}
+
+	ifMatch, err := common.GetIfMatch(c)
 	if err != nil {
 		common.AbortWithError(c, err)
 		return
 	}
 
+	todo := entity.Todo{}
+	if err := c.ShouldBind(&todo); err != nil {
+		common.AbortWithError(c, err)
+		return
+	}
+
 	userID := common.MustGetAccessUserID(c)
-	if err = bll.UpdateTodo(userID, &todo); err != nil {
+	todo.ID = todoID
+	if err = bll.UpdateTodo(userID, &todo, ifMatch); err != nil {
 		common.AbortWithError(c, err)
 		return
 	}
 
+	common.SetETag(c, todo.Version)
 	c.JSON(http.StatusOK, todo)
 }
 
-// Update todo partial
+// Update todo partial, fields not in payload are kept
 func PatchTodoHandler(c *gin.Context) {
-	// TODO How to do an patch
-	// Can we get fields by reflect json object?
-	c.Status(http.StatusNotImplemented)
+	todoID, err := common.GetRequiredParamID(c, "id")
+	if err != nil {
+		common.AbortWithError(c, err)
+		return
+	}
+
+	ifMatch, err := common.GetIfMatch(c)
+	if err != nil {
+		common.AbortWithError(c, err)
+		return
+	}
+
+	userID := common.MustGetAccessUserID(c)
+	todo, err := bll.GetTodo(userID, todoID)
+	if err != nil {
+		common.AbortWithError(c, err)
+		return
+	}
+
+	// Decoding json into the current todo only overwrites present fields
+	if err := c.ShouldBindJSON(&todo); err != nil {
+		common.AbortWithError(c, err)
+		return
+	}
+
+	todo.ID = todoID
+	if err = bll.UpdateTodo(userID, &todo, ifMatch); err != nil {
+		common.AbortWithError(c, err)
+		return
+	}
+
+	common.SetETag(c, todo.Version)
+	c.JSON(http.StatusOK, todo)
 }
 
 // Delete Todo
@@ -77,12 +123,395 @@ func DeleteTodoHandler(c *gin.Context) {
 		return
 	}
 
+	ifMatch, err := common.GetIfMatch(c)
+	if err != nil {
+		common.AbortWithError(c, err)
+		return
+	}
+
+	userID := common.MustGetAccessUserID(c)
+	todo, err := bll.DeleteTodo(userID, todoID, ifMatch)
+	if err != nil {
+		common.AbortWithError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, todo)
+}
+
+// Snooze reminder of todo
+func PostTodoSnoozeHandler(c *gin.Context) {
+	todoID, err := common.GetRequiredParamID(c, "id")
+	if err != nil {
+		common.AbortWithError(c, err)
+		return
+	}
+
+	ifMatch, err := common.GetIfMatch(c)
+	if err != nil {
+		common.AbortWithError(c, err)
+		return
+	}
+
+	payload := dto.TodoSnoozeDTO{}
+	if err := c.ShouldBind(&payload); err != nil {
+		common.AbortWithError(c, err)
+		return
+	}
+
+	until := time.Now().Add(time.Duration(payload.Minutes) * time.Minute)
+	if payload.Until != nil {
+		until = *payload.Until
+	}
+
+	userID := common.MustGetAccessUserID(c)
+	todo, err := bll.SnoozeTodo(userID, todoID, until, ifMatch)
+	if err != nil {
+		common.AbortWithError(c, err)
+		return
+	}
+
+	common.SetETag(c, todo.Version)
+	c.JSON(http.StatusOK, todo)
+}
+
+// Skip current occurrence of recurring todo
+func PostTodoSkipHandler(c *gin.Context) {
+	todoID, err := common.GetRequiredParamID(c, "id")
+	if err != nil {
+		common.AbortWithError(c, err)
+		return
+	}
+
+	ifMatch, err := common.GetIfMatch(c)
+	if err != nil {
+		common.AbortWithError(c, err)
+		return
+	}
+
+	userID := common.MustGetAccessUserID(c)
+	todo, err := bll.SkipTodo(userID, todoID, ifMatch)
+	if err != nil {
+		common.AbortWithError(c, err)
+		return
+	}
+
+	common.SetETag(c, todo.Version)
+	c.JSON(http.StatusOK, todo)
+}
+
+// Complete todo, returns successor if todo is recurring
+func PostTodoCompleteHandler(c *gin.Context) {
+	todoID, err := common.GetRequiredParamID(c, "id")
+	if err != nil {
+		common.AbortWithError(c, err)
+		return
+	}
+
+	ifMatch, err := common.GetIfMatch(c)
+	if err != nil {
+		common.AbortWithError(c, err)
+		return
+	}
+
+	userID := common.MustGetAccessUserID(c)
+	completion, err := bll.CompleteTodo(userID, todoID, ifMatch)
+	if err != nil {
+		common.AbortWithError(c, err)
+		return
+	}
+
+	common.SetETag(c, completion.Todo.Version)
+	c.JSON(http.StatusOK, completion)
+}
+
+// Uncomplete todo, the unmodified successor is deleted if `deleteNext=true`
+func PostTodoUncompleteHandler(c *gin.Context) {
+	todoID, err := common.GetRequiredParamID(c, "id")
+	if err != nil {
+		common.AbortWithError(c, err)
+		return
+	}
+
+	ifMatch, err := common.GetIfMatch(c)
+	if err != nil {
+		common.AbortWithError(c, err)
+		return
+	}
+
+	deleteNext, err := common.GetQueryBool(c, "deleteNext")
+	if err != nil {
+		common.AbortWithError(c, err)
+		return
+	}
+
+	userID := common.MustGetAccessUserID(c)
+	completion, err := bll.UncompleteTodo(userID, todoID, deleteNext != nil && *deleteNext, ifMatch)
+	if err != nil {
+		common.AbortWithError(c, err)
+		return
+	}
+
+	common.SetETag(c, completion.Todo.Version)
+	c.JSON(http.StatusOK, completion)
+}
+
+// Move todo after another one in user-defined order
+func PostTodoReorderHandler(c *gin.Context) {
+	todoID, err := common.GetRequiredParamID(c, "id")
+	if err != nil {
+		common.AbortWithError(c, err)
+		return
+	}
+
+	payload := dto.ReorderDTO{}
+	if err := c.ShouldBind(&payload); err != nil {
+		common.AbortWithError(c, err)
+		return
+	}
+
+	userID := common.MustGetAccessUserID(c)
+	todo, err := bll.ReorderTodo(userID, todoID, payload.After)
+	if err != nil {
+		common.AbortWithError(c, err)
+		return
+	}
+
+	common.SetETag(c, todo.Version)
+	c.JSON(http.StatusOK, todo)
+}
+
+// Move todo to another todo list
+func PostTodoMoveHandler(c *gin.Context) {
+	todoID, err := common.GetRequiredParamID(c, "id")
+	if err != nil {
+		common.AbortWithError(c, err)
+		return
+	}
+
+	ifMatch, err := common.GetIfMatch(c)
+	if err != nil {
+		common.AbortWithError(c, err)
+		return
+	}
+
+	payload := dto.TodoMoveDTO{}
+	if err := c.ShouldBind(&payload); err != nil {
+		common.AbortWithError(c, err)
+		return
+	}
+
+	userID := common.MustGetAccessUserID(c)
+	todo, err := bll.MoveTodo(userID, todoID, payload.TodoListID, ifMatch)
+	if err != nil {
+		common.AbortWithError(c, err)
+		return
+	}
+
+	common.SetETag(c, todo.Version)
+	c.JSON(http.StatusOK, todo)
+}
+
+// Copy todo to todo list, returns the copy
+func PostTodoCopyHandler(c *gin.Context) {
+	todoID, err := common.GetRequiredParamID(c, "id")
+	if err != nil {
+		common.AbortWithError(c, err)
+		return
+	}
+
+	payload := dto.TodoMoveDTO{}
+	if err := c.ShouldBind(&payload); err != nil {
+		common.AbortWithError(c, err)
+		return
+	}
+
 	userID := common.MustGetAccessUserID(c)
-	todo, err := bll.DeleteTodo(userID, todoID)
+	todo, err := bll.CopyTodo(userID, todoID, payload.TodoListID)
 	if err != nil {
 		common.AbortWithError(c, err)
 		return
 	}
 
+	common.SetETag(c, todo.Version)
 	c.JSON(http.StatusOK, todo)
 }
+
+// Move todos to another todo list, all or nothing
+func PostTodosMoveHandler(c *gin.Context) {
+	payload := dto.TodosMoveDTO{}
+	if err := c.ShouldBind(&payload); err != nil {
+		common.AbortWithError(c, err)
+		return
+	}
+
+	userID := common.MustGetAccessUserID(c)
+	todos, err := bll.MoveTodos(userID, payload.IDs, payload.TodoListID)
+	if err != nil {
+		common.AbortWithError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, todos)
+}
+
+// Copy todos to todo list, all or nothing, returns the copies
+func PostTodosCopyHandler(c *gin.Context) {
+	payload := dto.TodosMoveDTO{}
+	if err := c.ShouldBind(&payload); err != nil {
+		common.AbortWithError(c, err)
+		return
+	}
+
+	userID := common.MustGetAccessUserID(c)
+	todos, err := bll.CopyTodos(userID, payload.IDs, payload.TodoListID)
+	if err != nil {
+		common.AbortWithError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, todos)
+}
+
+// Apply operations on todos in one transaction, nothing is changed if any
+// operation fails
+func PostTodosBatchHandler(c *gin.Context) {
+	payload := dto.TodoBatchDTO{}
+	if err := c.ShouldBind(&payload); err != nil {
+		common.AbortWithError(c, err)
+		return
+	}
+
+	userID := common.MustGetAccessUserID(c)
+	result, err := bll.BatchTodos(userID, payload.Operations)
+	if err != nil {
+		common.AbortWithError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, result)
+}
+
+/**
+ * oTodo Sharing
+ */
+
+// Get shared users in todo
+func GetTodoSharedUsersHandler(c *gin.Context) {
+	userID := common.MustGetAccessUserID(c)
+	todoID, err := common.GetRequiredParamID(c, "id")
+	if err != nil {
+		common.AbortWithError(c, err)
+		return
+	}
+
+	users, err := bll.GetTodoSharedUsers(userID, todoID)
+	if err != nil {
+		common.AbortWithError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, users)
+}
+
+// Delete shared user from todo
+func DeleteTodoSharedUserHandler(c *gin.Context) {
+	operatorID := common.MustGetAccessUserID(c)
+	todoID, err := common.GetRequiredParamID(c, "id")
+	if err != nil {
+		common.AbortWithError(c, err)
+		return
+	}
+
+	userID, err := common.GetRequiredParamID(c, "user-id")
+	if err != nil {
+		common.AbortWithError(c, err)
+		return
+	}
+
+	if err := bll.DeleteTodoSharedUser(operatorID, userID, todoID); err != nil {
+		common.AbortWithError(c, err)
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
+
+// Create share link for todo
+func PostTodoSharingsHandler(c *gin.Context) {
+	userID := common.MustGetAccessUserID(c)
+	todoID, err := common.GetRequiredParamID(c, "id")
+	if err != nil {
+		common.AbortWithError(c, err)
+		return
+	}
+
+	// body is optional
+	payload := dto.SharingDTO{}
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&payload); err != nil {
+			common.AbortWithError(c, err)
+			return
+		}
+	}
+
+	sharing, err := bll.CreateTodoSharing(userID, todoID, payload)
+	if err != nil {
+		common.AbortWithError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.NewSharingToken(sharing))
+}
+
+// Get active share links with usage
+func GetTodoSharingsHandler(c *gin.Context) {
+	userID := common.MustGetAccessUserID(c)
+	todoID, err := common.GetRequiredParamID(c, "id")
+	if err != nil {
+		common.AbortWithError(c, err)
+		return
+	}
+
+	sharings, err := bll.GetActiveTodoSharings(userID, todoID)
+	if err != nil {
+		common.AbortWithError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, sharings)
+}
+
+// Join todo by share token
+func PostTodoSharingHandler(c *gin.Context) {
+	userID := common.MustGetAccessUserID(c)
+	token, err := common.GetRequiredParam(c, "token")
+	if err != nil {
+		common.AbortWithError(c, err)
+		return
+	}
+
+	if err := bll.CreateTodoSharedUser(userID, token); err != nil {
+		common.AbortWithError(c, err)
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
+
+// Inactive share link
+func DeleteTodoSharingHandler(c *gin.Context) {
+	userID := common.MustGetAccessUserID(c)
+	token, err := common.GetRequiredParam(c, "token")
+	if err != nil {
+		common.AbortWithError(c, err)
+		return
+	}
+
+	if err := bll.DeleteSharing(userID, token); err != nil {
+		common.AbortWithError(c, err)
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
@@ -42,6 +42,75 @@ func GetTodoListHandler(c *gin.Context) {
 		return
 	}
 
+	common.SetETag(c, todoList.Version)
+	c.JSON(http.StatusOK, todoList)
+}
+
+// Update todo list
+func PutTodoListHandler(c *gin.Context) {
+	todoListID, err := common.GetRequiredParamID(c, "id")
+	if err != nil {
+		common.AbortWithError(c, err)
+		return
+	}
+
+	ifMatch, err := common.GetIfMatch(c)
+	if err != nil {
+		common.AbortWithError(c, err)
+		return
+	}
+
+	todoList := entity.TodoList{}
+	if err := c.ShouldBind(&todoList); err != nil {
+		common.AbortWithError(c, err)
+		return
+	}
+
+	userID := common.MustGetAccessUserID(c)
+	todoList.ID = todoListID
+	if err := bll.UpdateTodoList(userID, &todoList, ifMatch); err != nil {
+		common.AbortWithError(c, err)
+		return
+	}
+
+	common.SetETag(c, todoList.Version)
+	c.JSON(http.StatusOK, todoList)
+}
+
+// Update todo list partial, e.g. rename or move into folder
+func PatchTodoListHandler(c *gin.Context) {
+	todoListID, err := common.GetRequiredParamID(c, "id")
+	if err != nil {
+		common.AbortWithError(c, err)
+		return
+	}
+
+	ifMatch, err := common.GetIfMatch(c)
+	if err != nil {
+		common.AbortWithError(c, err)
+		return
+	}
+
+	userID := common.MustGetAccessUserID(c)
+	todoList, err := bll.GetTodoList(userID, todoListID)
+	if err != nil {
+		common.AbortWithError(c, err)
+		return
+	}
+
+	// Decoding json into the current todo list only overwrites present fields
+	if err := c.ShouldBindJSON(&todoList); err != nil {
+		common.AbortWithError(c, err)
+		return
+	}
+
+	todoList.ID = todoListID
+	if err := bll.UpdateTodoList(userID, &todoList, ifMatch); err != nil {
+		common.AbortWithError(c, err)
+		return
+	}
+
+	common.SetETag(c, todoList.Version)
 	c.JSON(http.StatusOK, todoList)
 }
 
@@ -53,8 +122,51 @@ func GetTodoListTodosHandler(c *gin.Context) {
 		return
 	}
 
+	query, err := common.GetTodoQuery(c)
+	if err != nil {
+		common.AbortWithError(c, err)
+		return
+	}
+
+	userID := common.MustGetAccessUserID(c)
+	page, err := bll.GetTodos(userID, todoListID, query)
+	if err != nil {
+		common.AbortWithError(c, err)
+		return
+	}
+
+	common.SetNextCursor(c, page.Next)
+	c.JSON(http.StatusOK, page.Todos)
+}
+
+// Get moves from or to todo list, newest first
+func GetTodoListTodoMovesHandler(c *gin.Context) {
+	todoListID, err := common.GetRequiredParamID(c, "id")
+	if err != nil {
+		common.AbortWithError(c, err)
+		return
+	}
+
+	userID := common.MustGetAccessUserID(c)
+	moves, err := bll.GetTodoMoves(userID, todoListID)
+	if err != nil {
+		common.AbortWithError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, moves)
+}
+
+// Delete done todos in todo list, returns deleted todos
+func PostTodoListClearCompletedHandler(c *gin.Context) {
+	todoListID, err := common.GetRequiredParamID(c, "id")
+	if err != nil {
+		common.AbortWithError(c, err)
+		return
+	}
+
 	userID := common.MustGetAccessUserID(c)
-	todos, err := bll.GetTodos(userID, todoListID)
+	todos, err := bll.ClearCompletedTodos(userID, todoListID)
 	if err != nil {
 		common.AbortWithError(c, err)
 		return
@@ -71,8 +183,14 @@ func DeleteTodoListHandler(c *gin.Context) {
 		return
 	}
 
+	ifMatch, err := common.GetIfMatch(c)
+	if err != nil {
+		common.AbortWithError(c, err)
+		return
+	}
+
 	userID := common.MustGetAccessUserID(c)
-	todo, err := bll.DeleteTodoList(userID, id)
+	todo, err := bll.DeleteTodoList(userID, id, ifMatch)
 	if err != nil {
 		common.AbortWithError(c, err)
 		return
@@ -81,6 +199,96 @@ func DeleteTodoListHandler(c *gin.Context) {
 	c.JSON(http.StatusOK, todo)
 }
 
+// Move todo list after another item in menu
+func PostTodoListReorderHandler(c *gin.Context) {
+	todoListID, err := common.GetRequiredParamID(c, "id")
+	if err != nil {
+		common.AbortWithError(c, err)
+		return
+	}
+
+	payload := dto.ReorderDTO{}
+	if err := c.ShouldBind(&payload); err != nil {
+		common.AbortWithError(c, err)
+		return
+	}
+
+	userID := common.MustGetAccessUserID(c)
+	todoList, err := bll.ReorderTodoList(userID, todoListID, payload.After)
+	if err != nil {
+		common.AbortWithError(c, err)
+		return
+	}
+
+	common.SetETag(c, todoList.Version)
+	c.JSON(http.StatusOK, todoList)
+}
+
+/**
+ * oTodo List Transfer
+ */
+
+// Offer ownership of todo list to a shared user, by owner only
+func PostTodoListTransferHandler(c *gin.Context) {
+	todoListID, err := common.GetRequiredParamID(c, "id")
+	if err != nil {
+		common.AbortWithError(c, err)
+		return
+	}
+
+	payload := dto.TodoListTransferDTO{}
+	if err := c.ShouldBind(&payload); err != nil {
+		common.AbortWithError(c, err)
+		return
+	}
+
+	userID := common.MustGetAccessUserID(c)
+	todoList, err := bll.CreateTodoListTransfer(userID, todoListID, payload.UserID)
+	if err != nil {
+		common.AbortWithError(c, err)
+		return
+	}
+
+	common.SetETag(c, todoList.Version)
+	c.JSON(http.StatusOK, todoList)
+}
+
+// Accept ownership of todo list
+func PostTodoListTransferAcceptHandler(c *gin.Context) {
+	todoListID, err := common.GetRequiredParamID(c, "id")
+	if err != nil {
+		common.AbortWithError(c, err)
+		return
+	}
+
+	userID := common.MustGetAccessUserID(c)
+	todoList, err := bll.AcceptTodoListTransfer(userID, todoListID)
+	if err != nil {
+		common.AbortWithError(c, err)
+		return
+	}
+
+	common.SetETag(c, todoList.Version)
+	c.JSON(http.StatusOK, todoList)
+}
+
+// Cancel transfer by owner, or decline by the user offered
+func DeleteTodoListTransferHandler(c *gin.Context) {
+	todoListID, err := common.GetRequiredParamID(c, "id")
+	if err != nil {
+		common.AbortWithError(c, err)
+		return
+	}
+
+	userID := common.MustGetAccessUserID(c)
+	if err := bll.DeleteTodoListTransfer(userID, todoListID); err != nil {
+		common.AbortWithError(c, err)
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
+
 /**
  * oTodo List Sharing
  */
@@ -105,6 +313,36 @@ func GetTodoListSharedUsersHandler(c *gin.Context) {
 	c.JSON(http.StatusOK, users)
 }
 
+// Change role of shared user, by owner only
+func PatchTodoListSharedUserHandler(c *gin.Context) {
+	operatorID := common.MustGetAccessUserID(c)
+	todoListID, err := common.GetRequiredParamID(c, "id")
+	if err != nil {
+		common.AbortWithError(c, err)
+		return
+	}
+
+	userID, err := common.GetRequiredParamID(c, "user-id")
+	if err != nil {
+		common.AbortWithError(c, err)
+		return
+	}
+
+	payload := dto.TodoListSharedUserRoleDTO{}
+	if err := c.ShouldBind(&payload); err != nil {
+		common.AbortWithError(c, err)
+		return
+	}
+
+	user, err := bll.UpdateTodoListSharedUserRole(operatorID, userID, todoListID, payload.Role)
+	if err != nil {
+		common.AbortWithError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, user)
+}
+
 // Delete shared user from todo list
 func DeleteTodoListSharedUserHandler(c *gin.Context) {
 	operatorID := common.MustGetAccessUserID(c)
@@ -137,20 +375,25 @@ func PostTodoListSharingsHandler(c *gin.Context) {
 		return
 	}
 
-	sharing, err := bll.CreateTodoListSharing(userID, todoListID)
+	// body is optional
+	payload := dto.SharingDTO{}
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&payload); err != nil {
+			common.AbortWithError(c, err)
+			return
+		}
+	}
+
+	sharing, err := bll.CreateTodoListSharing(userID, todoListID, payload)
 	if err != nil {
 		common.AbortWithError(c, err)
 		return
 	}
 
-	c.JSON(http.StatusOK, dto.SharingToken{
-		Token:     sharing.Token,
-		Type:      sharing.Type,
-		CreatedAt: sharing.CreatedAt,
-	})
+	c.JSON(http.StatusOK, dto.NewSharingToken(sharing))
 }
 
-// Get current share link
+// Get active share links with usage
 func GetTodoListSharingsHandler(c *gin.Context) {
 	userID := common.MustGetAccessUserID(c)
 	todoListID, err := common.GetRequiredParamID(c, "id")
@@ -165,16 +408,7 @@ func GetTodoListSharingsHandler(c *gin.Context) {
 		return
 	}
 
-	vec := make([]dto.SharingToken, 0)
-	for i := range sharings {
-		vec = append(vec, dto.SharingToken{
-			Token:     sharings[i].Token,
-			Type:      sharings[i].Type,
-			CreatedAt: sharings[i].CreatedAt,
-		})
-	}
-
-	c.JSON(http.StatusOK, vec)
+	c.JSON(http.StatusOK, sharings)
 }
 
 // Join todo list by share token
@@ -203,7 +437,72 @@ func DeleteTodoListSharingHandler(c *gin.Context) {
 		return
 	}
 
-	if err := bll.DeleteTodoListSharing(userID, token); err != nil {
+	if err := bll.DeleteSharing(userID, token); err != nil {
+		common.AbortWithError(c, err)
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
+
+// Invite user to todo list by user name or email
+func PostTodoListInvitationsHandler(c *gin.Context) {
+	userID := common.MustGetAccessUserID(c)
+	todoListID, err := common.GetRequiredParamID(c, "id")
+	if err != nil {
+		common.AbortWithError(c, err)
+		return
+	}
+
+	payload := dto.TodoListInvitationDTO{}
+	if err := c.ShouldBind(&payload); err != nil {
+		common.AbortWithError(c, err)
+		return
+	}
+
+	invitation, err := bll.CreateTodoListInvitation(userID, todoListID, payload)
+	if err != nil {
+		common.AbortWithError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, invitation)
+}
+
+// Get pending invitations of todo list
+func GetTodoListInvitationsHandler(c *gin.Context) {
+	userID := common.MustGetAccessUserID(c)
+	todoListID, err := common.GetRequiredParamID(c, "id")
+	if err != nil {
+		common.AbortWithError(c, err)
+		return
+	}
+
+	invitations, err := bll.GetTodoListInvitations(userID, todoListID)
+	if err != nil {
+		common.AbortWithError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, invitations)
+}
+
+// Revoke pending invitation
+func DeleteTodoListInvitationHandler(c *gin.Context) {
+	userID := common.MustGetAccessUserID(c)
+	todoListID, err := common.GetRequiredParamID(c, "id")
+	if err != nil {
+		common.AbortWithError(c, err)
+		return
+	}
+
+	invitationID, err := common.GetRequiredParamID(c, "invitation-id")
+	if err != nil {
+		common.AbortWithError(c, err)
+		return
+	}
+
+	if err := bll.DeleteTodoListInvitation(userID, todoListID, invitationID); err != nil {
 		common.AbortWithError(c, err)
 		return
 	}
@@ -6,6 +6,7 @@ import (
 	"github.com/gin-gonic/gin"
 	"github.com/yzx9/otodo/api/common"
 	"github.com/yzx9/otodo/bll"
+	"github.com/yzx9/otodo/model/dto"
 	"github.com/yzx9/otodo/model/entity"
 )
 
@@ -44,7 +45,86 @@ func GetTodoListFolderHandler(c *gin.Context) {
 	c.JSON(http.StatusOK, folder)
 }
 
-// Delete todo list folder
+// Update todo list folder
+func PutTodoListFolderHandler(c *gin.Context) {
+	id, err := common.GetRequiredParamID(c, "id")
+	if err != nil {
+		common.AbortWithError(c, err)
+		return
+	}
+
+	folder := entity.TodoListFolder{}
+	if err := c.ShouldBind(&folder); err != nil {
+		common.AbortWithError(c, err)
+		return
+	}
+
+	userID := common.MustGetAccessUserID(c)
+	folder.ID = id
+	if err := bll.UpdateTodoListFolder(userID, &folder); err != nil {
+		common.AbortWithError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, folder)
+}
+
+// Update todo list folder partial, fields not in payload are kept
+func PatchTodoListFolderHandler(c *gin.Context) {
+	id, err := common.GetRequiredParamID(c, "id")
+	if err != nil {
+		common.AbortWithError(c, err)
+		return
+	}
+
+	userID := common.MustGetAccessUserID(c)
+	folder, err := bll.GetTodoListFolder(userID, id)
+	if err != nil {
+		common.AbortWithError(c, err)
+		return
+	}
+
+	// Decoding json into the current folder only overwrites present fields
+	if err := c.ShouldBindJSON(&folder); err != nil {
+		common.AbortWithError(c, err)
+		return
+	}
+
+	folder.ID = id
+	if err := bll.UpdateTodoListFolder(userID, &folder); err != nil {
+		common.AbortWithError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, folder)
+}
+
+// Move todo list folder after another item in menu
+func PostTodoListFolderReorderHandler(c *gin.Context) {
+	id, err := common.GetRequiredParamID(c, "id")
+	if err != nil {
+		common.AbortWithError(c, err)
+		return
+	}
+
+	payload := dto.ReorderDTO{}
+	if err := c.ShouldBind(&payload); err != nil {
+		common.AbortWithError(c, err)
+		return
+	}
+
+	userID := common.MustGetAccessUserID(c)
+	folder, err := bll.ReorderTodoListFolder(userID, id, payload.After)
+	if err != nil {
+		common.AbortWithError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, folder)
+}
+
+// Delete todo list folder, todo lists in it are moved to root if
+// `cascade=false`, otherwise deleted
 func DeleteTodoListFolderHandler(c *gin.Context) {
 	id, err := common.GetRequiredParamID(c, "id")
 	if err != nil {
@@ -52,8 +132,14 @@ func DeleteTodoListFolderHandler(c *gin.Context) {
 		return
 	}
 
+	cascade, err := common.GetQueryBool(c, "cascade")
+	if err != nil {
+		common.AbortWithError(c, err)
+		return
+	}
+
 	userID := common.MustGetAccessUserID(c)
-	todo, err := bll.DeleteTodoListFolder(userID, id)
+	todo, err := bll.DeleteTodoListFolder(userID, id, cascade == nil || *cascade)
 	if err != nil {
 		common.AbortWithError(c, err)
 		return
@@ -61,3 +147,127 @@ func DeleteTodoListFolderHandler(c *gin.Context) {
 
 	c.JSON(http.StatusOK, todo)
 }
+
+/**
+ * oTodo List Folder Sharing
+ */
+
+// Get shared users in todo list folder
+func GetTodoListFolderSharedUsersHandler(c *gin.Context) {
+	userID := common.MustGetAccessUserID(c)
+	todoListFolderID, err := common.GetRequiredParamID(c, "id")
+	if err != nil {
+		common.AbortWithError(c, err)
+		return
+	}
+
+	users, err := bll.GetTodoListFolderSharedUsers(userID, todoListFolderID)
+	if err != nil {
+		common.AbortWithError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, users)
+}
+
+// Delete shared user from todo list folder
+func DeleteTodoListFolderSharedUserHandler(c *gin.Context) {
+	operatorID := common.MustGetAccessUserID(c)
+	todoListFolderID, err := common.GetRequiredParamID(c, "id")
+	if err != nil {
+		common.AbortWithError(c, err)
+		return
+	}
+
+	userID, err := common.GetRequiredParamID(c, "user-id")
+	if err != nil {
+		common.AbortWithError(c, err)
+		return
+	}
+
+	if err := bll.DeleteTodoListFolderSharedUser(operatorID, userID, todoListFolderID); err != nil {
+		common.AbortWithError(c, err)
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
+
+// Create share link for todo list folder
+func PostTodoListFolderSharingsHandler(c *gin.Context) {
+	userID := common.MustGetAccessUserID(c)
+	todoListFolderID, err := common.GetRequiredParamID(c, "id")
+	if err != nil {
+		common.AbortWithError(c, err)
+		return
+	}
+
+	// body is optional
+	payload := dto.SharingDTO{}
+	if c.Request.ContentLength != 0 {
+		if err := c.ShouldBindJSON(&payload); err != nil {
+			common.AbortWithError(c, err)
+			return
+		}
+	}
+
+	sharing, err := bll.CreateTodoListFolderSharing(userID, todoListFolderID, payload)
+	if err != nil {
+		common.AbortWithError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, dto.NewSharingToken(sharing))
+}
+
+// Get active share links with usage
+func GetTodoListFolderSharingsHandler(c *gin.Context) {
+	userID := common.MustGetAccessUserID(c)
+	todoListFolderID, err := common.GetRequiredParamID(c, "id")
+	if err != nil {
+		common.AbortWithError(c, err)
+		return
+	}
+
+	sharings, err := bll.GetActiveTodoListFolderSharings(userID, todoListFolderID)
+	if err != nil {
+		common.AbortWithError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, sharings)
+}
+
+// Join todo list folder by share token
+func PostTodoListFolderSharingHandler(c *gin.Context) {
+	userID := common.MustGetAccessUserID(c)
+	token, err := common.GetRequiredParam(c, "token")
+	if err != nil {
+		common.AbortWithError(c, err)
+		return
+	}
+
+	if err := bll.CreateTodoListFolderSharedUser(userID, token); err != nil {
+		common.AbortWithError(c, err)
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
+
+// Inactive share link
+func DeleteTodoListFolderSharingHandler(c *gin.Context) {
+	userID := common.MustGetAccessUserID(c)
+	token, err := common.GetRequiredParam(c, "token")
+	if err != nil {
+		common.AbortWithError(c, err)
+		return
+	}
+
+	if err := bll.DeleteSharing(userID, token); err != nil {
+		common.AbortWithError(c, err)
+		return
+	}
+
+	c.Status(http.StatusOK)
+}
@@ -50,13 +50,20 @@ func PutTodoStepHandler(c *gin.Context) {
 		return
 	}
 
+	ifMatch, err := common.GetIfMatch(c)
+	if err != nil {
+		common.AbortWithError(c, err)
+		return
+	}
+
 	userID := common.MustGetAccessUserID(c)
 	step.ID = stepID
-	if err := bll.UpdateTodoStep(userID, &step); err != nil {
+	if err := bll.UpdateTodoStep(userID, &step, ifMatch); err != nil {
 		common.AbortWithError(c, err)
 		return
 	}
 
+	common.SetETag(c, step.Version)
 	c.JSON(http.StatusOK, step)
 }
 
@@ -74,12 +81,49 @@ func DeleteTodoStepHandler(c *gin.Context) {
 		return
 	}
 
+	ifMatch, err := common.GetIfMatch(c)
+	if err != nil {
+		common.AbortWithError(c, err)
+		return
+	}
+
+	userID := common.MustGetAccessUserID(c)
+	step, err := bll.DeleteTodoStep(userID, todoID, stepID, ifMatch)
+	if err != nil {
+		common.AbortWithError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, step)
+}
+
+// Move todo step after another one in user-defined order
+func PostTodoStepReorderHandler(c *gin.Context) {
+	todoID, err := common.GetRequiredParamID(c, "id")
+	if err != nil {
+		common.AbortWithError(c, err)
+		return
+	}
+
+	stepID, err := common.GetRequiredParamID(c, "step-id")
+	if err != nil {
+		common.AbortWithError(c, err)
+		return
+	}
+
+	payload := dto.ReorderDTO{}
+	if err := c.ShouldBind(&payload); err != nil {
+		common.AbortWithError(c, err)
+		return
+	}
+
 	userID := common.MustGetAccessUserID(c)
-	step, err := bll.DeleteTodoStep(userID, todoID, stepID)
+	step, err := bll.ReorderTodoStep(userID, todoID, stepID, payload.After)
 	if err != nil {
 		common.AbortWithError(c, err)
 		return
 	}
 
+	common.SetETag(c, step.Version)
 	c.JSON(http.StatusOK, step)
 }
@@ -0,0 +1,75 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/yzx9/otodo/api/common"
+	"github.com/yzx9/otodo/bll"
+)
+
+// Get deleted todos, todo lists and todo list folders of current user
+func GetCurrentUserTrashHandler(c *gin.Context) {
+	userID := common.MustGetAccessUserID(c)
+	items, err := bll.GetTrash(userID)
+	if err != nil {
+		common.AbortWithError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, items)
+}
+
+// Restore deleted todo
+func PostCurrentUserTrashTodoRestoreHandler(c *gin.Context) {
+	id, err := common.GetRequiredParamID(c, "id")
+	if err != nil {
+		common.AbortWithError(c, err)
+		return
+	}
+
+	userID := common.MustGetAccessUserID(c)
+	todo, err := bll.RestoreTodo(userID, id)
+	if err != nil {
+		common.AbortWithError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, todo)
+}
+
+// Restore deleted todo list, with todos deleted together
+func PostCurrentUserTrashTodoListRestoreHandler(c *gin.Context) {
+	id, err := common.GetRequiredParamID(c, "id")
+	if err != nil {
+		common.AbortWithError(c, err)
+		return
+	}
+
+	userID := common.MustGetAccessUserID(c)
+	list, err := bll.RestoreTodoList(userID, id)
+	if err != nil {
+		common.AbortWithError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, list)
+}
+
+// Restore deleted todo list folder, with todo lists deleted together
+func PostCurrentUserTrashTodoListFolderRestoreHandler(c *gin.Context) {
+	id, err := common.GetRequiredParamID(c, "id")
+	if err != nil {
+		common.AbortWithError(c, err)
+		return
+	}
+
+	userID := common.MustGetAccessUserID(c)
+	folder, err := bll.RestoreTodoListFolder(userID, id)
+	if err != nil {
+		common.AbortWithError(c, err)
+		return
+	}
+
+	c.JSON(http.StatusOK, folder)
+}
@@ -15,15 +15,18 @@ func ErrorMiddleware() func(*gin.Context) {
 
 		if c.IsAborted() {
 			err := c.Errors.Last()
-			typedError := otodo.Error{}
-			if errors.As(err, &typedError) {
-				code := getHttpCodeFromError(typedError)
-				c.AbortWithError(code, typedError)
+			if err == nil {
 				return
 			}
 
-			c.JSON(http.StatusBadRequest, dto.ErrorDTO{
-				Code:    getUserErrorCodeFromError(typedError),
+			code := http.StatusBadRequest
+			typedError := &otodo.Error{}
+			if errors.As(err, &typedError) {
+				code = getHttpCodeFromError(*typedError)
+			}
+
+			c.JSON(code, dto.ErrorDTO{
+				Code:    getUserErrorCodeFromError(*typedError),
 				Message: err.Error(),
 			})
 		}
@@ -56,6 +59,9 @@ func getHttpCodeFromError(err otodo.Error) int {
 	case otodo.ErrRequestEntityTooLarge:
 		return http.StatusRequestEntityTooLarge
 
+	case otodo.ErrBadRequest:
+		return http.StatusBadRequest
+
 	// Resource
 	case otodo.ErrDatabaseConnectFailed:
 		return http.StatusServiceUnavailable
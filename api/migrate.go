@@ -0,0 +1,73 @@
+package api
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/yzx9/otodo/bll"
+	"github.com/yzx9/otodo/otodo"
+)
+
+// Run migration command, one of `up`, `down [steps]` and `status`
+func (s *Server) Migrate(args ...string) *Server {
+	if s.Error != nil {
+		return s
+	}
+
+	if err := otodo.Init(); err != nil {
+		s.Error = err
+		return s
+	}
+
+	if err := bll.InitForMigration(); err != nil {
+		s.Error = err
+		return s
+	}
+
+	cmd := "status"
+	if len(args) > 0 {
+		cmd = args[0]
+	}
+
+	switch cmd {
+	case "up":
+		count, err := bll.MigrateUp()
+		fmt.Printf("%v migrations applied\n", count)
+		s.Error = err
+
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			n, err := strconv.Atoi(args[1])
+			if err != nil || n < 1 {
+				s.Error = fmt.Errorf("invalid steps: %v", args[1])
+				return s
+			}
+			steps = n
+		}
+
+		count, err := bll.MigrateDown(steps)
+		fmt.Printf("%v migrations rolled back\n", count)
+		s.Error = err
+
+	case "status":
+		status, err := bll.GetMigrationStatus()
+		if err != nil {
+			s.Error = err
+			return s
+		}
+
+		for _, m := range status {
+			state := "pending"
+			if m.Applied {
+				state = "applied at " + m.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%4d  %-32s %v\n", m.Version, m.Name, state)
+		}
+
+	default:
+		s.Error = fmt.Errorf("unknown migrate command: %v, should be one of up, down [steps], status", cmd)
+	}
+
+	return s
+}
@@ -37,6 +37,8 @@ func (s *Server) setupRouter() {
 		// Sharing
 		r.GET("/sharings/:token", handler.GetSharingHandler)
 		r.GET("/sharings/:token/todo-list", handler.GetSharingTodoListHandler)
+		r.GET("/sharings/:token/todo-list/todos", handler.GetSharingTodoListTodosHandler)
+		r.GET("/sharings/:token/todo-list/page", handler.GetSharingTodoListPageHandler)
 	}
 
 	// Authorized routes
@@ -51,6 +53,7 @@ func (s *Server) setupRouter() {
 
 		// Current User
 		r.GET("/users/current", handler.GetCurrentUserHandler)
+		r.PUT("/users/current/timezone", handler.PutCurrentUserTimezoneHandler)
 
 		r.GET("/users/current/menu", handler.GetCurrentUserMenu)
 
@@ -58,33 +61,83 @@ func (s *Server) setupRouter() {
 
 		r.GET("/users/current/todos/basic", handler.GetCurrentUserBasicTodoListTodosHandler)
 		r.GET("/users/current/todos/daily", handler.GetCurrentUserDailyTodosHandler)
+		r.GET("/users/current/todos/daily/suggestions", handler.GetCurrentUserDailyTodoSuggestionsHandler)
+		r.PUT("/users/current/todos/daily/:todo-id", handler.PutCurrentUserDailyTodoHandler)
+		r.DELETE("/users/current/todos/daily/:todo-id", handler.DeleteCurrentUserDailyTodoHandler)
 		r.GET("/users/current/todos/planned", handler.GetCurrentUserPlannedTodosHandler)
 		r.GET("/users/current/todos/important", handler.GetCurrentUserImportantTodosHandler)
+		r.GET("/users/current/todos/shared", handler.GetCurrentUserSharedTodosHandler)
 		r.GET("/users/current/todos/not-notified", handler.GetCurrentUserNotNotifiedTodosHandler)
+		r.GET("/users/current/todos/search", handler.GetCurrentUserSearchTodosHandler)
 
 		r.GET("/users/current/todo-list-folders", handler.GetCurrentUserTodoListFoldersHandler)
 
+		r.GET("/users/current/tags", handler.GetCurrentUserTagsHandler)
+
+		r.GET("/users/current/notifications", handler.GetCurrentUserNotificationsHandler)
+		r.POST("/users/current/notifications/read", handler.PostCurrentUserNotificationsReadHandler)
+		r.POST("/users/current/notifications/:id/read", handler.PostCurrentUserNotificationReadHandler)
+
+		r.GET("/users/current/invitations", handler.GetCurrentUserInvitationsHandler)
+		r.POST("/users/current/invitations/:id/accept", handler.PostCurrentUserInvitationAcceptHandler)
+		r.POST("/users/current/invitations/:id/decline", handler.PostCurrentUserInvitationDeclineHandler)
+
+		r.GET("/users/current/trash", handler.GetCurrentUserTrashHandler)
+		r.POST("/users/current/trash/todos/:id/restore", handler.PostCurrentUserTrashTodoRestoreHandler)
+		r.POST("/users/current/trash/todo-lists/:id/restore", handler.PostCurrentUserTrashTodoListRestoreHandler)
+		r.POST("/users/current/trash/todo-list-folders/:id/restore", handler.PostCurrentUserTrashTodoListFolderRestoreHandler)
+
 		// Todo
-		r.POST("/todos", handler.PostTodoHandler) // TODO[feat]: done 属性是否需要独立API？否则无法返回重复产生的Todo
+		r.POST("/todos", handler.PostTodoHandler)
+		r.POST("/todos/move", handler.PostTodosMoveHandler)
+		r.POST("/todos/copy", handler.PostTodosCopyHandler)
+		r.POST("/todos/batch", handler.PostTodosBatchHandler)
 		r.PUT("/todos/:id", handler.PutTodoHandler)
 		r.PATCH("/todos/:id", handler.PatchTodoHandler)
 		r.GET("/todos/:id", handler.GetTodoHandler)
 		r.DELETE("/todos/:id", handler.DeleteTodoHandler)
+		r.POST("/todos/:id/snooze", handler.PostTodoSnoozeHandler)
+		r.POST("/todos/:id/skip", handler.PostTodoSkipHandler)
+		r.POST("/todos/:id/complete", handler.PostTodoCompleteHandler)
+		r.POST("/todos/:id/uncomplete", handler.PostTodoUncompleteHandler)
+		r.POST("/todos/:id/reorder", handler.PostTodoReorderHandler)
+		r.POST("/todos/:id/move", handler.PostTodoMoveHandler)
+		r.POST("/todos/:id/copy", handler.PostTodoCopyHandler)
 
 		r.POST("/todos/:id/files", handler.PostTodoFileHandler)
 
 		r.POST("/todos/:id/steps", handler.PostTodoStepHandler)
 		r.PUT("/todos/:id/steps/:step-id", handler.PutTodoStepHandler)
 		r.DELETE("/todos/:id/steps/:step-id", handler.DeleteTodoStepHandler)
+		r.POST("/todos/:id/steps/:step-id/reorder", handler.PostTodoStepReorderHandler)
+
+		r.GET("/todos/:id/shared-users", handler.GetTodoSharedUsersHandler)
+		r.DELETE("/todos/:id/shared-users/:user-id", handler.DeleteTodoSharedUserHandler)
+
+		r.POST("/todos/:id/sharings", handler.PostTodoSharingsHandler)
+		r.GET("/todos/:id/sharings", handler.GetTodoSharingsHandler)
+
+		r.POST("/todos/:id/sharings/:token", handler.PostTodoSharingHandler)
+		r.DELETE("/todos/:id/sharings/:token", handler.DeleteTodoSharingHandler)
 
 		// Todo List
 		r.POST("/todo-lists", handler.PostTodoListHandler)
 		r.GET("/todo-lists/:id", handler.GetTodoListHandler)
+		r.PUT("/todo-lists/:id", handler.PutTodoListHandler)
+		r.PATCH("/todo-lists/:id", handler.PatchTodoListHandler)
 		r.DELETE("/todo-lists/:id", handler.DeleteTodoListHandler)
+		r.POST("/todo-lists/:id/reorder", handler.PostTodoListReorderHandler)
+		r.POST("/todo-lists/:id/clear-completed", handler.PostTodoListClearCompletedHandler)
+
+		r.POST("/todo-lists/:id/transfer", handler.PostTodoListTransferHandler)
+		r.POST("/todo-lists/:id/transfer/accept", handler.PostTodoListTransferAcceptHandler)
+		r.DELETE("/todo-lists/:id/transfer", handler.DeleteTodoListTransferHandler)
 
 		r.GET("/todo-lists/:id/todos", handler.GetTodoListTodosHandler)
+		r.GET("/todo-lists/:id/todo-moves", handler.GetTodoListTodoMovesHandler)
 
 		r.GET("/todo-lists/:id/shared-users", handler.GetTodoListSharedUsersHandler)
+		r.PATCH("/todo-lists/:id/shared-users/:user-id", handler.PatchTodoListSharedUserHandler)
 		r.DELETE("/todo-lists/:id/shared-users/:user-id", handler.DeleteTodoListSharedUserHandler)
 
 		r.POST("/todo-lists/:id/sharings", handler.PostTodoListSharingsHandler)
@@ -93,9 +146,32 @@ func (s *Server) setupRouter() {
 		r.POST("/todo-lists/:id/sharings/:token", handler.PostTodoListSharingHandler)
 		r.DELETE("/todo-lists/:id/sharings/:token", handler.DeleteTodoListSharingHandler)
 
+		r.POST("/todo-lists/:id/invitations", handler.PostTodoListInvitationsHandler)
+		r.GET("/todo-lists/:id/invitations", handler.GetTodoListInvitationsHandler)
+		r.DELETE("/todo-lists/:id/invitations/:invitation-id", handler.DeleteTodoListInvitationHandler)
+
+		// Tag
+		r.GET("/tags/:name", handler.GetTagHandler)
+		r.PATCH("/tags/:name", handler.PatchTagHandler)
+		r.DELETE("/tags/:name", handler.DeleteTagHandler)
+		r.GET("/tags/:name/todos", handler.GetTagTodosHandler)
+		r.POST("/tags/:name/merge", handler.PostTagMergeHandler)
+
 		// Todo List Folder
 		r.POST("/todo-list-folders", handler.PostTodoListFolderHandler)
 		r.GET("/todo-list-folders/:id", handler.GetTodoListFolderHandler)
+		r.PUT("/todo-list-folders/:id", handler.PutTodoListFolderHandler)
+		r.PATCH("/todo-list-folders/:id", handler.PatchTodoListFolderHandler)
 		r.DELETE("/todo-list-folders/:id", handler.DeleteTodoListFolderHandler)
+		r.POST("/todo-list-folders/:id/reorder", handler.PostTodoListFolderReorderHandler)
+
+		r.GET("/todo-list-folders/:id/shared-users", handler.GetTodoListFolderSharedUsersHandler)
+		r.DELETE("/todo-list-folders/:id/shared-users/:user-id", handler.DeleteTodoListFolderSharedUserHandler)
+
+		r.POST("/todo-list-folders/:id/sharings", handler.PostTodoListFolderSharingsHandler)
+		r.GET("/todo-list-folders/:id/sharings", handler.GetTodoListFolderSharingsHandler)
+
+		r.POST("/todo-list-folders/:id/sharings/:token", handler.PostTodoListFolderSharingHandler)
+		r.DELETE("/todo-list-folders/:id/sharings/:token", handler.DeleteTodoListFolderSharingHandler)
 	}
 }
@@ -94,6 +94,9 @@ func (s *Server) Run() *Server {
 		return s
 	}
 
+	bll.StartTrashPurge()
+	bll.StartReminderScheduler()
+
 	port := otodo.Conf.Server.Port
 	if port == 0 {
 		port = 8080
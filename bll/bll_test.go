@@ -0,0 +1,51 @@
+package bll
+
+import (
+	"testing"
+
+	"github.com/yzx9/otodo/dal/memory"
+	"github.com/yzx9/otodo/model/entity"
+	"github.com/yzx9/otodo/otodo"
+)
+
+// Use a fresh in-memory repository for the test
+func useMemoryRepository(t *testing.T) {
+	t.Helper()
+	if err := otodo.Init(); err != nil {
+		t.Fatal(err)
+	}
+
+	UseRepository(memory.New())
+}
+
+func createTestUser(t *testing.T, name string) entity.User {
+	t.Helper()
+	user := entity.User{Name: name, Email: name + "@example.com"}
+	must(t, createUser(&user))
+	return user
+}
+
+func createTestTodoList(t *testing.T, userID int64, name string) entity.TodoList {
+	t.Helper()
+	todoList := entity.TodoList{Name: name}
+	must(t, CreateTodoList(userID, &todoList))
+	return todoList
+}
+
+func createTestTodo(t *testing.T, userID int64, todo entity.Todo) entity.Todo {
+	t.Helper()
+	must(t, CreateTodo(userID, &todo))
+	return todo
+}
+
+func joinTestTodoList(t *testing.T, userID, todoListID int64, role entity.TodoListRole) {
+	t.Helper()
+	must(t, joinTodoList(repo, userID, todoListID, role))
+}
+
+func must(t *testing.T, err error) {
+	t.Helper()
+	if err != nil {
+		t.Fatal(err)
+	}
+}
@@ -0,0 +1,181 @@
+package bll
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/yzx9/otodo/dal"
+	"github.com/yzx9/otodo/model/dto"
+	"github.com/yzx9/otodo/model/entity"
+	"github.com/yzx9/otodo/util"
+)
+
+const dailyTodoDateLayout = "2006-01-02"
+
+// Add todo to user's "My Day", both own and shared todos are allowed
+func AddDailyTodo(userID, todoID int64) (entity.Todo, error) {
+	todo, err := OwnTodo(userID, todoID)
+	if err != nil {
+		return entity.Todo{}, err
+	}
+
+	today, _, err := getUserToday(userID)
+	if err != nil {
+		return entity.Todo{}, err
+	}
+
+	exist, err := repo.ExistDailyTodo(userID, todoID, today)
+	if err != nil {
+		return entity.Todo{}, fmt.Errorf("fails to get daily todo: %w", err)
+	}
+
+	if exist {
+		return todo, nil
+	}
+
+	daily := entity.DailyTodo{
+		Date:   today,
+		UserID: userID,
+		TodoID: todoID,
+	}
+	if err := repo.InsertDailyTodo(&daily); err != nil {
+		return entity.Todo{}, fmt.Errorf("fails to add daily todo: %w", err)
+	}
+
+	return todo, nil
+}
+
+// Remove todo from user's "My Day", the todo itself will not be deleted
+func RemoveDailyTodo(userID, todoID int64) error {
+	today, _, err := getUserToday(userID)
+	if err != nil {
+		return err
+	}
+
+	count, err := repo.DeleteDailyTodo(userID, todoID, today)
+	if err != nil {
+		return fmt.Errorf("fails to remove daily todo: %w", err)
+	}
+
+	if count == 0 {
+		return util.NewErrorWithNotFound("daily todo not found: %v", todoID)
+	}
+
+	return nil
+}
+
+// Suggest todos for "My Day": unfinished daily todos of yesterday,
+// and overdue todos
+func GetDailyTodoSuggestions(userID int64) (dto.DailyTodoSuggestions, error) {
+	write := func(err error) (dto.DailyTodoSuggestions, error) {
+		return dto.DailyTodoSuggestions{}, err
+	}
+
+	today, midnight, err := getUserToday(userID)
+	if err != nil {
+		return write(err)
+	}
+
+	todos, err := getDailyTodos(userID, today)
+	if err != nil {
+		return write(err)
+	}
+
+	selected := make(map[int64]bool)
+	for i := range todos {
+		selected[todos[i].ID] = true
+	}
+
+	yesterday := midnight.AddDate(0, 0, -1).Format(dailyTodoDateLayout)
+	yesterdayTodos, err := getDailyTodos(userID, yesterday)
+	if err != nil {
+		return write(err)
+	}
+
+	suggestions := dto.DailyTodoSuggestions{
+		Unfinished: make([]entity.Todo, 0),
+		Overdue:    make([]entity.Todo, 0),
+	}
+	for i := range yesterdayTodos {
+		if !yesterdayTodos[i].Done && !selected[yesterdayTodos[i].ID] {
+			selected[yesterdayTodos[i].ID] = true
+			suggestions.Unfinished = append(suggestions.Unfinished, yesterdayTodos[i])
+		}
+	}
+
+	overdue, err := repo.SelectOverdueTodos(userID, midnight)
+	if err != nil {
+		return write(fmt.Errorf("fails to get overdue todos: %w", err))
+	}
+
+	for i := range overdue {
+		if !selected[overdue[i].ID] {
+			suggestions.Overdue = append(suggestions.Overdue, overdue[i])
+		}
+	}
+
+	return suggestions, nil
+}
+
+func getDailyTodos(userID int64, date string) ([]entity.Todo, error) {
+	todos, err := repo.SelectDailyTodos(userID, date)
+	if err != nil {
+		return nil, fmt.Errorf("fails to get daily todos: %w", err)
+	}
+
+	// user may have left the shared todo list since then
+	lists, err := GetTodoLists(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	accessible := make(map[int64]bool)
+	for i := range lists {
+		accessible[lists[i].ID] = true
+	}
+
+	vec := make([]entity.Todo, 0, len(todos))
+	for i := range todos {
+		if accessible[todos[i].TodoListID] {
+			vec = append(vec, todos[i])
+		}
+	}
+
+	return vec, nil
+}
+
+// Get today and the beginning of today in user location,
+// so that "My Day" resets at user's local midnight
+func getUserToday(userID int64) (string, time.Time, error) {
+	user, err := GetUser(userID)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+
+	now := time.Now().In(getUserLocation(user))
+	midnight := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, now.Location())
+	return midnight.Format(dailyTodoDateLayout), midnight, nil
+}
+
+// Location of user by id, local if timezone is not set
+func getUserLocationByID(r dal.Repository, userID int64) (*time.Location, error) {
+	user, err := r.SelectUser(userID)
+	if err != nil {
+		return nil, fmt.Errorf("fails to get user: %w", err)
+	}
+
+	return getUserLocation(user), nil
+}
+
+func getUserLocation(user entity.User) *time.Location {
+	if user.Timezone == "" {
+		return time.Local
+	}
+
+	loc, err := time.LoadLocation(user.Timezone)
+	if err != nil {
+		return time.Local
+	}
+
+	return loc
+}
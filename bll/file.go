@@ -28,12 +28,19 @@ func UploadPublicFile(file *multipart.FileHeader) (entity.File, error) {
 		FileName:   file.Filename,
 		AccessType: int8(entity.FileTypePublic),
 	}
-	err := uploadFile(file, &record)
-	return record, err
+	err := repo.Transaction(func(r dal.Repository) error {
+		return uploadFile(r, file, &record)
+	})
+	if err != nil {
+		removeUploadedFile(&record)
+		return entity.File{}, err
+	}
+
+	return record, nil
 }
 
 func UploadTodoFile(userID, todoID int64, file *multipart.FileHeader) (entity.File, error) {
-	_, err := OwnTodo(userID, todoID)
+	_, err := AccessTodo(userID, todoID, entity.TodoListRoleEditor)
 	if err != nil {
 		return entity.File{}, err
 	}
@@ -43,18 +50,28 @@ func UploadTodoFile(userID, todoID int64, file *multipart.FileHeader) (entity.Fi
 		AccessType: int8(entity.FileTypeTodo),
 		RelatedID:  todoID,
 	}
-	if err := uploadFile(file, &record); err != nil {
-		return entity.File{}, err
-	}
+	err = repo.Transaction(func(r dal.Repository) error {
+		if err := uploadFile(r, file, &record); err != nil {
+			return err
+		}
 
-	if err := dal.InsertTodoFile(todoID, record.ID); err != nil {
-		return entity.File{}, fmt.Errorf("fails to upload todo file: %w", err)
+		if err := r.InsertTodoFile(todoID, record.ID); err != nil {
+			return fmt.Errorf("fails to upload todo file: %w", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		removeUploadedFile(&record)
+		return entity.File{}, err
 	}
 
 	return record, nil
 }
 
-func uploadFile(file *multipart.FileHeader, record *entity.File) error {
+// Upload file within transaction, caller should remove the written file by
+// removeUploadedFile if the transaction fails
+func uploadFile(r dal.Repository, file *multipart.FileHeader, record *entity.File) error {
 	write := func(err error) error {
 		return fmt.Errorf("fails to upload file: %w", err)
 	}
@@ -63,7 +80,7 @@ func uploadFile(file *multipart.FileHeader, record *entity.File) error {
 		return util.NewError(otodo.ErrRequestEntityTooLarge, "file too large")
 	}
 
-	if err := dal.InsertFile(record); err != nil {
+	if err := r.InsertFile(record); err != nil {
 		return write(err)
 	}
 
@@ -74,16 +91,31 @@ func uploadFile(file *multipart.FileHeader, record *entity.File) error {
 		return write(err)
 	}
 
-	if err := dal.SaveFile(record); err != nil {
+	if err := r.SaveFile(record); err != nil {
 		return write(err)
 	}
 
 	return nil
 }
 
+// Compensate for the rolled back file record
+func removeUploadedFile(record *entity.File) {
+	if record.FilePath == "" {
+		return
+	}
+
+	if err := util.RemoveFile(record.FilePath); err != nil {
+		fmt.Printf("fails to remove uploaded file %v: %v\n", record.FilePath, err)
+	}
+}
+
 func GetFile(fileID int64) (*entity.File, error) {
-	file, err := dal.SelectFile(fileID)
-	return file, fmt.Errorf("fails to get file: %w", err)
+	file, err := repo.SelectFile(fileID)
+	if err != nil {
+		return nil, fmt.Errorf("fails to get file: %w", err)
+	}
+
+	return file, nil
 }
 
 // Get file path, auto
@@ -8,6 +8,8 @@ import (
 
 var hasInit = false
 
+var repo dal.Repository
+
 func Init() error {
 	if hasInit {
 		return nil
@@ -15,9 +17,17 @@ func Init() error {
 
 	hasInit = true
 
-	if err := dal.Init(); err != nil {
+	r, err := dal.Init()
+	if err != nil {
 		return fmt.Errorf("fails to init database: %w", err)
 	}
 
+	UseRepository(r)
 	return nil
 }
+
+// Use specified repository instead of database, e.g. dal/memory
+func UseRepository(r dal.Repository) {
+	hasInit = true
+	repo = r
+}
@@ -0,0 +1,44 @@
+package bll
+
+import (
+	"fmt"
+
+	"github.com/yzx9/otodo/dal"
+	"github.com/yzx9/otodo/model/dto"
+)
+
+// Init for migration commands, schema version will not be checked
+func InitForMigration() error {
+	if err := dal.InitWithoutMigration(); err != nil {
+		return fmt.Errorf("fails to init database: %w", err)
+	}
+
+	return nil
+}
+
+func MigrateUp() (int, error) {
+	count, err := dal.MigrateUp()
+	if err != nil {
+		return count, fmt.Errorf("fails to migrate up: %w", err)
+	}
+
+	return count, nil
+}
+
+func MigrateDown(steps int) (int, error) {
+	count, err := dal.MigrateDown(steps)
+	if err != nil {
+		return count, fmt.Errorf("fails to migrate down: %w", err)
+	}
+
+	return count, nil
+}
+
+func GetMigrationStatus() ([]dto.MigrationStatus, error) {
+	status, err := dal.SelectMigrationStatus()
+	if err != nil {
+		return nil, fmt.Errorf("fails to get migration status: %w", err)
+	}
+
+	return status, nil
+}
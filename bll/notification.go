@@ -0,0 +1,42 @@
+package bll
+
+import (
+	"fmt"
+
+	"github.com/yzx9/otodo/model/entity"
+	"github.com/yzx9/otodo/util"
+)
+
+func GetNotifications(userID int64) ([]entity.Notification, error) {
+	notifications, err := repo.SelectNotifications(userID)
+	if err != nil {
+		return nil, fmt.Errorf("fails to get notifications: %w", err)
+	}
+
+	return notifications, nil
+}
+
+func ReadNotification(userID, notificationID int64) error {
+	notification, err := repo.SelectNotification(notificationID)
+	if err != nil {
+		return fmt.Errorf("fails to get notification: %w", err)
+	}
+
+	if notification.UserID != userID {
+		return util.NewErrorWithForbidden("unable to read non-owned notification")
+	}
+
+	if err := repo.ReadNotification(notificationID); err != nil {
+		return fmt.Errorf("fails to read notification: %w", err)
+	}
+
+	return nil
+}
+
+func ReadAllNotifications(userID int64) error {
+	if _, err := repo.ReadAllNotifications(userID); err != nil {
+		return fmt.Errorf("fails to read notifications: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,31 @@
+package bll
+
+import (
+	"github.com/yzx9/otodo/model/dto"
+	"github.com/yzx9/otodo/otodo"
+	"github.com/yzx9/otodo/util"
+)
+
+// Check If-Match against current version of resource. It is required for
+// shared resources, which may be modified by others concurrently.
+func checkIfMatch(ifMatch dto.IfMatch, version int64, shared bool) error {
+	if !ifMatch.Present {
+		if shared {
+			return util.NewError(otodo.ErrPreconditionRequired, "If-Match required for shared resource")
+		}
+
+		return nil
+	}
+
+	if ifMatch.Any {
+		return nil
+	}
+
+	for _, v := range ifMatch.Versions {
+		if v == version {
+			return nil
+		}
+	}
+
+	return util.NewErrorWithPreconditionFailed("resource has been modified, current version: %v", version)
+}
@@ -0,0 +1,297 @@
+package bll
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/yzx9/otodo/dal"
+	"github.com/yzx9/otodo/model/entity"
+	"github.com/yzx9/otodo/util"
+)
+
+// Digits of rank key, lowercase only so that databases sort keys in the
+// same order under case-insensitive collations
+const rankDigits = "0123456789abcdefghijklmnopqrstuvwxyz"
+const rankMaxLength = 128 // size of rank key column
+
+// Item in user-defined order
+type rankItem struct {
+	Type entity.RankType
+	ID   int64
+	Rank string // empty if unranked
+}
+
+// Ranked items are sorted by key, and before unranked ones
+func rankLess(a, b string) bool {
+	return a != "" && (b == "" || a < b)
+}
+
+// Sort by rank, unranked items keep their order
+func sortRankItems(items []rankItem) {
+	sort.SliceStable(items, func(i, j int) bool { return rankLess(items[i].Rank, items[j].Rank) })
+}
+
+// Move item after the previous one in sorted items, or to the first if
+// previous is 0. Unranked items up to the previous one are ranked in their
+// current order, other ranks are never changed
+func moveRankItem(r dal.Repository, userID int64, items []rankItem, id, previousID int64) (string, error) {
+	if id == previousID {
+		return "", util.NewErrorWithBadRequest("unable to move item after itself: %v", id)
+	}
+
+	var item *rankItem
+	vec := make([]rankItem, 0, len(items))
+	for i := range items {
+		if items[i].ID == id {
+			item = &items[i]
+			continue
+		}
+		vec = append(vec, items[i])
+	}
+	if item == nil {
+		return "", util.NewErrorWithNotFound("item not found: %v", id)
+	}
+
+	pos := 0 // position to insert
+	if previousID != 0 {
+		pos = -1
+		for i := range vec {
+			if vec[i].ID == previousID {
+				pos = i + 1
+				break
+			}
+		}
+		if pos == -1 {
+			return "", util.NewErrorWithBadRequest("previous item not found: %v", previousID)
+		}
+	}
+
+	// unranked items are after ranked ones, so they are in [ranked, pos)
+	ranked := 0
+	for ranked < pos && vec[ranked].Rank != "" {
+		ranked++
+	}
+
+	prev, next := "", ""
+	if ranked > 0 {
+		prev = vec[ranked-1].Rank
+	}
+	if pos < len(vec) {
+		next = vec[pos].Rank
+	}
+
+	// items to rank, and the moved one is the last
+	batch := append(vec[ranked:pos:pos], *item)
+	keys, ok := rankKeysBetween(prev, next, len(batch))
+	if !ok {
+		// keys are too long or conflicted, which is rare, rank all items
+		batch = append(append(vec[:pos:pos], *item), vec[pos:]...)
+		keys, _ = rankKeysBetween("", "", len(batch))
+	}
+
+	key := ""
+	for i := range batch {
+		if err := saveRank(r, userID, batch[i].Type, batch[i].ID, keys[i]); err != nil {
+			return "", err
+		}
+		if batch[i].ID == id {
+			key = keys[i]
+		}
+	}
+
+	return key, nil
+}
+
+func saveRank(r dal.Repository, userID int64, rankType entity.RankType, relatedID int64, key string) error {
+	rank := entity.Rank{UserID: userID, Type: rankType, RelatedID: relatedID, Key: key}
+	if err := r.SaveRank(&rank); err != nil {
+		return fmt.Errorf("fails to save rank: %w", err)
+	}
+
+	return nil
+}
+
+// Rank keys of user, unranked items are absent
+func getRanks(r dal.Repository, userID int64, rankType entity.RankType, ids []int64) (map[int64]string, error) {
+	ranks, err := r.SelectRanks(userID, rankType, ids)
+	if err != nil {
+		return nil, fmt.Errorf("fails to get ranks: %w", err)
+	}
+
+	return ranks, nil
+}
+
+// Items of ids with ranks of user, sorted by rank
+func getRankItems(r dal.Repository, userID int64, rankType entity.RankType, ids []int64) ([]rankItem, error) {
+	ranks, err := getRanks(r, userID, rankType, ids)
+	if err != nil {
+		return nil, err
+	}
+
+	items := make([]rankItem, 0, len(ids))
+	for _, id := range ids {
+		items = append(items, rankItem{Type: rankType, ID: id, Rank: ranks[id]})
+	}
+
+	sortRankItems(items)
+	return items, nil
+}
+
+// Fill ranks of todo list folders, and sort by rank
+func fillTodoListFolderRanks(r dal.Repository, userID int64, folders []entity.TodoListFolder) error {
+	ids := make([]int64, 0, len(folders))
+	for i := range folders {
+		ids = append(ids, folders[i].ID)
+	}
+
+	ranks, err := getRanks(r, userID, entity.RankTypeTodoListFolder, ids)
+	if err != nil {
+		return err
+	}
+
+	for i := range folders {
+		folders[i].Rank = ranks[folders[i].ID]
+	}
+	sort.SliceStable(folders, func(i, j int) bool { return rankLess(folders[i].Rank, folders[j].Rank) })
+	return nil
+}
+
+// Fill ranks of todo lists, and sort by rank
+func fillTodoListRanks(r dal.Repository, userID int64, lists []entity.TodoList) error {
+	ids := make([]int64, 0, len(lists))
+	for i := range lists {
+		ids = append(ids, lists[i].ID)
+	}
+
+	ranks, err := getRanks(r, userID, entity.RankTypeTodoList, ids)
+	if err != nil {
+		return err
+	}
+
+	for i := range lists {
+		lists[i].Rank = ranks[lists[i].ID]
+	}
+	sort.SliceStable(lists, func(i, j int) bool { return rankLess(lists[i].Rank, lists[j].Rank) })
+	return nil
+}
+
+// Fill ranks of todos and their steps, steps are sorted by rank
+func fillTodoRanks(r dal.Repository, userID int64, todos []entity.Todo) error {
+	todoIDs := make([]int64, 0, len(todos))
+	stepIDs := make([]int64, 0)
+	for i := range todos {
+		todoIDs = append(todoIDs, todos[i].ID)
+		for j := range todos[i].Steps {
+			stepIDs = append(stepIDs, todos[i].Steps[j].ID)
+		}
+	}
+
+	todoRanks, err := getRanks(r, userID, entity.RankTypeTodo, todoIDs)
+	if err != nil {
+		return err
+	}
+
+	stepRanks, err := getRanks(r, userID, entity.RankTypeTodoStep, stepIDs)
+	if err != nil {
+		return err
+	}
+
+	for i := range todos {
+		todos[i].Rank = todoRanks[todos[i].ID]
+		steps := todos[i].Steps
+		for j := range steps {
+			steps[j].Rank = stepRanks[steps[j].ID]
+		}
+		sort.SliceStable(steps, func(a, b int) bool { return rankLess(steps[a].Rank, steps[b].Rank) })
+	}
+
+	return nil
+}
+
+/**
+ * Key
+ */
+
+// Key between a and b in lexicographic order, a is empty if no lower bound
+// and b is empty if no upper bound. Keys never end with the zero digit, so
+// that there is always a key before any other one. Not ok if a is not less
+// than b, or key is too long
+func rankBetween(a, b string) (string, bool) {
+	if b != "" && a >= b {
+		return "", false
+	}
+
+	key := rankMidpoint(a, b)
+	return key, len(key) <= rankMaxLength
+}
+
+// N keys between a and b, which are evenly distributed to keep them short
+func rankKeysBetween(a, b string, n int) ([]string, bool) {
+	if n == 0 {
+		return []string{}, true
+	}
+
+	mid, ok := rankBetween(a, b)
+	if !ok {
+		return nil, false
+	}
+
+	left, ok := rankKeysBetween(a, mid, n/2)
+	if !ok {
+		return nil, false
+	}
+
+	right, ok := rankKeysBetween(mid, b, n-n/2-1)
+	if !ok {
+		return nil, false
+	}
+
+	keys := append(left, mid)
+	return append(keys, right...), true
+}
+
+func rankMidpoint(a, b string) string {
+	if b != "" {
+		// common prefix, a is padded with zero digits
+		n := 0
+		for n < len(b) && rankDigitAt(a, n) == b[n] {
+			n++
+		}
+		if n > 0 {
+			return b[:n] + rankMidpoint(rankSuffix(a, n), b[n:])
+		}
+	}
+
+	digitA, digitB := 0, len(rankDigits)
+	if a != "" {
+		digitA = strings.IndexByte(rankDigits, a[0])
+	}
+	if b != "" {
+		digitB = strings.IndexByte(rankDigits, b[0])
+	}
+
+	if digitB-digitA > 1 {
+		return string(rankDigits[(digitA+digitB+1)/2])
+	}
+
+	// consecutive digits
+	if len(b) > 1 {
+		return b[:1]
+	}
+	return string(rankDigits[digitA]) + rankMidpoint(rankSuffix(a, 1), "")
+}
+
+func rankDigitAt(key string, i int) byte {
+	if i < len(key) {
+		return key[i]
+	}
+	return rankDigits[0]
+}
+
+func rankSuffix(key string, i int) string {
+	if i < len(key) {
+		return key[i:]
+	}
+	return ""
+}
@@ -0,0 +1,136 @@
+package bll
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/yzx9/otodo/model/dto"
+	"github.com/yzx9/otodo/model/entity"
+	"github.com/yzx9/otodo/otodo"
+	"github.com/yzx9/otodo/util"
+)
+
+const reminderBatchSize = 100
+
+// ReminderChannel dispatches reminders, e.g. in-app, email and webhook
+type ReminderChannel interface {
+	Name() string
+	Remind(user entity.User, todo entity.Todo) error
+}
+
+// Channels registered besides built-in ones
+var extraReminderChannels []ReminderChannel
+
+// Register a channel, should be called before StartReminderScheduler
+func RegisterReminderChannel(channel ReminderChannel) {
+	extraReminderChannels = append(extraReminderChannels, channel)
+}
+
+func StartReminderScheduler() {
+	go func() {
+		for {
+			if err := DispatchDueReminders(); err != nil {
+				fmt.Println(err)
+			}
+
+			interval := otodo.Conf.Reminder.Interval
+			if interval <= 0 {
+				interval = 30
+			}
+
+			time.Sleep(time.Duration(interval) * time.Second)
+		}
+	}()
+}
+
+// Dispatch due reminders through all channels. Reminders failed to claim
+// are left to next dispatching, so that it stops if nothing claimed
+func DispatchDueReminders() error {
+	for {
+		todos, err := repo.SelectDueReminders(time.Now(), reminderBatchSize)
+		if err != nil {
+			return fmt.Errorf("fails to get due reminders: %w", err)
+		}
+
+		claimed := 0
+		for i := range todos {
+			if dispatchReminder(todos[i]) {
+				claimed++
+			}
+		}
+
+		if len(todos) < reminderBatchSize || claimed == 0 {
+			return nil
+		}
+	}
+}
+
+// Snooze reminder of todo, it will be notified again at until
+func SnoozeTodo(userID, todoID int64, until time.Time, ifMatch dto.IfMatch) (entity.Todo, error) {
+	todo, err := AccessTodo(userID, todoID, entity.TodoListRoleEditor)
+	if err != nil {
+		return entity.Todo{}, err
+	}
+
+	if err := checkTodoIfMatch(repo, todo, ifMatch); err != nil {
+		return entity.Todo{}, err
+	}
+
+	if !until.After(time.Now()) {
+		return entity.Todo{}, util.NewErrorWithBadRequest("snooze until a past time")
+	}
+
+	todo.NotifyAt = &until
+	todo.Notified = false
+	if err := repo.SaveTodo(&todo); err != nil {
+		return entity.Todo{}, fmt.Errorf("fails to snooze todo: %w", err)
+	}
+
+	return todo, nil
+}
+
+// Dispatch reminder if claimed, returns whether it is claimed
+func dispatchReminder(todo entity.Todo) bool {
+	// claim first, so that reminder is sent at most once
+	claimed, err := repo.ClaimReminder(todo.ID, *todo.NotifyAt)
+	if err != nil {
+		fmt.Printf("fails to claim reminder of todo %v: %v\n", todo.ID, err)
+		return false
+	}
+
+	if !claimed {
+		return false
+	}
+	todo.Notified = true
+	todo.Version++
+
+	user, err := repo.SelectUser(todo.UserID)
+	if err != nil {
+		fmt.Printf("fails to get user of todo %v: %v\n", todo.ID, err)
+		return true
+	}
+
+	for _, channel := range getReminderChannels() {
+		if err := channel.Remind(user, todo); err != nil {
+			fmt.Printf("fails to remind todo %v by %v: %v\n", todo.ID, channel.Name(), err)
+		}
+	}
+
+	return true
+}
+
+// Channels are built each time, so that changes of config take effect
+func getReminderChannels() []ReminderChannel {
+	c := otodo.Conf.Reminder
+	channels := []ReminderChannel{inAppReminderChannel{}}
+
+	if c.SMTP.Host != "" {
+		channels = append(channels, smtpReminderChannel{c.SMTP})
+	}
+
+	if c.Webhook.URL != "" {
+		channels = append(channels, webhookReminderChannel{c.Webhook})
+	}
+
+	return append(channels, extraReminderChannels...)
+}
@@ -0,0 +1,139 @@
+package bll
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"mime"
+	"net/http"
+	"net/mail"
+	"net/smtp"
+	"strings"
+	"time"
+
+	"github.com/yzx9/otodo/model/dto"
+	"github.com/yzx9/otodo/model/entity"
+	"github.com/yzx9/otodo/otodo"
+)
+
+const webhookSignatureHeaderKey = "X-Otodo-Signature"
+const webhookTimeout = 10 * time.Second
+
+/**
+ * In-App
+ */
+
+type inAppReminderChannel struct{}
+
+func (inAppReminderChannel) Name() string { return "in-app" }
+
+func (inAppReminderChannel) Remind(user entity.User, todo entity.Todo) error {
+	return repo.InsertNotification(&entity.Notification{
+		Title:    todo.Title,
+		NotifyAt: *todo.NotifyAt,
+		UserID:   user.ID,
+		TodoID:   todo.ID,
+	})
+}
+
+/**
+ * Email
+ */
+
+type smtpReminderChannel struct {
+	config otodo.ConfigSMTP
+}
+
+func (smtpReminderChannel) Name() string { return "smtp" }
+
+func (ch smtpReminderChannel) Remind(user entity.User, todo entity.Todo) error {
+	if user.Email == "" {
+		return nil
+	}
+
+	// also avoid header injection, since CR and LF are rejected
+	to, err := mail.ParseAddress(user.Email)
+	if err != nil {
+		return fmt.Errorf("invalid email of user %v: %w", user.ID, err)
+	}
+
+	c := ch.config
+	var auth smtp.Auth
+	if c.UserName != "" {
+		auth = smtp.PlainAuth("", c.UserName, c.Password, c.Host)
+	}
+
+	msg := newReminderEmail(c.From, to, todo)
+	addr := fmt.Sprintf("%v:%v", c.Host, c.Port)
+	return smtp.SendMail(addr, auth, c.From, []string{to.Address}, []byte(msg))
+}
+
+// Message of reminder email, non-ASCII subject is encoded as RFC 2047
+func newReminderEmail(from string, to *mail.Address, todo entity.Todo) string {
+	subject := mime.QEncoding.Encode("utf-8", "Reminder: "+sanitizeHeader(todo.Title))
+	body := todo.Title
+	if todo.Memo != "" {
+		body += "\r\n\r\n" + todo.Memo
+	}
+
+	return "From: " + from + "\r\n" +
+		"To: " + to.String() + "\r\n" +
+		"Subject: " + subject + "\r\n" +
+		"MIME-Version: 1.0\r\n" +
+		"Content-Type: text/plain; charset=UTF-8\r\n" +
+		"\r\n" + body + "\r\n"
+}
+
+// Avoid header injection
+func sanitizeHeader(value string) string {
+	return strings.NewReplacer("\r", " ", "\n", " ").Replace(value)
+}
+
+/**
+ * Webhook
+ */
+
+type webhookReminderChannel struct {
+	config otodo.ConfigWebhook
+}
+
+func (webhookReminderChannel) Name() string { return "webhook" }
+
+func (ch webhookReminderChannel) Remind(user entity.User, todo entity.Todo) error {
+	payload, err := json.Marshal(dto.ReminderWebhookPayload{
+		Event:  "reminder",
+		UserID: user.ID,
+		Todo:   todo,
+	})
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, ch.config.URL, bytes.NewReader(payload))
+	if err != nil {
+		return err
+	}
+
+	req.Header.Set("Content-Type", "application/json")
+	if ch.config.Secret != "" {
+		mac := hmac.New(sha256.New, []byte(ch.config.Secret))
+		mac.Write(payload)
+		req.Header.Set(webhookSignatureHeaderKey, "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+
+	client := http.Client{Timeout: webhookTimeout}
+	res, err := client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer res.Body.Close()
+
+	if res.StatusCode < 200 || res.StatusCode >= 300 {
+		return fmt.Errorf("webhook responds %v", res.Status)
+	}
+
+	return nil
+}
@@ -0,0 +1,55 @@
+package bll
+
+import (
+	"net/mail"
+	"strings"
+	"testing"
+
+	"github.com/yzx9/otodo/model/entity"
+)
+
+// Invalid email is rejected before sending, including header injection
+func TestSMTPReminderChannelInvalidEmail(t *testing.T) {
+	tests := []string{
+		"alice",
+		"alice@example.com\r\nBcc: bob@example.com",
+		"alice@example.com\nBcc: bob@example.com",
+		"alice@example.com, bob@example.com",
+	}
+
+	for _, email := range tests {
+		t.Run(email, func(t *testing.T) {
+			user := entity.User{Email: email}
+			if err := (smtpReminderChannel{}).Remind(user, entity.Todo{Title: "milk"}); err == nil {
+				t.Errorf("Remind() with email %q error = nil, want invalid email", email)
+			}
+		})
+	}
+}
+
+func TestNewReminderEmail(t *testing.T) {
+	tests := []struct {
+		name  string
+		title string
+		want  string
+	}{
+		{"ascii", "buy milk", "Subject: Reminder: buy milk\r\n"},
+		{"non-ascii", "买牛奶", "Subject: =?utf-8?q?Reminder:_=E4=B9=B0=E7=89=9B=E5=A5=B6?=\r\n"},
+		{"header injection", "milk\r\nBcc: bob@example.com", "Subject: Reminder: milk  Bcc: bob@example.com\r\n"},
+	}
+
+	to := &mail.Address{Address: "alice@example.com"}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			msg := newReminderEmail("otodo@example.com", to, entity.Todo{Title: tt.title})
+			header := msg[:strings.Index(msg, "\r\n\r\n")+2]
+			if !strings.Contains(header, tt.want) {
+				t.Errorf("header = %q, want %q", header, tt.want)
+			}
+
+			if !strings.Contains(header, "MIME-Version: 1.0\r\n") {
+				t.Errorf("header = %q, want MIME-Version", header)
+			}
+		})
+	}
+}
@@ -0,0 +1,584 @@
+package bll
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Recurrence of RFC 5545, supports RRULE with FREQ of DAILY, WEEKLY, MONTHLY
+// and YEARLY, and INTERVAL, COUNT, UNTIL, BYDAY, BYMONTHDAY, BYMONTH,
+// BYSETPOS and WKST. DTSTART and EXDATE lines are also supported.
+type recurrence struct {
+	freq       recurrenceFreq
+	interval   int
+	count      int // unlimited if zero
+	until      *time.Time
+	byDay      []recurrenceWeekday
+	byMonthDay []int
+	byMonth    []int
+	bySetPos   []int
+	wkst       time.Weekday
+	dtstart    *time.Time
+	exdates    []recurrenceDate
+}
+
+type recurrenceFreq string
+
+const (
+	recurrenceFreqDaily   recurrenceFreq = "DAILY"
+	recurrenceFreqWeekly  recurrenceFreq = "WEEKLY"
+	recurrenceFreqMonthly recurrenceFreq = "MONTHLY"
+	recurrenceFreqYearly  recurrenceFreq = "YEARLY"
+)
+
+// Weekday with ordinal, e.g. -1FR is the last Friday
+type recurrenceWeekday struct {
+	weekday time.Weekday
+	n       int // every weekday if zero
+}
+
+type recurrenceDate struct {
+	time.Time
+	dateOnly bool
+}
+
+// Max periods to iterate, avoid infinite loop for rules never occur
+const recurrenceMaxPeriods = 100000
+
+// Years to find the first occurrence in when validating, rules occur less
+// often are rejected, e.g. "FREQ=YEARLY;BYMONTH=2;BYMONTHDAY=30"
+const recurrenceCheckYears = 30
+
+var recurrenceWeekdays = map[string]time.Weekday{
+	"SU": time.Sunday,
+	"MO": time.Monday,
+	"TU": time.Tuesday,
+	"WE": time.Wednesday,
+	"TH": time.Thursday,
+	"FR": time.Friday,
+	"SA": time.Saturday,
+}
+
+/**
+ * Parser
+ */
+
+// Parse recurrence, which contains a RRULE and optional DTSTART and EXDATE
+// lines, e.g. "DTSTART:20220101T090000Z\nRRULE:FREQ=MONTHLY;BYDAY=-1FR".
+// Floating times are in loc.
+func parseRecurrence(value string, loc *time.Location) (recurrence, error) {
+	var rec recurrence
+	hasRule := false
+	for _, line := range strings.Split(value, "\n") {
+		line = strings.TrimSpace(line)
+		if line == "" {
+			continue
+		}
+
+		name, params, content := splitContentLine(line)
+		switch name {
+		case "RRULE":
+			if hasRule {
+				return recurrence{}, fmt.Errorf("multiple RRULE")
+			}
+			if err := rec.parseRule(content, loc); err != nil {
+				return recurrence{}, err
+			}
+			hasRule = true
+
+		case "DTSTART":
+			dates, err := parseRecurrenceDates(content, params, loc)
+			if err != nil || len(dates) != 1 {
+				return recurrence{}, fmt.Errorf("invalid DTSTART: %v", content)
+			}
+			rec.dtstart = &dates[0].Time
+
+		case "EXDATE":
+			dates, err := parseRecurrenceDates(content, params, loc)
+			if err != nil {
+				return recurrence{}, fmt.Errorf("invalid EXDATE: %v", content)
+			}
+			rec.exdates = append(rec.exdates, dates...)
+
+		default:
+			return recurrence{}, fmt.Errorf("unsupported property: %v", name)
+		}
+	}
+
+	if !hasRule {
+		return recurrence{}, fmt.Errorf("RRULE required")
+	}
+
+	return rec, nil
+}
+
+// Split content line into name, params and value, e.g.
+// "EXDATE;TZID=Asia/Shanghai:20220101T090000". Line without name is
+// treated as RRULE, e.g. "FREQ=DAILY"
+func splitContentLine(line string) (string, map[string]string, string) {
+	i := strings.Index(line, ":")
+	if i < 0 {
+		return "RRULE", nil, line
+	}
+
+	parts := strings.Split(line[:i], ";")
+	params := make(map[string]string)
+	for _, param := range parts[1:] {
+		if kv := strings.SplitN(param, "=", 2); len(kv) == 2 {
+			params[strings.ToUpper(kv[0])] = kv[1]
+		}
+	}
+
+	return strings.ToUpper(parts[0]), params, line[i+1:]
+}
+
+func (rec *recurrence) parseRule(rule string, loc *time.Location) error {
+	rec.interval = 1
+	rec.wkst = time.Monday
+
+	for _, part := range strings.Split(rule, ";") {
+		kv := strings.SplitN(part, "=", 2)
+		if len(kv) != 2 {
+			return fmt.Errorf("invalid rule part: %v", part)
+		}
+
+		key, value := strings.ToUpper(kv[0]), strings.ToUpper(kv[1])
+		var err error
+		switch key {
+		case "FREQ":
+			rec.freq = recurrenceFreq(value)
+			switch rec.freq {
+			case recurrenceFreqDaily, recurrenceFreqWeekly, recurrenceFreqMonthly, recurrenceFreqYearly:
+			default:
+				return fmt.Errorf("unsupported FREQ: %v", value)
+			}
+
+		case "INTERVAL":
+			rec.interval, err = strconv.Atoi(value)
+			if err == nil && rec.interval < 1 {
+				err = fmt.Errorf("out of range")
+			}
+
+		case "COUNT":
+			rec.count, err = strconv.Atoi(value)
+			if err == nil && rec.count < 1 {
+				err = fmt.Errorf("out of range")
+			}
+
+		case "UNTIL":
+			var dates []recurrenceDate
+			dates, err = parseRecurrenceDates(value, nil, loc)
+			if err == nil {
+				until := dates[0].Time
+				if dates[0].dateOnly {
+					until = until.AddDate(0, 0, 1).Add(-time.Nanosecond) // the whole day
+				}
+				rec.until = &until
+			}
+
+		case "BYDAY":
+			for _, day := range strings.Split(value, ",") {
+				var wd recurrenceWeekday
+				if wd, err = parseRecurrenceWeekday(day); err != nil {
+					break
+				}
+				rec.byDay = append(rec.byDay, wd)
+			}
+
+		case "BYMONTHDAY":
+			rec.byMonthDay, err = parseRecurrenceInts(value, -31, 31)
+
+		case "BYMONTH":
+			rec.byMonth, err = parseRecurrenceInts(value, 1, 12)
+
+		case "BYSETPOS":
+			rec.bySetPos, err = parseRecurrenceInts(value, -366, 366)
+
+		case "WKST":
+			var ok bool
+			if rec.wkst, ok = recurrenceWeekdays[value]; !ok {
+				err = fmt.Errorf("unknown weekday")
+			}
+
+		default:
+			return fmt.Errorf("unsupported rule part: %v", key)
+		}
+
+		if err != nil {
+			return fmt.Errorf("invalid %v: %v", key, value)
+		}
+	}
+
+	if rec.freq == "" {
+		return fmt.Errorf("FREQ required")
+	}
+
+	if rec.count > 0 && rec.until != nil {
+		return fmt.Errorf("COUNT and UNTIL must not occur both")
+	}
+
+	if rec.freq != recurrenceFreqMonthly && rec.freq != recurrenceFreqYearly {
+		for _, wd := range rec.byDay {
+			if wd.n != 0 {
+				return fmt.Errorf("ordinal BYDAY is only allowed in MONTHLY and YEARLY")
+			}
+		}
+	}
+
+	return nil
+}
+
+func parseRecurrenceWeekday(value string) (recurrenceWeekday, error) {
+	if len(value) < 2 {
+		return recurrenceWeekday{}, fmt.Errorf("invalid weekday")
+	}
+
+	weekday, ok := recurrenceWeekdays[value[len(value)-2:]]
+	if !ok {
+		return recurrenceWeekday{}, fmt.Errorf("unknown weekday")
+	}
+
+	n := 0
+	if prefix := value[:len(value)-2]; prefix != "" {
+		var err error
+		if n, err = strconv.Atoi(prefix); err != nil || n == 0 || n < -53 || n > 53 {
+			return recurrenceWeekday{}, fmt.Errorf("invalid ordinal")
+		}
+	}
+
+	return recurrenceWeekday{weekday: weekday, n: n}, nil
+}
+
+// Parse comma separated non-zero integers in range
+func parseRecurrenceInts(value string, min, max int) ([]int, error) {
+	vec := make([]int, 0)
+	for _, s := range strings.Split(value, ",") {
+		n, err := strconv.Atoi(s)
+		if err != nil || n == 0 || n < min || n > max {
+			return nil, fmt.Errorf("out of range")
+		}
+		vec = append(vec, n)
+	}
+	return vec, nil
+}
+
+// Parse comma separated dates or date-times, in UTC if ends with Z,
+// otherwise in TZID or loc
+func parseRecurrenceDates(value string, params map[string]string, loc *time.Location) ([]recurrenceDate, error) {
+	if tzid, ok := params["TZID"]; ok {
+		var err error
+		if loc, err = time.LoadLocation(tzid); err != nil {
+			return nil, err
+		}
+	}
+
+	dates := make([]recurrenceDate, 0)
+	for _, s := range strings.Split(value, ",") {
+		var t time.Time
+		var err error
+		dateOnly := false
+		switch {
+		case strings.HasSuffix(s, "Z"):
+			t, err = time.Parse("20060102T150405Z", s)
+		case strings.Contains(s, "T"):
+			t, err = time.ParseInLocation("20060102T150405", s, loc)
+		default:
+			t, err = time.ParseInLocation("20060102", s, loc)
+			dateOnly = true
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		dates = append(dates, recurrenceDate{Time: t, dateOnly: dateOnly})
+	}
+
+	return dates, nil
+}
+
+/**
+ * Expansion
+ */
+
+// Get the first occurrence after t, series starts at DTSTART if exists,
+// otherwise start. Occurrences keep wall clock of start in loc.
+func (rec recurrence) next(start, t time.Time, loc *time.Location) (time.Time, bool) {
+	return rec.nextInPeriods(start, t, loc, recurrenceMaxPeriods)
+}
+
+// Whether series has any occurrence in years, which starts at DTSTART if
+// exists, otherwise start
+func (rec recurrence) occursInYears(start time.Time, years int, loc *time.Location) bool {
+	periods := years
+	switch rec.freq {
+	case recurrenceFreqDaily:
+		periods = years * 366
+	case recurrenceFreqWeekly:
+		periods = years * 53
+	case recurrenceFreqMonthly:
+		periods = years * 12
+	}
+
+	// one more for the period of start, which may be partial
+	_, ok := rec.nextInPeriods(start, time.Time{}, loc, periods/rec.interval+1)
+	return ok
+}
+
+func (rec recurrence) nextInPeriods(start, t time.Time, loc *time.Location, periods int) (time.Time, bool) {
+	if rec.dtstart != nil {
+		start = *rec.dtstart
+	}
+	start = start.In(loc)
+
+	count := 0
+	for k := 0; k < periods; k++ {
+		for _, occurrence := range rec.expand(start, k, loc) {
+			if occurrence.Before(start) {
+				continue
+			}
+
+			if rec.until != nil && occurrence.After(*rec.until) {
+				return time.Time{}, false
+			}
+
+			count++
+			if rec.count > 0 && count > rec.count {
+				return time.Time{}, false
+			}
+
+			if !rec.isExcluded(occurrence, loc) && occurrence.After(t) {
+				return occurrence, true
+			}
+		}
+	}
+
+	return time.Time{}, false
+}
+
+// Occurrences in the k-th period, sorted
+func (rec recurrence) expand(start time.Time, k int, loc *time.Location) []time.Time {
+	hour, min, sec := start.Clock()
+	at := func(year int, month time.Month, day int) time.Time {
+		return localTime(time.Date(year, month, day, hour, min, sec, 0, time.UTC), loc)
+	}
+
+	days := make([]time.Time, 0)
+	switch rec.freq {
+	case recurrenceFreqDaily:
+		day := at(start.Year(), start.Month(), start.Day()+k*rec.interval)
+		if rec.matchMonth(day.Month()) && rec.matchMonthDay(day) && rec.matchWeekday(day.Weekday()) {
+			days = append(days, day)
+		}
+
+	case recurrenceFreqWeekly:
+		offset := (int(start.Weekday()) - int(rec.wkst) + 7) % 7
+		weekStart := start.Day() - offset + k*rec.interval*7
+		for i := 0; i < 7; i++ {
+			day := at(start.Year(), start.Month(), weekStart+i)
+			match := day.Weekday() == start.Weekday()
+			if len(rec.byDay) != 0 {
+				match = rec.matchWeekday(day.Weekday())
+			}
+			if match && rec.matchMonth(day.Month()) {
+				days = append(days, day)
+			}
+		}
+
+	case recurrenceFreqMonthly:
+		first := at(start.Year(), start.Month()+time.Month(k*rec.interval), 1)
+		if rec.matchMonth(first.Month()) {
+			days = rec.monthDays(first, start.Day(), at)
+		}
+
+	case recurrenceFreqYearly:
+		year := start.Year() + k*rec.interval
+		switch {
+		case len(rec.byMonth) != 0:
+			for _, month := range sortedInts(rec.byMonth) {
+				days = append(days, rec.monthDays(at(year, time.Month(month), 1), start.Day(), at)...)
+			}
+
+		case len(rec.byDay) != 0:
+			days = rec.yearDays(year, at)
+
+		case len(rec.byMonthDay) != 0:
+			for month := time.January; month <= time.December; month++ {
+				days = append(days, rec.monthDays(at(year, month, 1), start.Day(), at)...)
+			}
+
+		default:
+			if day := at(year, start.Month(), start.Day()); day.Day() == start.Day() {
+				days = append(days, day)
+			}
+		}
+	}
+
+	return rec.applySetPos(days)
+}
+
+// Days in month of first, day of start is used if no BYDAY nor BYMONTHDAY
+func (rec recurrence) monthDays(first time.Time, startDay int, at func(int, time.Month, int) time.Time) []time.Time {
+	year, month := first.Year(), first.Month()
+	dim := daysIn(year, month)
+
+	days := make([]time.Time, 0)
+	for day := 1; day <= dim; day++ {
+		t := at(year, month, day)
+		var match bool
+		switch {
+		case len(rec.byDay) == 0 && len(rec.byMonthDay) == 0:
+			match = day == startDay
+
+		case len(rec.byDay) == 0:
+			match = rec.matchMonthDay(t)
+
+		default:
+			match = rec.matchNthWeekday(t, day, dim) && (len(rec.byMonthDay) == 0 || rec.matchMonthDay(t))
+		}
+
+		if match {
+			days = append(days, t)
+		}
+	}
+
+	return days
+}
+
+// Days in year matching BYDAY, ordinal is within year
+func (rec recurrence) yearDays(year int, at func(int, time.Month, int) time.Time) []time.Time {
+	diy := at(year, time.December, 31).YearDay()
+	days := make([]time.Time, 0)
+	for i := 1; i <= diy; i++ {
+		t := at(year, time.January, i)
+		if rec.matchNthWeekday(t, i, diy) && (len(rec.byMonthDay) == 0 || rec.matchMonthDay(t)) {
+			days = append(days, t)
+		}
+	}
+	return days
+}
+
+func (rec recurrence) applySetPos(days []time.Time) []time.Time {
+	if len(rec.bySetPos) == 0 || len(days) == 0 {
+		return days
+	}
+
+	picked := make(map[int]bool)
+	for _, pos := range rec.bySetPos {
+		i := pos - 1
+		if pos < 0 {
+			i = len(days) + pos
+		}
+		if i >= 0 && i < len(days) {
+			picked[i] = true
+		}
+	}
+
+	vec := make([]time.Time, 0, len(picked))
+	for i := range days {
+		if picked[i] {
+			vec = append(vec, days[i])
+		}
+	}
+	return vec
+}
+
+func (rec recurrence) matchMonth(month time.Month) bool {
+	if len(rec.byMonth) == 0 {
+		return true
+	}
+
+	for _, m := range rec.byMonth {
+		if time.Month(m) == month {
+			return true
+		}
+	}
+	return false
+}
+
+func (rec recurrence) matchMonthDay(t time.Time) bool {
+	if len(rec.byMonthDay) == 0 {
+		return true
+	}
+
+	dim := daysIn(t.Year(), t.Month())
+	for _, d := range rec.byMonthDay {
+		if d == t.Day() || dim+d+1 == t.Day() {
+			return true
+		}
+	}
+	return false
+}
+
+func (rec recurrence) matchWeekday(weekday time.Weekday) bool {
+	if len(rec.byDay) == 0 {
+		return true
+	}
+
+	for _, wd := range rec.byDay {
+		if wd.weekday == weekday {
+			return true
+		}
+	}
+	return false
+}
+
+// Match BYDAY with ordinal, i is the index of day in period of size n
+func (rec recurrence) matchNthWeekday(t time.Time, i, n int) bool {
+	for _, wd := range rec.byDay {
+		if wd.weekday != t.Weekday() {
+			continue
+		}
+
+		nth := (i-1)/7 + 1        // nth weekday in period
+		nthLast := -((n-i)/7 + 1) // nth last weekday in period
+		if wd.n == 0 || wd.n == nth || wd.n == nthLast {
+			return true
+		}
+	}
+	return false
+}
+
+func (rec recurrence) isExcluded(t time.Time, loc *time.Location) bool {
+	for _, exdate := range rec.exdates {
+		if exdate.dateOnly {
+			y1, m1, d1 := t.In(loc).Date()
+			y2, m2, d2 := exdate.In(loc).Date()
+			if y1 == y2 && m1 == m2 && d1 == d2 {
+				return true
+			}
+		} else if exdate.Equal(t) {
+			return true
+		}
+	}
+	return false
+}
+
+// Wall clock in loc, which is interpreted with offset before DST gap if not
+// exists, and is the first one if occurs twice, see RFC 5545 section 3.3.5
+func localTime(wall time.Time, loc *time.Location) time.Time {
+	_, before := wall.AddDate(0, 0, -1).In(loc).Zone()
+	_, after := wall.AddDate(0, 0, 1).In(loc).Zone()
+	withBefore := wall.Add(-time.Duration(before) * time.Second).In(loc)
+	withAfter := wall.Add(-time.Duration(after) * time.Second).In(loc)
+
+	exists := func(t time.Time) bool {
+		return t.Hour() == wall.Hour() && t.Minute() == wall.Minute() && t.Second() == wall.Second()
+	}
+	if exists(withBefore) || !exists(withAfter) {
+		return withBefore
+	}
+	return withAfter
+}
+
+func daysIn(year int, month time.Month) int {
+	return time.Date(year, month+1, 0, 0, 0, 0, 0, time.UTC).Day()
+}
+
+func sortedInts(vec []int) []int {
+	sorted := append([]int(nil), vec...)
+	sort.Ints(sorted)
+	return sorted
+}
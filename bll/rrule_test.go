@@ -0,0 +1,238 @@
+package bll
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+// Examples of RFC 5545 section 3.8.5.3, in America/New_York
+func TestRecurrenceNext(t *testing.T) {
+	loc, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	const layout = "20060102T150405"
+	dtstart := func(s string) string {
+		return "DTSTART;TZID=America/New_York:" + s + "\n"
+	}
+
+	tests := []struct {
+		name string
+		rule string
+		more bool // series continues after wanted occurrences
+		want []string
+	}{
+		{
+			name: "daily count",
+			rule: dtstart("19970902T090000") + "RRULE:FREQ=DAILY;COUNT=10",
+			want: []string{
+				"19970902T090000", "19970903T090000", "19970904T090000", "19970905T090000", "19970906T090000",
+				"19970907T090000", "19970908T090000", "19970909T090000", "19970910T090000", "19970911T090000",
+			},
+		},
+		{
+			name: "daily interval",
+			rule: dtstart("19970902T090000") + "RRULE:FREQ=DAILY;INTERVAL=10;COUNT=5",
+			want: []string{"19970902T090000", "19970912T090000", "19970922T090000", "19971002T090000", "19971012T090000"},
+		},
+		{
+			name: "daily by month",
+			rule: dtstart("19980101T090000") + "RRULE:FREQ=DAILY;UNTIL=20000131T140000Z;BYMONTH=1",
+			more: true,
+			want: []string{"19980101T090000", "19980102T090000", "19980103T090000"},
+		},
+		{
+			// 9:00 EDT before and 9:00 EST after Oct 26
+			name: "weekly until across dst",
+			rule: dtstart("19970902T090000") + "RRULE:FREQ=WEEKLY;UNTIL=19971224T000000Z",
+			want: []string{
+				"19970902T090000", "19970909T090000", "19970916T090000", "19970923T090000", "19970930T090000",
+				"19971007T090000", "19971014T090000", "19971021T090000", "19971028T090000", "19971104T090000",
+				"19971111T090000", "19971118T090000", "19971125T090000", "19971202T090000", "19971209T090000",
+				"19971216T090000", "19971223T090000",
+			},
+		},
+		{
+			name: "weekly by day until",
+			rule: dtstart("19970902T090000") + "RRULE:FREQ=WEEKLY;UNTIL=19971007T000000Z;WKST=SU;BYDAY=TU,TH",
+			want: []string{
+				"19970902T090000", "19970904T090000", "19970909T090000", "19970911T090000", "19970916T090000",
+				"19970918T090000", "19970923T090000", "19970925T090000", "19970930T090000", "19971002T090000",
+			},
+		},
+		{
+			name: "every other week by day",
+			rule: dtstart("19970902T090000") + "RRULE:FREQ=WEEKLY;INTERVAL=2;COUNT=8;WKST=SU;BYDAY=TU,TH",
+			want: []string{
+				"19970902T090000", "19970904T090000", "19970916T090000", "19970918T090000",
+				"19970930T090000", "19971002T090000", "19971014T090000", "19971016T090000",
+			},
+		},
+		{
+			name: "wkst monday",
+			rule: dtstart("19970805T090000") + "RRULE:FREQ=WEEKLY;INTERVAL=2;COUNT=4;BYDAY=TU,SU;WKST=MO",
+			want: []string{"19970805T090000", "19970810T090000", "19970819T090000", "19970824T090000"},
+		},
+		{
+			name: "wkst sunday",
+			rule: dtstart("19970805T090000") + "RRULE:FREQ=WEEKLY;INTERVAL=2;COUNT=4;BYDAY=TU,SU;WKST=SU",
+			want: []string{"19970805T090000", "19970817T090000", "19970819T090000", "19970831T090000"},
+		},
+		{
+			name: "monthly first friday",
+			rule: dtstart("19970905T090000") + "RRULE:FREQ=MONTHLY;COUNT=10;BYDAY=1FR",
+			want: []string{
+				"19970905T090000", "19971003T090000", "19971107T090000", "19971205T090000", "19980102T090000",
+				"19980206T090000", "19980306T090000", "19980403T090000", "19980501T090000", "19980605T090000",
+			},
+		},
+		{
+			name: "every other month first and last sunday",
+			rule: dtstart("19970907T090000") + "RRULE:FREQ=MONTHLY;INTERVAL=2;COUNT=10;BYDAY=1SU,-1SU",
+			want: []string{
+				"19970907T090000", "19970928T090000", "19971102T090000", "19971130T090000", "19980104T090000",
+				"19980125T090000", "19980301T090000", "19980329T090000", "19980503T090000", "19980531T090000",
+			},
+		},
+		{
+			name: "monthly second to last monday",
+			rule: dtstart("19970922T090000") + "RRULE:FREQ=MONTHLY;COUNT=6;BYDAY=-2MO",
+			want: []string{
+				"19970922T090000", "19971020T090000", "19971117T090000",
+				"19971222T090000", "19980119T090000", "19980216T090000",
+			},
+		},
+		{
+			name: "monthly third to last day",
+			rule: dtstart("19970928T090000") + "RRULE:FREQ=MONTHLY;BYMONTHDAY=-3",
+			more: true,
+			want: []string{"19970928T090000", "19971029T090000", "19971128T090000", "19971229T090000", "19980129T090000", "19980226T090000"},
+		},
+		{
+			name: "monthly first and last day",
+			rule: dtstart("19970930T090000") + "RRULE:FREQ=MONTHLY;COUNT=10;BYMONTHDAY=1,-1",
+			want: []string{
+				"19970930T090000", "19971001T090000", "19971031T090000", "19971101T090000", "19971130T090000",
+				"19971201T090000", "19971231T090000", "19980101T090000", "19980131T090000", "19980201T090000",
+			},
+		},
+		{
+			name: "monthly invalid dates are ignored",
+			rule: dtstart("20070115T090000") + "RRULE:FREQ=MONTHLY;BYMONTHDAY=15,30;COUNT=5",
+			want: []string{"20070115T090000", "20070130T090000", "20070215T090000", "20070315T090000", "20070330T090000"},
+		},
+		{
+			name: "friday the 13th",
+			rule: dtstart("19970902T090000") + "EXDATE;TZID=America/New_York:19970902T090000\n" +
+				"RRULE:FREQ=MONTHLY;BYDAY=FR;BYMONTHDAY=13",
+			more: true,
+			want: []string{"19980213T090000", "19980313T090000", "19981113T090000", "19990813T090000", "20001013T090000"},
+		},
+		{
+			name: "first saturday after first sunday",
+			rule: dtstart("19970913T090000") + "RRULE:FREQ=MONTHLY;BYDAY=SA;BYMONTHDAY=7,8,9,10,11,12,13",
+			more: true,
+			want: []string{"19970913T090000", "19971011T090000", "19971108T090000", "19971213T090000", "19980110T090000"},
+		},
+		{
+			name: "third of tuesday to thursday",
+			rule: dtstart("19970904T090000") + "RRULE:FREQ=MONTHLY;COUNT=3;BYDAY=TU,WE,TH;BYSETPOS=3",
+			want: []string{"19970904T090000", "19971007T090000", "19971106T090000"},
+		},
+		{
+			name: "second to last weekday",
+			rule: dtstart("19970929T090000") + "RRULE:FREQ=MONTHLY;BYDAY=MO,TU,WE,TH,FR;BYSETPOS=-2",
+			more: true,
+			want: []string{"19970929T090000", "19971030T090000", "19971127T090000", "19971230T090000", "19980129T090000"},
+		},
+		{
+			name: "yearly by month",
+			rule: dtstart("19970610T090000") + "RRULE:FREQ=YEARLY;COUNT=10;BYMONTH=6,7",
+			want: []string{
+				"19970610T090000", "19970710T090000", "19980610T090000", "19980710T090000", "19990610T090000",
+				"19990710T090000", "20000610T090000", "20000710T090000", "20010610T090000", "20010710T090000",
+			},
+		},
+		{
+			name: "yearly twentieth monday",
+			rule: dtstart("19970519T090000") + "RRULE:FREQ=YEARLY;BYDAY=20MO",
+			more: true,
+			want: []string{"19970519T090000", "19980518T090000", "19990517T090000"},
+		},
+		{
+			name: "election day",
+			rule: dtstart("19961105T090000") + "RRULE:FREQ=YEARLY;INTERVAL=4;BYMONTH=11;BYDAY=TU;BYMONTHDAY=2,3,4,5,6,7,8",
+			more: true,
+			want: []string{"19961105T090000", "20001107T090000", "20041102T090000"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec, err := parseRecurrence(tt.rule, loc)
+			if err != nil {
+				t.Fatalf("parseRecurrence() error = %v", err)
+			}
+
+			n := len(tt.want)
+			if !tt.more {
+				n++ // series ends
+			}
+
+			got := make([]string, 0)
+			start := *rec.dtstart
+			for after := start.Add(-time.Second); len(got) < n; {
+				next, ok := rec.next(start, after, loc)
+				if !ok {
+					break
+				}
+				got = append(got, next.In(loc).Format(layout))
+				after = next
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("occurrences = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// Nonexistent local time is shifted by the gap, and ambiguous one is the
+// first, see RFC 5545 section 3.3.5
+func TestRecurrenceNextInDSTTransition(t *testing.T) {
+	tests := []struct {
+		name     string
+		location string
+		dtstart  string
+		after    string
+		want     string
+	}{
+		{"gap", "America/New_York", "20200307T023000", "20200307T023000", "20200308T033000-0400"},
+		{"after gap", "America/New_York", "20200307T023000", "20200308T033000", "20200309T023000-0400"},
+		{"ambiguous", "America/New_York", "20201031T013000", "20201031T013000", "20201101T013000-0400"},
+		{"southern gap", "Australia/Sydney", "20201003T023000", "20201003T023000", "20201004T033000+1100"},
+		{"southern ambiguous", "Australia/Sydney", "20200404T023000", "20200404T023000", "20200405T023000+1100"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			loc, err := time.LoadLocation(tt.location)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			rec, err := parseRecurrence("DTSTART:"+tt.dtstart+"\nRRULE:FREQ=DAILY", loc)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			after, _ := time.ParseInLocation("20060102T150405", tt.after, loc)
+			next, ok := rec.next(*rec.dtstart, after, loc)
+			if got := next.In(loc).Format("20060102T150405-0700"); !ok || got != tt.want {
+				t.Errorf("next(%v) = %v, want %v", tt.after, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,344 @@
+package bll
+
+import (
+	"fmt"
+	"html"
+	"math"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/yzx9/otodo/model/dto"
+	"github.com/yzx9/otodo/model/entity"
+	"github.com/yzx9/otodo/util"
+)
+
+const searchCandidateLimit = 1000 // candidates to rank
+const searchSnippetRadius = 40    // runes around first match in snippet
+
+var searchFieldWeights = map[dto.TodoSearchField]float64{
+	dto.TodoSearchFieldTitle: 4,
+	dto.TodoSearchFieldStep:  2,
+	dto.TodoSearchFieldFile:  2,
+	dto.TodoSearchFieldMemo:  1,
+}
+
+// Search todos in owned and shared todo lists, and todos shared directly,
+// ordered by relevance.
+// Supported operators: `#tag`, `is:done`, `is:undone`, `is:important`,
+// `list:"name"` and `due:<date` (also `<=`, `>`, `>=` and `=`)
+func SearchTodos(userID int64, q string, limit int) ([]dto.TodoSearchResult, error) {
+	lists, err := GetTodoLists(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := GetUser(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	sharedIDs, err := repo.SelectSharedTodoIDs(userID)
+	if err != nil {
+		return nil, fmt.Errorf("fails to get shared todos: %w", err)
+	}
+
+	search, err := parseTodoSearch(q, lists, sharedIDs, getUserLocation(user))
+	if err != nil {
+		return nil, err
+	}
+
+	search.TagUserID = userID
+	search.Limit = searchCandidateLimit
+	todos, err := repo.SearchTodos(search)
+	if err != nil {
+		return nil, fmt.Errorf("fails to search todos: %w", err)
+	}
+
+	results := make([]dto.TodoSearchResult, 0, len(todos))
+	for i := range todos {
+		results = append(results, rankTodo(todos[i], search.Terms))
+	}
+
+	// candidates are newest first, keep it for same score
+	sort.SliceStable(results, func(i, j int) bool { return results[i].Score > results[j].Score })
+	if limit > 0 && len(results) > limit {
+		results = results[:limit]
+	}
+
+	return results, nil
+}
+
+/**
+ * Parser
+ */
+
+func parseTodoSearch(q string, lists []entity.TodoList, sharedIDs []int64, loc *time.Location) (dto.TodoSearch, error) {
+	search := dto.TodoSearch{SharedIDs: sharedIDs}
+	listIDs := make([]int64, 0, len(lists))
+	for i := range lists {
+		listIDs = append(listIDs, lists[i].ID)
+	}
+
+	yes, no := true, false
+	for _, token := range tokenizeSearch(q) {
+		if token.quoted {
+			search.Terms = append(search.Terms, token.text)
+			continue
+		}
+
+		text := token.text
+		switch {
+		case strings.HasPrefix(text, "#") && len(text) > 1:
+			search.Tags = append(search.Tags, text[1:])
+
+		case text == "is:done":
+			search.Done = &yes
+
+		case text == "is:undone":
+			search.Done = &no
+
+		case text == "is:important":
+			search.Importance = &yes
+
+		case strings.HasPrefix(text, "list:"):
+			name := strings.TrimPrefix(text, "list:")
+			vec := make([]int64, 0)
+			for i := range lists {
+				if strings.EqualFold(lists[i].Name, name) && containsID(listIDs, lists[i].ID) {
+					vec = append(vec, lists[i].ID)
+				}
+			}
+			listIDs = vec
+			search.SharedIDs = nil // not in todo lists of user
+
+		case strings.HasPrefix(text, "due:"):
+			if err := parseSearchDue(&search, strings.TrimPrefix(text, "due:"), loc); err != nil {
+				return dto.TodoSearch{}, err
+			}
+
+		default:
+			search.Terms = append(search.Terms, text)
+		}
+	}
+
+	search.TodoListIDs = listIDs
+	return search, nil
+}
+
+type searchToken struct {
+	text   string
+	quoted bool // quoted as a phrase, e.g. "buy milk"
+}
+
+// Split by spaces, except in quotes
+func tokenizeSearch(q string) []searchToken {
+	tokens := make([]searchToken, 0)
+	var sb strings.Builder
+	var token searchToken
+	inQuote := false
+	flush := func() {
+		token.text = strings.TrimSpace(sb.String())
+		if token.text != "" {
+			tokens = append(tokens, token)
+		}
+		sb.Reset()
+		token = searchToken{}
+	}
+
+	for _, r := range q {
+		switch {
+		case r == '"':
+			if !inQuote && sb.Len() == 0 {
+				token.quoted = true
+			}
+			inQuote = !inQuote
+
+		case unicode.IsSpace(r) && !inQuote:
+			flush()
+
+		default:
+			sb.WriteRune(r)
+		}
+	}
+	flush()
+
+	return tokens
+}
+
+// Parse `due:<2022-01-01`, date is in user location
+func parseSearchDue(search *dto.TodoSearch, value string, loc *time.Location) error {
+	op := ""
+	for _, prefix := range []string{"<=", ">=", "<", ">", "="} {
+		if strings.HasPrefix(value, prefix) {
+			op = prefix
+			value = strings.TrimPrefix(value, prefix)
+			break
+		}
+	}
+
+	date, err := time.ParseInLocation("2006-01-02", value, loc)
+	if err != nil {
+		return util.NewErrorWithBadRequest("invalid due date: %v", value)
+	}
+
+	next := date.AddDate(0, 0, 1)
+	switch op {
+	case "<":
+		search.DeadlineTo = &date
+	case "<=":
+		search.DeadlineTo = &next
+	case ">":
+		search.DeadlineFrom = &next
+	case ">=":
+		search.DeadlineFrom = &date
+	default:
+		search.DeadlineFrom = &date
+		search.DeadlineTo = &next
+	}
+
+	return nil
+}
+
+/**
+ * Ranking
+ */
+
+// Score by weighted occurrences of terms, and highlight matched fields
+func rankTodo(todo entity.Todo, terms []string) dto.TodoSearchResult {
+	result := dto.TodoSearchResult{Todo: todo, Highlights: make([]dto.TodoSearchHighlight, 0)}
+	if len(terms) == 0 {
+		return result
+	}
+
+	add := func(field dto.TodoSearchField, text string) {
+		matches := findSearchMatches([]rune(text), terms)
+		if len(matches) == 0 {
+			return
+		}
+
+		result.Score += searchFieldWeights[field] * (1 + math.Log(float64(len(matches))))
+		result.Highlights = append(result.Highlights, dto.TodoSearchHighlight{
+			Field:   field,
+			Snippet: highlightSearchMatches([]rune(text), matches),
+		})
+	}
+
+	add(dto.TodoSearchFieldTitle, todo.Title)
+	add(dto.TodoSearchFieldMemo, todo.Memo)
+	for _, step := range todo.Steps {
+		add(dto.TodoSearchFieldStep, step.Name)
+	}
+	for _, file := range todo.Files {
+		add(dto.TodoSearchFieldFile, file.FileName)
+	}
+
+	// exact title is the best
+	if strings.EqualFold(strings.TrimSpace(todo.Title), strings.Join(terms, " ")) {
+		result.Score += searchFieldWeights[dto.TodoSearchFieldTitle]
+	}
+
+	return result
+}
+
+// Find case-insensitive matches of terms, returns sorted and non-overlapping ranges
+func findSearchMatches(text []rune, terms []string) [][2]int {
+	lower := toLowerRunes(text)
+	matches := make([][2]int, 0)
+	for _, term := range terms {
+		t := toLowerRunes([]rune(term))
+		if len(t) == 0 {
+			continue
+		}
+
+		for i := 0; i+len(t) <= len(lower); i++ {
+			if string(lower[i:i+len(t)]) == string(t) {
+				matches = append(matches, [2]int{i, i + len(t)})
+				i += len(t) - 1
+			}
+		}
+	}
+
+	sort.Slice(matches, func(i, j int) bool { return matches[i][0] < matches[j][0] })
+	merged := make([][2]int, 0, len(matches))
+	for _, m := range matches {
+		if n := len(merged); n > 0 && m[0] <= merged[n-1][1] {
+			if m[1] > merged[n-1][1] {
+				merged[n-1][1] = m[1]
+			}
+			continue
+		}
+		merged = append(merged, m)
+	}
+
+	return merged
+}
+
+// Snippet around first match, escaped and matches are wrapped in <mark>
+func highlightSearchMatches(text []rune, matches [][2]int) string {
+	start, end := 0, len(text)
+	if s := matches[0][0] - searchSnippetRadius; s > 0 {
+		start = s
+	}
+	if e := matches[0][1] + searchSnippetRadius; e < end {
+		end = e
+	}
+
+	var sb strings.Builder
+	if start > 0 {
+		sb.WriteString("…")
+	}
+
+	pos := start
+	for _, m := range matches {
+		if m[1] <= start || m[0] >= end {
+			continue
+		}
+
+		from, to := maxInt(m[0], start), minInt(m[1], end)
+		sb.WriteString(html.EscapeString(string(text[pos:from])))
+		sb.WriteString("<mark>")
+		sb.WriteString(html.EscapeString(string(text[from:to])))
+		sb.WriteString("</mark>")
+		pos = to
+	}
+	sb.WriteString(html.EscapeString(string(text[pos:end])))
+
+	if end < len(text) {
+		sb.WriteString("…")
+	}
+
+	return sb.String()
+}
+
+func toLowerRunes(s []rune) []rune {
+	lower := make([]rune, len(s))
+	for i, r := range s {
+		lower[i] = unicode.ToLower(r)
+	}
+	return lower
+}
+
+func containsID(ids []int64, id int64) bool {
+	for _, v := range ids {
+		if v == id {
+			return true
+		}
+	}
+	return false
+}
+
+func minInt(a, b int) int {
+	if a < b {
+		return a
+	}
+	return b
+}
+
+func maxInt(a, b int) int {
+	if a > b {
+		return a
+	}
+	return b
+}
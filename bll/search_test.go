@@ -0,0 +1,39 @@
+package bll
+
+import (
+	"testing"
+
+	"github.com/yzx9/otodo/model/dto"
+	"github.com/yzx9/otodo/model/entity"
+)
+
+// Todos shared directly are found, as todos in shared todo lists
+func TestSearchSharedTodos(t *testing.T) {
+	useMemoryRepository(t)
+	alice := createTestUser(t, "alice")
+	bob := createTestUser(t, "bob")
+	todo := createTestTodo(t, alice.ID, entity.Todo{Title: "buy milk", TodoListID: alice.BasicTodoListID})
+	createTestTodo(t, alice.ID, entity.Todo{Title: "milk tea", TodoListID: alice.BasicTodoListID})
+	sharing, err := CreateTodoSharing(alice.ID, todo.ID, dto.SharingDTO{})
+	must(t, err)
+	must(t, CreateTodoSharedUser(bob.ID, sharing.Token))
+	must(t, updateUserTags(repo, bob.ID, todo.ID, map[string]bool{"dairy": true}))
+
+	results, err := SearchTodos(bob.ID, "milk", 0)
+	must(t, err)
+	if len(results) != 1 || results[0].Todo.ID != todo.ID {
+		t.Errorf("search results = %+v, want shared todo only", results)
+	}
+
+	results, err = SearchTodos(bob.ID, "milk list:Inbox", 0)
+	must(t, err)
+	if len(results) != 0 {
+		t.Errorf("search results in todo list = %+v, want none", results)
+	}
+
+	page, err := GetTagTodos(bob.ID, "dairy", dto.TodoQuery{})
+	must(t, err)
+	if len(page.Todos) != 1 || page.Todos[0].ID != todo.ID {
+		t.Errorf("tag todos = %+v, want shared todo", page.Todos)
+	}
+}
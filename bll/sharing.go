@@ -3,18 +3,29 @@ package bll
 import (
 	"encoding/base64"
 	"fmt"
+	"sort"
+	"time"
+	"unicode/utf8"
 
 	"github.com/google/uuid"
 	"github.com/yzx9/otodo/dal"
+	"github.com/yzx9/otodo/model/dto"
 	"github.com/yzx9/otodo/model/entity"
 	"github.com/yzx9/otodo/util"
 )
 
+const sharingLabelMaxLength = 64 // size of label column
+
 /**
  * oTodo List Sharing
  */
-func CreateTodoListSharing(userID, todoListID int64) (entity.Sharing, error) {
-	todoList, err := OwnTodoList(userID, todoListID)
+// Create sharing token for todo list, users joined by token have role.
+// Managers are able to invite, but only owner is able to invite managers
+// or publish todo list to anyone. A todo list can have multiple active
+// tokens, each of them optionally expires or limits the number of users
+// joining by it
+func CreateTodoListSharing(userID, todoListID int64, payload dto.SharingDTO) (entity.Sharing, error) {
+	todoList, userRole, err := accessTodoList(repo, userID, todoListID, entity.TodoListRoleManager)
 	if err != nil {
 		return entity.Sharing{}, err
 	}
@@ -23,80 +34,295 @@ func CreateTodoListSharing(userID, todoListID int64) (entity.Sharing, error) {
 		return entity.Sharing{}, fmt.Errorf("unable to share basic todo list: %v", todoListID)
 	}
 
-	// Only allow one sharing active
-	if _, err = dal.DeleteSharings(userID, entity.SharingTypeTodoList); err != nil {
-		return entity.Sharing{}, fmt.Errorf("fails to delete old sharing tokens: %w", err)
+	if payload.Type == 0 {
+		payload.Type = entity.SharingTypeTodoList
 	}
 
-	sharing := entity.Sharing{
-		Token:     newSharingToken(),
-		Active:    true,
-		Type:      entity.SharingTypeTodoList,
-		RelatedID: todoListID,
-		UserID:    userID,
+	sharing, err := newSharing(userID, todoListID, payload)
+	if err != nil {
+		return entity.Sharing{}, err
+	}
+
+	switch sharing.Type {
+	case entity.SharingTypeTodoList:
+		if err := setSharingRole(&sharing, userRole, payload); err != nil {
+			return entity.Sharing{}, err
+		}
+
+	case entity.SharingTypeTodoListPublish:
+		if userRole != entity.TodoListRoleOwner {
+			return entity.Sharing{}, insufficientTodoListRole(todoListID)
+		}
+
+		sharing.Role = entity.TodoListRoleViewer
+		sharing.PublishMemo = payload.PublishMemo
+		sharing.PublishFiles = payload.PublishFiles
+
+	default:
+		return entity.Sharing{}, util.NewErrorWithBadRequest("unsupported sharing type: %v", payload.Type)
 	}
-	if err := dal.InsertSharing(&sharing); err != nil {
+
+	if err := repo.InsertSharing(&sharing); err != nil {
 		return entity.Sharing{}, fmt.Errorf("fails to create sharing token: %w", err)
 	}
 
 	return sharing, nil
 }
 
-func GetActiveTodoListSharings(userID, todoListID int64) ([]entity.Sharing, error) {
-	sharings, err := dal.SelectActiveSharings(userID, entity.SharingTypeTodoList)
+// Get active sharing tokens of todo list with usage, by managers only
+func GetActiveTodoListSharings(userID, todoListID int64) ([]dto.SharingTokenStats, error) {
+	if _, err := AccessTodoList(userID, todoListID, entity.TodoListRoleManager); err != nil {
+		return nil, err
+	}
+
+	return getActiveSharings(todoListID, entity.SharingTypeTodoList, entity.SharingTypeTodoListPublish)
+}
+
+// Inactive sharing token, by creator or managers of shared resource
+func DeleteSharing(userID int64, token string) error {
+	sharing, err := repo.SelectSharing(token)
 	if err != nil {
-		return nil, fmt.Errorf("fails to get sharing tokens: %w", err)
+		return fmt.Errorf("invalid sharing token: %w", err)
 	}
 
-	vec := make([]entity.Sharing, 0)
-	for i := range sharings {
-		if sharings[i].RelatedID == todoListID {
-			vec = append(vec, sharings[i])
+	if sharing.UserID != userID {
+		if err := checkSharingManager(userID, sharing); err != nil {
+			return util.NewErrorWithForbidden("unable to delete non-own sharing token")
 		}
 	}
 
-	return vec, nil
+	if !sharing.Active {
+		return nil
+	}
+
+	sharing.Active = false
+	if err := repo.SaveSharing(&sharing); err != nil {
+		return fmt.Errorf("fails to delete sharing: %w", err)
+	}
+
+	return nil
 }
 
-func DeleteTodoListSharing(userID int64, token string) error {
+/**
+ * Published Todo List
+ */
+
+// Get todos and steps of published todo list in order of owner, memos and
+// files are hidden unless opted in. No account is required
+func GetPublishedTodoList(token string) (dto.PublishedTodoList, error) {
 	sharing, err := ValidSharing(token)
 	if err != nil {
-		return err
+		return dto.PublishedTodoList{}, err
 	}
 
-	if sharing.Type != entity.SharingTypeTodoList {
-		return util.NewErrorWithForbidden("invalid sharing token: %v")
+	if sharing.Type != entity.SharingTypeTodoListPublish {
+		return dto.PublishedTodoList{}, util.NewErrorWithForbidden("todo list not published: %v", token)
 	}
 
-	if sharing.UserID != userID {
-		return util.NewErrorWithForbidden("unable to delete non-own sharing token")
+	todoList, err := ForceGetTodoList(sharing.RelatedID)
+	if err != nil {
+		return dto.PublishedTodoList{}, err
 	}
 
-	sharing.Active = false
-	if err := dal.SaveSharing(&sharing); err != nil {
-		return fmt.Errorf("fails to delete sharing: %w", err)
+	owner, err := GetUser(todoList.UserID)
+	if err != nil {
+		return dto.PublishedTodoList{}, err
 	}
 
-	return nil
+	page, err := ForceGetTodos(owner.ID, todoList.ID, dto.TodoQuery{Sort: dto.TodoSortRank})
+	if err != nil {
+		return dto.PublishedTodoList{}, err
+	}
+
+	todos := make([]dto.PublishedTodo, 0, len(page.Todos))
+	for _, todo := range page.Todos {
+		published := dto.PublishedTodo{
+			Title:      todo.Title,
+			Importance: todo.Importance,
+			Deadline:   todo.Deadline,
+			Done:       todo.Done,
+			DoneAt:     todo.DoneAt,
+			Steps:      make([]dto.PublishedTodoStep, 0, len(todo.Steps)),
+		}
+
+		for _, step := range todo.Steps {
+			published.Steps = append(published.Steps, dto.PublishedTodoStep{Name: step.Name, Done: step.Done})
+		}
+
+		if sharing.PublishMemo {
+			published.Memo = todo.Memo
+		}
+
+		if sharing.PublishFiles {
+			for _, file := range todo.Files {
+				// signed by owner, who is able to access files
+				id, err := CreateFilePreSignID(owner.ID, file.ID)
+				if err != nil {
+					return dto.PublishedTodoList{}, err
+				}
+				published.Files = append(published.Files, dto.PublishedFile{FileID: id, FileName: file.FileName})
+			}
+		}
+
+		todos = append(todos, published)
+	}
+
+	return dto.PublishedTodoList{
+		TodoListName: todoList.Name,
+		UserNickname: owner.Nickname,
+		Todos:        todos,
+	}, nil
 }
 
 func ValidSharing(token string) (entity.Sharing, error) {
-	sharing, err := dal.SelectSharing(token)
+	sharing, err := repo.SelectSharing(token)
 	if err != nil {
 		return entity.Sharing{}, fmt.Errorf("invalid sharing token: %w", err)
 	}
 
-	if !sharing.Active {
-		return entity.Sharing{}, util.NewErrorWithForbidden("sharing token has been inactive: %v", token)
+	if err := checkSharingRedeemable(repo, sharing); err != nil {
+		return entity.Sharing{}, err
 	}
 
 	return sharing, nil
 }
 
+// Check if sharing is active, not expired, and not used up
+func checkSharingRedeemable(r dal.Repository, sharing entity.Sharing) error {
+	if !sharing.Active {
+		return util.NewErrorWithForbidden("sharing token has been inactive: %v", sharing.Token)
+	}
+
+	if sharing.ExpiresAt != nil && !sharing.ExpiresAt.After(time.Now()) {
+		return util.NewErrorWithForbidden("sharing token has expired: %v", sharing.Token)
+	}
+
+	if sharing.MaxRedemptions == 0 {
+		return nil
+	}
+
+	count, err := r.CountSharingRedemptions(sharing.ID)
+	if err != nil {
+		return fmt.Errorf("fails to get sharing redemptions: %w", err)
+	}
+
+	if count >= sharing.MaxRedemptions {
+		return util.NewErrorWithForbidden("sharing token has been used up: %v", sharing.Token)
+	}
+
+	return nil
+}
+
+// Record that user joins by sharing token, which should be redeemable
+func redeemSharing(r dal.Repository, userID int64, sharing entity.Sharing) error {
+	if err := checkSharingRedeemable(r, sharing); err != nil {
+		return err
+	}
+
+	redemption := entity.SharingRedemption{SharingID: sharing.ID, UserID: userID}
+	if err := r.InsertSharingRedemption(&redemption); err != nil {
+		return fmt.Errorf("fails to create sharing redemption: %w", err)
+	}
+
+	return nil
+}
+
 /**
  * common
  */
 
+// Sharing token of payload related to resource, label and expiry are checked
+func newSharing(userID, relatedID int64, payload dto.SharingDTO) (entity.Sharing, error) {
+	if utf8.RuneCountInString(payload.Label) > sharingLabelMaxLength {
+		return entity.Sharing{}, util.NewErrorWithBadRequest("label too long, max %v", sharingLabelMaxLength)
+	}
+
+	if payload.ExpiresAt != nil && !payload.ExpiresAt.After(time.Now()) {
+		return entity.Sharing{}, util.NewErrorWithBadRequest("expiry time has passed: %v", payload.ExpiresAt)
+	}
+
+	return entity.Sharing{
+		Token:     newSharingToken(),
+		Active:    true,
+		Type:      payload.Type,
+		RelatedID: relatedID,
+		Label:     payload.Label,
+		ExpiresAt: payload.ExpiresAt,
+		UserID:    userID,
+	}, nil
+}
+
+// Set role and max redemptions of sharing token which users join by
+func setSharingRole(sharing *entity.Sharing, operatorRole entity.TodoListRole, payload dto.SharingDTO) error {
+	role, err := getInvitationRole(operatorRole, payload.Role)
+	if err != nil {
+		return err
+	}
+
+	if payload.MaxRedemptions < 0 {
+		return util.NewErrorWithBadRequest("invalid max redemptions: %v", payload.MaxRedemptions)
+	}
+
+	sharing.Role = role
+	sharing.MaxRedemptions = payload.MaxRedemptions
+	return nil
+}
+
+// Active sharing tokens of types related to resource with usage, ordered
+// by id
+func getActiveSharings(relatedID int64, sharingTypes ...entity.SharingType) ([]dto.SharingTokenStats, error) {
+	sharings := make([]entity.Sharing, 0)
+	for _, sharingType := range sharingTypes {
+		vec, err := repo.SelectActiveRelatedSharings(sharingType, relatedID)
+		if err != nil {
+			return nil, fmt.Errorf("fails to get sharing tokens: %w", err)
+		}
+		sharings = append(sharings, vec...)
+	}
+	sort.Slice(sharings, func(i, j int) bool { return sharings[i].ID < sharings[j].ID })
+
+	vec := make([]dto.SharingTokenStats, 0, len(sharings))
+	for i := range sharings {
+		redemptions, err := repo.SelectSharingRedemptions(sharings[i].ID)
+		if err != nil {
+			return nil, fmt.Errorf("fails to get sharing redemptions: %w", err)
+		}
+
+		stats := dto.SharingTokenStats{
+			SharingToken:    dto.NewSharingToken(sharings[i]),
+			UserID:          sharings[i].UserID,
+			RedemptionCount: int64(len(redemptions)),
+			Redemptions:     make([]dto.SharingRedemption, 0, len(redemptions)),
+		}
+		for _, redemption := range redemptions {
+			stats.Redemptions = append(stats.Redemptions, dto.SharingRedemption{
+				UserID:       redemption.UserID,
+				UserNickname: redemption.User.Nickname,
+				RedeemedAt:   redemption.CreatedAt,
+			})
+		}
+		vec = append(vec, stats)
+	}
+
+	return vec, nil
+}
+
+// Check if user is manager of the resource shared by token
+func checkSharingManager(userID int64, sharing entity.Sharing) error {
+	var err error
+	switch sharing.Type {
+	case entity.SharingTypeTodoList, entity.SharingTypeTodoListPublish:
+		_, err = AccessTodoList(userID, sharing.RelatedID, entity.TodoListRoleManager)
+	case entity.SharingTypeTodoListFolder:
+		_, err = AccessTodoListFolder(userID, sharing.RelatedID, entity.TodoListRoleManager)
+	case entity.SharingTypeTodo:
+		_, err = AccessTodo(userID, sharing.RelatedID, entity.TodoListRoleManager)
+	default:
+		err = util.NewErrorWithForbidden("unsupported sharing type: %v", sharing.Type)
+	}
+	return err
+}
+
 func newSharingToken() string {
 	return base64.RawStdEncoding.EncodeToString([]byte(uuid.NewString()))
 }
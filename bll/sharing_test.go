@@ -0,0 +1,90 @@
+package bll
+
+import (
+	"testing"
+
+	"github.com/yzx9/otodo/model/dto"
+	"github.com/yzx9/otodo/model/entity"
+	"github.com/yzx9/otodo/otodo"
+	"github.com/yzx9/otodo/util"
+)
+
+// Memos and files of published todo list are hidden unless opted in
+func TestGetPublishedTodoList(t *testing.T) {
+	tests := []struct {
+		name    string
+		payload dto.SharingDTO
+	}{
+		{"hidden", dto.SharingDTO{}},
+		{"memo", dto.SharingDTO{PublishMemo: true}},
+		{"files", dto.SharingDTO{PublishFiles: true}},
+		{"memo and files", dto.SharingDTO{PublishMemo: true, PublishFiles: true}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useMemoryRepository(t)
+			user := createTestUser(t, "alice")
+			todoList := createTestTodoList(t, user.ID, "list")
+			todo := createTestTodo(t, user.ID, entity.Todo{Title: "milk", Memo: "2 bottles", TodoListID: todoList.ID})
+			file := entity.File{FileName: "receipt.png", AccessType: int8(entity.FileTypeTodo), RelatedID: todo.ID}
+			must(t, repo.InsertFile(&file))
+			must(t, repo.InsertTodoFile(todo.ID, file.ID))
+
+			tt.payload.Type = entity.SharingTypeTodoListPublish
+			sharing, err := CreateTodoListSharing(user.ID, todoList.ID, tt.payload)
+			must(t, err)
+
+			published, err := GetPublishedTodoList(sharing.Token)
+			must(t, err)
+			if len(published.Todos) != 1 || published.Todos[0].Title != todo.Title {
+				t.Fatalf("published todos = %+v, want %v", published.Todos, todo.Title)
+			}
+
+			got := published.Todos[0]
+			if hasMemo := got.Memo != ""; hasMemo != tt.payload.PublishMemo {
+				t.Errorf("published memo = %q, want published %v", got.Memo, tt.payload.PublishMemo)
+			}
+
+			if hasFiles := len(got.Files) != 0; hasFiles != tt.payload.PublishFiles {
+				t.Errorf("published files = %+v, want published %v", got.Files, tt.payload.PublishFiles)
+			}
+		})
+	}
+}
+
+// Publish token is read-only, which is unable to join todo list by
+func TestCreateTodoListSharedUserByPublishToken(t *testing.T) {
+	useMemoryRepository(t)
+	alice := createTestUser(t, "alice")
+	bob := createTestUser(t, "bob")
+	todoList := createTestTodoList(t, alice.ID, "list")
+	sharing, err := CreateTodoListSharing(alice.ID, todoList.ID, dto.SharingDTO{Type: entity.SharingTypeTodoListPublish})
+	must(t, err)
+
+	if err := CreateTodoListSharedUser(bob.ID, sharing.Token); !util.IsErrorCode(err, otodo.ErrForbidden) {
+		t.Fatalf("join by publish token error = %v, want forbidden", err)
+	}
+
+	if _, err := OwnOrSharedTodoList(bob.ID, todoList.ID); err == nil {
+		t.Error("user joined todo list by publish token")
+	}
+
+	if err := CreateTodoSharedUser(bob.ID, sharing.Token); !util.IsErrorCode(err, otodo.ErrForbidden) {
+		t.Errorf("join todo by publish token error = %v, want forbidden", err)
+	}
+}
+
+// Only owner is able to publish todo list
+func TestCreateTodoListSharingPublishByManager(t *testing.T) {
+	useMemoryRepository(t)
+	alice := createTestUser(t, "alice")
+	bob := createTestUser(t, "bob")
+	todoList := createTestTodoList(t, alice.ID, "list")
+	joinTestTodoList(t, bob.ID, todoList.ID, entity.TodoListRoleManager)
+
+	_, err := CreateTodoListSharing(bob.ID, todoList.ID, dto.SharingDTO{Type: entity.SharingTypeTodoListPublish})
+	if !util.IsErrorCode(err, otodo.ErrForbidden) {
+		t.Errorf("publish by manager error = %v, want forbidden", err)
+	}
+}
@@ -3,41 +3,409 @@ package bll
 import (
 	"fmt"
 	"regexp"
+	"sort"
 	"strings"
+	"unicode"
+	"unicode/utf8"
 
 	"github.com/yzx9/otodo/dal"
+	"github.com/yzx9/otodo/model/dto"
 	"github.com/yzx9/otodo/model/entity"
+	"github.com/yzx9/otodo/otodo"
 	"github.com/yzx9/otodo/util"
 )
 
-// Update tag, should be called with `go UpdateTagAsync()`
-func UpdateTag(todo *entity.Todo, oldTodoTitle string) error {
-	// TODO[bug]: handle error
-	if todo.Title == oldTodoTitle {
+const tagNameMaxLength = 32 // in runes, same as size of column
+
+var tagColorRegex = regexp.MustCompile(`^#([0-9a-fA-F]{3}|[0-9a-fA-F]{6})$`)
+
+// Tags of user with count of todos in owned and shared todo lists
+func GetTags(userID int64) ([]dto.TagSummary, error) {
+	listIDs, err := getTodoListIDs(repo, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	tags, err := repo.SelectTags(userID)
+	if err != nil {
+		return nil, fmt.Errorf("fails to get tags: %w", err)
+	}
+
+	counts, err := repo.CountTagTodos(userID, listIDs)
+	if err != nil {
+		return nil, fmt.Errorf("fails to count todos of tags: %w", err)
+	}
+
+	summaries := make([]dto.TagSummary, 0, len(tags))
+	for i := range tags {
+		summaries = append(summaries, dto.TagSummary{Tag: tags[i], TodoCount: counts[tags[i].ID]})
+	}
+
+	sort.Slice(summaries, func(i, j int) bool { return summaries[i].Name < summaries[j].Name })
+	return summaries, nil
+}
+
+func GetTag(userID int64, name string) (entity.Tag, error) {
+	tag, err := repo.SelectTag(userID, name)
+	if err != nil {
+		return entity.Tag{}, fmt.Errorf("fails to get tag: %w", err)
+	}
+
+	return tag, nil
+}
+
+// Todos with tag in owned and shared todo lists, and todos shared directly
+func GetTagTodos(userID int64, name string, query dto.TodoQuery) (dto.TodoPage, error) {
+	if _, err := GetTag(userID, name); err != nil {
+		return dto.TodoPage{}, err
+	}
+
+	listIDs, err := getTodoListIDs(repo, userID)
+	if err != nil {
+		return dto.TodoPage{}, err
+	}
+
+	sharedIDs, err := repo.SelectSharedTodoIDs(userID)
+	if err != nil {
+		return dto.TodoPage{}, fmt.Errorf("fails to get shared todos: %w", err)
+	}
+
+	query.TodoListIDs = listIDs
+	query.SharedIDs = sharedIDs
+	query.Tag = name
+	return queryTodos(userID, query, "tag todos")
+}
+
+// Update name and color of tag, tagged todos are retagged if renamed
+func UpdateTag(userID int64, name string, tag *entity.Tag) error {
+	oldTag, err := GetTag(userID, name)
+	if err != nil {
+		return err
+	}
+
+	tag.Entity = oldTag.Entity
+	tag.UserID = oldTag.UserID
+	if err := checkTag(*tag); err != nil {
+		return err
+	}
+
+	if tag.Name == oldTag.Name {
+		if err := repo.SaveTag(tag); err != nil {
+			return fmt.Errorf("fails to update tag: %w", err)
+		}
+
+		return nil
+	}
+
+	exist, err := repo.ExistTag(userID, tag.Name)
+	if err != nil {
+		return fmt.Errorf("fails to get tag: %w", err)
+	}
+
+	if exist {
+		return util.NewErrorWithBadRequest("tag already exists, merge into it instead: %v", tag.Name)
+	}
+
+	// rename is merging into a new tag
+	return repo.Transaction(func(r dal.Repository) error {
+		target, err := mergeTag(r, oldTag, tag.Name)
+		if err != nil {
+			return err
+		}
+
+		target.Color = tag.Color
+		if err := r.SaveTag(&target); err != nil {
+			return fmt.Errorf("fails to update tag: %w", err)
+		}
+
+		*tag = target
+		return nil
+	})
+}
+
+// Merge tag into another one, which is created if not exists
+func MergeTag(userID int64, name, into string) (entity.Tag, error) {
+	tag, err := GetTag(userID, name)
+	if err != nil {
+		return entity.Tag{}, err
+	}
+
+	if into == name {
+		return entity.Tag{}, util.NewErrorWithBadRequest("unable to merge tag into itself")
+	}
+
+	if err := checkTag(entity.Tag{Name: into}); err != nil {
+		return entity.Tag{}, err
+	}
+
+	var target entity.Tag
+	err = repo.Transaction(func(r dal.Repository) (err error) {
+		target, err = mergeTag(r, tag, into)
+		return err
+	})
+	if err != nil {
+		return entity.Tag{}, err
+	}
+
+	return target, nil
+}
+
+// Delete tag, and remove it from todos
+func DeleteTag(userID int64, name string) (entity.Tag, error) {
+	tag, err := GetTag(userID, name)
+	if err != nil {
+		return entity.Tag{}, err
+	}
+
+	err = repo.Transaction(func(r dal.Repository) error {
+		if err := retagTodos(r, userID, name, ""); err != nil {
+			return err
+		}
+
+		if err := r.DeleteTag(tag.ID); err != nil {
+			return fmt.Errorf("fails to delete tag: %w", err)
+		}
+
 		return nil
+	})
+	if err != nil {
+		return entity.Tag{}, err
 	}
 
-	tags := getTags(todo.Title)
-	oldTags := getTags(oldTodoTitle)
+	return tag, nil
+}
+
+func mergeTag(r dal.Repository, tag entity.Tag, into string) (entity.Tag, error) {
+	if err := retagTodos(r, tag.UserID, tag.Name, into); err != nil {
+		return entity.Tag{}, err
+	}
+
+	if err := r.DeleteTag(tag.ID); err != nil {
+		return entity.Tag{}, fmt.Errorf("fails to delete tag: %w", err)
+	}
+
+	target, err := r.SelectTag(tag.UserID, into)
+	if util.IsErrorCode(err, otodo.ErrNotFound) {
+		target = entity.Tag{Name: into, Color: tag.Color, UserID: tag.UserID}
+		err = r.InsertTag(&target)
+	}
+	if err != nil {
+		return entity.Tag{}, fmt.Errorf("fails to get tag: %w", err)
+	}
 
-	// diff, avoid duplicate tag in title
-	for tagName := range oldTags {
-		_, ok := tags[tagName]
-		if ok {
-			delete(tags, tagName)
+	return target, nil
+}
+
+func checkTag(tag entity.Tag) error {
+	if !isValidTagName(tag.Name) {
+		return util.NewErrorWithBadRequest("invalid tag name: %v", tag.Name)
+	}
+
+	if tag.Color != "" && !tagColorRegex.MatchString(tag.Color) {
+		return util.NewErrorWithBadRequest("invalid tag color: %v", tag.Color)
+	}
+
+	return nil
+}
+
+// Replace tag of todos visible to user, both inline and explicit ones.
+// Tag is removed if newName is empty, and todos in todo lists which user
+// is unable to edit are skipped. Text is only rewritten in todos owned by
+// user alone, since each participant has their own tags, only tags of user
+// are replaced in shared todos
+func retagTodos(r dal.Repository, userID int64, name, newName string) error {
+	lists, err := getTodoLists(r, userID)
+	if err != nil {
+		return err
+	}
+
+	listIDs := make([]int64, 0, len(lists))
+	private := make(map[int64]bool) // todo lists owned by user, not shared
+	for i := range lists {
+		role, err := getTodoListRole(r, userID, lists[i])
+		if err != nil {
+			return err
+		}
+
+		if role < entity.TodoListRoleEditor {
+			continue
+		}
+
+		listIDs = append(listIDs, lists[i].ID)
+		if role == entity.TodoListRoleOwner {
+			userIDs, err := getTodoListUserIDs(r, lists[i].ID)
+			if err != nil {
+				return err
+			}
+
+			private[lists[i].ID] = len(userIDs) == 1
+		}
+	}
+
+	todos, err := r.SelectTodosByQuery(dto.TodoQuery{TodoListIDs: listIDs, Tag: name, TagUserID: userID})
+	if err != nil {
+		return fmt.Errorf("fails to get tag todos: %w", err)
+	}
+
+	for i := range todos {
+		shared := !private[todos[i].TodoListID]
+		if !shared {
+			sharedUsers, err := r.SelectTodoSharedUsers(todos[i].ID)
+			if err != nil {
+				return fmt.Errorf("fails to get todo shared users: %w", err)
+			}
+
+			shared = len(sharedUsers) != 0
+		}
+
+		if shared {
+			tags := map[string]bool{name: false}
+			if newName != "" {
+				tags[newName] = true
+			}
+			err = updateUserTags(r, userID, todos[i].ID, tags)
 		} else {
-			tags[tagName] = false
+			err = rewriteTodoTag(r, todos[i], name, newName)
+		}
+		if err != nil {
+			return err
 		}
 	}
 
-	// TODO How to update shared user
-	userID := todo.UserID
+	return nil
+}
+
+// Replace tag in text and explicit tags of todo
+func rewriteTodoTag(r dal.Repository, todo entity.Todo, name, newName string) error {
+	oldTags := todo.Tags
+	todo.Title = replaceTag(todo.Title, name, newName)
+	todo.Memo = replaceTag(todo.Memo, name, newName)
+	todo.Tags = make([]string, 0, len(oldTags))
+	for _, tag := range oldTags {
+		if tag != name && tag != newName {
+			todo.Tags = append(todo.Tags, tag)
+		}
+	}
+	if newName != "" {
+		todo.Tags = append(todo.Tags, newName)
+		sort.Strings(todo.Tags)
+	}
+
+	if err := r.SaveTodo(&todo); err != nil {
+		return fmt.Errorf("fails to update todo: %w", err)
+	}
+
+	return setTodoTags(r, todo, oldTags)
+}
+
+/**
+ * Tags of Todo
+ */
+
+// Tags of todo, consist of inline tags in title and memo, and explicit tags.
+// Explicit tags are kept if nil, and inline tags of old todo are not
+// treated as explicit, so that tags removed from text are removed
+func getTodoTags(todo, oldTodo entity.Todo) ([]string, error) {
+	explicit := todo.Tags
+	if explicit == nil {
+		explicit = oldTodo.Tags
+	}
+
+	oldInline := make(map[string]bool)
+	for _, tag := range append(parseTags(oldTodo.Title), parseTags(oldTodo.Memo)...) {
+		oldInline[tag] = true
+	}
+
+	tags := make([]string, 0)
+	seen := make(map[string]bool)
+	add := func(tag string) {
+		if !seen[tag] {
+			seen[tag] = true
+			tags = append(tags, tag)
+		}
+	}
+
+	for _, tag := range append(parseTags(todo.Title), parseTags(todo.Memo)...) {
+		add(tag)
+	}
+
+	for _, tag := range explicit {
+		if !isValidTagName(tag) {
+			return nil, util.NewErrorWithBadRequest("invalid tag name: %v", tag)
+		}
+
+		if !oldInline[tag] {
+			add(tag)
+		}
+	}
+
+	sort.Strings(tags)
+	return tags, nil
+}
+
+// Set tags of todo to todo.Tags for owner and shared users of todo list
+func setTodoTags(r dal.Repository, todo entity.Todo, oldTags []string) error {
+	// diff, true for insert and false for remove
+	tags := make(map[string]bool)
+	for _, tag := range oldTags {
+		tags[tag] = false
+	}
+	for _, tag := range todo.Tags {
+		if _, ok := tags[tag]; ok {
+			delete(tags, tag)
+		} else {
+			tags[tag] = true
+		}
+	}
+
+	if len(tags) == 0 {
+		return nil
+	}
+
+	// each participant has their own tags
+	userIDs, err := getTodoListUserIDs(r, todo.TodoListID)
+	if err != nil {
+		return err
+	}
+
+	for _, userID := range userIDs {
+		if err := updateUserTags(r, userID, todo.ID, tags); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Add tags of todos in todo list to user, called when todo list is shared
+func shareTodoListTags(r dal.Repository, userID, todoListID int64) error {
+	todos, err := r.SelectTodos(todoListID)
+	if err != nil {
+		return fmt.Errorf("fails to get todos: %w", err)
+	}
+
+	for i := range todos {
+		tags := make(map[string]bool)
+		for _, tag := range todos[i].Tags {
+			tags[tag] = true
+		}
+
+		if err := updateUserTags(r, userID, todos[i].ID, tags); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Insert (true) or remove (false) tags of todo for user
+func updateUserTags(r dal.Repository, userID, todoID int64, tags map[string]bool) error {
 	for tagName, op := range tags {
 		if op {
 			// Insert new tag
-			exist, err := dal.ExistTag(userID, tagName)
+			exist, err := r.ExistTag(userID, tagName)
 			if err != nil {
-				return util.NewErrorWithUnknown("unknown error: %w", err)
+				return fmt.Errorf("fails to get tag: %w", err)
 			}
 
 			if !exist {
@@ -46,17 +414,18 @@ func UpdateTag(todo *entity.Todo, oldTodoTitle string) error {
 					UserID: userID,
 					Todos:  make([]entity.Todo, 0),
 				}
-				if err := dal.InsertTag(&tag); err != nil {
+				if err := r.InsertTag(&tag); err != nil {
 					return fmt.Errorf("fails to create tag: %w", err)
 				}
 			}
 
-			if err := dal.InsertTagTodo(userID, todo.ID, tagName); err != nil {
+			if err := r.InsertTagTodo(userID, todoID, tagName); err != nil {
 				return fmt.Errorf("fails to update tag: %w", err)
 			}
 		} else {
-			// Remove old tag
-			if err := dal.DeleteTagTodo(userID, todo.ID, tagName); err != nil {
+			// Remove old tag, which may be deleted by user
+			err := r.DeleteTagTodo(userID, todoID, tagName)
+			if err != nil && !util.IsErrorCode(err, otodo.ErrNotFound) {
 				return fmt.Errorf("fails to update tag: %w", err)
 			}
 		}
@@ -65,25 +434,103 @@ func UpdateTag(todo *entity.Todo, oldTodoTitle string) error {
 	return nil
 }
 
-func UpdateTagAsync(todo *entity.Todo, oldTodoTitle string) {
-	if err := UpdateTag(todo, oldTodoTitle); err != nil {
-		// TODO[bug]: handle error
-		fmt.Println(err)
+/**
+ * Parser
+ */
+
+// Inline tags in text, e.g. `#work`, `＃工作`, in order of occurrence
+func parseTags(text string) []string {
+	tags := make([]string, 0)
+	seen := make(map[string]bool)
+	scanTags(text, func(start, end int, name string) {
+		if !seen[name] {
+			seen[name] = true
+			tags = append(tags, name)
+		}
+	})
+	return tags
+}
+
+// Replace inline tag in text, removed with a following space if newName is empty
+func replaceTag(text, name, newName string) string {
+	var sb strings.Builder
+	pos := 0
+	scanTags(text, func(start, end int, tag string) {
+		if tag != name {
+			return
+		}
+
+		sb.WriteString(text[pos:start])
+		if newName != "" {
+			r, _ := utf8.DecodeRuneInString(text[start:])
+			sb.WriteRune(r) // keep the sign
+			sb.WriteString(newName)
+		} else if end < len(text) && text[end] == ' ' {
+			end++
+		}
+		pos = end
+	})
+
+	if pos == 0 {
+		return text
 	}
+
+	sb.WriteString(text[pos:])
+	return strings.TrimSpace(sb.String())
 }
 
-var tagRegex = regexp.MustCompile(`^#(?P<tag>\\S{1,16}) `)
+// Find inline tags, a tag starts with a sign `#` or `＃` which is not a part
+// of word, url or another tag, followed by letters, digits, marks, `_` or
+// `-`, and must not be all digits, so that `#1` is not a tag
+func scanTags(text string, fn func(start, end int, name string)) {
+	prev := ' '
+	for i := 0; i < len(text); {
+		r, size := utf8.DecodeRuneInString(text[i:])
+		if !isTagSign(r) || isTagRune(prev) || strings.ContainsRune("#＃/&@", prev) {
+			prev = r
+			i += size
+			continue
+		}
 
-func getTags(title string) map[string]bool {
-	tags := make(map[string]bool)
-	for {
-		matches := tagRegex.FindStringSubmatch(title)
-		if len(matches) == 0 {
-			break
+		start, end := i, i+size
+		for end < len(text) {
+			r, size := utf8.DecodeRuneInString(text[end:])
+			if !isTagRune(r) {
+				break
+			}
+			end += size
+		}
+
+		name := text[start+size : end]
+		if isValidTagName(name) {
+			fn(start, end, name)
 		}
 
-		tags[matches[1]] = true
-		title = strings.TrimLeft(title, " ")
+		prev, _ = utf8.DecodeLastRuneInString(text[:end])
+		i = end
 	}
-	return tags
+}
+
+func isValidTagName(name string) bool {
+	n := utf8.RuneCountInString(name)
+	if n == 0 || n > tagNameMaxLength {
+		return false
+	}
+
+	digits := true
+	for _, r := range name {
+		if !isTagRune(r) {
+			return false
+		}
+		digits = digits && unicode.IsDigit(r)
+	}
+	return !digits
+}
+
+func isTagSign(r rune) bool {
+	return r == '#' || r == '＃'
+}
+
+func isTagRune(r rune) bool {
+	return unicode.IsLetter(r) || unicode.IsDigit(r) || unicode.IsMark(r) || r == '_' || r == '-'
 }
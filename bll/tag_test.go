@@ -0,0 +1,276 @@
+package bll
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/yzx9/otodo/model/dto"
+	"github.com/yzx9/otodo/model/entity"
+)
+
+func TestParseTags(t *testing.T) {
+	tests := []struct {
+		name string
+		text string
+		want []string
+	}{
+		{"empty", "", []string{}},
+		{"no tag", "buy milk", []string{}},
+		{"prefix", "#work fix sink", []string{"work"}},
+		{"prefix without space", "#work", []string{"work"}},
+		{"anywhere", "fix #home sink #urgent", []string{"home", "urgent"}},
+		{"duplicated", "#work and #work", []string{"work"}},
+		{"order of occurrence", "#b #a #b", []string{"b", "a"}},
+		{"unicode", "修水槽 #工作 #日本語", []string{"工作", "日本語"}},
+		{"fullwidth sign", "修水槽 ＃工作", []string{"工作"}},
+		{"combining mark", "#café time", []string{"café"}},
+		{"underscore and hyphen", "#to_do #follow-up", []string{"to_do", "follow-up"}},
+		{"punctuation ends tag", "#work, #home. (#misc)", []string{"work", "home", "misc"}},
+		{"chinese punctuation", "任务，#工作。", []string{"工作"}},
+		{"all digits", "issue #123", []string{}},
+		{"digits and letters", "#2022q1 plan", []string{"2022q1"}},
+		{"inside word", "C# and a#b", []string{}},
+		{"url fragment", "see https://example.com/#anchor", []string{}},
+		{"html entity", "&#39;quoted&#39;", []string{}},
+		{"mention", "@#user", []string{}},
+		{"double sign", "##work", []string{}},
+		{"sign only", "# heading", []string{}},
+		{"too long", "#" + strings.Repeat("a", 33), []string{}},
+		{"max length", "#" + strings.Repeat("字", 32), []string{strings.Repeat("字", 32)}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseTags(tt.text); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseTags(%q) = %q, want %q", tt.text, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestReplaceTag(t *testing.T) {
+	tests := []struct {
+		name    string
+		text    string
+		tag     string
+		newName string
+		want    string
+	}{
+		{"rename prefix", "#work fix sink", "work", "job", "#job fix sink"},
+		{"rename anywhere", "fix #work sink #work", "work", "job", "fix #job sink #job"},
+		{"keep other tags", "#work #home fix", "home", "house", "#work #house fix"},
+		{"not a prefix of other tag", "#workshop #work", "work", "job", "#workshop #job"},
+		{"keep fullwidth sign", "修 ＃工作", "工作", "活", "修 ＃活"},
+		{"remove prefix", "#work fix sink", "work", "", "fix sink"},
+		{"remove middle", "fix #work sink", "work", "", "fix sink"},
+		{"remove suffix", "fix sink #work", "work", "", "fix sink"},
+		{"remove punctuated", "fix #work, now", "work", "", "fix , now"},
+		{"no tag", "fix sink", "work", "job", "fix sink"},
+		{"not a tag", "see example.com/#work", "work", "job", "see example.com/#work"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := replaceTag(tt.text, tt.tag, tt.newName); got != tt.want {
+				t.Errorf("replaceTag(%q, %q, %q) = %q, want %q", tt.text, tt.tag, tt.newName, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestIsValidTagName(t *testing.T) {
+	tests := []struct {
+		name string
+		want bool
+	}{
+		{"work", true},
+		{"工作", true},
+		{"follow-up", true},
+		{"2022q1", true},
+		{"", false},
+		{"123", false},
+		{"has space", false},
+		{"#work", false},
+		{"a/b", false},
+		{strings.Repeat("a", 32), true},
+		{strings.Repeat("a", 33), false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isValidTagName(tt.name); got != tt.want {
+				t.Errorf("isValidTagName(%q) = %v, want %v", tt.name, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGetTodoTags(t *testing.T) {
+	tests := []struct {
+		name    string
+		todo    entity.Todo
+		oldTodo entity.Todo
+		want    []string
+		wantErr bool
+	}{
+		{
+			name: "inline",
+			todo: entity.Todo{Title: "#work fix", Memo: "see #home"},
+			want: []string{"home", "work"},
+		},
+		{
+			name: "explicit",
+			todo: entity.Todo{Title: "fix", Tags: []string{"work", "home"}},
+			want: []string{"home", "work"},
+		},
+		{
+			name: "inline and explicit",
+			todo: entity.Todo{Title: "#work fix", Tags: []string{"work", "home"}},
+			want: []string{"home", "work"},
+		},
+		{
+			name:    "keep explicit if nil",
+			todo:    entity.Todo{Title: "fix"},
+			oldTodo: entity.Todo{Title: "fix", Tags: []string{"home"}},
+			want:    []string{"home"},
+		},
+		{
+			name:    "clear explicit",
+			todo:    entity.Todo{Title: "fix", Tags: []string{}},
+			oldTodo: entity.Todo{Title: "fix", Tags: []string{"home"}},
+			want:    []string{},
+		},
+		{
+			name:    "removed from title",
+			todo:    entity.Todo{Title: "fix"},
+			oldTodo: entity.Todo{Title: "#work fix", Tags: []string{"home", "work"}},
+			want:    []string{"home"},
+		},
+		{
+			name:    "removed from title with current tags",
+			todo:    entity.Todo{Title: "fix", Tags: []string{"home", "work"}},
+			oldTodo: entity.Todo{Title: "#work fix", Tags: []string{"home", "work"}},
+			want:    []string{"home"},
+		},
+		{
+			name:    "moved from title to memo",
+			todo:    entity.Todo{Title: "fix", Memo: "#work"},
+			oldTodo: entity.Todo{Title: "#work fix", Tags: []string{"work"}},
+			want:    []string{"work"},
+		},
+		{
+			name:    "invalid explicit",
+			todo:    entity.Todo{Title: "fix", Tags: []string{"has space"}},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := getTodoTags(tt.todo, tt.oldTodo)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("getTodoTags() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("getTodoTags() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRetagTodosByViewer(t *testing.T) {
+	tests := []struct {
+		name  string
+		retag func(userID int64) error
+	}{
+		{"delete", func(userID int64) error {
+			_, err := DeleteTag(userID, "milk")
+			return err
+		}},
+		{"rename", func(userID int64) error {
+			return UpdateTag(userID, "milk", &entity.Tag{Name: "dairy"})
+		}},
+		{"merge", func(userID int64) error {
+			_, err := MergeTag(userID, "milk", "dairy")
+			return err
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useMemoryRepository(t)
+			owner := createTestUser(t, "alice")
+			viewer := createTestUser(t, "bobby")
+			todoList := createTestTodoList(t, owner.ID, "shopping")
+			todo := createTestTodo(t, owner.ID, entity.Todo{Title: "buy #milk now", TodoListID: todoList.ID})
+			joinTestTodoList(t, viewer.ID, todoList.ID, entity.TodoListRoleViewer)
+
+			must(t, tt.retag(viewer.ID))
+
+			got, err := GetTodo(owner.ID, todo.ID)
+			must(t, err)
+			if got.Title != todo.Title || !reflect.DeepEqual(got.Tags, todo.Tags) {
+				t.Errorf("todo changed by viewer, got %q %q, want %q %q", got.Title, got.Tags, todo.Title, todo.Tags)
+			}
+
+			if _, err := GetTag(owner.ID, "milk"); err != nil {
+				t.Errorf("tag of owner changed by viewer: %v", err)
+			}
+		})
+	}
+}
+
+func TestRetagTodos(t *testing.T) {
+	tests := []struct {
+		name      string
+		shared    bool
+		byMember  bool
+		wantTitle string
+		wantOwner string // tag of owner on todo
+		wantOther string // tag of member on todo
+	}{
+		{"private", false, false, "buy #dairy now", "dairy", ""},
+		{"shared by owner", true, false, "buy #milk now", "dairy", "milk"},
+		{"shared by editor", true, true, "buy #milk now", "milk", "dairy"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useMemoryRepository(t)
+			owner := createTestUser(t, "alice")
+			member := createTestUser(t, "bobby")
+			todoList := createTestTodoList(t, owner.ID, "shopping")
+			todo := createTestTodo(t, owner.ID, entity.Todo{Title: "buy #milk now", TodoListID: todoList.ID})
+			if tt.shared {
+				joinTestTodoList(t, member.ID, todoList.ID, entity.TodoListRoleEditor)
+			}
+
+			userID := owner.ID
+			if tt.byMember {
+				userID = member.ID
+			}
+			must(t, UpdateTag(userID, "milk", &entity.Tag{Name: "dairy"}))
+
+			got, err := GetTodo(owner.ID, todo.ID)
+			must(t, err)
+			if got.Title != tt.wantTitle {
+				t.Errorf("title = %q, want %q", got.Title, tt.wantTitle)
+			}
+
+			for _, user := range []struct {
+				id   int64
+				want string
+			}{{owner.ID, tt.wantOwner}, {member.ID, tt.wantOther}} {
+				for _, tag := range []string{"milk", "dairy"} {
+					todos, err := repo.SelectTodosByQuery(dto.TodoQuery{IDs: []int64{todo.ID}, Tag: tag, TagUserID: user.id})
+					must(t, err)
+					if tagged := len(todos) != 0; tagged != (tag == user.want) {
+						t.Errorf("tag %q of user %v on todo = %v, want %v", tag, user.id, tagged, !tagged)
+					}
+				}
+			}
+		})
+	}
+}
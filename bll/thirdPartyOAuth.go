@@ -3,20 +3,19 @@ package bll
 import (
 	"fmt"
 
-	"github.com/yzx9/otodo/dal"
 	"github.com/yzx9/otodo/model/entity"
 )
 
 func UpdateThirdPartyOAuthToken(token *entity.ThirdPartyOAuthToken) error {
 	// TODO[bug]: handle error
-	exist, err := dal.ExistActiveThirdPartyOAuthToken(token.UserID, entity.ThirdPartyTokenType(token.Type))
+	exist, err := repo.ExistActiveThirdPartyOAuthToken(token.UserID, entity.ThirdPartyTokenType(token.Type))
 	if err != nil {
 		return fmt.Errorf("fails to update third party oauth token: %w", err)
 	}
 
-	handle := dal.UpdateThirdPartyOAuthToken
+	handle := repo.UpdateThirdPartyOAuthToken
 	if !exist {
-		handle = dal.InsertThirdPartyOAuthToken
+		handle = repo.InsertThirdPartyOAuthToken
 	}
 
 	if err := handle(token); err != nil {
@@ -5,29 +5,37 @@ import (
 	"time"
 
 	"github.com/yzx9/otodo/dal"
+	"github.com/yzx9/otodo/model/dto"
 	"github.com/yzx9/otodo/model/entity"
+	"github.com/yzx9/otodo/otodo"
 	"github.com/yzx9/otodo/util"
 )
 
 func CreateTodo(userID int64, todo *entity.Todo) error {
-	_, err := OwnOrSharedTodoList(userID, todo.TodoListID)
+	_, err := AccessTodoList(userID, todo.TodoListID, entity.TodoListRoleEditor)
 	if err != nil {
 		return fmt.Errorf("fails to get todo list: %w", err)
 	}
 
 	todo.UserID = userID // override user
-
-	plan, err := CreateTodoRepeatPlan(todo.TodoRepeatPlan)
-	if err != nil {
-		return fmt.Errorf("fails to create todo repeat plan: %w", err)
+	if todo.Tags, err = getTodoTags(*todo, entity.Todo{}); err != nil {
+		return err
 	}
-	todo.TodoRepeatPlanID = plan.ID
 
-	if err := dal.InsertTodo(todo); err != nil {
-		return fmt.Errorf("fails to create todo: %w", err)
-	}
+	return repo.Transaction(func(r dal.Repository) error {
+		plan, err := createTodoRepeatPlan(r, todo.UserID, todo.TodoRepeatPlan, todo.Deadline)
+		if err != nil {
+			return fmt.Errorf("fails to create todo repeat plan: %w", err)
+		}
+		todo.TodoRepeatPlanID = plan.ID
+		todo.TodoRepeatPlan = plan
 
-	return nil
+		if err := r.InsertTodo(todo); err != nil {
+			return fmt.Errorf("fails to create todo: %w", err)
+		}
+
+		return setTodoTags(r, *todo, nil)
+	})
 }
 
 func GetTodo(userID, todoID int64) (entity.Todo, error) {
@@ -36,123 +44,410 @@ func GetTodo(userID, todoID int64) (entity.Todo, error) {
 		return entity.Todo{}, fmt.Errorf("fails to get todo: %w", err)
 	}
 
-	return todo, nil
+	todos := []entity.Todo{todo}
+	if err := fillTodoRanks(repo, userID, todos); err != nil {
+		return entity.Todo{}, err
+	}
+
+	return todos[0], nil
 }
 
-func GetTodos(userID, todoListID int64) ([]entity.Todo, error) {
+func GetTodos(userID, todoListID int64, query dto.TodoQuery) (dto.TodoPage, error) {
 	if _, err := OwnOrSharedTodoList(userID, todoListID); err != nil {
-		return nil, err
+		return dto.TodoPage{}, err
 	}
 
-	return ForceGetTodos(todoListID)
+	return ForceGetTodos(userID, todoListID, query)
 }
 
-func ForceGetTodos(todoListID int64) ([]entity.Todo, error) {
-	todos, err := dal.SelectTodos(todoListID)
-	if err != nil {
-		return nil, fmt.Errorf("fails to get todos: %w", err)
-	}
-
-	return todos, nil
+// Get todos without permission check, ranks of user are used
+func ForceGetTodos(userID, todoListID int64, query dto.TodoQuery) (dto.TodoPage, error) {
+	query.TodoListID = todoListID
+	return queryTodos(userID, query, "todos")
 }
 
-func GetImportantTodos(userID int64) ([]entity.Todo, error) {
-	todos, err := dal.SelectImportantTodos(userID)
+func GetDailyTodos(userID int64, query dto.TodoQuery) (dto.TodoPage, error) {
+	today, _, err := getUserToday(userID)
 	if err != nil {
-		return nil, fmt.Errorf("fails to get important todos: %w", err)
+		return dto.TodoPage{}, err
 	}
 
-	return todos, nil
-}
-
-func GetPlannedTodos(userID int64) ([]entity.Todo, error) {
-	todos, err := dal.SelectPlanedTodos(userID)
+	todos, err := getDailyTodos(userID, today)
 	if err != nil {
-		return nil, fmt.Errorf("fails to get planed todos: %w", err)
+		return dto.TodoPage{}, err
+	}
+
+	query.IDs = make([]int64, 0, len(todos))
+	for i := range todos {
+		query.IDs = append(query.IDs, todos[i].ID)
 	}
 
-	return todos, nil
+	return queryTodos(userID, query, "daily todos")
 }
 
-func GetNotNotifiedTodos(userID int64) ([]entity.Todo, error) {
-	todos, err := dal.SelectNotNotifiedTodos(userID)
-	if err != nil {
-		return nil, fmt.Errorf("fails to get not-notified todos: %w", err)
+func GetImportantTodos(userID int64, query dto.TodoQuery) (dto.TodoPage, error) {
+	importance := true
+	query.UserID = userID
+	query.Importance = &importance
+	return queryTodos(userID, query, "important todos")
+}
+
+func GetPlannedTodos(userID int64, query dto.TodoQuery) (dto.TodoPage, error) {
+	hasDeadline := true
+	query.UserID = userID
+	query.HasDeadline = &hasDeadline
+	if query.Sort == dto.TodoSortID {
+		query.Sort = dto.TodoSortDeadline
 	}
+	return queryTodos(userID, query, "planed todos")
+}
 
-	return todos, nil
+func GetNotNotifiedTodos(userID int64, query dto.TodoQuery) (dto.TodoPage, error) {
+	notified := false
+	query.UserID = userID
+	query.Notified = &notified
+	if query.Sort == dto.TodoSortID {
+		query.Sort = dto.TodoSortNotifyAt
+	}
+	return queryTodos(userID, query, "not-notified todos")
 }
 
-func UpdateTodo(userID int64, todo *entity.Todo) error {
+func UpdateTodo(userID int64, todo *entity.Todo, ifMatch dto.IfMatch) error {
 	// Limits
-	oldTodo, err := OwnTodo(userID, todo.ID)
+	oldTodo, err := AccessTodo(userID, todo.ID, entity.TodoListRoleEditor)
 	if err != nil {
 		return err
 	}
 
+	if err := checkTodoIfMatch(repo, oldTodo, ifMatch); err != nil {
+		return err
+	}
+
+	todo.Version = oldTodo.Version
 	todo.CreatedAt = oldTodo.CreatedAt
 	todo.UserID = oldTodo.UserID
+	todo.TodoListID = oldTodo.TodoListID // moved by MoveTodo only, which checks target todo list
 	todo.Files = oldTodo.Files
 	todo.Steps = oldTodo.Steps
 	todo.NextID = oldTodo.NextID
 
-	if !oldTodo.Done && todo.Done {
-		t := time.Now()
-		todo.DoneAt = &t
+	// notified is managed by reminder scheduler, rearm if rescheduled
+	todo.Notified = oldTodo.Notified
+	if !equalTime(todo.NotifyAt, oldTodo.NotifyAt) {
+		todo.Notified = false
+	}
+
+	if todo.Tags, err = getTodoTags(*todo, oldTodo); err != nil {
+		return err
+	}
+
+	return repo.Transaction(func(r dal.Repository) error {
+		plan, err := updateTodoRepeatPlan(r, oldTodo.UserID, todo.TodoRepeatPlan, oldTodo.TodoRepeatPlan, todo.Deadline)
+		if err != nil {
+			return err
+		}
+		todo.TodoRepeatPlanID = plan.ID
+		todo.TodoRepeatPlan = plan
 
-		// Create Repeat Todo If Need
-		if todo.NextID == nil {
-			created, next, err := CreateRepeatTodoIfNeed(*todo)
-			if err != nil {
+		if !oldTodo.Done && todo.Done {
+			if err := completeTodo(r, todo); err != nil {
 				return err
 			}
+		}
+
+		// Save
+		if err := r.SaveTodo(todo); err != nil {
+			return err
+		}
+
+		return setTodoTags(r, *todo, oldTodo.Tags)
+	})
+}
+
+// Mark todo as done, the successor is created if todo is recurring.
+// Completing a done todo changes nothing
+func CompleteTodo(userID, todoID int64, ifMatch dto.IfMatch) (dto.TodoCompletion, error) {
+	todo, err := AccessTodo(userID, todoID, entity.TodoListRoleEditor)
+	if err != nil {
+		return dto.TodoCompletion{}, err
+	}
+
+	if err := checkTodoIfMatch(repo, todo, ifMatch); err != nil {
+		return dto.TodoCompletion{}, err
+	}
 
-			if created {
-				todo.NextID = &next.ID
+	if !todo.Done {
+		err = repo.Transaction(func(r dal.Repository) error {
+			if err := completeTodo(r, &todo); err != nil {
+				return err
 			}
+
+			return r.SaveTodo(&todo)
+		})
+		if err != nil {
+			return dto.TodoCompletion{}, fmt.Errorf("fails to complete todo: %w", err)
 		}
 	}
 
-	plan, err := UpdateTodoRepeatPlan(todo.TodoRepeatPlan, oldTodo.TodoRepeatPlan)
+	return getTodoCompletion(todo)
+}
+
+// Mark todo as undone. If deleteNext, the successor is deleted unless it
+// has been modified, so that it will not be duplicated on next completion
+func UncompleteTodo(userID, todoID int64, deleteNext bool, ifMatch dto.IfMatch) (dto.TodoCompletion, error) {
+	todo, err := AccessTodo(userID, todoID, entity.TodoListRoleEditor)
 	if err != nil {
-		return err
+		return dto.TodoCompletion{}, err
+	}
+
+	if err := checkTodoIfMatch(repo, todo, ifMatch); err != nil {
+		return dto.TodoCompletion{}, err
+	}
+
+	if todo.Done {
+		err = repo.Transaction(func(r dal.Repository) error {
+			if err := uncompleteTodo(r, &todo, deleteNext); err != nil {
+				return err
+			}
+
+			return r.SaveTodo(&todo)
+		})
+		if err != nil {
+			return dto.TodoCompletion{}, fmt.Errorf("fails to uncomplete todo: %w", err)
+		}
 	}
-	todo.TodoRepeatPlanID = plan.ID
 
-	// Save
-	if err = dal.SaveTodo(todo); err != nil {
+	return getTodoCompletion(todo)
+}
+
+// Set done, and create successor if not created yet
+func completeTodo(r dal.Repository, todo *entity.Todo) error {
+	t := time.Now()
+	todo.Done = true
+	todo.DoneAt = &t
+
+	if todo.NextID != nil {
+		return nil
+	}
+
+	created, next, err := createRepeatTodoIfNeed(r, *todo)
+	if err != nil || !created {
 		return err
 	}
 
-	go UpdateTagAsync(todo, oldTodo.Title)
+	todo.NextID = &next.ID
+	return nil
+}
+
+// Set undone, and delete successor if deleteNext and it is unmodified
+func uncompleteTodo(r dal.Repository, todo *entity.Todo, deleteNext bool) error {
+	todo.Done = false
+	todo.DoneAt = nil
+
+	if !deleteNext || todo.NextID == nil {
+		return nil
+	}
+
+	next, err := r.SelectTodo(*todo.NextID)
+	if util.IsErrorCode(err, otodo.ErrNotFound) {
+		return nil // next todo has been deleted
+	} else if err != nil {
+		return fmt.Errorf("fails to get next todo: %w", err)
+	}
+
+	if !isUnmodifiedRepeatTodo(next) {
+		return nil
+	}
 
+	if err := r.DeleteTodo(next.ID, next.Version); err != nil {
+		return fmt.Errorf("fails to delete next todo: %w", err)
+	}
+
+	todo.NextID = nil
 	return nil
 }
 
-func DeleteTodo(userID, todoID int64) (entity.Todo, error) {
-	todo, err := OwnTodo(userID, todoID)
+func getTodoCompletion(todo entity.Todo) (dto.TodoCompletion, error) {
+	completion := dto.TodoCompletion{Todo: todo}
+	if todo.NextID == nil {
+		return completion, nil
+	}
+
+	next, err := repo.SelectTodo(*todo.NextID)
+	if util.IsErrorCode(err, otodo.ErrNotFound) {
+		return completion, nil // next todo has been deleted
+	} else if err != nil {
+		return dto.TodoCompletion{}, fmt.Errorf("fails to get next todo: %w", err)
+	}
+
+	completion.Next = &next
+	return completion, nil
+}
+
+// Successor is never updated since created by completion
+func isUnmodifiedRepeatTodo(todo entity.Todo) bool {
+	return todo.Version == 1 && !todo.Done && len(todo.Steps) == 0 && len(todo.Files) == 0
+}
+
+func DeleteTodo(userID, todoID int64, ifMatch dto.IfMatch) (entity.Todo, error) {
+	todo, err := AccessTodo(userID, todoID, entity.TodoListRoleEditor)
 	if err != nil {
 		return entity.Todo{}, err
 	}
 
-	if err = dal.DeleteTodo(todoID); err != nil {
-		return entity.Todo{}, fmt.Errorf("fails to delete todo: %w", err)
+	if err := checkTodoIfMatch(repo, todo, ifMatch); err != nil {
+		return entity.Todo{}, err
 	}
 
-	go UpdateTagAsync(&todo, "")
+	if err = repo.DeleteTodo(todoID, todo.Version); err != nil {
+		return entity.Todo{}, fmt.Errorf("fails to delete todo: %w", err)
+	}
 
 	return todo, nil
 }
 
+// Move todo after another one in the same todo list, or to the first if
+// after is 0. Only ranks of user are changed, so that ordering of shared
+// todo list does not affect others
+func ReorderTodo(userID, todoID, afterID int64) (entity.Todo, error) {
+	todo, err := OwnTodo(userID, todoID)
+	if err != nil {
+		return entity.Todo{}, err
+	}
+
+	err = repo.Transaction(func(r dal.Repository) error {
+		todos, err := r.SelectTodosByQuery(dto.TodoQuery{TodoListID: todo.TodoListID})
+		if err != nil {
+			return fmt.Errorf("fails to get todos: %w", err)
+		}
+
+		ids := make([]int64, 0, len(todos))
+		for i := range todos {
+			ids = append(ids, todos[i].ID)
+		}
+
+		items, err := getRankItems(r, userID, entity.RankTypeTodo, ids)
+		if err != nil {
+			return err
+		}
+
+		_, err = moveRankItem(r, userID, items, todoID, afterID)
+		return err
+	})
+	if err != nil {
+		return entity.Todo{}, err
+	}
+
+	return GetTodo(userID, todoID)
+}
+
+// Todo in todo list of owner or shared user with any role
 func OwnTodo(userID, todoID int64) (entity.Todo, error) {
-	todo, err := dal.SelectTodo(todoID)
+	return AccessTodo(userID, todoID, entity.TodoListRoleViewer)
+}
+
+// Todo in todo list of owner or shared user with role, or roles after it.
+// Shared users of todo have their role in it, and the higher one is taken
+// if user is shared with todo list too
+func AccessTodo(userID, todoID int64, role entity.TodoListRole) (entity.Todo, error) {
+	todo, _, err := accessTodo(repo, userID, todoID, role)
+	return todo, err
+}
+
+func accessTodo(r dal.Repository, userID, todoID int64, role entity.TodoListRole) (entity.Todo, entity.TodoListRole, error) {
+	todo, err := r.SelectTodo(todoID)
 	if err != nil {
-		return entity.Todo{}, fmt.Errorf("fails to get todo: %w", err)
+		return entity.Todo{}, 0, fmt.Errorf("fails to get todo: %w", err)
 	}
 
-	if _, err = OwnOrSharedTodoList(userID, todo.TodoListID); err != nil {
-		return entity.Todo{}, util.NewErrorWithForbidden("unable to handle non-owned todo: %v", todo.ID)
+	userRole, err := getTodoRole(r, userID, todo)
+	if err != nil {
+		return entity.Todo{}, 0, err
 	}
 
-	return todo, nil
+	if userRole == 0 {
+		return entity.Todo{}, 0, util.NewErrorWithForbidden("unable to handle non-owned todo: %v", todo.ID)
+	}
+
+	if userRole < role {
+		return entity.Todo{}, 0, insufficientTodoListRole(todo.TodoListID)
+	}
+
+	return todo, userRole, nil
+}
+
+// Role of user in todo, 0 if user is neither in todo list nor shared user
+// of todo
+func getTodoRole(r dal.Repository, userID int64, todo entity.Todo) (entity.TodoListRole, error) {
+	todoList, err := r.SelectTodoList(todo.TodoListID)
+	if err != nil {
+		return 0, fmt.Errorf("fails to get todo list: %w", err)
+	}
+
+	role, err := getTodoListRole(r, userID, todoList)
+	if err != nil {
+		return 0, err
+	}
+
+	sharedRole, err := r.SelectTodoSharedUserRole(userID, todo.ID)
+	if util.IsErrorCode(err, otodo.ErrNotFound) {
+		return role, nil
+	} else if err != nil {
+		return 0, fmt.Errorf("fails to get todo sharing: %w", err)
+	}
+
+	if sharedRole > role {
+		role = sharedRole
+	}
+	return role, nil
+}
+
+func checkTodoIfMatch(r dal.Repository, todo entity.Todo, ifMatch dto.IfMatch) error {
+	shared, err := isSharedTodo(r, todo)
+	if err != nil {
+		return err
+	}
+
+	return checkIfMatch(ifMatch, todo.Version, shared)
+}
+
+func equalTime(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+
+	return a.Equal(*b)
+}
+
+// Query a page of todos, the cursor of next page is set if there are more
+// todos. Ranks of user are filled
+func queryTodos(userID int64, query dto.TodoQuery, resource string) (dto.TodoPage, error) {
+	if after := query.After; after != nil && (after.Sort != query.Sort || after.Desc != query.Desc) {
+		return dto.TodoPage{}, util.NewErrorWithBadRequest("cursor does not match sort")
+	}
+
+	limit := query.Limit
+	if limit > 0 {
+		query.Limit++ // one more to detect next page
+	}
+
+	query.RankUserID = userID
+	query.TagUserID = userID
+	todos, err := repo.SelectTodosByQuery(query)
+	if err != nil {
+		return dto.TodoPage{}, fmt.Errorf("fails to get %v: %w", resource, err)
+	}
+
+	if err := fillTodoRanks(repo, userID, todos); err != nil {
+		return dto.TodoPage{}, err
+	}
+
+	page := dto.TodoPage{Todos: todos}
+	if limit > 0 && len(todos) > limit {
+		page.Todos = todos[:limit]
+		next := dto.NewTodoCursor(page.Todos[limit-1], query.Sort, query.Desc)
+		page.Next = &next
+	}
+
+	return page, nil
 }
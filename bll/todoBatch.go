@@ -0,0 +1,252 @@
+package bll
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/yzx9/otodo/dal"
+	"github.com/yzx9/otodo/model/dto"
+	"github.com/yzx9/otodo/model/entity"
+	"github.com/yzx9/otodo/otodo"
+	"github.com/yzx9/otodo/util"
+)
+
+const todoBatchMaxOperations = 100
+
+// Apply operations on todos in one transaction. Permissions of all
+// operations are checked first, and nothing is changed if any operation
+// fails, the error is reported in the result of failed operation. Version
+// is required for operations on shared todos, like If-Match
+func BatchTodos(userID int64, ops []dto.TodoBatchOperation) (dto.TodoBatchResult, error) {
+	if len(ops) > todoBatchMaxOperations {
+		return dto.TodoBatchResult{}, util.NewErrorWithBadRequest("too many operations, max %v", todoBatchMaxOperations)
+	}
+
+	results := make([]dto.TodoBatchItemResult, len(ops))
+	for i, op := range ops {
+		results[i] = dto.TodoBatchItemResult{ID: op.ID, Op: op.Op}
+	}
+
+	err := repo.Transaction(func(r dal.Repository) error {
+		failed := false
+		for i, op := range ops {
+			if err := checkTodoBatchOperation(r, userID, op); err != nil {
+				results[i].Error = newErrorDTO(err)
+				failed = true
+			}
+		}
+		if failed {
+			return errors.New("fails to check todo batch operations")
+		}
+
+		for i, op := range ops {
+			todo, err := applyTodoBatchOperation(r, userID, op)
+			if err != nil {
+				results[i].Error = newErrorDTO(err)
+				return err
+			}
+			results[i].Todo = &todo
+		}
+		return nil
+	})
+	if err != nil {
+		// changes have been rolled back
+		for i := range results {
+			results[i].Todo = nil
+		}
+		return dto.TodoBatchResult{Committed: false, Results: results}, nil
+	}
+
+	todos := make([]entity.Todo, 0, len(results))
+	for i := range results {
+		todos = append(todos, *results[i].Todo)
+	}
+	if err := fillTodoRanks(repo, userID, todos); err != nil {
+		return dto.TodoBatchResult{}, err
+	}
+	for i := range results {
+		results[i].Todo = &todos[i]
+	}
+
+	return dto.TodoBatchResult{Committed: true, Results: results}, nil
+}
+
+// Delete done todos in todo list, returns deleted todos
+func ClearCompletedTodos(userID, todoListID int64) ([]entity.Todo, error) {
+	if _, err := AccessTodoList(userID, todoListID, entity.TodoListRoleEditor); err != nil {
+		return nil, err
+	}
+
+	var todos []entity.Todo
+	err := repo.Transaction(func(r dal.Repository) error {
+		done := true
+		var err error
+		todos, err = r.SelectTodosByQuery(dto.TodoQuery{TodoListID: todoListID, Done: &done})
+		if err != nil {
+			return fmt.Errorf("fails to get todos: %w", err)
+		}
+
+		for i := range todos {
+			if err := r.DeleteTodo(todos[i].ID, todos[i].Version); err != nil {
+				return fmt.Errorf("fails to delete todo: %w", err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("fails to clear completed todos: %w", err)
+	}
+
+	return todos, nil
+}
+
+func checkTodoBatchOperation(r dal.Repository, userID int64, op dto.TodoBatchOperation) error {
+	todo, _, err := accessTodo(r, userID, op.ID, entity.TodoListRoleEditor)
+	if err != nil {
+		return err
+	}
+
+	ifMatch := dto.IfMatch{}
+	if op.Version != nil {
+		ifMatch = dto.IfMatch{Present: true, Versions: []int64{*op.Version}}
+	}
+	if err := checkTodoIfMatch(r, todo, ifMatch); err != nil {
+		return err
+	}
+
+	switch op.Op {
+	case dto.TodoBatchOpComplete,
+		dto.TodoBatchOpUncomplete,
+		dto.TodoBatchOpDelete,
+		dto.TodoBatchOpSetImportance,
+		dto.TodoBatchOpSetDeadline:
+		return nil
+
+	case dto.TodoBatchOpMove:
+		if err := checkTodoMovable(r, userID, todo); err != nil {
+			return err
+		}
+
+		if _, _, err := accessTodoList(r, userID, op.TodoListID, entity.TodoListRoleEditor); err != nil {
+			return fmt.Errorf("fails to get todo list: %w", err)
+		}
+		return nil
+
+	case dto.TodoBatchOpAddTag, dto.TodoBatchOpRemoveTag:
+		if !isValidTagName(op.Tag) {
+			return util.NewErrorWithBadRequest("invalid tag name: %v", op.Tag)
+		}
+		return nil
+
+	default:
+		return util.NewErrorWithBadRequest("unsupported operation: %v", op.Op)
+	}
+}
+
+// Apply operation, returns todo after operation, or before if deleted
+func applyTodoBatchOperation(r dal.Repository, userID int64, op dto.TodoBatchOperation) (entity.Todo, error) {
+	todo, err := r.SelectTodo(op.ID)
+	if err != nil {
+		return entity.Todo{}, fmt.Errorf("fails to get todo: %w", err)
+	}
+
+	switch op.Op {
+	case dto.TodoBatchOpComplete:
+		if todo.Done {
+			return todo, nil
+		}
+
+		if err := completeTodo(r, &todo); err != nil {
+			return entity.Todo{}, err
+		}
+
+	case dto.TodoBatchOpUncomplete:
+		if !todo.Done {
+			return todo, nil
+		}
+
+		// as uncompleting with deleteNext, successor is not duplicated on
+		// next completion
+		if err := uncompleteTodo(r, &todo, true); err != nil {
+			return entity.Todo{}, err
+		}
+
+	case dto.TodoBatchOpDelete:
+		if err := r.DeleteTodo(todo.ID, todo.Version); err != nil {
+			return entity.Todo{}, fmt.Errorf("fails to delete todo: %w", err)
+		}
+		return todo, nil
+
+	case dto.TodoBatchOpMove:
+		if err := moveTodo(r, userID, &todo, op.TodoListID); err != nil {
+			return entity.Todo{}, err
+		}
+		return todo, nil
+
+	case dto.TodoBatchOpSetImportance:
+		todo.Importance = op.Importance
+
+	case dto.TodoBatchOpSetDeadline:
+		// rrule starts from deadline
+		plan, err := updateTodoRepeatPlan(r, todo.UserID, todo.TodoRepeatPlan, todo.TodoRepeatPlan, op.Deadline)
+		if err != nil {
+			return entity.Todo{}, err
+		}
+		todo.Deadline = op.Deadline
+		todo.TodoRepeatPlanID = plan.ID
+		todo.TodoRepeatPlan = plan
+
+	case dto.TodoBatchOpAddTag, dto.TodoBatchOpRemoveTag:
+		if err := updateTodoTag(r, &todo, op.Tag, op.Op == dto.TodoBatchOpAddTag); err != nil {
+			return entity.Todo{}, err
+		}
+	}
+
+	if err := r.SaveTodo(&todo); err != nil {
+		return entity.Todo{}, err
+	}
+
+	return todo, nil
+}
+
+// Add or remove explicit tag, inline tags in title and memo can not be
+// removed
+func updateTodoTag(r dal.Repository, todo *entity.Todo, tag string, add bool) error {
+	oldTodo := *todo
+	tags := make([]string, 0, len(todo.Tags)+1)
+	for _, name := range todo.Tags {
+		if name != tag {
+			tags = append(tags, name)
+		}
+	}
+	if add {
+		tags = append(tags, tag)
+	}
+
+	todo.Tags = tags
+	tags, err := getTodoTags(*todo, oldTodo)
+	if err != nil {
+		return err
+	}
+
+	if !add {
+		for _, name := range tags {
+			if name == tag {
+				return util.NewErrorWithBadRequest("unable to remove inline tag: %v", tag)
+			}
+		}
+	}
+
+	todo.Tags = tags
+	return setTodoTags(r, *todo, oldTodo.Tags)
+}
+
+func newErrorDTO(err error) *dto.ErrorDTO {
+	code := otodo.ErrUnknown
+	var e *otodo.Error
+	if errors.As(err, &e) {
+		code = e.Code
+	}
+
+	return &dto.ErrorDTO{Code: int(code), Message: err.Error()}
+}
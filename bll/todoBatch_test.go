@@ -0,0 +1,151 @@
+package bll
+
+import (
+	"testing"
+	"time"
+
+	"github.com/yzx9/otodo/model/dto"
+	"github.com/yzx9/otodo/model/entity"
+	"github.com/yzx9/otodo/otodo"
+	"github.com/yzx9/otodo/util"
+)
+
+func TestBatchTodos(t *testing.T) {
+	useMemoryRepository(t)
+	alice := createTestUser(t, "alice")
+	bob := createTestUser(t, "bob")
+	todoList := createTestTodoList(t, alice.ID, "list")
+	todo := createTestTodo(t, alice.ID, entity.Todo{Title: "milk", TodoListID: todoList.ID})
+	other := createTestTodo(t, bob.ID, entity.Todo{Title: "bread", TodoListID: bob.BasicTodoListID})
+
+	result, err := BatchTodos(alice.ID, []dto.TodoBatchOperation{
+		{Op: dto.TodoBatchOpComplete, ID: todo.ID},
+		{Op: dto.TodoBatchOpComplete, ID: other.ID},
+	})
+	must(t, err)
+	if result.Committed || result.Results[0].Error != nil || result.Results[1].Error == nil {
+		t.Fatalf("batch with non-owned todo = %+v, want rejected by second operation", result)
+	}
+
+	got, err := repo.SelectTodo(todo.ID)
+	must(t, err)
+	if got.Done {
+		t.Fatal("todo completed, want rolled back")
+	}
+
+	result, err = BatchTodos(alice.ID, []dto.TodoBatchOperation{
+		{Op: dto.TodoBatchOpComplete, ID: todo.ID},
+		{Op: dto.TodoBatchOpAddTag, ID: todo.ID, Tag: "dairy"},
+	})
+	must(t, err)
+	if !result.Committed || !result.Results[1].Todo.Done || len(result.Results[1].Todo.Tags) != 1 {
+		t.Fatalf("batch = %+v, want committed", result)
+	}
+}
+
+// Version is required for shared todos, which is If-Match of batch
+func TestBatchTodosOnSharedTodo(t *testing.T) {
+	useMemoryRepository(t)
+	alice := createTestUser(t, "alice")
+	bob := createTestUser(t, "bob")
+	todoList := createTestTodoList(t, alice.ID, "shared")
+	joinTestTodoList(t, bob.ID, todoList.ID, entity.TodoListRoleEditor)
+	todo := createTestTodo(t, alice.ID, entity.Todo{Title: "milk", TodoListID: todoList.ID})
+
+	stale := todo.Version - 1
+	tests := []struct {
+		name    string
+		version *int64
+		want    otodo.ErrCode
+	}{
+		{"without version", nil, otodo.ErrPreconditionRequired},
+		{"stale version", &stale, otodo.ErrPreconditionFailed},
+		{"current version", &todo.Version, 0},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result, err := BatchTodos(bob.ID, []dto.TodoBatchOperation{
+				{Op: dto.TodoBatchOpSetImportance, ID: todo.ID, Importance: true, Version: tt.version},
+			})
+			must(t, err)
+
+			if tt.want == 0 {
+				if !result.Committed {
+					t.Fatalf("batch error = %v, want committed", result.Results[0].Error)
+				}
+				return
+			}
+
+			if result.Committed || result.Results[0].Error == nil || result.Results[0].Error.Code != int(tt.want) {
+				t.Fatalf("batch = %+v, want error %v", result.Results[0], tt.want)
+			}
+		})
+	}
+}
+
+// Unmodified successor is deleted on uncompleting, as UncompleteTodo with
+// deleteNext
+func TestBatchTodosUncomplete(t *testing.T) {
+	useMemoryRepository(t)
+	user := createTestUser(t, "alice")
+	deadline := time.Now().Add(time.Hour)
+	todo := createTestTodo(t, user.ID, entity.Todo{
+		Title:          "water plants",
+		TodoListID:     user.BasicTodoListID,
+		Deadline:       &deadline,
+		TodoRepeatPlan: entity.TodoRepeatPlan{Type: string(entity.TodoRepeatPlanTypeDay), Interval: 1},
+	})
+
+	completion, err := CompleteTodo(user.ID, todo.ID, dto.IfMatch{})
+	must(t, err)
+	if completion.Next == nil {
+		t.Fatal("successor not created")
+	}
+
+	result, err := BatchTodos(user.ID, []dto.TodoBatchOperation{{Op: dto.TodoBatchOpUncomplete, ID: todo.ID}})
+	must(t, err)
+	if !result.Committed || result.Results[0].Todo.Done || result.Results[0].Todo.NextID != nil {
+		t.Fatalf("batch = %+v, want uncompleted without successor", result)
+	}
+
+	if _, err := repo.SelectTodo(completion.Next.ID); !util.IsErrorCode(err, otodo.ErrNotFound) {
+		t.Fatalf("successor error = %v, want deleted", err)
+	}
+
+	completion, err = CompleteTodo(user.ID, todo.ID, dto.IfMatch{})
+	must(t, err)
+	if completion.Next == nil {
+		t.Fatal("successor not created again")
+	}
+}
+
+func TestClearCompletedTodos(t *testing.T) {
+	useMemoryRepository(t)
+	alice := createTestUser(t, "alice")
+	bob := createTestUser(t, "bob")
+	todoList := createTestTodoList(t, alice.ID, "list")
+	joinTestTodoList(t, bob.ID, todoList.ID, entity.TodoListRoleViewer)
+	done := createTestTodo(t, alice.ID, entity.Todo{Title: "done", TodoListID: todoList.ID})
+	undone := createTestTodo(t, alice.ID, entity.Todo{Title: "undone", TodoListID: todoList.ID})
+	_, err := CompleteTodo(alice.ID, done.ID, dto.IfMatch{Present: true, Any: true})
+	must(t, err)
+
+	if _, err := ClearCompletedTodos(bob.ID, todoList.ID); !util.IsErrorCode(err, otodo.ErrForbidden) {
+		t.Fatalf("clear by viewer error = %v, want forbidden", err)
+	}
+
+	todos, err := ClearCompletedTodos(alice.ID, todoList.ID)
+	must(t, err)
+	if len(todos) != 1 || todos[0].ID != done.ID {
+		t.Fatalf("cleared todos = %+v, want done one", todos)
+	}
+
+	if _, err := repo.SelectDeletedTodo(done.ID); err != nil {
+		t.Fatalf("done todo error = %v, want deleted", err)
+	}
+
+	if _, err := repo.SelectTodo(undone.ID); err != nil {
+		t.Fatalf("undone todo error = %v, want kept", err)
+	}
+}
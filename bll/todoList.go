@@ -4,6 +4,7 @@ import (
 	"fmt"
 
 	"github.com/yzx9/otodo/dal"
+	"github.com/yzx9/otodo/model/dto"
 	"github.com/yzx9/otodo/model/entity"
 	"github.com/yzx9/otodo/util"
 )
@@ -11,8 +12,11 @@ import (
 func CreateTodoList(userID int64, todoList *entity.TodoList) error {
 	todoList.IsBasic = false
 	todoList.UserID = userID
-	todoList.TodoListFolderID = 0
-	if err := dal.InsertTodoList(todoList); err != nil {
+	if err := checkTodoListFolder(userID, todoList.TodoListFolderID); err != nil {
+		return err
+	}
+
+	if err := repo.InsertTodoList(todoList); err != nil {
 		return fmt.Errorf("fails to create todo list: %w", err)
 	}
 
@@ -20,11 +24,21 @@ func CreateTodoList(userID int64, todoList *entity.TodoList) error {
 }
 
 func GetTodoList(userID, todoListID int64) (entity.TodoList, error) {
-	return OwnOrSharedTodoList(userID, todoListID)
+	list, err := OwnOrSharedTodoList(userID, todoListID)
+	if err != nil {
+		return entity.TodoList{}, err
+	}
+
+	lists := []entity.TodoList{list}
+	if err := fillTodoListRanks(repo, userID, lists); err != nil {
+		return entity.TodoList{}, err
+	}
+
+	return lists[0], nil
 }
 
 func ForceGetTodoList(todoListID int64) (entity.TodoList, error) {
-	list, err := dal.SelectTodoList(todoListID)
+	list, err := repo.SelectTodoList(todoListID)
 	if err != nil {
 		return entity.TodoList{}, fmt.Errorf("fails to get todo list: %w", err)
 	}
@@ -33,22 +47,75 @@ func ForceGetTodoList(todoListID int64) (entity.TodoList, error) {
 }
 
 func GetTodoLists(userID int64) ([]entity.TodoList, error) {
-	vec, err := dal.SelectTodoLists(userID)
+	vec, err := getTodoLists(repo, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := fillTodoListRanks(repo, userID, vec); err != nil {
+		return nil, err
+	}
+
+	return vec, nil
+}
+
+// Owned and shared todo lists, including todo lists in shared folders
+func getTodoLists(r dal.Repository, userID int64) ([]entity.TodoList, error) {
+	lists, err := r.SelectTodoLists(userID)
 	if err != nil {
 		return nil, fmt.Errorf("fails to get user todo lists: %w", err)
 	}
 
-	shared, err := dal.SelectSharedTodoLists(userID)
+	shared, err := r.SelectSharedTodoLists(userID)
 	if err != nil {
 		return nil, fmt.Errorf("fails to get user shared todo lists: %w", err)
 	}
 
-	vec = append(vec, shared...)
+	folders, err := r.SelectSharedTodoListFolders(userID)
+	if err != nil {
+		return nil, fmt.Errorf("fails to get user shared todo list folders: %w", err)
+	}
+
+	folderIDs := make([]int64, 0, len(folders))
+	for i := range folders {
+		folderIDs = append(folderIDs, folders[i].ID)
+	}
+
+	inFolders, err := r.SelectTodoListsByFolders(folderIDs)
+	if err != nil {
+		return nil, fmt.Errorf("fails to get todo lists in shared folders: %w", err)
+	}
+
+	// todo list may be shared both directly and by folder
+	seen := make(map[int64]bool)
+	vec := make([]entity.TodoList, 0, len(lists)+len(shared)+len(inFolders))
+	for _, list := range append(append(lists, shared...), inFolders...) {
+		if !seen[list.ID] {
+			seen[list.ID] = true
+			vec = append(vec, list)
+		}
+	}
 	return vec, nil
 }
 
-func UpdateTodoList(userID int64, todoList *entity.TodoList) error {
-	oldTodoList, err := OwnTodoList(userID, todoList.ID)
+// IDs of owned and shared todo lists
+func getTodoListIDs(r dal.Repository, userID int64) ([]int64, error) {
+	lists, err := getTodoLists(r, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	ids := make([]int64, 0, len(lists))
+	for _, list := range lists {
+		ids = append(ids, list.ID)
+	}
+	return ids, nil
+}
+
+// Update todo list by owner or manager, folder is only updated by owner since
+// it belongs to menu of owner
+func UpdateTodoList(userID int64, todoList *entity.TodoList, ifMatch dto.IfMatch) error {
+	oldTodoList, role, err := accessTodoList(repo, userID, todoList.ID, entity.TodoListRoleManager)
 	if err != nil {
 		return err
 	}
@@ -57,32 +124,92 @@ func UpdateTodoList(userID int64, todoList *entity.TodoList) error {
 		return util.NewErrorWithForbidden("unable to update basic todo list")
 	}
 
-	if err := dal.SaveTodoList(todoList); err != nil {
+	if err := checkTodoListIfMatch(oldTodoList, ifMatch); err != nil {
+		return err
+	}
+
+	if role != entity.TodoListRoleOwner {
+		todoList.TodoListFolderID = oldTodoList.TodoListFolderID
+	} else if err := checkTodoListFolder(userID, todoList.TodoListFolderID); err != nil {
+		return err
+	}
+
+	// keep fields which are not allowed to update
+	todoList.UserID = oldTodoList.UserID
+	todoList.TransferUserID = oldTodoList.TransferUserID
+	todoList.IsBasic = oldTodoList.IsBasic
+	todoList.IsSharing = oldTodoList.IsSharing
+	todoList.CreatedAt = oldTodoList.CreatedAt
+	todoList.Version = oldTodoList.Version
+
+	if err := repo.SaveTodoList(todoList); err != nil {
 		return fmt.Errorf("fails to update todo list: %w", err)
 	}
 
 	return nil
 }
 
-func DeleteTodoList(userID, todoListID int64) (entity.TodoList, error) {
+// Move todo list after another item in the same folder of menu, or to the
+// first if after is 0. Shared todo lists are in the root of menu, unless
+// their folders are shared too
+func ReorderTodoList(userID, todoListID, afterID int64) (entity.TodoList, error) {
+	list, err := OwnOrSharedTodoList(userID, todoListID)
+	if err != nil {
+		return entity.TodoList{}, err
+	}
+
+	err = repo.Transaction(func(r dal.Repository) error {
+		folders, err := getTodoListFolders(r, userID)
+		if err != nil {
+			return err
+		}
+
+		items, err := getMenuRankItems(r, userID, getMenuFolderID(folders, list))
+		if err != nil {
+			return err
+		}
+
+		_, err = moveRankItem(r, userID, items, todoListID, afterID)
+		return err
+	})
+	if err != nil {
+		return entity.TodoList{}, err
+	}
+
+	return GetTodoList(userID, todoListID)
+}
+
+func DeleteTodoList(userID, todoListID int64, ifMatch dto.IfMatch) (entity.TodoList, error) {
 	// only allow delete by owner, not shared users
 	todoList, err := OwnTodoList(userID, todoListID)
 	if err != nil {
 		return entity.TodoList{}, err
 	}
 
+	if err := checkTodoListIfMatch(todoList, ifMatch); err != nil {
+		return entity.TodoList{}, err
+	}
+
 	// disable to delete basic todo list
 	if todoList.IsBasic {
 		return entity.TodoList{}, util.NewErrorWithPreconditionFailed("unable to delete basic todo list: %v", todoListID)
 	}
 
-	// cascade delete todos
-	if _, err = dal.DeleteTodos(todoListID); err != nil {
-		return entity.TodoList{}, fmt.Errorf("fails to cascade delete todos: %w", err)
-	}
+	err = repo.Transaction(func(r dal.Repository) error {
+		// delete todo list first, see trash for details
+		if err := r.DeleteTodoList(todoListID, todoList.Version); err != nil {
+			return fmt.Errorf("fails to delete todo list: %w", err)
+		}
+
+		// cascade delete todos
+		if _, err := r.DeleteTodos(todoListID); err != nil {
+			return fmt.Errorf("fails to cascade delete todos: %w", err)
+		}
 
-	if err = dal.DeleteTodoList(todoListID); err != nil {
-		return entity.TodoList{}, fmt.Errorf("fails to delete todo list: %w", err)
+		return nil
+	})
+	if err != nil {
+		return entity.TodoList{}, err
 	}
 
 	return todoList, nil
@@ -90,7 +217,7 @@ func DeleteTodoList(userID, todoListID int64) (entity.TodoList, error) {
 
 // owner
 func OwnTodoList(userID, todoListID int64) (entity.TodoList, error) {
-	todoList, err := dal.SelectTodoList(todoListID)
+	todoList, err := repo.SelectTodoList(todoListID)
 	if err != nil {
 		return entity.TodoList{}, fmt.Errorf("fails to get todo list: %v", todoListID)
 	}
@@ -101,3 +228,22 @@ func OwnTodoList(userID, todoListID int64) (entity.TodoList, error) {
 
 	return todoList, nil
 }
+
+// Folder should be owned by user, 0 for root
+func checkTodoListFolder(userID, todoListFolderID int64) error {
+	if todoListFolderID == 0 {
+		return nil
+	}
+
+	_, err := OwnTodoListFolder(userID, todoListFolderID)
+	return err
+}
+
+func checkTodoListIfMatch(todoList entity.TodoList, ifMatch dto.IfMatch) error {
+	shared, err := isSharedTodoList(repo, todoList.ID)
+	if err != nil {
+		return err
+	}
+
+	return checkIfMatch(ifMatch, todoList.Version, shared)
+}
@@ -5,12 +5,13 @@ import (
 
 	"github.com/yzx9/otodo/dal"
 	"github.com/yzx9/otodo/model/entity"
+	"github.com/yzx9/otodo/otodo"
 	"github.com/yzx9/otodo/util"
 )
 
 func CreateTodoListFolder(userID int64, folder *entity.TodoListFolder) error {
 	folder.UserID = userID
-	if err := dal.InsertTodoListFolder(folder); err != nil {
+	if err := repo.InsertTodoListFolder(folder); err != nil {
 		return fmt.Errorf("fails to create todo list folder: %w", err)
 	}
 
@@ -18,19 +19,88 @@ func CreateTodoListFolder(userID int64, folder *entity.TodoListFolder) error {
 }
 
 func GetTodoListFolder(userID, todoListFolderID int64) (entity.TodoListFolder, error) {
-	return OwnTodoListFolder(userID, todoListFolderID)
+	folder, err := AccessTodoListFolder(userID, todoListFolderID, entity.TodoListRoleViewer)
+	if err != nil {
+		return entity.TodoListFolder{}, err
+	}
+
+	folders := []entity.TodoListFolder{folder}
+	if err := fillTodoListFolderRanks(repo, userID, folders); err != nil {
+		return entity.TodoListFolder{}, err
+	}
+
+	return folders[0], nil
 }
 
+// Owned and shared todo list folders
 func GetTodoListFolders(userID int64) ([]entity.TodoListFolder, error) {
-	vec, err := dal.SelectTodoListFolders(userID)
+	vec, err := getTodoListFolders(repo, userID)
 	if err != nil {
-		return nil, fmt.Errorf("fails to get todo list folder: %w", err)
+		return nil, err
+	}
+
+	if err := fillTodoListFolderRanks(repo, userID, vec); err != nil {
+		return nil, err
 	}
 
 	return vec, nil
 }
 
-func DeleteTodoListFolder(userID, todoListFolderID int64) (entity.TodoListFolder, error) {
+func getTodoListFolders(r dal.Repository, userID int64) ([]entity.TodoListFolder, error) {
+	vec, err := r.SelectTodoListFolders(userID)
+	if err != nil {
+		return nil, fmt.Errorf("fails to get todo list folder: %w", err)
+	}
+
+	shared, err := r.SelectSharedTodoListFolders(userID)
+	if err != nil {
+		return nil, fmt.Errorf("fails to get user shared todo list folders: %w", err)
+	}
+
+	return append(vec, shared...), nil
+}
+
+func UpdateTodoListFolder(userID int64, folder *entity.TodoListFolder) error {
+	oldFolder, err := OwnTodoListFolder(userID, folder.ID)
+	if err != nil {
+		return err
+	}
+
+	folder.UserID = oldFolder.UserID
+	folder.CreatedAt = oldFolder.CreatedAt
+	if err := repo.SaveTodoListFolder(folder); err != nil {
+		return fmt.Errorf("fails to update todo list folder: %w", err)
+	}
+
+	return nil
+}
+
+// Move todo list folder after another item in the root of menu, or to the
+// first if after is 0
+func ReorderTodoListFolder(userID, todoListFolderID, afterID int64) (entity.TodoListFolder, error) {
+	if _, err := AccessTodoListFolder(userID, todoListFolderID, entity.TodoListRoleViewer); err != nil {
+		return entity.TodoListFolder{}, err
+	}
+
+	err := repo.Transaction(func(r dal.Repository) error {
+		items, err := getMenuRankItems(r, userID, 0)
+		if err != nil {
+			return err
+		}
+
+		_, err = moveRankItem(r, userID, items, todoListFolderID, afterID)
+		return err
+	})
+	if err != nil {
+		return entity.TodoListFolder{}, err
+	}
+
+	return GetTodoListFolder(userID, todoListFolderID)
+}
+
+// Delete todo list folder, todo lists in it and their todos are deleted if
+// cascade, otherwise moved to root
+func DeleteTodoListFolder(userID, todoListFolderID int64, cascade bool) (entity.TodoListFolder, error) {
 	write := func(err error) (entity.TodoListFolder, error) {
 		return entity.TodoListFolder{}, err
 	}
@@ -40,14 +110,39 @@ func DeleteTodoListFolder(userID, todoListFolderID int64) (entity.TodoListFolder
 		return write(err)
 	}
 
-	// TODO[feat] Whether to cascade delete todo lists
-	// Cascade delete todo lists
-	if _, err = dal.DeleteTodoListsByFolder(todoListFolderID); err != nil {
-		return write(fmt.Errorf("fails to cascade delete todo lists: %w", err))
-	}
+	err = repo.Transaction(func(r dal.Repository) error {
+		// Delete todo list folder first, see trash for details
+		if err := r.DeleteTodoListFolder(todoListFolderID); err != nil {
+			return fmt.Errorf("fails to delete todo list folder: %w", err)
+		}
+
+		if !cascade {
+			if _, err := r.UngroupTodoListsByFolder(todoListFolderID); err != nil {
+				return fmt.Errorf("fails to ungroup todo lists: %w", err)
+			}
+			return nil
+		}
+
+		// Cascade delete todo lists and their todos
+		lists, err := r.SelectTodoListsByFolders([]int64{todoListFolderID})
+		if err != nil {
+			return fmt.Errorf("fails to get todo lists: %w", err)
+		}
 
-	if err = dal.DeleteTodoListFolder(todoListFolderID); err != nil {
-		return write(fmt.Errorf("fails to delete todo list folder: %w", err))
+		if _, err := r.DeleteTodoListsByFolder(todoListFolderID); err != nil {
+			return fmt.Errorf("fails to cascade delete todo lists: %w", err)
+		}
+
+		for _, list := range lists {
+			if _, err := r.DeleteTodos(list.ID); err != nil {
+				return fmt.Errorf("fails to cascade delete todos: %w", err)
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return write(err)
 	}
 
 	return folder, nil
@@ -55,9 +150,9 @@ func DeleteTodoListFolder(userID, todoListFolderID int64) (entity.TodoListFolder
 
 // Verify permission
 func OwnTodoListFolder(userID, todoListFolderID int64) (entity.TodoListFolder, error) {
-	todoListFolder, err := dal.SelectTodoListFolder(todoListFolderID)
+	todoListFolder, err := repo.SelectTodoListFolder(todoListFolderID)
 	if err != nil {
-		return entity.TodoListFolder{}, fmt.Errorf("fails to get todo list folder: %v", todoListFolderID)
+		return entity.TodoListFolder{}, fmt.Errorf("fails to get todo list folder: %w", err)
 	}
 
 	if todoListFolder.UserID != userID {
@@ -66,3 +161,47 @@ func OwnTodoListFolder(userID, todoListFolderID int64) (entity.TodoListFolder, e
 
 	return todoListFolder, nil
 }
+
+// Todo list folder of owner or shared user with role, or roles after it
+func AccessTodoListFolder(userID, todoListFolderID int64, role entity.TodoListRole) (entity.TodoListFolder, error) {
+	folder, _, err := accessTodoListFolder(userID, todoListFolderID, role)
+	return folder, err
+}
+
+func accessTodoListFolder(userID, todoListFolderID int64, role entity.TodoListRole) (entity.TodoListFolder, entity.TodoListRole, error) {
+	folder, err := repo.SelectTodoListFolder(todoListFolderID)
+	if err != nil {
+		return entity.TodoListFolder{}, 0, fmt.Errorf("fails to get todo list folder: %w", err)
+	}
+
+	userRole, err := getTodoListFolderRole(repo, userID, folder)
+	if err != nil {
+		return entity.TodoListFolder{}, 0, err
+	}
+
+	if userRole == 0 {
+		return entity.TodoListFolder{}, 0, util.NewErrorWithForbidden("unable to handle unauthorized todo list folder: %v", todoListFolderID)
+	}
+
+	if userRole < role {
+		return entity.TodoListFolder{}, 0, util.NewErrorWithForbidden("insufficient role for todo list folder: %v", todoListFolderID)
+	}
+
+	return folder, userRole, nil
+}
+
+// Role of user in todo list folder, 0 if user is neither owner nor shared user
+func getTodoListFolderRole(r dal.Repository, userID int64, folder entity.TodoListFolder) (entity.TodoListRole, error) {
+	if folder.UserID == userID {
+		return entity.TodoListRoleOwner, nil
+	}
+
+	role, err := r.SelectTodoListFolderSharedUserRole(userID, folder.ID)
+	if util.IsErrorCode(err, otodo.ErrNotFound) {
+		return 0, nil
+	} else if err != nil {
+		return 0, fmt.Errorf("fails to get todo list folder sharing: %w", err)
+	}
+
+	return role, nil
+}
@@ -0,0 +1,162 @@
+package bll
+
+import (
+	"fmt"
+
+	"github.com/yzx9/otodo/dal"
+	"github.com/yzx9/otodo/model/dto"
+	"github.com/yzx9/otodo/model/entity"
+	"github.com/yzx9/otodo/util"
+)
+
+/**
+ * oTodo List Folder Sharing
+ */
+
+// Create sharing token for todo list folder, users joined by token have
+// role in all todo lists of it, including todo lists added later
+func CreateTodoListFolderSharing(userID, todoListFolderID int64, payload dto.SharingDTO) (entity.Sharing, error) {
+	_, userRole, err := accessTodoListFolder(userID, todoListFolderID, entity.TodoListRoleManager)
+	if err != nil {
+		return entity.Sharing{}, err
+	}
+
+	if payload.Type != 0 && payload.Type != entity.SharingTypeTodoListFolder {
+		return entity.Sharing{}, util.NewErrorWithBadRequest("unsupported sharing type: %v", payload.Type)
+	}
+
+	payload.Type = entity.SharingTypeTodoListFolder
+	sharing, err := newSharing(userID, todoListFolderID, payload)
+	if err != nil {
+		return entity.Sharing{}, err
+	}
+
+	if err := setSharingRole(&sharing, userRole, payload); err != nil {
+		return entity.Sharing{}, err
+	}
+
+	if err := repo.InsertSharing(&sharing); err != nil {
+		return entity.Sharing{}, fmt.Errorf("fails to create sharing token: %w", err)
+	}
+
+	return sharing, nil
+}
+
+// Get active sharing tokens of todo list folder with usage, by managers only
+func GetActiveTodoListFolderSharings(userID, todoListFolderID int64) ([]dto.SharingTokenStats, error) {
+	if _, err := AccessTodoListFolder(userID, todoListFolderID, entity.TodoListRoleManager); err != nil {
+		return nil, err
+	}
+
+	return getActiveSharings(todoListFolderID, entity.SharingTypeTodoListFolder)
+}
+
+/**
+ * oTodo List Folder Shared Users
+ */
+
+// Join todo list folder by sharing token, role of existing shared user is
+// not changed by token
+func CreateTodoListFolderSharedUser(userID int64, token string) error {
+	sharing, err := ValidSharing(token)
+	if err != nil {
+		return err
+	}
+
+	if sharing.Type != entity.SharingTypeTodoListFolder {
+		return util.NewErrorWithForbidden("unable to join todo list folder by sharing token: %v", token)
+	}
+
+	folder, err := repo.SelectTodoListFolder(sharing.RelatedID)
+	if err != nil {
+		return fmt.Errorf("fails to get todo list folder: %w", err)
+	}
+
+	role, err := getTodoListFolderRole(repo, userID, folder)
+	if err != nil {
+		return err
+	}
+
+	if role != 0 {
+		return nil
+	}
+
+	return repo.Transaction(func(r dal.Repository) error {
+		if err := redeemSharing(r, userID, sharing); err != nil {
+			return err
+		}
+
+		return joinTodoListFolder(r, userID, folder.ID, sharing.Role)
+	})
+}
+
+func GetTodoListFolderSharedUsers(userID, todoListFolderID int64) ([]dto.TodoListSharedUser, error) {
+	if _, err := AccessTodoListFolder(userID, todoListFolderID, entity.TodoListRoleViewer); err != nil {
+		return nil, err
+	}
+
+	sharedUsers, err := repo.SelectTodoListFolderSharedUsers(todoListFolderID)
+	if err != nil {
+		return nil, fmt.Errorf("fails to get todo list folder shared users: %w", err)
+	}
+
+	vec := make([]dto.TodoListSharedUser, 0, len(sharedUsers))
+	for _, sharedUser := range sharedUsers {
+		vec = append(vec, dto.TodoListSharedUser{User: sharedUser.User, Role: sharedUser.Role})
+	}
+
+	return vec, nil
+}
+
+// Delete shared user from todo list folder,
+// can be called by owner to delete anyone,
+// or called by manager to delete viewers and editors,
+// or called by shared user to delete themselves
+func DeleteTodoListFolderSharedUser(operatorID, userID, todoListFolderID int64) error {
+	folder, role, err := accessTodoListFolder(operatorID, todoListFolderID, entity.TodoListRoleViewer)
+	if err != nil {
+		return err
+	}
+
+	if userID != operatorID && role != entity.TodoListRoleOwner {
+		if role < entity.TodoListRoleManager {
+			return util.NewErrorWithForbidden("unable to delete shared user")
+		}
+
+		userRole, err := getTodoListFolderRole(repo, userID, folder)
+		if err != nil {
+			return err
+		}
+
+		if userRole >= entity.TodoListRoleManager {
+			return util.NewErrorWithForbidden("unable to delete manager of todo list folder")
+		}
+	}
+
+	if err := repo.DeleteTodoListFolderSharedUser(userID, todoListFolderID); err != nil {
+		return fmt.Errorf("fails to delete todo list folder shared users: %w", err)
+	}
+
+	return nil
+}
+
+// Add user to todo list folder as shared user with role, tags of todo
+// lists in it are shared
+func joinTodoListFolder(r dal.Repository, userID, todoListFolderID int64, role entity.TodoListRole) error {
+	if err := r.InsertTodoListFolderSharedUser(userID, todoListFolderID, role); err != nil {
+		return fmt.Errorf("fails to create todo list folder shared user: %w", err)
+	}
+
+	lists, err := r.SelectTodoListsByFolders([]int64{todoListFolderID})
+	if err != nil {
+		return fmt.Errorf("fails to get todo lists: %w", err)
+	}
+
+	for i := range lists {
+		if err := shareTodoListTags(r, userID, lists[i].ID); err != nil {
+			return fmt.Errorf("fails to share tags: %w", err)
+		}
+	}
+
+	return nil
+}
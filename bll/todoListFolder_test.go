@@ -0,0 +1,51 @@
+package bll
+
+import (
+	"testing"
+
+	"github.com/yzx9/otodo/model/dto"
+	"github.com/yzx9/otodo/model/entity"
+)
+
+func TestDeleteTodoListFolder(t *testing.T) {
+	tests := []struct {
+		name    string
+		cascade bool
+	}{
+		{"ungroup", false},
+		{"cascade", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useMemoryRepository(t)
+			user := createTestUser(t, "alice")
+			folder := entity.TodoListFolder{Name: "folder"}
+			must(t, CreateTodoListFolder(user.ID, &folder))
+			todoList := entity.TodoList{Name: "list", TodoListFolderID: folder.ID}
+			must(t, CreateTodoList(user.ID, &todoList))
+			todo := createTestTodo(t, user.ID, entity.Todo{Title: "todo", TodoListID: todoList.ID})
+
+			_, err := DeleteTodoListFolder(user.ID, folder.ID, tt.cascade)
+			must(t, err)
+
+			_, err = repo.SelectDeletedTodo(todo.ID)
+			if deleted := err == nil; deleted != tt.cascade {
+				t.Fatalf("todo deleted = %v, want %v", deleted, tt.cascade)
+			}
+
+			if !tt.cascade {
+				return
+			}
+
+			// restored with folder
+			_, err = RestoreTodoListFolder(user.ID, folder.ID)
+			must(t, err)
+			page, err := GetTodos(user.ID, todoList.ID, dto.TodoQuery{})
+			must(t, err)
+			if len(page.Todos) != 1 || page.Todos[0].ID != todo.ID {
+				t.Errorf("todos should be restored with folder, got %+v", page.Todos)
+			}
+		})
+	}
+}
@@ -0,0 +1,247 @@
+package bll
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/yzx9/otodo/dal"
+	"github.com/yzx9/otodo/model/dto"
+	"github.com/yzx9/otodo/model/entity"
+	"github.com/yzx9/otodo/otodo"
+	"github.com/yzx9/otodo/util"
+)
+
+const invitationEmailMaxLength = 32 // size of user email column
+
+/**
+ * oTodo List Invitation
+ */
+
+// Invite user to todo list by user name or email, invitee joins after
+// accepting. Invitation for email not registered yet is held until the
+// email registers
+func CreateTodoListInvitation(userID, todoListID int64, payload dto.TodoListInvitationDTO) (dto.TodoListInvitation, error) {
+	todoList, userRole, err := accessTodoList(repo, userID, todoListID, entity.TodoListRoleManager)
+	if err != nil {
+		return dto.TodoListInvitation{}, err
+	}
+
+	if todoList.IsBasic {
+		return dto.TodoListInvitation{}, util.NewErrorWithBadRequest("unable to share basic todo list: %v", todoListID)
+	}
+
+	role, err := getInvitationRole(userRole, payload.Role)
+	if err != nil {
+		return dto.TodoListInvitation{}, err
+	}
+
+	invitee, email, err := getInvitee(payload)
+	if err != nil {
+		return dto.TodoListInvitation{}, err
+	}
+
+	if invitee.ID != 0 {
+		inviteeRole, err := getTodoListRole(repo, invitee.ID, todoList)
+		if err != nil {
+			return dto.TodoListInvitation{}, err
+		}
+
+		if inviteeRole != 0 {
+			return dto.TodoListInvitation{}, util.NewError(otodo.ErrDuplicateID, "user has joined todo list: %v", invitee.Name)
+		}
+	}
+
+	invitations, err := repo.SelectTodoListInvitations(todoListID)
+	if err != nil {
+		return dto.TodoListInvitation{}, fmt.Errorf("fails to get todo list invitations: %w", err)
+	}
+
+	for _, invitation := range invitations {
+		if (invitee.ID != 0 && invitation.InviteeID == invitee.ID) || (email != "" && invitation.InviteeID == 0 && invitation.Email == email) {
+			return dto.TodoListInvitation{}, util.NewError(otodo.ErrDuplicateID, "user has been invited to todo list: %v", todoListID)
+		}
+	}
+
+	invitation := entity.TodoListInvitation{
+		Role:       role,
+		Email:      email,
+		InviteeID:  invitee.ID,
+		TodoListID: todoListID,
+		UserID:     userID,
+	}
+	if err := repo.InsertTodoListInvitation(&invitation); err != nil {
+		return dto.TodoListInvitation{}, fmt.Errorf("fails to create todo list invitation: %w", err)
+	}
+
+	inviter, err := GetUser(userID)
+	if err != nil {
+		return dto.TodoListInvitation{}, err
+	}
+
+	invitation.Invitee = invitee
+	invitation.TodoList = todoList
+	invitation.User = inviter
+	return dto.NewTodoListInvitation(invitation), nil
+}
+
+// Get pending invitations of todo list, by managers only
+func GetTodoListInvitations(userID, todoListID int64) ([]dto.TodoListInvitation, error) {
+	todoList, err := AccessTodoList(userID, todoListID, entity.TodoListRoleManager)
+	if err != nil {
+		return nil, err
+	}
+
+	invitations, err := repo.SelectTodoListInvitations(todoListID)
+	if err != nil {
+		return nil, fmt.Errorf("fails to get todo list invitations: %w", err)
+	}
+
+	vec := make([]dto.TodoListInvitation, 0, len(invitations))
+	for _, invitation := range invitations {
+		invitation.TodoList = todoList
+		vec = append(vec, dto.NewTodoListInvitation(invitation))
+	}
+
+	return vec, nil
+}
+
+// Revoke pending invitation of todo list, by managers only
+func DeleteTodoListInvitation(userID, todoListID, invitationID int64) error {
+	if _, err := AccessTodoList(userID, todoListID, entity.TodoListRoleManager); err != nil {
+		return err
+	}
+
+	invitation, err := repo.SelectTodoListInvitation(invitationID)
+	if err != nil {
+		return fmt.Errorf("fails to get todo list invitation: %w", err)
+	}
+
+	if invitation.TodoListID != todoListID {
+		return util.NewErrorWithNotFound("todo list invitation not found: %v", invitationID)
+	}
+
+	if err := repo.DeleteTodoListInvitation(invitationID); err != nil {
+		return fmt.Errorf("fails to delete todo list invitation: %w", err)
+	}
+
+	return nil
+}
+
+// Get pending invitations to user, invitations of deleted todo lists are
+// hidden until restored
+func GetUserTodoListInvitations(userID int64) ([]dto.TodoListInvitation, error) {
+	invitations, err := repo.SelectUserTodoListInvitations(userID)
+	if err != nil {
+		return nil, fmt.Errorf("fails to get todo list invitations: %w", err)
+	}
+
+	vec := make([]dto.TodoListInvitation, 0, len(invitations))
+	for _, invitation := range invitations {
+		if invitation.TodoList.ID != 0 {
+			vec = append(vec, dto.NewTodoListInvitation(invitation))
+		}
+	}
+
+	return vec, nil
+}
+
+// Accept invitation and join todo list, role of existing shared user is not
+// changed by invitation
+func AcceptTodoListInvitation(userID, invitationID int64) error {
+	invitation, err := getUserTodoListInvitation(userID, invitationID)
+	if err != nil {
+		return err
+	}
+
+	todoList, err := ForceGetTodoList(invitation.TodoListID)
+	if err != nil {
+		return err
+	}
+
+	role, err := getTodoListRole(repo, userID, todoList)
+	if err != nil {
+		return err
+	}
+
+	return repo.Transaction(func(r dal.Repository) error {
+		if err := r.DeleteTodoListInvitation(invitation.ID); err != nil {
+			return fmt.Errorf("fails to delete todo list invitation: %w", err)
+		}
+
+		if role != 0 {
+			return nil
+		}
+
+		return joinTodoList(r, userID, invitation.TodoListID, invitation.Role)
+	})
+}
+
+func DeclineTodoListInvitation(userID, invitationID int64) error {
+	invitation, err := getUserTodoListInvitation(userID, invitationID)
+	if err != nil {
+		return err
+	}
+
+	if err := repo.DeleteTodoListInvitation(invitation.ID); err != nil {
+		return fmt.Errorf("fails to delete todo list invitation: %w", err)
+	}
+
+	return nil
+}
+
+// Hand invitations held for email over to the new user
+func handOverTodoListInvitations(r dal.Repository, user entity.User) error {
+	if user.Email == "" {
+		return nil
+	}
+
+	if _, err := r.UpdateTodoListInvitationsInvitee(user.Email, user.ID); err != nil {
+		return fmt.Errorf("fails to update todo list invitations: %w", err)
+	}
+
+	return nil
+}
+
+// Registered invitee, or email if not registered
+func getInvitee(payload dto.TodoListInvitationDTO) (entity.User, string, error) {
+	switch {
+	case payload.UserName != "" && payload.Email != "":
+		return entity.User{}, "", util.NewErrorWithBadRequest("invite by either user name or email")
+
+	case payload.UserName != "":
+		user, err := repo.SelectUserByUserName(payload.UserName)
+		if err != nil {
+			return entity.User{}, "", fmt.Errorf("fails to get user: %w", err)
+		}
+		return user, "", nil
+
+	case payload.Email != "":
+		if len(payload.Email) > invitationEmailMaxLength || !strings.Contains(payload.Email, "@") {
+			return entity.User{}, "", util.NewErrorWithBadRequest("invalid email: %v", payload.Email)
+		}
+
+		user, err := repo.SelectUserByEmail(payload.Email)
+		if util.IsErrorCode(err, otodo.ErrNotFound) {
+			return entity.User{}, payload.Email, nil
+		} else if err != nil {
+			return entity.User{}, "", fmt.Errorf("fails to get user: %w", err)
+		}
+		return user, "", nil
+
+	default:
+		return entity.User{}, "", util.NewErrorWithBadRequest("user name or email required")
+	}
+}
+
+func getUserTodoListInvitation(userID, invitationID int64) (entity.TodoListInvitation, error) {
+	invitation, err := repo.SelectTodoListInvitation(invitationID)
+	if err != nil {
+		return entity.TodoListInvitation{}, fmt.Errorf("fails to get todo list invitation: %w", err)
+	}
+
+	if invitation.InviteeID != userID {
+		return entity.TodoListInvitation{}, util.NewErrorWithNotFound("todo list invitation not found: %v", invitationID)
+	}
+
+	return invitation, nil
+}
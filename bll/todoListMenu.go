@@ -2,9 +2,11 @@ package bll
 
 import (
 	"fmt"
+	"sort"
 
 	"github.com/yzx9/otodo/dal"
 	"github.com/yzx9/otodo/model/dto"
+	"github.com/yzx9/otodo/model/entity"
 )
 
 // Get Menu, folder+list tree
@@ -14,12 +16,36 @@ func GetTodoListMenu(userID int64) ([]dto.TodoListMenuItem, error) {
 		return nil, fmt.Errorf("fails to get user menu: %w", err)
 	}
 
-	lists, err := dal.SelectTodoListsWithMenuFormat(userID)
+	lists, err := repo.SelectTodoListsWithMenuFormat(userID)
 	if err != nil {
 		return nil, fmt.Errorf("fails to get user menu: %w", err)
 	}
 
-	// TODO[feat]: Sortable
+	// todo lists in shared folders, including todo lists added later
+	sharedFolderIDs := make([]int64, 0)
+	for i := range folders {
+		if folders[i].UserID != userID {
+			sharedFolderIDs = append(sharedFolderIDs, folders[i].ID)
+		}
+	}
+
+	inSharedFolders, err := repo.SelectTodoListsWithMenuFormatByFolders(sharedFolderIDs)
+	if err != nil {
+		return nil, fmt.Errorf("fails to get user menu: %w", err)
+	}
+	lists = append(lists, inSharedFolders...)
+
+	ids := make([]int64, 0, len(lists))
+	for i := range lists {
+		ids = append(ids, lists[i].ID)
+	}
+
+	ranks, err := getRanks(repo, userID, entity.RankTypeTodoList, ids)
+	if err != nil {
+		return nil, fmt.Errorf("fails to get user menu: %w", err)
+	}
+
+	// folders are sorted and before unranked todo lists
 	menu := make([]dto.TodoListMenuItem, 0)
 	for i := range folders {
 		menu = append(menu, dto.TodoListMenuItem{
@@ -27,6 +53,7 @@ func GetTodoListMenu(userID int64) ([]dto.TodoListMenuItem, error) {
 				ID:    folders[i].ID,
 				Name:  folders[i].Name,
 				Count: 0,
+				Rank:  folders[i].Rank,
 			},
 			IsLeaf:   false,
 			Children: make([]dto.TodoListMenuItem, 0),
@@ -34,6 +61,7 @@ func GetTodoListMenu(userID int64) ([]dto.TodoListMenuItem, error) {
 	}
 
 	for i := range lists {
+		lists[i].Rank = ranks[lists[i].ID]
 		item := dto.TodoListMenuItem{
 			TodoListMenuItemRaw: lists[i],
 			IsLeaf:              true,
@@ -45,7 +73,7 @@ func GetTodoListMenu(userID int64) ([]dto.TodoListMenuItem, error) {
 		}
 
 		for j := range menu {
-			if menu[j].ID == lists[i].TodoListFolderID {
+			if !menu[j].IsLeaf && menu[j].ID == lists[i].TodoListFolderID {
 				menu[j].Count += lists[i].Count
 				menu[j].Children = append(menu[j].Children, item)
 			}
@@ -53,5 +81,67 @@ func GetTodoListMenu(userID int64) ([]dto.TodoListMenuItem, error) {
 		// TODO[bug]: need log if data inconsistency
 	}
 
+	sortTodoListMenu(menu)
+	for i := range menu {
+		sortTodoListMenu(menu[i].Children)
+	}
+
 	return menu, nil
 }
+
+func sortTodoListMenu(menu []dto.TodoListMenuItem) {
+	sort.SliceStable(menu, func(i, j int) bool { return rankLess(menu[i].Rank, menu[j].Rank) })
+}
+
+// Items in folder of menu sorted by rank, folders and todo lists are in
+// root if folder is 0, and so are shared todo lists unless their folders
+// are shared too
+func getMenuRankItems(r dal.Repository, userID, todoListFolderID int64) ([]rankItem, error) {
+	folders, err := getTodoListFolders(r, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	lists, err := getTodoLists(r, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	listIDs := make([]int64, 0, len(lists))
+	for i := range lists {
+		if getMenuFolderID(folders, lists[i]) == todoListFolderID {
+			listIDs = append(listIDs, lists[i].ID)
+		}
+	}
+
+	items, err := getRankItems(r, userID, entity.RankTypeTodoList, listIDs)
+	if err != nil || todoListFolderID != 0 {
+		return items, err
+	}
+
+	folderIDs := make([]int64, 0, len(folders))
+	for i := range folders {
+		folderIDs = append(folderIDs, folders[i].ID)
+	}
+
+	folderItems, err := getRankItems(r, userID, entity.RankTypeTodoListFolder, folderIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	items = append(folderItems, items...)
+	sortRankItems(items)
+	return items, nil
+}
+
+// Folder of todo list in menu of user, which is root if folder is not in
+// folders of user
+func getMenuFolderID(folders []entity.TodoListFolder, list entity.TodoList) int64 {
+	for i := range folders {
+		if folders[i].ID == list.TodoListFolderID {
+			return list.TodoListFolderID
+		}
+	}
+
+	return 0
+}
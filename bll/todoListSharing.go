@@ -4,7 +4,9 @@ import (
 	"fmt"
 
 	"github.com/yzx9/otodo/dal"
+	"github.com/yzx9/otodo/model/dto"
 	"github.com/yzx9/otodo/model/entity"
+	"github.com/yzx9/otodo/otodo"
 	"github.com/yzx9/otodo/util"
 )
 
@@ -17,59 +19,135 @@ func CreateTodoListSharedUser(userID int64, token string) error {
 		return err
 	}
 
-	exist, err := ExistTodoListSharing(userID, sharing.RelatedID)
+	if sharing.Type != entity.SharingTypeTodoList {
+		return util.NewErrorWithForbidden("unable to join todo list by sharing token: %v", token)
+	}
+
+	todoList, err := ForceGetTodoList(sharing.RelatedID)
 	if err != nil {
 		return err
 	}
 
-	if exist {
+	if todoList.UserID == userID {
 		return nil
 	}
 
-	err = dal.InsertTodoListSharedUser(userID, sharing.RelatedID)
+	// role of existing shared user is not changed by token
+	exist, err := ExistTodoListSharing(userID, sharing.RelatedID)
 	if err != nil {
-		return fmt.Errorf("fails to create todo list shared user: %w", err)
+		return err
 	}
 
-	return nil
+	if exist {
+		return nil
+	}
+
+	return repo.Transaction(func(r dal.Repository) error {
+		if err := redeemSharing(r, userID, sharing); err != nil {
+			return err
+		}
+
+		return joinTodoList(r, userID, sharing.RelatedID, sharing.Role)
+	})
 }
 
-func GetTodoListSharedUsers(userID, todoListID int64) ([]entity.User, error) {
+func GetTodoListSharedUsers(userID, todoListID int64) ([]dto.TodoListSharedUser, error) {
 	_, err := OwnOrSharedTodoList(userID, todoListID)
 	if err != nil {
 		return nil, err
 	}
 
-	users, err := dal.SelectTodoListSharedUsers(todoListID)
+	users, err := repo.SelectTodoListSharedUsers(todoListID)
 	if err != nil {
 		return nil, fmt.Errorf("fails to get todo list shared users: %w", err)
 	}
 
-	return users, nil
+	roles, err := repo.SelectTodoListSharedUserRoles(todoListID)
+	if err != nil {
+		return nil, fmt.Errorf("fails to get todo list shared users: %w", err)
+	}
+
+	vec := make([]dto.TodoListSharedUser, 0, len(users))
+	for _, user := range users {
+		vec = append(vec, dto.TodoListSharedUser{User: user, Role: roles[user.ID]})
+	}
+
+	return vec, nil
+}
+
+// Change role of shared user, only owner is allowed
+func UpdateTodoListSharedUserRole(operatorID, userID, todoListID int64, role entity.TodoListRole) (dto.TodoListSharedUser, error) {
+	if _, err := OwnTodoList(operatorID, todoListID); err != nil {
+		return dto.TodoListSharedUser{}, err
+	}
+
+	if !isValidTodoListRole(role) {
+		return dto.TodoListSharedUser{}, util.NewErrorWithBadRequest("invalid role: %v", role)
+	}
+
+	if _, err := repo.SelectTodoListSharedUserRole(userID, todoListID); err != nil {
+		return dto.TodoListSharedUser{}, fmt.Errorf("fails to get todo list shared user: %w", err)
+	}
+
+	if err := repo.UpdateTodoListSharedUserRole(userID, todoListID, role); err != nil {
+		return dto.TodoListSharedUser{}, fmt.Errorf("fails to update todo list shared user: %w", err)
+	}
+
+	user, err := GetUser(userID)
+	if err != nil {
+		return dto.TodoListSharedUser{}, err
+	}
+
+	return dto.TodoListSharedUser{User: user, Role: role}, nil
 }
 
 // Delete shared user from todo list,
 // can be called by owner to delete anyone,
+// or called by manager to delete viewers and editors,
 // or called by shared user to delete themselves
 func DeleteTodoListSharedUser(operatorID, userID, todoListID int64) error {
-	todoList, err := OwnOrSharedTodoList(operatorID, todoListID)
+	todoList, role, err := accessTodoList(repo, operatorID, todoListID, entity.TodoListRoleViewer)
 	if err != nil {
 		return err
 	}
 
-	if todoList.UserID != operatorID && userID != operatorID {
-		return util.NewErrorWithForbidden("unable to delete shared user")
+	if userID != operatorID && role != entity.TodoListRoleOwner {
+		if role < entity.TodoListRoleManager {
+			return util.NewErrorWithForbidden("unable to delete shared user")
+		}
+
+		userRole, err := getTodoListRole(repo, userID, todoList)
+		if err != nil {
+			return err
+		}
+
+		if userRole >= entity.TodoListRoleManager {
+			return util.NewErrorWithForbidden("unable to delete manager of todo list")
+		}
 	}
 
-	if err := dal.DeleteTodoListSharedUser(userID, todoListID); err != nil {
+	if err := repo.DeleteTodoListSharedUser(userID, todoListID); err != nil {
 		return fmt.Errorf("fails to delete todo list shared users: %w", err)
 	}
 
 	return nil
 }
 
+// Add user to todo list as shared user with role
+func joinTodoList(r dal.Repository, userID, todoListID int64, role entity.TodoListRole) error {
+	if err := r.InsertTodoListSharedUser(userID, todoListID, role); err != nil {
+		return fmt.Errorf("fails to create todo list shared user: %w", err)
+	}
+
+	if err := shareTodoListTags(r, userID, todoListID); err != nil {
+		return fmt.Errorf("fails to share tags: %w", err)
+	}
+
+	return nil
+}
+
 func ExistTodoListSharing(userID, todoListID int64) (bool, error) {
-	exist, err := dal.ExistTodoListSharing(userID, todoListID)
+	exist, err := repo.ExistTodoListSharing(userID, todoListID)
 	if err != nil {
 		return false, fmt.Errorf("fails to valid sharing: %w", err)
 	}
@@ -77,23 +155,140 @@ func ExistTodoListSharing(userID, todoListID int64) (bool, error) {
 	return exist, nil
 }
 
-// owner or shared user
-func OwnOrSharedTodoList(userID, todoListID int64) (entity.TodoList, error) {
-	todoList, err := dal.SelectTodoList(todoListID)
+// Whether todo list is shared with others, directly or by folder
+func isSharedTodoList(r dal.Repository, todoListID int64) (bool, error) {
+	ids, err := getTodoListUserIDs(r, todoListID)
 	if err != nil {
-		return entity.TodoList{}, fmt.Errorf("fails to get todo list: %v", todoListID)
+		return false, err
 	}
 
-	if todoList.UserID != userID {
-		sharing, err := ExistTodoListSharing(userID, todoListID)
-		if err != nil {
-			return entity.TodoList{}, fmt.Errorf("fails to get todo list sharing: %v", todoListID)
-		}
+	return len(ids) > 1, nil
+}
+
+// Owner and shared users of todo list, including shared users of folder
+func getTodoListUserIDs(r dal.Repository, todoListID int64) ([]int64, error) {
+	todoList, err := r.SelectTodoList(todoListID)
+	if err != nil {
+		return nil, fmt.Errorf("fails to get todo list: %w", err)
+	}
+
+	users, err := r.SelectTodoListSharedUsers(todoListID)
+	if err != nil {
+		return nil, fmt.Errorf("fails to get todo list shared users: %w", err)
+	}
+
+	ids := []int64{todoList.UserID}
+	for i := range users {
+		ids = append(ids, users[i].ID)
+	}
+
+	if todoList.TodoListFolderID == 0 {
+		return ids, nil
+	}
+
+	folderUsers, err := r.SelectTodoListFolderSharedUsers(todoList.TodoListFolderID)
+	if err != nil {
+		return nil, fmt.Errorf("fails to get todo list folder shared users: %w", err)
+	}
 
-		if !sharing {
-			return entity.TodoList{}, util.NewErrorWithForbidden("unable to handle unauthorized todo list: %v", todoListID)
+	for i := range folderUsers {
+		if !containsID(ids, folderUsers[i].UserID) {
+			ids = append(ids, folderUsers[i].UserID)
 		}
 	}
+	return ids, nil
+}
+
+// Todo list of owner or shared user with any role
+func OwnOrSharedTodoList(userID, todoListID int64) (entity.TodoList, error) {
+	return AccessTodoList(userID, todoListID, entity.TodoListRoleViewer)
+}
+
+// Todo list of owner or shared user with role, or roles after it
+func AccessTodoList(userID, todoListID int64, role entity.TodoListRole) (entity.TodoList, error) {
+	todoList, _, err := accessTodoList(repo, userID, todoListID, role)
+	return todoList, err
+}
+
+func accessTodoList(r dal.Repository, userID, todoListID int64, role entity.TodoListRole) (entity.TodoList, entity.TodoListRole, error) {
+	todoList, err := r.SelectTodoList(todoListID)
+	if err != nil {
+		return entity.TodoList{}, 0, fmt.Errorf("fails to get todo list: %v", todoListID)
+	}
+
+	userRole, err := getTodoListRole(r, userID, todoList)
+	if err != nil {
+		return entity.TodoList{}, 0, err
+	}
+
+	if userRole == 0 {
+		return entity.TodoList{}, 0, util.NewErrorWithForbidden("unable to handle unauthorized todo list: %v", todoListID)
+	}
+
+	if userRole < role {
+		return entity.TodoList{}, 0, insufficientTodoListRole(todoListID)
+	}
+
+	return todoList, userRole, nil
+}
+
+// Role of user in todo list, 0 if user is neither owner nor shared user.
+// Shared users of folder have their role in todo lists of it, and the
+// higher one is taken if user is shared with both
+func getTodoListRole(r dal.Repository, userID int64, todoList entity.TodoList) (entity.TodoListRole, error) {
+	if todoList.UserID == userID {
+		return entity.TodoListRoleOwner, nil
+	}
+
+	role, err := r.SelectTodoListSharedUserRole(userID, todoList.ID)
+	if util.IsErrorCode(err, otodo.ErrNotFound) {
+		role = 0
+	} else if err != nil {
+		return 0, fmt.Errorf("fails to get todo list sharing: %w", err)
+	}
+
+	if todoList.TodoListFolderID == 0 {
+		return role, nil
+	}
+
+	folderRole, err := r.SelectTodoListFolderSharedUserRole(userID, todoList.TodoListFolderID)
+	if util.IsErrorCode(err, otodo.ErrNotFound) {
+		return role, nil
+	} else if err != nil {
+		return 0, fmt.Errorf("fails to get todo list folder sharing: %w", err)
+	}
+
+	if folderRole > role {
+		role = folderRole
+	}
+	return role, nil
+}
+
+func insufficientTodoListRole(todoListID int64) error {
+	return util.NewErrorWithForbidden("insufficient role for todo list: %v", todoListID)
+}
+
+// Role of users invited by operator, editor if empty. Managers are able to
+// invite, but only owner is able to invite managers
+func getInvitationRole(operatorRole, role entity.TodoListRole) (entity.TodoListRole, error) {
+	if role == 0 {
+		role = entity.TodoListRoleEditor
+	}
+
+	if !isValidTodoListRole(role) {
+		return 0, util.NewErrorWithBadRequest("invalid role: %v", role)
+	}
+
+	if role >= entity.TodoListRoleManager && operatorRole != entity.TodoListRoleOwner {
+		return 0, util.NewErrorWithForbidden("only owner is able to invite managers")
+	}
+
+	return role, nil
+}
 
-	return todoList, nil
+// Roles which can be assigned to shared users
+func isValidTodoListRole(role entity.TodoListRole) bool {
+	return role == entity.TodoListRoleViewer ||
+		role == entity.TodoListRoleEditor ||
+		role == entity.TodoListRoleManager
 }
@@ -0,0 +1,125 @@
+package bll
+
+import (
+	"fmt"
+
+	"github.com/yzx9/otodo/dal"
+	"github.com/yzx9/otodo/model/entity"
+	"github.com/yzx9/otodo/otodo"
+	"github.com/yzx9/otodo/util"
+)
+
+/**
+ * oTodo List Transfer
+ */
+
+// Offer ownership of todo list to a shared user, which replaces the pending
+// one if any. Owner is changed after the user accepts
+func CreateTodoListTransfer(ownerID, todoListID, userID int64) (entity.TodoList, error) {
+	todoList, err := OwnTodoList(ownerID, todoListID)
+	if err != nil {
+		return entity.TodoList{}, err
+	}
+
+	// basic todo list is the inbox of owner
+	if todoList.IsBasic {
+		return entity.TodoList{}, util.NewErrorWithForbidden("unable to transfer basic todo list: %v", todoListID)
+	}
+
+	if userID == ownerID {
+		return entity.TodoList{}, util.NewErrorWithBadRequest("unable to transfer todo list to owner")
+	}
+
+	if err := checkTodoListTransferUser(repo, userID, todoListID); err != nil {
+		return entity.TodoList{}, err
+	}
+
+	todoList.TransferUserID = userID
+	if err := repo.SaveTodoList(&todoList); err != nil {
+		return entity.TodoList{}, fmt.Errorf("fails to transfer todo list: %w", err)
+	}
+
+	return todoList, nil
+}
+
+// Accept ownership of todo list. Previous owner stays as a manager, and
+// todo list is moved to root since the folder belongs to previous owner
+func AcceptTodoListTransfer(userID, todoListID int64) (entity.TodoList, error) {
+	todoList, err := getTodoListTransfer(userID, todoListID)
+	if err != nil {
+		return entity.TodoList{}, err
+	}
+
+	err = repo.Transaction(func(r dal.Repository) error {
+		if err := checkTodoListTransferUser(r, userID, todoListID); err != nil {
+			return err
+		}
+
+		if err := r.DeleteTodoListSharedUser(userID, todoListID); err != nil {
+			return fmt.Errorf("fails to delete todo list shared user: %w", err)
+		}
+
+		if err := r.InsertTodoListSharedUser(todoList.UserID, todoListID, entity.TodoListRoleManager); err != nil {
+			return fmt.Errorf("fails to create todo list shared user: %w", err)
+		}
+
+		todoList.UserID = userID
+		todoList.TransferUserID = 0
+		todoList.TodoListFolderID = 0
+		return r.SaveTodoList(&todoList)
+	})
+	if err != nil {
+		return entity.TodoList{}, fmt.Errorf("fails to transfer todo list: %w", err)
+	}
+
+	return GetTodoList(userID, todoListID)
+}
+
+// Cancel pending transfer by owner, or decline by the user offered
+func DeleteTodoListTransfer(userID, todoListID int64) error {
+	todoList, err := OwnOrSharedTodoList(userID, todoListID)
+	if err != nil {
+		return err
+	}
+
+	if todoList.TransferUserID == 0 {
+		return util.NewErrorWithNotFound("todo list transfer not found: %v", todoListID)
+	}
+
+	if todoList.UserID != userID && todoList.TransferUserID != userID {
+		return util.NewErrorWithForbidden("unable to cancel todo list transfer: %v", todoListID)
+	}
+
+	todoList.TransferUserID = 0
+	if err := repo.SaveTodoList(&todoList); err != nil {
+		return fmt.Errorf("fails to cancel todo list transfer: %w", err)
+	}
+
+	return nil
+}
+
+func getTodoListTransfer(userID, todoListID int64) (entity.TodoList, error) {
+	todoList, err := ForceGetTodoList(todoListID)
+	if err != nil {
+		return entity.TodoList{}, err
+	}
+
+	if todoList.TransferUserID != userID {
+		return entity.TodoList{}, util.NewErrorWithNotFound("todo list transfer not found: %v", todoListID)
+	}
+
+	return todoList, nil
+}
+
+// Todo list is only transferred to its own shared users, shared users of
+// folder are not taken into account
+func checkTodoListTransferUser(r dal.Repository, userID, todoListID int64) error {
+	_, err := r.SelectTodoListSharedUserRole(userID, todoListID)
+	if util.IsErrorCode(err, otodo.ErrNotFound) {
+		return util.NewErrorWithBadRequest("unable to transfer todo list to non-shared user: %v", userID)
+	} else if err != nil {
+		return fmt.Errorf("fails to get todo list shared user: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,57 @@
+package bll
+
+import (
+	"testing"
+
+	"github.com/yzx9/otodo/model/entity"
+	"github.com/yzx9/otodo/otodo"
+	"github.com/yzx9/otodo/util"
+)
+
+func TestAcceptTodoListTransfer(t *testing.T) {
+	useMemoryRepository(t)
+	alice := createTestUser(t, "alice")
+	bob := createTestUser(t, "bob")
+	carol := createTestUser(t, "carol")
+	folder := entity.TodoListFolder{Name: "folder"}
+	must(t, CreateTodoListFolder(alice.ID, &folder))
+	todoList := entity.TodoList{Name: "list", TodoListFolderID: folder.ID}
+	must(t, CreateTodoList(alice.ID, &todoList))
+	joinTestTodoList(t, bob.ID, todoList.ID, entity.TodoListRoleEditor)
+
+	if _, err := CreateTodoListTransfer(alice.ID, todoList.ID, carol.ID); !util.IsErrorCode(err, otodo.ErrBadRequest) {
+		t.Fatalf("transfer to non-shared user error = %v, want bad request", err)
+	}
+
+	if _, err := CreateTodoListTransfer(alice.ID, alice.BasicTodoListID, bob.ID); !util.IsErrorCode(err, otodo.ErrForbidden) {
+		t.Fatalf("transfer basic todo list error = %v, want forbidden", err)
+	}
+
+	_, err := CreateTodoListTransfer(alice.ID, todoList.ID, bob.ID)
+	must(t, err)
+
+	if _, err := AcceptTodoListTransfer(carol.ID, todoList.ID); !util.IsErrorCode(err, otodo.ErrNotFound) {
+		t.Fatalf("accept by other error = %v, want not found", err)
+	}
+
+	accepted, err := AcceptTodoListTransfer(bob.ID, todoList.ID)
+	must(t, err)
+	if accepted.UserID != bob.ID || accepted.TransferUserID != 0 || accepted.TodoListFolderID != 0 {
+		t.Fatalf("accepted todo list = %+v, want owned by %v in root", accepted, bob.ID)
+	}
+
+	roles := []struct {
+		user entity.User
+		want entity.TodoListRole
+	}{
+		{alice, entity.TodoListRoleManager},
+		{bob, entity.TodoListRoleOwner},
+	}
+	for _, tt := range roles {
+		role, err := getTodoListRole(repo, tt.user.ID, accepted)
+		must(t, err)
+		if role != tt.want {
+			t.Errorf("role of %v = %v, want %v", tt.user.Name, role, tt.want)
+		}
+	}
+}
@@ -0,0 +1,302 @@
+package bll
+
+import (
+	"fmt"
+
+	"github.com/yzx9/otodo/dal"
+	"github.com/yzx9/otodo/model/dto"
+	"github.com/yzx9/otodo/model/entity"
+	"github.com/yzx9/otodo/otodo"
+	"github.com/yzx9/otodo/util"
+)
+
+// Move todo to another todo list, steps, files, tags and repeat plan are
+// carried along. The move is recorded so that participants of both todo
+// lists can find where it went
+func MoveTodo(userID, todoID, todoListID int64, ifMatch dto.IfMatch) (entity.Todo, error) {
+	todo, err := AccessTodo(userID, todoID, entity.TodoListRoleEditor)
+	if err != nil {
+		return entity.Todo{}, err
+	}
+
+	if err := checkTodoIfMatch(repo, todo, ifMatch); err != nil {
+		return entity.Todo{}, err
+	}
+
+	if err := checkTodoMovable(repo, userID, todo); err != nil {
+		return entity.Todo{}, err
+	}
+
+	if _, err := AccessTodoList(userID, todoListID, entity.TodoListRoleEditor); err != nil {
+		return entity.Todo{}, fmt.Errorf("fails to get todo list: %w", err)
+	}
+
+	err = repo.Transaction(func(r dal.Repository) error {
+		return moveTodo(r, userID, &todo, todoListID)
+	})
+	if err != nil {
+		return entity.Todo{}, fmt.Errorf("fails to move todo: %w", err)
+	}
+
+	return GetTodo(userID, todoID)
+}
+
+// Move todos to another todo list, all or nothing
+func MoveTodos(userID int64, todoIDs []int64, todoListID int64) ([]entity.Todo, error) {
+	todos, err := accessTodos(userID, todoIDs, entity.TodoListRoleEditor)
+	if err != nil {
+		return nil, err
+	}
+
+	for i := range todos {
+		if err := checkTodoMovable(repo, userID, todos[i]); err != nil {
+			return nil, err
+		}
+	}
+
+	if _, err := AccessTodoList(userID, todoListID, entity.TodoListRoleEditor); err != nil {
+		return nil, fmt.Errorf("fails to get todo list: %w", err)
+	}
+
+	err = repo.Transaction(func(r dal.Repository) error {
+		for i := range todos {
+			if err := moveTodo(r, userID, &todos[i], todoListID); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("fails to move todos: %w", err)
+	}
+
+	if err := fillTodoRanks(repo, userID, todos); err != nil {
+		return nil, err
+	}
+
+	return todos, nil
+}
+
+// Copy todo to todo list, which can be the same one. Steps, files, tags and
+// repeat plan are copied, and the copy is owned by user
+func CopyTodo(userID, todoID, todoListID int64) (entity.Todo, error) {
+	todos, err := CopyTodos(userID, []int64{todoID}, todoListID)
+	if err != nil {
+		return entity.Todo{}, err
+	}
+
+	return todos[0], nil
+}
+
+// Copy todos to todo list, all or nothing
+func CopyTodos(userID int64, todoIDs []int64, todoListID int64) ([]entity.Todo, error) {
+	todos, err := accessTodos(userID, todoIDs, entity.TodoListRoleViewer)
+	if err != nil {
+		return nil, err
+	}
+
+	if _, err := AccessTodoList(userID, todoListID, entity.TodoListRoleEditor); err != nil {
+		return nil, fmt.Errorf("fails to get todo list: %w", err)
+	}
+
+	copies := make([]entity.Todo, 0, len(todos))
+	err = repo.Transaction(func(r dal.Repository) error {
+		for i := range todos {
+			todo, err := copyTodo(r, userID, todos[i], todoListID)
+			if err != nil {
+				return err
+			}
+			copies = append(copies, todo)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("fails to copy todos: %w", err)
+	}
+
+	if err := fillTodoRanks(repo, userID, copies); err != nil {
+		return nil, err
+	}
+
+	return copies, nil
+}
+
+// Get moves from or to todo list, newest first
+func GetTodoMoves(userID, todoListID int64) ([]entity.TodoMove, error) {
+	if _, err := OwnOrSharedTodoList(userID, todoListID); err != nil {
+		return nil, err
+	}
+
+	moves, err := repo.SelectTodoMoves(todoListID)
+	if err != nil {
+		return nil, fmt.Errorf("fails to get todo moves: %w", err)
+	}
+
+	return moves, nil
+}
+
+// Todos in order of ids, duplicated ids are ignored
+func accessTodos(userID int64, todoIDs []int64, role entity.TodoListRole) ([]entity.Todo, error) {
+	seen := make(map[int64]bool)
+	todos := make([]entity.Todo, 0, len(todoIDs))
+	for _, id := range todoIDs {
+		if seen[id] {
+			continue
+		}
+		seen[id] = true
+
+		todo, err := AccessTodo(userID, id, role)
+		if err != nil {
+			return nil, err
+		}
+		todos = append(todos, todo)
+	}
+
+	return todos, nil
+}
+
+// Todo is movable by editors of todo list, but not by shared users of todo
+// only, which would take it away from todo list
+func checkTodoMovable(r dal.Repository, userID int64, todo entity.Todo) error {
+	if _, _, err := accessTodoList(r, userID, todo.TodoListID, entity.TodoListRoleEditor); err != nil {
+		return util.NewErrorWithForbidden("unable to move todo out of todo list: %v", todo.ID)
+	}
+
+	return nil
+}
+
+func moveTodo(r dal.Repository, userID int64, todo *entity.Todo, todoListID int64) error {
+	if todo.TodoListID == todoListID {
+		return nil
+	}
+
+	fromTodoListID := todo.TodoListID
+	todo.TodoListID = todoListID
+	if err := r.SaveTodo(todo); err != nil {
+		return err
+	}
+
+	if err := moveTodoTags(r, *todo, fromTodoListID); err != nil {
+		return err
+	}
+
+	move := entity.TodoMove{
+		Title:          todo.Title,
+		TodoID:         todo.ID,
+		FromTodoListID: fromTodoListID,
+		ToTodoListID:   todoListID,
+		UserID:         userID,
+	}
+	if err := r.InsertTodoMove(&move); err != nil {
+		return fmt.Errorf("fails to create todo move: %w", err)
+	}
+
+	return nil
+}
+
+// Move tags of todo to participants of the new todo list, participants of
+// both todo lists keep their tags
+func moveTodoTags(r dal.Repository, todo entity.Todo, fromTodoListID int64) error {
+	if len(todo.Tags) == 0 {
+		return nil
+	}
+
+	// participants of deleted todo list are unknown, who keep their tags
+	oldUserIDs, err := getTodoListUserIDs(r, fromTodoListID)
+	if util.IsErrorCode(err, otodo.ErrNotFound) {
+		oldUserIDs = nil
+	} else if err != nil {
+		return err
+	}
+
+	userIDs, err := getTodoListUserIDs(r, todo.TodoListID)
+	if err != nil {
+		return err
+	}
+
+	removes := make(map[string]bool)
+	inserts := make(map[string]bool)
+	for _, tag := range todo.Tags {
+		removes[tag] = false
+		inserts[tag] = true
+	}
+
+	for _, userID := range oldUserIDs {
+		if !containsID(userIDs, userID) {
+			if err := updateUserTags(r, userID, todo.ID, removes); err != nil {
+				return err
+			}
+		}
+	}
+
+	for _, userID := range userIDs {
+		if !containsID(oldUserIDs, userID) {
+			if err := updateUserTags(r, userID, todo.ID, inserts); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+func copyTodo(r dal.Repository, userID int64, todo entity.Todo, todoListID int64) (entity.Todo, error) {
+	steps, files := todo.Steps, todo.Files
+
+	todo.Entity = entity.Entity{} // insert as a new todo
+	todo.Version = 0
+	todo.UserID = userID
+	todo.TodoListID = todoListID
+	todo.Files = nil
+	todo.Steps = nil
+	todo.TagList = nil
+	todo.NextID = nil
+	todo.Next = nil
+
+	if todo.TodoRepeatPlanID != 0 {
+		plan := todo.TodoRepeatPlan
+		plan.Entity = entity.Entity{}
+		if err := r.InsertTodoRepeatPlan(&plan); err != nil {
+			return entity.Todo{}, fmt.Errorf("fails to create todo repeat plan: %w", err)
+		}
+		todo.TodoRepeatPlanID = plan.ID
+		todo.TodoRepeatPlan = plan
+	}
+
+	if err := r.InsertTodo(&todo); err != nil {
+		return entity.Todo{}, fmt.Errorf("fails to create todo: %w", err)
+	}
+
+	for _, step := range steps {
+		step.Entity = entity.Entity{}
+		step.Version = 0
+		step.TodoID = todo.ID
+		if err := r.InsertTodoStep(&step); err != nil {
+			return entity.Todo{}, fmt.Errorf("fails to create todo step: %w", err)
+		}
+	}
+
+	// files are shared on file server, only records are copied
+	for _, file := range files {
+		file.Entity = entity.Entity{}
+		file.RelatedID = todo.ID
+		if err := r.InsertFile(&file); err != nil {
+			return entity.Todo{}, fmt.Errorf("fails to create file: %w", err)
+		}
+
+		if err := r.InsertTodoFile(todo.ID, file.ID); err != nil {
+			return entity.Todo{}, fmt.Errorf("fails to create todo file: %w", err)
+		}
+	}
+
+	if err := setTodoTags(r, todo, nil); err != nil {
+		return entity.Todo{}, err
+	}
+
+	todo, err := r.SelectTodo(todo.ID)
+	if err != nil {
+		return entity.Todo{}, fmt.Errorf("fails to get todo: %w", err)
+	}
+
+	return todo, nil
+}
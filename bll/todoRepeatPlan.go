@@ -2,63 +2,152 @@ package bll
 
 import (
 	"fmt"
+	"strings"
 	"time"
 
 	"github.com/yzx9/otodo/dal"
+	"github.com/yzx9/otodo/model/dto"
 	"github.com/yzx9/otodo/model/entity"
+	"github.com/yzx9/otodo/util"
 )
 
-func CreateTodoRepeatPlan(plan entity.TodoRepeatPlan) (entity.TodoRepeatPlan, error) {
-	if !isValidTodoRepeatPlan(plan) {
-		return entity.TodoRepeatPlan{}, nil
+func GetTodoRepeatPlan(id int64) (entity.TodoRepeatPlan, error) {
+	plan, err := repo.SelectTodoRepeatPlan(id)
+	if err != nil {
+		return entity.TodoRepeatPlan{}, fmt.Errorf("fails to get todo repeat plan: %v", err)
 	}
 
-	if err := dal.InsertTodoRepeatPlan(&plan); err != nil {
+	return plan, nil
+}
+
+// Create plan of todo owned by user, whose location rrule is expanded in
+func createTodoRepeatPlan(r dal.Repository, userID int64, plan entity.TodoRepeatPlan, deadline *time.Time) (entity.TodoRepeatPlan, error) {
+	loc, err := getUserLocationByID(r, userID)
+	if err != nil {
+		return entity.TodoRepeatPlan{}, err
+	}
+
+	plan, ok, err := normalizeTodoRepeatPlan(plan, deadline, loc)
+	if err != nil || !ok {
+		return entity.TodoRepeatPlan{}, err
+	}
+
+	if err := r.InsertTodoRepeatPlan(&plan); err != nil {
 		return entity.TodoRepeatPlan{}, fmt.Errorf("fails to create todo repeat plan: %w", err)
 	}
 
 	return plan, nil
 }
 
-func UpdateTodoRepeatPlan(plan, oldPlan entity.TodoRepeatPlan) (entity.TodoRepeatPlan, error) {
-	if !isValidTodoRepeatPlan(plan) || isSameTodoRepeatPlan(plan, oldPlan) {
+func updateTodoRepeatPlan(r dal.Repository, userID int64, plan, oldPlan entity.TodoRepeatPlan, deadline *time.Time) (entity.TodoRepeatPlan, error) {
+	loc, err := getUserLocationByID(r, userID)
+	if err != nil {
+		return entity.TodoRepeatPlan{}, err
+	}
+
+	plan, ok, err := normalizeTodoRepeatPlan(plan, deadline, loc)
+	if err != nil {
+		return entity.TodoRepeatPlan{}, err
+	}
+
+	if !ok || isSameTodoRepeatPlan(plan, oldPlan) {
 		return oldPlan, nil
 	}
 
-	if err := dal.InsertTodoRepeatPlan(&plan); err != nil {
+	plan.Entity = entity.Entity{}
+	if err := r.InsertTodoRepeatPlan(&plan); err != nil {
 		return entity.TodoRepeatPlan{}, fmt.Errorf("fails to create todo repeat plan: %w", err)
 	}
 
 	return plan, nil
 }
 
-func GetTodoRepeatPlan(id int64) (entity.TodoRepeatPlan, error) {
-	plan, err := dal.SelectTodoRepeatPlan(id)
+// Skip current occurrence of a recurring todo, deadline is advanced to the
+// next occurrence after it without marking done
+func SkipTodo(userID, todoID int64, ifMatch dto.IfMatch) (entity.Todo, error) {
+	todo, err := AccessTodo(userID, todoID, entity.TodoListRoleEditor)
 	if err != nil {
-		return entity.TodoRepeatPlan{}, fmt.Errorf("fails to get todo repeat plan: %v", err)
+		return entity.Todo{}, err
 	}
 
-	return plan, nil
+	if err := checkTodoIfMatch(repo, todo, ifMatch); err != nil {
+		return entity.Todo{}, err
+	}
+
+	if todo.Done {
+		return entity.Todo{}, util.NewErrorWithBadRequest("unable to skip done todo")
+	}
+
+	if todo.TodoRepeatPlanID == 0 || todo.Deadline == nil {
+		return entity.Todo{}, util.NewErrorWithBadRequest("unable to skip non-recurring todo")
+	}
+
+	user, err := GetUser(todo.UserID)
+	if err != nil {
+		return entity.Todo{}, err
+	}
+
+	next, ok := getTodoNextOccurrence(todo, getUserLocation(user))
+	if !ok {
+		return entity.Todo{}, util.NewErrorWithBadRequest("no more occurrences of todo")
+	}
+
+	rescheduleTodo(&todo, next)
+	if err := repo.SaveTodo(&todo); err != nil {
+		return entity.Todo{}, fmt.Errorf("fails to skip todo: %w", err)
+	}
+
+	return todo, nil
 }
 
-func CreateRepeatTodoIfNeed(todo entity.Todo) (bool, entity.Todo, error) {
+func createRepeatTodoIfNeed(r dal.Repository, todo entity.Todo) (bool, entity.Todo, error) {
 	if todo.TodoRepeatPlanID == 0 {
 		return false, entity.Todo{}, nil
 	}
 
-	nextDeadline := getTodoNextRepeatTime(todo)
-	if todo.TodoRepeatPlan.Before.Before(nextDeadline) {
+	loc, err := getUserLocationByID(r, todo.UserID)
+	if err != nil {
+		return false, entity.Todo{}, err
+	}
+
+	nextDeadline, ok := getTodoNextRepeatTime(todo, loc)
+	if !ok {
 		return false, entity.Todo{}, nil
 	}
 
-	todo.Deadline = &nextDeadline
-	if err := dal.InsertTodo(&todo); err != nil {
+	todo.Entity = entity.Entity{} // insert as a new todo
+	todo.Version = 0
+	todo.Files = nil
+	todo.Steps = nil
+	rescheduleTodo(&todo, nextDeadline)
+	todo.Done = false
+	todo.DoneAt = nil
+	todo.NextID = nil
+	if err := r.InsertTodo(&todo); err != nil {
 		return false, entity.Todo{}, fmt.Errorf("fails to create todo: %w", err)
 	}
 
+	if err := setTodoTags(r, todo, nil); err != nil {
+		return false, entity.Todo{}, err
+	}
+
 	return true, todo, nil
 }
 
+// Move deadline, and reminder before deadline as same as the previous one
+func rescheduleTodo(todo *entity.Todo, deadline time.Time) {
+	if todo.NotifyAt != nil {
+		if todo.Deadline != nil {
+			notifyAt := deadline.Add(todo.NotifyAt.Sub(*todo.Deadline))
+			todo.NotifyAt = &notifyAt
+		} else {
+			todo.NotifyAt = nil // unable to keep the offset
+		}
+		todo.Notified = false
+	}
+	todo.Deadline = &deadline
+}
+
 func isValidTodoRepeatPlan(plan entity.TodoRepeatPlan) bool {
 	t := entity.TodoRepeatPlanType(plan.Type)
 	if t != entity.TodoRepeatPlanTypeDay &&
@@ -76,10 +165,40 @@ func isValidTodoRepeatPlan(plan entity.TodoRepeatPlan) bool {
 	return plan.Interval > 0
 }
 
+// Validate plan, returns false if plan is empty
+func normalizeTodoRepeatPlan(plan entity.TodoRepeatPlan, deadline *time.Time, loc *time.Location) (entity.TodoRepeatPlan, bool, error) {
+	from := entity.TodoRepeatFrom(plan.RepeatFrom)
+	if from != "" &&
+		from != entity.TodoRepeatFromDeadline &&
+		from != entity.TodoRepeatFromCompletion {
+		return entity.TodoRepeatPlan{}, false, util.NewErrorWithBadRequest("invalid repeat from: %v", plan.RepeatFrom)
+	}
+
+	if plan.RRule == "" {
+		return plan, isValidTodoRepeatPlan(plan), nil
+	}
+
+	rrule, err := normalizeRRule(plan.RRule, deadline, from == entity.TodoRepeatFromCompletion, loc)
+	if err != nil {
+		return entity.TodoRepeatPlan{}, false, err
+	}
+
+	plan.RRule = rrule
+	return plan, true, nil
+}
+
 func isSameTodoRepeatPlan(plan, oldPlan entity.TodoRepeatPlan) bool {
+	if plan.RepeatFrom != oldPlan.RepeatFrom {
+		return false
+	}
+
+	if plan.RRule != "" || oldPlan.RRule != "" {
+		return plan.RRule == oldPlan.RRule
+	}
+
 	if plan.Type != oldPlan.Type ||
 		plan.Interval != oldPlan.Interval ||
-		plan.Before != oldPlan.Before {
+		!equalTime(plan.Before, oldPlan.Before) {
 		return false
 	}
 
@@ -92,38 +211,120 @@ func isSameTodoRepeatPlan(plan, oldPlan entity.TodoRepeatPlan) bool {
 	return true
 }
 
-func getTodoNextRepeatTime(todo entity.Todo) time.Time {
-	deadline := *todo.Deadline
-	interval := todo.TodoRepeatPlan.Interval
+// Validate rrule, and pin series start to deadline if DTSTART not exists,
+// so that COUNT works across repeated todos. Series restarts on each
+// completion if repeat from completion, so that COUNT is not allowed.
+// Rules never occur are rejected, otherwise repeating scans for long. Rule
+// is checked in location of user, as it is expanded
+func normalizeRRule(rrule string, deadline *time.Time, fromCompletion bool, loc *time.Location) (string, error) {
+	rec, err := parseRecurrence(rrule, loc)
+	if err != nil {
+		return "", util.NewErrorWithBadRequest("invalid rrule: %v", err)
+	}
 
-	weekend := time.Sunday // TODO 此处默认周一为一周开始
+	start := time.Now()
+	if deadline != nil {
+		start = *deadline
+	}
+	if !rec.occursInYears(start, recurrenceCheckYears, loc) {
+		return "", util.NewErrorWithBadRequest("invalid rrule: no occurrence in %v years", recurrenceCheckYears)
+	}
 
-	switch entity.TodoRepeatPlanType(todo.TodoRepeatPlan.Type) {
-	case entity.TodoRepeatPlanTypeDay:
-		return deadline.AddDate(0, 0, interval)
+	if fromCompletion {
+		if rec.count > 0 {
+			return "", util.NewErrorWithBadRequest("invalid rrule: COUNT is not allowed when repeat from completion")
+		}
+		deadline = nil // DTSTART is ignored
+	}
 
-	case entity.TodoRepeatPlanTypeMonth:
-		return deadline.AddDate(0, interval, 0)
+	lines := make([]string, 0)
+	for _, line := range strings.Split(rrule, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			if !strings.Contains(line, ":") {
+				line = "RRULE:" + line
+			}
+			lines = append(lines, line)
+		}
+	}
 
-	case entity.TodoRepeatPlanTypeYear:
-		return deadline.AddDate(interval, 0, 0)
+	if rec.dtstart == nil && deadline != nil {
+		lines = append([]string{"DTSTART:" + deadline.UTC().Format("20060102T150405Z")}, lines...)
+	}
 
-	case entity.TodoRepeatPlanTypeWeek:
-		if deadline.Weekday() == weekend {
-			deadline = deadline.AddDate(0, 0, (interval-1)*7)
-		}
+	return strings.Join(lines, "\n"), nil
+}
+
+// Get next repeat time of done todo, in user location
+func getTodoNextRepeatTime(todo entity.Todo, loc *time.Location) (time.Time, bool) {
+	from := entity.TodoRepeatFrom(todo.TodoRepeatPlan.RepeatFrom)
+	if from != entity.TodoRepeatFromCompletion || todo.DoneAt == nil {
+		return getTodoNextOccurrence(todo, loc)
+	}
+
+	rec, err := getTodoRecurrence(todo.TodoRepeatPlan, loc)
+	if err != nil {
+		return time.Time{}, false
+	}
 
-		deadline = deadline.AddDate(0, 0, 1)
-		for i := 0; i < 7-1; i++ {
-			mask := int8(0x01 << deadline.Weekday())
-			if todo.TodoRepeatPlan.Weekday&mask != 0 {
-				return deadline
+	// restart series on the day of completion, at time of deadline if exists
+	done := todo.DoneAt.In(loc)
+	hour, min, sec := 0, 0, 0
+	if todo.Deadline != nil {
+		hour, min, sec = todo.Deadline.In(loc).Clock()
+	}
+	start := time.Date(done.Year(), done.Month(), done.Day(), hour, min, sec, 0, loc)
+
+	rec.dtstart = nil
+	return rec.next(start, start, loc)
+}
+
+// Get next occurrence after deadline of todo, in user location
+func getTodoNextOccurrence(todo entity.Todo, loc *time.Location) (time.Time, bool) {
+	if todo.Deadline == nil {
+		return time.Time{}, false
+	}
+
+	rec, err := getTodoRecurrence(todo.TodoRepeatPlan, loc)
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	if entity.TodoRepeatFrom(todo.TodoRepeatPlan.RepeatFrom) == entity.TodoRepeatFromCompletion {
+		rec.dtstart = nil // series restarts at each deadline
+	}
+
+	return rec.next(*todo.Deadline, *todo.Deadline, loc)
+}
+
+// Recurrence of plan, simple fields are shorthand of rrule
+func getTodoRecurrence(plan entity.TodoRepeatPlan, loc *time.Location) (recurrence, error) {
+	if plan.RRule != "" {
+		return parseRecurrence(plan.RRule, loc)
+	}
+
+	if !isValidTodoRepeatPlan(plan) {
+		return recurrence{}, fmt.Errorf("invalid todo repeat plan")
+	}
+
+	rec := recurrence{interval: plan.Interval, until: plan.Before, wkst: time.Monday}
+	switch entity.TodoRepeatPlanType(plan.Type) {
+	case entity.TodoRepeatPlanTypeDay:
+		rec.freq = recurrenceFreqDaily
+
+	case entity.TodoRepeatPlanTypeWeek:
+		rec.freq = recurrenceFreqWeekly
+		for wd := time.Sunday; wd <= time.Saturday; wd++ {
+			if plan.Weekday&int8(0x01<<wd) != 0 {
+				rec.byDay = append(rec.byDay, recurrenceWeekday{weekday: wd})
 			}
-			deadline = deadline.AddDate(0, 0, 1)
 		}
-		return time.Time{}
 
-	default:
-		return time.Time{}
+	case entity.TodoRepeatPlanTypeMonth:
+		rec.freq = recurrenceFreqMonthly
+
+	case entity.TodoRepeatPlanTypeYear:
+		rec.freq = recurrenceFreqYearly
 	}
+
+	return rec, nil
 }
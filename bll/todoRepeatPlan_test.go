@@ -0,0 +1,171 @@
+package bll
+
+import (
+	"testing"
+	"time"
+
+	"github.com/yzx9/otodo/model/dto"
+	"github.com/yzx9/otodo/model/entity"
+	"github.com/yzx9/otodo/otodo"
+	"github.com/yzx9/otodo/util"
+)
+
+func TestNormalizeRRule(t *testing.T) {
+	deadline := func(year int) *time.Time {
+		t := time.Date(year, time.January, 10, 9, 0, 0, 0, time.UTC)
+		return &t
+	}
+
+	tests := []struct {
+		name           string
+		rrule          string
+		deadline       *time.Time
+		fromCompletion bool
+		want           string
+		wantErr        bool
+	}{
+		{"pin start", "FREQ=DAILY", deadline(2022), false, "DTSTART:20220110T090000Z\nRRULE:FREQ=DAILY", false},
+		{"keep start", "DTSTART:20210101T080000Z\nRRULE:FREQ=DAILY", deadline(2022), false, "DTSTART:20210101T080000Z\nRRULE:FREQ=DAILY", false},
+		{"no deadline", "FREQ=WEEKLY;BYDAY=MO", nil, false, "RRULE:FREQ=WEEKLY;BYDAY=MO", false},
+		{"from completion", "FREQ=DAILY", deadline(2022), true, "RRULE:FREQ=DAILY", false},
+		{"count from completion", "FREQ=DAILY;COUNT=3", deadline(2022), true, "", true},
+		{"invalid", "FREQ=HOURLY", deadline(2022), false, "", true},
+		{"leap day daily", "FREQ=DAILY;BYMONTH=2;BYMONTHDAY=29", deadline(2097), false, "DTSTART:20970110T090000Z\nRRULE:FREQ=DAILY;BYMONTH=2;BYMONTHDAY=29", false},
+		{"leap day every 4 years", "FREQ=YEARLY;INTERVAL=4;BYMONTH=2;BYMONTHDAY=29", deadline(2024), false, "DTSTART:20240110T090000Z\nRRULE:FREQ=YEARLY;INTERVAL=4;BYMONTH=2;BYMONTHDAY=29", false},
+		{"never leap day", "FREQ=YEARLY;INTERVAL=4;BYMONTH=2;BYMONTHDAY=29", deadline(2023), false, "", true},
+		{"never day", "FREQ=YEARLY;BYMONTH=2;BYMONTHDAY=30", deadline(2022), false, "", true},
+		{"never set pos", "FREQ=YEARLY;BYDAY=MO;BYSETPOS=366", deadline(2022), false, "", true},
+		{"never weekday", "FREQ=MONTHLY;BYDAY=5MO;BYMONTH=2;BYMONTHDAY=1", deadline(2022), false, "", true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := normalizeRRule(tt.rrule, tt.deadline, tt.fromCompletion, time.UTC)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("normalizeRRule() error = %v, wantErr %v", err, tt.wantErr)
+			}
+
+			if got != tt.want {
+				t.Errorf("normalizeRRule() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+// Rule is checked in location of user, in which it is expanded. Monday
+// 00:30 UTC is Sunday in New York
+func TestNormalizeRRuleInLocation(t *testing.T) {
+	ny, err := time.LoadLocation("America/New_York")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	deadline := time.Date(2022, time.January, 3, 0, 30, 0, 0, time.UTC)
+	rrule := "FREQ=WEEKLY;BYDAY=MO;UNTIL=20220103T010000Z"
+	tests := []struct {
+		loc     *time.Location
+		wantErr bool
+	}{
+		{time.UTC, false},
+		{ny, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.loc.String(), func(t *testing.T) {
+			if _, err := normalizeRRule(rrule, &deadline, false, tt.loc); (err != nil) != tt.wantErr {
+				t.Errorf("normalizeRRule() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSkipTodo(t *testing.T) {
+	useMemoryRepository(t)
+	user := createTestUser(t, "alice")
+	deadline := time.Date(2030, time.January, 10, 9, 0, 0, 0, time.Local)
+	notifyAt := deadline.Add(-time.Hour)
+	before := deadline.AddDate(0, 0, 1)
+	daily := entity.TodoRepeatPlan{Type: string(entity.TodoRepeatPlanTypeDay), Interval: 1}
+
+	todo := createTestTodo(t, user.ID, entity.Todo{
+		Title:          "water plants",
+		TodoListID:     user.BasicTodoListID,
+		Deadline:       &deadline,
+		NotifyAt:       &notifyAt,
+		TodoRepeatPlan: daily,
+	})
+
+	skipped, err := SkipTodo(user.ID, todo.ID, dto.IfMatch{})
+	must(t, err)
+	if want := deadline.AddDate(0, 0, 1); skipped.Done || !skipped.Deadline.Equal(want) {
+		t.Fatalf("skipped todo deadline = %v, done = %v, want %v and undone", skipped.Deadline, skipped.Done, want)
+	}
+
+	if want := notifyAt.AddDate(0, 0, 1); !skipped.NotifyAt.Equal(want) {
+		t.Errorf("skipped todo notify at = %v, want %v", skipped.NotifyAt, want)
+	}
+
+	todos, err := repo.SelectTodos(user.BasicTodoListID)
+	must(t, err)
+	if len(todos) != 1 {
+		t.Errorf("todos after skipping = %v, want no successor", len(todos))
+	}
+
+	tests := []struct {
+		name string
+		todo entity.Todo
+	}{
+		{"non-recurring", entity.Todo{Deadline: &deadline}},
+		{"without deadline", entity.Todo{TodoRepeatPlan: daily}},
+		{"no more occurrences", entity.Todo{Deadline: &before, TodoRepeatPlan: entity.TodoRepeatPlan{Type: daily.Type, Interval: 1, Before: &before}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.todo.Title = tt.name
+			tt.todo.TodoListID = user.BasicTodoListID
+			todo := createTestTodo(t, user.ID, tt.todo)
+			if _, err := SkipTodo(user.ID, todo.ID, dto.IfMatch{}); !util.IsErrorCode(err, otodo.ErrBadRequest) {
+				t.Errorf("SkipTodo() error = %v, want bad request", err)
+			}
+		})
+	}
+
+	t.Run("done", func(t *testing.T) {
+		_, err := CompleteTodo(user.ID, todo.ID, dto.IfMatch{})
+		must(t, err)
+		if _, err := SkipTodo(user.ID, todo.ID, dto.IfMatch{}); !util.IsErrorCode(err, otodo.ErrBadRequest) {
+			t.Errorf("SkipTodo() error = %v, want bad request", err)
+		}
+	})
+}
+
+// Successor of todo repeated from completion is due after the day of
+// completion, at time of deadline
+func TestCompleteTodoRepeatFromCompletion(t *testing.T) {
+	useMemoryRepository(t)
+	user := createTestUser(t, "alice")
+	deadline := time.Date(2020, time.January, 10, 9, 0, 0, 0, time.Local)
+	todo := createTestTodo(t, user.ID, entity.Todo{
+		Title:      "water plants",
+		TodoListID: user.BasicTodoListID,
+		Deadline:   &deadline,
+		TodoRepeatPlan: entity.TodoRepeatPlan{
+			Type:       string(entity.TodoRepeatPlanTypeDay),
+			Interval:   3,
+			RepeatFrom: string(entity.TodoRepeatFromCompletion),
+		},
+	})
+
+	completion, err := CompleteTodo(user.ID, todo.ID, dto.IfMatch{})
+	must(t, err)
+	if completion.Next == nil {
+		t.Fatal("successor not created")
+	}
+
+	done := completion.Todo.DoneAt.In(time.Local)
+	want := time.Date(done.Year(), done.Month(), done.Day()+3, 9, 0, 0, 0, time.Local)
+	if !completion.Next.Deadline.Equal(want) {
+		t.Errorf("successor deadline = %v, want %v", completion.Next.Deadline, want)
+	}
+}
@@ -0,0 +1,170 @@
+package bll
+
+import (
+	"fmt"
+
+	"github.com/yzx9/otodo/dal"
+	"github.com/yzx9/otodo/model/dto"
+	"github.com/yzx9/otodo/model/entity"
+	"github.com/yzx9/otodo/util"
+)
+
+/**
+ * oTodo Sharing
+ */
+
+// Create sharing token for todo, users joined by token have role in the
+// todo only, e.g. delegate a task to someone without sharing todo list
+func CreateTodoSharing(userID, todoID int64, payload dto.SharingDTO) (entity.Sharing, error) {
+	_, userRole, err := accessTodo(repo, userID, todoID, entity.TodoListRoleManager)
+	if err != nil {
+		return entity.Sharing{}, err
+	}
+
+	if payload.Type != 0 && payload.Type != entity.SharingTypeTodo {
+		return entity.Sharing{}, util.NewErrorWithBadRequest("unsupported sharing type: %v", payload.Type)
+	}
+
+	payload.Type = entity.SharingTypeTodo
+	sharing, err := newSharing(userID, todoID, payload)
+	if err != nil {
+		return entity.Sharing{}, err
+	}
+
+	if err := setSharingRole(&sharing, userRole, payload); err != nil {
+		return entity.Sharing{}, err
+	}
+
+	if err := repo.InsertSharing(&sharing); err != nil {
+		return entity.Sharing{}, fmt.Errorf("fails to create sharing token: %w", err)
+	}
+
+	return sharing, nil
+}
+
+// Get active sharing tokens of todo with usage, by managers only
+func GetActiveTodoSharings(userID, todoID int64) ([]dto.SharingTokenStats, error) {
+	if _, err := AccessTodo(userID, todoID, entity.TodoListRoleManager); err != nil {
+		return nil, err
+	}
+
+	return getActiveSharings(todoID, entity.SharingTypeTodo)
+}
+
+/**
+ * oTodo Shared Users
+ */
+
+// Join todo by sharing token, nothing changes if user is able to access
+// todo already
+func CreateTodoSharedUser(userID int64, token string) error {
+	sharing, err := ValidSharing(token)
+	if err != nil {
+		return err
+	}
+
+	if sharing.Type != entity.SharingTypeTodo {
+		return util.NewErrorWithForbidden("unable to join todo by sharing token: %v", token)
+	}
+
+	todo, err := repo.SelectTodo(sharing.RelatedID)
+	if err != nil {
+		return fmt.Errorf("fails to get todo: %w", err)
+	}
+
+	role, err := getTodoRole(repo, userID, todo)
+	if err != nil {
+		return err
+	}
+
+	if role != 0 {
+		return nil
+	}
+
+	return repo.Transaction(func(r dal.Repository) error {
+		if err := redeemSharing(r, userID, sharing); err != nil {
+			return err
+		}
+
+		if err := r.InsertTodoSharedUser(userID, todo.ID, sharing.Role); err != nil {
+			return fmt.Errorf("fails to create todo shared user: %w", err)
+		}
+
+		return nil
+	})
+}
+
+func GetTodoSharedUsers(userID, todoID int64) ([]dto.TodoListSharedUser, error) {
+	if _, err := OwnTodo(userID, todoID); err != nil {
+		return nil, err
+	}
+
+	sharedUsers, err := repo.SelectTodoSharedUsers(todoID)
+	if err != nil {
+		return nil, fmt.Errorf("fails to get todo shared users: %w", err)
+	}
+
+	vec := make([]dto.TodoListSharedUser, 0, len(sharedUsers))
+	for _, sharedUser := range sharedUsers {
+		vec = append(vec, dto.TodoListSharedUser{User: sharedUser.User, Role: sharedUser.Role})
+	}
+
+	return vec, nil
+}
+
+// Delete shared user from todo,
+// can be called by owner to delete anyone,
+// or called by manager to delete viewers and editors,
+// or called by shared user to delete themselves
+func DeleteTodoSharedUser(operatorID, userID, todoID int64) error {
+	todo, role, err := accessTodo(repo, operatorID, todoID, entity.TodoListRoleViewer)
+	if err != nil {
+		return err
+	}
+
+	if userID != operatorID && role != entity.TodoListRoleOwner {
+		if role < entity.TodoListRoleManager {
+			return util.NewErrorWithForbidden("unable to delete shared user")
+		}
+
+		userRole, err := getTodoRole(repo, userID, todo)
+		if err != nil {
+			return err
+		}
+
+		if userRole >= entity.TodoListRoleManager {
+			return util.NewErrorWithForbidden("unable to delete manager of todo")
+		}
+	}
+
+	if err := repo.DeleteTodoSharedUser(userID, todoID); err != nil {
+		return fmt.Errorf("fails to delete todo shared users: %w", err)
+	}
+
+	return nil
+}
+
+// Get todos shared with user, not including todos in shared todo lists
+func GetSharedTodos(userID int64, query dto.TodoQuery) (dto.TodoPage, error) {
+	ids, err := repo.SelectSharedTodoIDs(userID)
+	if err != nil {
+		return dto.TodoPage{}, fmt.Errorf("fails to get shared todos: %w", err)
+	}
+
+	query.IDs = ids
+	return queryTodos(userID, query, "shared todos")
+}
+
+// Whether todo is shared with others, directly or by todo list
+func isSharedTodo(r dal.Repository, todo entity.Todo) (bool, error) {
+	sharedUsers, err := r.SelectTodoSharedUsers(todo.ID)
+	if err != nil {
+		return false, fmt.Errorf("fails to get todo shared users: %w", err)
+	}
+
+	if len(sharedUsers) != 0 {
+		return true, nil
+	}
+
+	return isSharedTodoList(r, todo.TodoListID)
+}
@@ -5,33 +5,39 @@ import (
 	"time"
 
 	"github.com/yzx9/otodo/dal"
+	"github.com/yzx9/otodo/model/dto"
 	"github.com/yzx9/otodo/model/entity"
 	"github.com/yzx9/otodo/util"
 )
 
 func CreateTodoStep(userID, todoID int64, name string) (entity.TodoStep, error) {
-	_, err := OwnTodo(userID, todoID)
+	_, err := AccessTodo(userID, todoID, entity.TodoListRoleEditor)
 	if err != nil {
-		return entity.TodoStep{}, util.NewErrorWithNotFound("todo not found: %v", todoID)
+		return entity.TodoStep{}, err
 	}
 
 	step := entity.TodoStep{
 		Name:   name,
 		TodoID: todoID,
 	}
-	if err = dal.InsertTodoStep(&step); err != nil {
+	if err = repo.InsertTodoStep(&step); err != nil {
 		return entity.TodoStep{}, util.NewErrorWithUnknown("fails to create todo step")
 	}
 
 	return step, nil
 }
 
-func UpdateTodoStep(userID int64, step *entity.TodoStep) error {
-	oldStep, err := OwnTodoStep(userID, step.ID)
+func UpdateTodoStep(userID int64, step *entity.TodoStep, ifMatch dto.IfMatch) error {
+	oldStep, err := AccessTodoStep(userID, step.ID, entity.TodoListRoleEditor)
 	if err != nil {
 		return err
 	}
 
+	if err := checkTodoStepIfMatch(oldStep, ifMatch); err != nil {
+		return err
+	}
+
+	step.Version = oldStep.Version
 	step.CreatedAt = oldStep.CreatedAt
 	step.TodoID = oldStep.TodoID
 
@@ -40,14 +46,33 @@ func UpdateTodoStep(userID int64, step *entity.TodoStep) error {
 		step.DoneAt = &t
 	}
 
-	if err = dal.SaveTodoStep(step); err != nil {
-		return util.NewErrorWithUnknown("fails to update todo step")
+	if err = repo.SaveTodoStep(step); err != nil {
+		return fmt.Errorf("fails to update todo step: %w", err)
 	}
 
 	return nil
 }
 
-func DeleteTodoStep(userID, todoID, todoStepID int64) (entity.TodoStep, error) {
+func DeleteTodoStep(userID, todoID, todoStepID int64, ifMatch dto.IfMatch) (entity.TodoStep, error) {
+	step, err := AccessTodoStep(userID, todoStepID, entity.TodoListRoleEditor)
+	if err != nil {
+		return entity.TodoStep{}, err
+	}
+
+	if step.TodoID != todoID {
+		return entity.TodoStep{}, util.NewErrorWithNotFound("todo step not found in todo: %v", todoStepID)
+	}
+
+	if err := checkTodoStepIfMatch(step, ifMatch); err != nil {
+		return entity.TodoStep{}, err
+	}
+
+	return step, repo.DeleteTodoStep(todoStepID)
+}
+
+// Move todo step after another one in the same todo, or to the first if
+// after is 0. Only ranks of user are changed
+func ReorderTodoStep(userID, todoID, todoStepID, afterID int64) (entity.TodoStep, error) {
 	step, err := OwnTodoStep(userID, todoStepID)
 	if err != nil {
 		return entity.TodoStep{}, err
@@ -57,19 +82,61 @@ func DeleteTodoStep(userID, todoID, todoStepID int64) (entity.TodoStep, error) {
 		return entity.TodoStep{}, util.NewErrorWithNotFound("todo step not found in todo: %v", todoStepID)
 	}
 
-	return step, dal.DeleteTodoStep(todoStepID)
+	err = repo.Transaction(func(r dal.Repository) error {
+		todo, err := r.SelectTodo(todoID)
+		if err != nil {
+			return fmt.Errorf("fails to get todo: %w", err)
+		}
+
+		ids := make([]int64, 0, len(todo.Steps))
+		for i := range todo.Steps {
+			ids = append(ids, todo.Steps[i].ID)
+		}
+
+		items, err := getRankItems(r, userID, entity.RankTypeTodoStep, ids)
+		if err != nil {
+			return err
+		}
+
+		step.Rank, err = moveRankItem(r, userID, items, todoStepID, afterID)
+		return err
+	})
+	if err != nil {
+		return entity.TodoStep{}, err
+	}
+
+	return step, nil
 }
 
+// Todo step in todo list of owner or shared user with any role
 func OwnTodoStep(userID, todoStepID int64) (entity.TodoStep, error) {
-	step, err := dal.SelectTodoStep(todoStepID)
+	return AccessTodoStep(userID, todoStepID, entity.TodoListRoleViewer)
+}
+
+// Todo step in todo list of owner or shared user with role, or roles after it
+func AccessTodoStep(userID, todoStepID int64, role entity.TodoListRole) (entity.TodoStep, error) {
+	step, err := repo.SelectTodoStep(todoStepID)
 	if err != nil {
 		return entity.TodoStep{}, fmt.Errorf("fails to get todo step: %w", err)
 	}
 
-	_, err = OwnTodo(userID, step.TodoID)
-	if err != nil {
-		return entity.TodoStep{}, util.NewErrorWithForbidden("unable to handle non-owned todo: %v", step.ID)
+	if _, err = AccessTodo(userID, step.TodoID, role); err != nil {
+		return entity.TodoStep{}, err
 	}
 
 	return step, nil
 }
+
+func checkTodoStepIfMatch(step entity.TodoStep, ifMatch dto.IfMatch) error {
+	todo, err := repo.SelectTodo(step.TodoID)
+	if err != nil {
+		return fmt.Errorf("fails to get todo: %w", err)
+	}
+
+	shared, err := isSharedTodo(repo, todo)
+	if err != nil {
+		return err
+	}
+
+	return checkIfMatch(ifMatch, step.Version, shared)
+}
@@ -0,0 +1,145 @@
+package bll
+
+import (
+	"testing"
+	"time"
+
+	"github.com/yzx9/otodo/model/dto"
+	"github.com/yzx9/otodo/model/entity"
+	"github.com/yzx9/otodo/otodo"
+	"github.com/yzx9/otodo/util"
+)
+
+func TestCompleteTodo(t *testing.T) {
+	useMemoryRepository(t)
+	user := createTestUser(t, "alice")
+	deadline := time.Date(2030, time.January, 10, 9, 0, 0, 0, time.Local)
+	todo := createTestTodo(t, user.ID, entity.Todo{
+		Title:          "water plants",
+		TodoListID:     user.BasicTodoListID,
+		Deadline:       &deadline,
+		TodoRepeatPlan: entity.TodoRepeatPlan{Type: string(entity.TodoRepeatPlanTypeDay), Interval: 1},
+	})
+
+	completion, err := CompleteTodo(user.ID, todo.ID, dto.IfMatch{})
+	must(t, err)
+	if !completion.Todo.Done || completion.Todo.DoneAt == nil || completion.Next == nil {
+		t.Fatalf("completion = %+v, want done with successor", completion)
+	}
+
+	if want := deadline.AddDate(0, 0, 1); completion.Next.Done || !completion.Next.Deadline.Equal(want) {
+		t.Errorf("successor deadline = %v, want %v", completion.Next.Deadline, want)
+	}
+
+	// completing again returns the same successor
+	again, err := CompleteTodo(user.ID, todo.ID, dto.IfMatch{})
+	must(t, err)
+	if again.Next == nil || again.Next.ID != completion.Next.ID {
+		t.Errorf("successor of completing again = %+v, want %v", again.Next, completion.Next.ID)
+	}
+
+	todos, err := repo.SelectTodos(user.BasicTodoListID)
+	must(t, err)
+	if len(todos) != 2 {
+		t.Errorf("todos = %v, want todo and one successor", len(todos))
+	}
+}
+
+func TestUncompleteTodo(t *testing.T) {
+	tests := []struct {
+		name       string
+		deleteNext bool
+		modify     func(t *testing.T, userID int64, next entity.Todo)
+		wantNext   bool
+	}{
+		{"keep next", false, nil, true},
+		{"delete next", true, nil, false},
+		{"keep modified next", true, func(t *testing.T, userID int64, next entity.Todo) {
+			next.Title = "water flowers"
+			must(t, UpdateTodo(userID, &next, dto.IfMatch{}))
+		}, true},
+		{"keep next with step", true, func(t *testing.T, userID int64, next entity.Todo) {
+			_, err := CreateTodoStep(userID, next.ID, "fill can")
+			must(t, err)
+		}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			useMemoryRepository(t)
+			user := createTestUser(t, "alice")
+			deadline := time.Date(2030, time.January, 10, 9, 0, 0, 0, time.Local)
+			todo := createTestTodo(t, user.ID, entity.Todo{
+				Title:          "water plants",
+				TodoListID:     user.BasicTodoListID,
+				Deadline:       &deadline,
+				TodoRepeatPlan: entity.TodoRepeatPlan{Type: string(entity.TodoRepeatPlanTypeDay), Interval: 1},
+			})
+
+			completion, err := CompleteTodo(user.ID, todo.ID, dto.IfMatch{})
+			must(t, err)
+			if tt.modify != nil {
+				tt.modify(t, user.ID, *completion.Next)
+			}
+
+			uncompletion, err := UncompleteTodo(user.ID, todo.ID, tt.deleteNext, dto.IfMatch{})
+			must(t, err)
+			if uncompletion.Todo.Done || uncompletion.Todo.DoneAt != nil {
+				t.Fatalf("todo = %+v, want undone", uncompletion.Todo)
+			}
+
+			_, err = repo.SelectTodo(completion.Next.ID)
+			if exists := err == nil; exists != tt.wantNext || (uncompletion.Next != nil) != tt.wantNext {
+				t.Fatalf("next exists = %v, want %v", exists, tt.wantNext)
+			}
+
+			// successor is not duplicated on next completion
+			completion, err = CompleteTodo(user.ID, todo.ID, dto.IfMatch{})
+			must(t, err)
+			todos, err := repo.SelectTodos(user.BasicTodoListID)
+			must(t, err)
+			if completion.Next == nil || len(todos) != 2 {
+				t.Errorf("todos after completing again = %v, want todo and one successor", len(todos))
+			}
+		})
+	}
+}
+
+func TestIsUnmodifiedRepeatTodo(t *testing.T) {
+	tests := []struct {
+		name string
+		todo entity.Todo
+		want bool
+	}{
+		{"created", entity.Todo{Version: 1}, true},
+		{"updated", entity.Todo{Version: 2}, false},
+		{"done", entity.Todo{Version: 1, Done: true}, false},
+		{"with step", entity.Todo{Version: 1, Steps: []entity.TodoStep{{}}}, false},
+		{"with file", entity.Todo{Version: 1, Files: []entity.File{{}}}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := isUnmodifiedRepeatTodo(tt.todo); got != tt.want {
+				t.Errorf("isUnmodifiedRepeatTodo() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestCompleteSharedTodo(t *testing.T) {
+	useMemoryRepository(t)
+	alice := createTestUser(t, "alice")
+	bob := createTestUser(t, "bob")
+	todoList := createTestTodoList(t, alice.ID, "shared")
+	joinTestTodoList(t, bob.ID, todoList.ID, entity.TodoListRoleViewer)
+	todo := createTestTodo(t, alice.ID, entity.Todo{Title: "milk", TodoListID: todoList.ID})
+
+	if _, err := CompleteTodo(bob.ID, todo.ID, dto.IfMatch{Present: true, Any: true}); !util.IsErrorCode(err, otodo.ErrForbidden) {
+		t.Errorf("complete by viewer error = %v, want forbidden", err)
+	}
+
+	if _, err := CompleteTodo(alice.ID, todo.ID, dto.IfMatch{}); !util.IsErrorCode(err, otodo.ErrPreconditionRequired) {
+		t.Errorf("complete without If-Match error = %v, want precondition required", err)
+	}
+}
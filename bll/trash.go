@@ -0,0 +1,249 @@
+package bll
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/yzx9/otodo/dal"
+	"github.com/yzx9/otodo/model/dto"
+	"github.com/yzx9/otodo/model/entity"
+	"github.com/yzx9/otodo/otodo"
+	"github.com/yzx9/otodo/util"
+)
+
+// Items deleted by cascade have a deleted time not before their parent,
+// since parents are deleted first. Restoring a parent restores them
+// together, but not those deleted before the parent.
+
+func GetTrash(userID int64) ([]dto.TrashItem, error) {
+	todos, err := repo.SelectDeletedTodos(userID)
+	if err != nil {
+		return nil, fmt.Errorf("fails to get deleted todos: %w", err)
+	}
+
+	lists, err := repo.SelectDeletedTodoLists(userID)
+	if err != nil {
+		return nil, fmt.Errorf("fails to get deleted todo lists: %w", err)
+	}
+
+	folders, err := repo.SelectDeletedTodoListFolders(userID)
+	if err != nil {
+		return nil, fmt.Errorf("fails to get deleted todo list folders: %w", err)
+	}
+
+	items := make([]dto.TrashItem, 0, len(todos)+len(lists)+len(folders))
+	for _, todo := range todos {
+		items = append(items, dto.TrashItem{
+			Type:      dto.TrashItemTypeTodo,
+			ID:        todo.ID,
+			Name:      todo.Title,
+			ParentID:  todo.TodoListID,
+			DeletedAt: todo.DeletedAt.Time,
+		})
+	}
+
+	for _, list := range lists {
+		items = append(items, dto.TrashItem{
+			Type:      dto.TrashItemTypeTodoList,
+			ID:        list.ID,
+			Name:      list.Name,
+			ParentID:  list.TodoListFolderID,
+			DeletedAt: list.DeletedAt.Time,
+		})
+	}
+
+	for _, folder := range folders {
+		items = append(items, dto.TrashItem{
+			Type:      dto.TrashItemTypeTodoListFolder,
+			ID:        folder.ID,
+			Name:      folder.Name,
+			DeletedAt: folder.DeletedAt.Time,
+		})
+	}
+
+	sort.SliceStable(items, func(i, j int) bool { return items[i].DeletedAt.After(items[j].DeletedAt) })
+	return items, nil
+}
+
+// Restore todo by creator or owner of todo list, re-attach to basic todo
+// list if its todo list has been deleted
+func RestoreTodo(userID, todoID int64) (entity.Todo, error) {
+	todo, err := repo.SelectDeletedTodo(todoID)
+	if err != nil {
+		return entity.Todo{}, fmt.Errorf("fails to get deleted todo: %w", err)
+	}
+
+	if todo.UserID != userID {
+		if _, err := OwnTodoList(userID, todo.TodoListID); err != nil {
+			return entity.Todo{}, util.NewErrorWithForbidden("unable to restore non-owned todo: %v", todoID)
+		}
+	}
+
+	todoListID := todo.TodoListID
+	if _, err := repo.SelectTodoList(todoListID); util.IsErrorCode(err, otodo.ErrNotFound) {
+		user, err := GetUser(userID)
+		if err != nil {
+			return entity.Todo{}, err
+		}
+
+		todoListID = user.BasicTodoListID
+	} else if err != nil {
+		return entity.Todo{}, fmt.Errorf("fails to get todo list: %w", err)
+	} else if _, err := AccessTodoList(userID, todoListID, entity.TodoListRoleEditor); err != nil {
+		return entity.Todo{}, util.NewErrorWithForbidden("unable to restore todo into todo list: %v", todoListID)
+	}
+
+	err = repo.Transaction(func(r dal.Repository) error {
+		if err := r.RestoreTodo(todoID, todoListID); err != nil {
+			return fmt.Errorf("fails to restore todo: %w", err)
+		}
+
+		if todoListID == todo.TodoListID {
+			return nil
+		}
+
+		restored, err := r.SelectTodo(todoID)
+		if err != nil {
+			return fmt.Errorf("fails to get todo: %w", err)
+		}
+
+		return moveTodoTags(r, restored, todo.TodoListID)
+	})
+	if err != nil {
+		return entity.Todo{}, err
+	}
+
+	todo, err = repo.SelectTodo(todoID)
+	if err != nil {
+		return entity.Todo{}, fmt.Errorf("fails to get todo: %w", err)
+	}
+
+	return todo, nil
+}
+
+// Restore todo list and its todos deleted together, re-attach to root if
+// its folder is not available
+func RestoreTodoList(userID, todoListID int64) (entity.TodoList, error) {
+	list, err := repo.SelectDeletedTodoList(todoListID)
+	if err != nil {
+		return entity.TodoList{}, fmt.Errorf("fails to get deleted todo list: %w", err)
+	}
+
+	if list.UserID != userID {
+		return entity.TodoList{}, util.NewErrorWithForbidden("unable to restore non-owned todo list: %v", todoListID)
+	}
+
+	folderID := list.TodoListFolderID
+	if folderID != 0 {
+		if _, err := OwnTodoListFolder(userID, folderID); err != nil {
+			folderID = 0
+		}
+	}
+
+	err = repo.Transaction(func(r dal.Repository) error {
+		return restoreTodoList(r, list, folderID)
+	})
+	if err != nil {
+		return entity.TodoList{}, err
+	}
+
+	return ForceGetTodoList(todoListID)
+}
+
+// Restore todo list folder and its todo lists deleted together
+func RestoreTodoListFolder(userID, todoListFolderID int64) (entity.TodoListFolder, error) {
+	write := func(err error) (entity.TodoListFolder, error) {
+		return entity.TodoListFolder{}, err
+	}
+
+	folder, err := repo.SelectDeletedTodoListFolder(todoListFolderID)
+	if err != nil {
+		return write(fmt.Errorf("fails to get deleted todo list folder: %w", err))
+	}
+
+	if folder.UserID != userID {
+		return write(util.NewErrorWithForbidden("unable to restore non-owned todo list folder: %v", todoListFolderID))
+	}
+
+	lists, err := repo.SelectDeletedTodoLists(userID)
+	if err != nil {
+		return write(fmt.Errorf("fails to get deleted todo lists: %w", err))
+	}
+
+	err = repo.Transaction(func(r dal.Repository) error {
+		if err := r.RestoreTodoListFolder(todoListFolderID); err != nil {
+			return fmt.Errorf("fails to restore todo list folder: %w", err)
+		}
+
+		for _, list := range lists {
+			if list.TodoListFolderID == todoListFolderID && !list.DeletedAt.Time.Before(folder.DeletedAt.Time) {
+				if err := restoreTodoList(r, list, todoListFolderID); err != nil {
+					return err
+				}
+			}
+		}
+
+		return nil
+	})
+	if err != nil {
+		return write(err)
+	}
+
+	return GetTodoListFolder(userID, todoListFolderID)
+}
+
+// Purge items deleted before retention, keep forever if retention not set
+func PurgeTrash() error {
+	retention := otodo.Conf.Trash.Retention
+	if retention <= 0 {
+		return nil
+	}
+
+	before := time.Now().Add(-time.Duration(retention) * time.Second)
+	return repo.Transaction(func(r dal.Repository) error {
+		if _, err := r.PurgeTodos(before); err != nil {
+			return fmt.Errorf("fails to purge todos: %w", err)
+		}
+
+		if _, err := r.PurgeTodoLists(before); err != nil {
+			return fmt.Errorf("fails to purge todo lists: %w", err)
+		}
+
+		if _, err := r.PurgeTodoListFolders(before); err != nil {
+			return fmt.Errorf("fails to purge todo list folders: %w", err)
+		}
+
+		return nil
+	})
+}
+
+// Purge trash periodically in background
+func StartTrashPurge() {
+	go func() {
+		for {
+			if err := PurgeTrash(); err != nil {
+				fmt.Println(err)
+			}
+
+			interval := otodo.Conf.Trash.PurgeInterval
+			if interval <= 0 {
+				interval = 3600
+			}
+
+			time.Sleep(time.Duration(interval) * time.Second)
+		}
+	}()
+}
+
+func restoreTodoList(r dal.Repository, list entity.TodoList, todoListFolderID int64) error {
+	if err := r.RestoreTodoList(list.ID, todoListFolderID); err != nil {
+		return fmt.Errorf("fails to restore todo list: %w", err)
+	}
+
+	if _, err := r.RestoreTodos(list.ID, list.DeletedAt.Time); err != nil {
+		return fmt.Errorf("fails to restore todos: %w", err)
+	}
+
+	return nil
+}
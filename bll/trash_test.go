@@ -0,0 +1,58 @@
+package bll
+
+import (
+	"testing"
+
+	"github.com/yzx9/otodo/model/dto"
+	"github.com/yzx9/otodo/model/entity"
+	"github.com/yzx9/otodo/otodo"
+	"github.com/yzx9/otodo/util"
+)
+
+func TestRestoreTodo(t *testing.T) {
+	useMemoryRepository(t)
+	alice := createTestUser(t, "alice")
+	bob := createTestUser(t, "bob")
+	todoList := createTestTodoList(t, alice.ID, "shared")
+	joinTestTodoList(t, bob.ID, todoList.ID, entity.TodoListRoleEditor)
+
+	// creator is restoring into todo list
+	todo := createTestTodo(t, bob.ID, entity.Todo{Title: "milk", TodoListID: todoList.ID})
+	_, err := DeleteTodo(bob.ID, todo.ID, dto.IfMatch{Present: true, Any: true})
+	must(t, err)
+	restored, err := RestoreTodo(bob.ID, todo.ID)
+	must(t, err)
+	if restored.TodoListID != todoList.ID {
+		t.Errorf("restored todo list = %v, want %v", restored.TodoListID, todoList.ID)
+	}
+
+	// removed creator is unable to take it away from todo list
+	_, err = DeleteTodo(bob.ID, todo.ID, dto.IfMatch{Present: true, Any: true})
+	must(t, err)
+	must(t, DeleteTodoListSharedUser(alice.ID, bob.ID, todoList.ID))
+	if _, err := RestoreTodo(bob.ID, todo.ID); !util.IsErrorCode(err, otodo.ErrForbidden) {
+		t.Fatalf("restore by removed creator error = %v, want forbidden", err)
+	}
+
+	if _, err := repo.SelectDeletedTodo(todo.ID); err != nil {
+		t.Fatalf("todo error = %v, want deleted", err)
+	}
+
+	// owner of todo list is able to restore
+	restored, err = RestoreTodo(alice.ID, todo.ID)
+	must(t, err)
+	if restored.TodoListID != todoList.ID {
+		t.Errorf("restored todo list = %v, want %v", restored.TodoListID, todoList.ID)
+	}
+
+	// basic todo list is used if todo list has been deleted
+	own := createTestTodoList(t, bob.ID, "own")
+	todo = createTestTodo(t, bob.ID, entity.Todo{Title: "#work report", TodoListID: own.ID})
+	_, err = DeleteTodoList(bob.ID, own.ID, dto.IfMatch{})
+	must(t, err)
+	restored, err = RestoreTodo(bob.ID, todo.ID)
+	must(t, err)
+	if restored.TodoListID != bob.BasicTodoListID {
+		t.Errorf("restored todo list = %v, want basic %v", restored.TodoListID, bob.BasicTodoListID)
+	}
+}
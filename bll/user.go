@@ -3,6 +3,7 @@ package bll
 import (
 	"crypto/sha256"
 	"fmt"
+	"time"
 
 	"github.com/yzx9/otodo/dal"
 	"github.com/yzx9/otodo/model/dto"
@@ -20,7 +21,7 @@ func CreateUser(payload dto.CreateUserDTO) (entity.User, error) {
 		return entity.User{}, fmt.Errorf("password too short")
 	}
 
-	exist, err := dal.ExistUserByUserName(payload.UserName)
+	exist, err := repo.ExistUserByUserName(payload.UserName)
 	if err != nil {
 		return entity.User{}, fmt.Errorf("fails to valid user name: %w", err)
 	}
@@ -42,7 +43,7 @@ func CreateUser(payload dto.CreateUserDTO) (entity.User, error) {
 }
 
 func GetUser(userID int64) (entity.User, error) {
-	user, err := dal.SelectUser(userID)
+	user, err := repo.SelectUser(userID)
 	if err != nil {
 		return entity.User{}, fmt.Errorf("fails to get user: %w", err)
 	}
@@ -50,6 +51,24 @@ func GetUser(userID int64) (entity.User, error) {
 	return user, nil
 }
 
+func UpdateUserTimezone(userID int64, timezone string) (entity.User, error) {
+	if _, err := time.LoadLocation(timezone); err != nil {
+		return entity.User{}, util.NewErrorWithBadRequest("invalid timezone: %v", timezone)
+	}
+
+	user, err := GetUser(userID)
+	if err != nil {
+		return entity.User{}, err
+	}
+
+	user.Timezone = timezone
+	if err := repo.SaveUser(&user); err != nil {
+		return entity.User{}, fmt.Errorf("fails to update user timezone: %w", err)
+	}
+
+	return user, nil
+}
+
 /**
  * Invalid User Refresh Token
  */
@@ -59,7 +78,7 @@ func CreateUserInvalidRefreshToken(userID int64, tokenID string) (entity.UserInv
 		UserID:  userID,
 		TokenID: tokenID,
 	}
-	if err := dal.InsertUserInvalidRefreshToken(&model); err != nil {
+	if err := repo.InsertUserInvalidRefreshToken(&model); err != nil {
 		return entity.UserInvalidRefreshToken{}, fmt.Errorf("fails to make user refresh token invalid: %w", err)
 	}
 
@@ -69,7 +88,7 @@ func CreateUserInvalidRefreshToken(userID int64, tokenID string) (entity.UserInv
 // Verify is it an valid token.
 // Note: This func don't check token expire time
 func IsValidRefreshToken(userID int64, tokenID string) (bool, error) {
-	valid, err := dal.ExistUserInvalidRefreshToken(userID, tokenID)
+	valid, err := repo.ExistUserInvalidRefreshToken(userID, tokenID)
 	if err != nil {
 		return false, fmt.Errorf("fails to get user refresh token: %w", err)
 	}
@@ -88,13 +107,13 @@ func GetCryptoPassword(password string) []byte {
  */
 
 func getOrRegisterUserByGithub(profile dto.GithubUserPublicProfile) (entity.User, error) {
-	exist, err := dal.ExistUserByGithubID(profile.ID)
+	exist, err := repo.ExistUserByGithubID(profile.ID)
 	if err != nil {
 		return entity.User{}, util.NewErrorWithUnknown("fails to register user: %w", err)
 	}
 
 	if exist {
-		user, err := dal.SelectUserByGithubID(profile.ID)
+		user, err := repo.SelectUserByGithubID(profile.ID)
 		if err != nil {
 			return entity.User{}, util.NewErrorWithUnknown("fails to get user: %w", err)
 		}
@@ -122,30 +141,37 @@ func getOrRegisterUserByGithub(profile dto.GithubUserPublicProfile) (entity.User
  */
 
 func createUser(user *entity.User) error {
-	if err := dal.InsertUser(user); err != nil {
-		return fmt.Errorf("fails to create user: %w", err)
-	}
+	return repo.Transaction(func(r dal.Repository) error {
+		if err := r.InsertUser(user); err != nil {
+			return fmt.Errorf("fails to create user: %w", err)
+		}
 
-	// create base todo list
-	if _, err := createBasicTodoList(user); err != nil {
-		return fmt.Errorf("fails to create user basic todo list: %w", err)
-	}
+		// create base todo list
+		if _, err := createBasicTodoList(r, user); err != nil {
+			return fmt.Errorf("fails to create user basic todo list: %w", err)
+		}
+
+		// invitations held for email are pending until accepted
+		if err := handOverTodoListInvitations(r, *user); err != nil {
+			return err
+		}
 
-	return nil
+		return nil
+	})
 }
 
-func createBasicTodoList(user *entity.User) (entity.TodoList, error) {
+func createBasicTodoList(r dal.Repository, user *entity.User) (entity.TodoList, error) {
 	basicTodoList := entity.TodoList{
 		Name:    "Todos", // TODO i18n
 		IsBasic: true,
 		UserID:  user.ID,
 	}
-	if err := dal.InsertTodoList(&basicTodoList); err != nil {
+	if err := r.InsertTodoList(&basicTodoList); err != nil {
 		return entity.TodoList{}, fmt.Errorf("fails to create user basic todo list: %w", err)
 	}
 
 	user.BasicTodoListID = basicTodoList.ID
-	if err := dal.SaveUser(user); err != nil {
+	if err := r.SaveUser(user); err != nil {
 		return entity.TodoList{}, fmt.Errorf("fails to create user basic todo list: %w", err)
 	}
 
@@ -0,0 +1,45 @@
+package dal
+
+import (
+	"github.com/yzx9/otodo/model/entity"
+	"github.com/yzx9/otodo/util"
+)
+
+type DailyTodoRepository interface {
+	InsertDailyTodo(daily *entity.DailyTodo) error
+	SelectDailyTodos(userID int64, date string) ([]entity.Todo, error)
+	DeleteDailyTodo(userID, todoID int64, date string) (int64, error)
+	ExistDailyTodo(userID, todoID int64, date string) (bool, error)
+}
+
+func (r *gormRepository) InsertDailyTodo(daily *entity.DailyTodo) error {
+	re := r.db.Create(daily)
+	return util.WrapGormErr(re.Error, "daily todo")
+}
+
+func (r *gormRepository) SelectDailyTodos(userID int64, date string) ([]entity.Todo, error) {
+	var todos []entity.Todo
+	re := r.db.
+		Scopes(todoPreload).
+		Joins("JOIN daily_todos ON daily_todos.todo_id = todos.id AND daily_todos.deleted_at IS NULL").
+		Where("daily_todos.user_id = ? AND daily_todos.date = ?", userID, date).
+		Order("daily_todos.created_at").
+		Find(&todos)
+	return todos, util.WrapGormErr(re.Error, "daily todos")
+}
+
+func (r *gormRepository) DeleteDailyTodo(userID, todoID int64, date string) (int64, error) {
+	re := r.db.
+		Where(entity.DailyTodo{UserID: userID, TodoID: todoID, Date: date}).
+		Delete(&entity.DailyTodo{})
+	return re.RowsAffected, util.WrapGormErr(re.Error, "daily todo")
+}
+
+func (r *gormRepository) ExistDailyTodo(userID, todoID int64, date string) (bool, error) {
+	var count int64
+	re := r.db.
+		Model(&entity.DailyTodo{}).
+		Where(entity.DailyTodo{UserID: userID, TodoID: todoID, Date: date}).
+		Count(&count)
+	return count != 0, util.WrapGormErr(re.Error, "daily todo")
+}
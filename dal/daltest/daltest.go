@@ -0,0 +1,1773 @@
+// Package daltest provides a conformance suite for dal.Repository
+// implementations, every implementation should pass it.
+package daltest
+
+import (
+	"errors"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/yzx9/otodo/dal"
+	"github.com/yzx9/otodo/model/dto"
+	"github.com/yzx9/otodo/model/entity"
+	"github.com/yzx9/otodo/otodo"
+)
+
+// Run the conformance suite, newRepository should returns an empty
+// repository for each call
+func Run(t *testing.T, newRepository func(t *testing.T) dal.Repository) {
+	if err := otodo.Init(); err != nil {
+		t.Fatalf("fails to init otodo: %v", err)
+	}
+
+	tests := []struct {
+		name string
+		test func(t *testing.T, r dal.Repository)
+	}{
+		{"File", testFile},
+		{"User", testUser},
+		{"UserInvalidRefreshToken", testUserInvalidRefreshToken},
+		{"ThirdPartyOAuthToken", testThirdPartyOAuthToken},
+		{"Todo", testTodo},
+		{"TodoQuery", testTodoQuery},
+		{"SearchTodos", testSearchTodos},
+		{"Reminder", testReminder},
+		{"TodoFile", testTodoFile},
+		{"TodoStep", testTodoStep},
+		{"TodoRepeatPlan", testTodoRepeatPlan},
+		{"TodoMove", testTodoMove},
+		{"DailyTodo", testDailyTodo},
+		{"TodoList", testTodoList},
+		{"TodoListSharing", testTodoListSharing},
+		{"TodoListInvitation", testTodoListInvitation},
+		{"TodoListFolder", testTodoListFolder},
+		{"TodoListFolderSharing", testTodoListFolderSharing},
+		{"TodoSharing", testTodoSharing},
+		{"Sharing", testSharing},
+		{"Tag", testTag},
+		{"Notification", testNotification},
+		{"Rank", testRank},
+		{"Version", testVersion},
+		{"Trash", testTrash},
+		{"Transaction", testTransaction},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			tt.test(t, newRepository(t))
+		})
+	}
+}
+
+/**
+ * File
+ */
+
+func testFile(t *testing.T, r dal.Repository) {
+	file := entity.File{FileName: "a.txt", FilePath: "/tmp/a.txt", AccessType: int8(entity.FileTypePublic)}
+	must(t, r.InsertFile(&file))
+	if file.ID == 0 {
+		t.Fatal("id should be assigned after insert")
+	}
+
+	got, err := r.SelectFile(file.ID)
+	must(t, err)
+	if got.FileName != "a.txt" || got.FilePath != "/tmp/a.txt" {
+		t.Errorf("unexpected file: %+v", got)
+	}
+
+	file.FilePath = "/tmp/b.txt"
+	must(t, r.SaveFile(&file))
+	got, err = r.SelectFile(file.ID)
+	must(t, err)
+	if got.FilePath != "/tmp/b.txt" {
+		t.Errorf("file path should be updated, got %v", got.FilePath)
+	}
+
+	_, err = r.SelectFile(file.ID + 1)
+	mustNotFound(t, err)
+}
+
+/**
+ * User
+ */
+
+func testUser(t *testing.T, r dal.Repository) {
+	alice := entity.User{Name: "alice", Nickname: "Alice", GithubID: 42}
+	must(t, r.InsertUser(&alice))
+	bob := entity.User{Name: "bob", Nickname: "Bob", Email: "bob@example.com"}
+	must(t, r.InsertUser(&bob))
+
+	got, err := r.SelectUser(alice.ID)
+	must(t, err)
+	if got.Name != "alice" || got.Nickname != "Alice" {
+		t.Errorf("unexpected user: %+v", got)
+	}
+
+	got, err = r.SelectUserByUserName("bob")
+	must(t, err)
+	if got.ID != bob.ID {
+		t.Errorf("expected user %v, got %v", bob.ID, got.ID)
+	}
+
+	got, err = r.SelectUserByGithubID(42)
+	must(t, err)
+	if got.ID != alice.ID {
+		t.Errorf("expected user %v, got %v", alice.ID, got.ID)
+	}
+
+	got, err = r.SelectUserByEmail("bob@example.com")
+	must(t, err)
+	if got.ID != bob.ID {
+		t.Errorf("expected user %v, got %v", bob.ID, got.ID)
+	}
+
+	_, err = r.SelectUserByUserName("carol")
+	mustNotFound(t, err)
+
+	_, err = r.SelectUserByEmail("carol@example.com")
+	mustNotFound(t, err)
+
+	exist, err := r.ExistUserByUserName("alice")
+	must(t, err)
+	expectBool(t, "user alice exists", exist, true)
+
+	exist, err = r.ExistUserByUserName("carol")
+	must(t, err)
+	expectBool(t, "user carol exists", exist, false)
+
+	exist, err = r.ExistUserByGithubID(42)
+	must(t, err)
+	expectBool(t, "github user exists", exist, true)
+
+	exist, err = r.ExistUserByGithubID(43)
+	must(t, err)
+	expectBool(t, "github user exists", exist, false)
+
+	bob.Nickname = "Bobby"
+	must(t, r.SaveUser(&bob))
+	got, err = r.SelectUser(bob.ID)
+	must(t, err)
+	if got.Nickname != "Bobby" {
+		t.Errorf("nickname should be updated, got %v", got.Nickname)
+	}
+
+	todo := entity.Todo{Title: "todo", UserID: bob.ID}
+	must(t, r.InsertTodo(&todo))
+	got, err = r.SelectUserByTodo(todo.ID)
+	must(t, err)
+	if got.ID != bob.ID {
+		t.Errorf("expected owner %v, got %v", bob.ID, got.ID)
+	}
+}
+
+func testUserInvalidRefreshToken(t *testing.T, r dal.Repository) {
+	user := insertUser(t, r, "alice")
+	must(t, r.InsertUserInvalidRefreshToken(&entity.UserInvalidRefreshToken{UserID: user.ID, TokenID: "token"}))
+
+	exist, err := r.ExistUserInvalidRefreshToken(user.ID, "token")
+	must(t, err)
+	expectBool(t, "invalid token exists", exist, true)
+
+	exist, err = r.ExistUserInvalidRefreshToken(user.ID, "another")
+	must(t, err)
+	expectBool(t, "invalid token exists", exist, false)
+
+	exist, err = r.ExistUserInvalidRefreshToken(user.ID+1, "token")
+	must(t, err)
+	expectBool(t, "invalid token of other user exists", exist, false)
+}
+
+func testThirdPartyOAuthToken(t *testing.T, r dal.Repository) {
+	user := insertUser(t, r, "alice")
+	tokenType := entity.ThirdPartyTokenTypeGithubAccessToken
+
+	exist, err := r.ExistActiveThirdPartyOAuthToken(user.ID, tokenType)
+	must(t, err)
+	expectBool(t, "token exists", exist, false)
+
+	token := entity.ThirdPartyOAuthToken{UserID: user.ID, Type: int8(tokenType), Token: "a", Active: true}
+	must(t, r.InsertThirdPartyOAuthToken(&token))
+
+	exist, err = r.ExistActiveThirdPartyOAuthToken(user.ID, tokenType)
+	must(t, err)
+	expectBool(t, "token exists", exist, true)
+
+	must(t, r.UpdateThirdPartyOAuthToken(&entity.ThirdPartyOAuthToken{UserID: user.ID, Type: int8(tokenType), Token: "b", Active: false}))
+	exist, err = r.ExistActiveThirdPartyOAuthToken(user.ID, tokenType)
+	must(t, err)
+	expectBool(t, "inactive token exists", exist, false)
+}
+
+/**
+ * Todo
+ */
+
+func testTodo(t *testing.T, r dal.Repository) {
+	user := insertUser(t, r, "alice")
+	other := insertUser(t, r, "bob")
+	list := insertTodoList(t, r, user.ID, "list")
+	otherList := insertTodoList(t, r, user.ID, "other list")
+
+	now := time.Now()
+	yesterday, tomorrow := now.Add(-24*time.Hour), now.Add(24*time.Hour)
+
+	plain := entity.Todo{Title: "plain", UserID: user.ID, TodoListID: list.ID}
+	important := entity.Todo{Title: "important", UserID: user.ID, TodoListID: list.ID, Importance: true}
+	planed := entity.Todo{Title: "planed", UserID: user.ID, TodoListID: otherList.ID, Deadline: &tomorrow}
+	overdue := entity.Todo{Title: "overdue", UserID: user.ID, TodoListID: otherList.ID, Deadline: &yesterday}
+	done := entity.Todo{Title: "done", UserID: user.ID, TodoListID: otherList.ID, Deadline: &yesterday, Done: true}
+	others := entity.Todo{Title: "others", UserID: other.ID, Importance: true}
+	for _, todo := range []*entity.Todo{&plain, &important, &planed, &overdue, &done, &others} {
+		must(t, r.InsertTodo(todo))
+	}
+
+	got, err := r.SelectTodo(plain.ID)
+	must(t, err)
+	if got.Title != "plain" || got.UserID != user.ID || got.TodoListID != list.ID {
+		t.Errorf("unexpected todo: %+v", got)
+	}
+
+	todos, err := r.SelectTodos(list.ID)
+	must(t, err)
+	expectTodos(t, "todos of list", todos, plain, important)
+
+	todos, err = r.SelectAllTodos(user.ID)
+	must(t, err)
+	expectTodos(t, "all todos", todos, plain, important, planed, overdue, done)
+
+	todos, err = r.SelectImportantTodos(user.ID)
+	must(t, err)
+	expectTodos(t, "important todos", todos, important)
+
+	todos, err = r.SelectPlanedTodos(user.ID)
+	must(t, err)
+	expectTodosInOrder(t, "planed todos", todos, overdue, done, planed)
+
+	todos, err = r.SelectOverdueTodos(user.ID, now)
+	must(t, err)
+	expectTodos(t, "overdue todos", todos, overdue)
+
+	plain.Memo = "memo"
+	plain.Done = true
+	must(t, r.SaveTodo(&plain))
+	got, err = r.SelectTodo(plain.ID)
+	must(t, err)
+	if got.Memo != "memo" || !got.Done {
+		t.Errorf("todo should be updated, got %+v", got)
+	}
+
+	must(t, r.DeleteTodo(plain.ID, plain.Version))
+	_, err = r.SelectTodo(plain.ID)
+	mustNotFound(t, err)
+
+	count, err := r.DeleteTodos(otherList.ID)
+	must(t, err)
+	if count != 3 {
+		t.Errorf("expected 3 todos deleted, got %v", count)
+	}
+
+	todos, err = r.SelectAllTodos(user.ID)
+	must(t, err)
+	expectTodos(t, "all todos after delete", todos, important)
+}
+
+func testTodoQuery(t *testing.T, r dal.Repository) {
+	user := insertUser(t, r, "alice")
+	other := insertUser(t, r, "bob")
+	list := insertTodoList(t, r, user.ID, "list")
+	otherList := insertTodoList(t, r, user.ID, "other list")
+
+	base := time.Date(2022, 1, 1, 0, 0, 0, 0, time.UTC)
+	day := func(n int) *time.Time {
+		t := base.AddDate(0, 0, n)
+		return &t
+	}
+
+	a := entity.Todo{Title: "a", UserID: user.ID, TodoListID: list.ID, Deadline: day(2)}
+	b := entity.Todo{Title: "b", UserID: user.ID, TodoListID: list.ID, Deadline: day(1), Importance: true}
+	c := entity.Todo{Title: "c", UserID: user.ID, TodoListID: list.ID, Done: true}
+	d := entity.Todo{Title: "d", UserID: user.ID, TodoListID: list.ID, Deadline: day(1)}
+	e := entity.Todo{Title: "e", UserID: user.ID, TodoListID: otherList.ID, Importance: true}
+	others := entity.Todo{Title: "others", UserID: other.ID, Deadline: day(1)}
+	for _, todo := range []*entity.Todo{&a, &b, &c, &d, &e, &others} {
+		must(t, r.InsertTodo(todo))
+	}
+
+	file := entity.File{FileName: "a.txt"}
+	must(t, r.InsertFile(&file))
+	must(t, r.InsertTodoFile(d.ID, file.ID))
+	must(t, r.InsertTag(&entity.Tag{Name: "work", UserID: user.ID}))
+	must(t, r.InsertTagTodo(user.ID, a.ID, "work"))
+	must(t, r.InsertTagTodo(user.ID, e.ID, "work"))
+	must(t, r.InsertTag(&entity.Tag{Name: "work", UserID: other.ID}))
+	must(t, r.InsertTagTodo(other.ID, b.ID, "work"))
+
+	for _, rank := range []entity.Rank{
+		{UserID: user.ID, Type: entity.RankTypeTodo, RelatedID: b.ID, Key: "m"},
+		{UserID: user.ID, Type: entity.RankTypeTodo, RelatedID: d.ID, Key: "g"},
+		{UserID: other.ID, Type: entity.RankTypeTodo, RelatedID: a.ID, Key: "0"},
+		{UserID: user.ID, Type: entity.RankTypeTodoStep, RelatedID: c.ID, Key: "0"},
+	} {
+		rank := rank
+		must(t, r.SaveRank(&rank))
+	}
+
+	yes, no := true, false
+	tests := []struct {
+		name     string
+		query    dto.TodoQuery
+		expected []entity.Todo
+	}{
+		{"user", dto.TodoQuery{UserID: user.ID}, []entity.Todo{a, b, c, d, e}},
+		{"todo list", dto.TodoQuery{TodoListID: otherList.ID}, []entity.Todo{e}},
+		{"ids", dto.TodoQuery{IDs: []int64{b.ID, others.ID}}, []entity.Todo{b, others}},
+		{"empty ids", dto.TodoQuery{IDs: []int64{}}, []entity.Todo{}},
+		{"todo lists", dto.TodoQuery{TodoListIDs: []int64{list.ID, otherList.ID}, Tag: "work", TagUserID: user.ID}, []entity.Todo{a, e}},
+		{"empty todo lists", dto.TodoQuery{TodoListIDs: []int64{}}, []entity.Todo{}},
+		{"todo lists and shared", dto.TodoQuery{TodoListIDs: []int64{otherList.ID}, SharedIDs: []int64{others.ID}}, []entity.Todo{e, others}},
+		{"shared only", dto.TodoQuery{TodoListIDs: []int64{}, SharedIDs: []int64{b.ID}, Importance: &yes}, []entity.Todo{b}},
+		{"done", dto.TodoQuery{UserID: user.ID, Done: &yes}, []entity.Todo{c}},
+		{"importance", dto.TodoQuery{UserID: user.ID, Importance: &yes}, []entity.Todo{b, e}},
+		{"has deadline", dto.TodoQuery{UserID: user.ID, HasDeadline: &no}, []entity.Todo{c, e}},
+		{"deadline range", dto.TodoQuery{UserID: user.ID, DeadlineFrom: day(1), DeadlineTo: day(2)}, []entity.Todo{b, d}},
+		{"tag", dto.TodoQuery{UserID: user.ID, Tag: "work", TagUserID: user.ID}, []entity.Todo{a, e}},
+		{"tag of other", dto.TodoQuery{UserID: user.ID, Tag: "work", TagUserID: other.ID}, []entity.Todo{b}},
+		{"has files", dto.TodoQuery{UserID: user.ID, HasFiles: &yes}, []entity.Todo{d}},
+		{"has no files", dto.TodoQuery{UserID: user.ID, HasFiles: &no, Done: &no}, []entity.Todo{a, b, e}},
+		{"sort by deadline", dto.TodoQuery{UserID: user.ID, Sort: dto.TodoSortDeadline}, []entity.Todo{b, d, a, c, e}},
+		{"sort by deadline desc", dto.TodoQuery{UserID: user.ID, Sort: dto.TodoSortDeadline, Desc: true}, []entity.Todo{e, c, a, d, b}},
+		{"sort by title desc", dto.TodoQuery{UserID: user.ID, Sort: dto.TodoSortTitle, Desc: true}, []entity.Todo{e, d, c, b, a}},
+		{"sort by importance", dto.TodoQuery{UserID: user.ID, Sort: dto.TodoSortImportance}, []entity.Todo{a, c, d, b, e}},
+		{"sort by created at", dto.TodoQuery{UserID: user.ID, Sort: dto.TodoSortCreatedAt}, []entity.Todo{a, b, c, d, e}},
+		{"sort by rank", dto.TodoQuery{UserID: user.ID, Sort: dto.TodoSortRank, RankUserID: user.ID}, []entity.Todo{d, b, a, c, e}},
+		{"sort by rank desc", dto.TodoQuery{UserID: user.ID, Sort: dto.TodoSortRank, RankUserID: user.ID, Desc: true}, []entity.Todo{e, c, a, b, d}},
+		{"sort by rank of other", dto.TodoQuery{UserID: user.ID, Sort: dto.TodoSortRank, RankUserID: other.ID}, []entity.Todo{a, b, c, d, e}},
+		{"limit", dto.TodoQuery{UserID: user.ID, Limit: 2}, []entity.Todo{a, b}},
+	}
+	for _, tt := range tests {
+		todos, err := r.SelectTodosByQuery(tt.query)
+		must(t, err)
+		expectTodosInOrder(t, tt.name, todos, tt.expected...)
+	}
+
+	// walk through pages by cursor
+	for _, tt := range tests {
+		if tt.query.Limit != 0 {
+			continue
+		}
+
+		query := tt.query
+		query.Limit = 2
+		got := make([]entity.Todo, 0)
+		for i := 0; i <= len(tt.expected); i++ {
+			todos, err := r.SelectTodosByQuery(query)
+			must(t, err)
+			got = append(got, todos...)
+			if len(todos) < query.Limit {
+				break
+			}
+
+			last := todos[len(todos)-1]
+			ranks, err := r.SelectRanks(query.RankUserID, entity.RankTypeTodo, []int64{last.ID})
+			must(t, err)
+			last.Rank = ranks[last.ID]
+
+			cursor := dto.NewTodoCursor(last, query.Sort, query.Desc)
+			query.After = &cursor
+		}
+		expectTodosInOrder(t, tt.name+" by pages", got, tt.expected...)
+	}
+}
+
+func testSearchTodos(t *testing.T, r dal.Repository) {
+	user := insertUser(t, r, "alice")
+	list := insertTodoList(t, r, user.ID, "list")
+	otherList := insertTodoList(t, r, user.ID, "other list")
+
+	tomorrow := time.Now().Add(24 * time.Hour)
+	milk := entity.Todo{Title: "Buy Milk", UserID: user.ID, TodoListID: list.ID, Deadline: &tomorrow}
+	memo := entity.Todo{Title: "shopping", Memo: "milk and eggs", UserID: user.ID, TodoListID: list.ID, Done: true}
+	step := entity.Todo{Title: "breakfast", UserID: user.ID, TodoListID: list.ID, Importance: true}
+	file := entity.Todo{Title: "receipt", UserID: user.ID, TodoListID: list.ID}
+	percent := entity.Todo{Title: "100% done", UserID: user.ID, TodoListID: list.ID}
+	other := entity.Todo{Title: "milk", UserID: user.ID, TodoListID: otherList.ID}
+	cjk := entity.Todo{Title: "买牛奶", UserID: user.ID, TodoListID: list.ID}
+	short := entity.Todo{Title: "Fix TV", UserID: user.ID, TodoListID: list.ID}
+	for _, todo := range []*entity.Todo{&milk, &memo, &step, &file, &percent, &other, &cjk, &short} {
+		must(t, r.InsertTodo(todo))
+	}
+
+	must(t, r.InsertTodoStep(&entity.TodoStep{Name: "pour milk", TodoID: step.ID}))
+	f := entity.File{FileName: "milk-receipt.png"}
+	must(t, r.InsertFile(&f))
+	must(t, r.InsertTodoFile(file.ID, f.ID))
+	must(t, r.InsertTag(&entity.Tag{Name: "food", UserID: user.ID}))
+	must(t, r.InsertTagTodo(user.ID, memo.ID, "food"))
+	bob := insertUser(t, r, "bob")
+	must(t, r.InsertTag(&entity.Tag{Name: "food", UserID: bob.ID}))
+	must(t, r.InsertTagTodo(bob.ID, milk.ID, "food"))
+
+	yes := true
+	lists := []int64{list.ID}
+	tests := []struct {
+		name     string
+		search   dto.TodoSearch
+		expected []entity.Todo
+	}{
+		{"terms", dto.TodoSearch{TodoListIDs: lists, Terms: []string{"MILK"}}, []entity.Todo{file, step, memo, milk}},
+		{"all terms", dto.TodoSearch{TodoListIDs: lists, Terms: []string{"milk", "eggs"}}, []entity.Todo{memo}},
+		{"escape", dto.TodoSearch{TodoListIDs: lists, Terms: []string{"0%"}}, []entity.Todo{percent}},
+		{"cjk", dto.TodoSearch{TodoListIDs: lists, Terms: []string{"牛奶"}}, []entity.Todo{cjk}},
+		{"short", dto.TodoSearch{TodoListIDs: lists, Terms: []string{"tv"}}, []entity.Todo{short}},
+		{"tag", dto.TodoSearch{TodoListIDs: lists, TagUserID: user.ID, Tags: []string{"food"}}, []entity.Todo{memo}},
+		{"tag of other", dto.TodoSearch{TodoListIDs: lists, TagUserID: bob.ID, Tags: []string{"food"}}, []entity.Todo{milk}},
+		{"done", dto.TodoSearch{TodoListIDs: lists, Terms: []string{"milk"}, Done: &yes}, []entity.Todo{memo}},
+		{"importance", dto.TodoSearch{TodoListIDs: lists, Importance: &yes}, []entity.Todo{step}},
+		{"deadline", dto.TodoSearch{TodoListIDs: lists, DeadlineFrom: &milk.CreatedAt}, []entity.Todo{milk}},
+		{"limit", dto.TodoSearch{TodoListIDs: lists, Terms: []string{"milk"}, Limit: 1}, []entity.Todo{file}},
+		{"no list", dto.TodoSearch{Terms: []string{"milk"}}, []entity.Todo{}},
+		{"shared", dto.TodoSearch{TodoListIDs: lists, SharedIDs: []int64{other.ID}, Terms: []string{"buy", "milk"}}, []entity.Todo{milk}},
+		{"shared only", dto.TodoSearch{SharedIDs: []int64{other.ID}, Terms: []string{"milk"}}, []entity.Todo{other}},
+	}
+	for _, tt := range tests {
+		todos, err := r.SearchTodos(tt.search)
+		must(t, err)
+		expectTodosInOrder(t, tt.name, todos, tt.expected...)
+	}
+
+	todos, err := r.SearchTodos(dto.TodoSearch{TodoListIDs: lists, Terms: []string{"pour"}})
+	must(t, err)
+	if len(todos) != 1 || len(todos[0].Steps) != 1 {
+		t.Errorf("search result should be preloaded, got %+v", todos)
+	}
+
+	// modified text is searchable
+	cjk.Title = "买燕麦奶"
+	must(t, r.SaveTodo(&cjk))
+	step2 := entity.TodoStep{Name: "clean up", TodoID: short.ID}
+	must(t, r.InsertTodoStep(&step2))
+	step2.Name = "wipe screen"
+	must(t, r.SaveTodoStep(&step2))
+	for term, expected := range map[string][]entity.Todo{"燕麦": {cjk}, "牛奶": {}, "screen": {short}} {
+		todos, err := r.SearchTodos(dto.TodoSearch{TodoListIDs: lists, Terms: []string{term}})
+		must(t, err)
+		expectTodosInOrder(t, "modified "+term, todos, expected...)
+	}
+}
+
+func testReminder(t *testing.T, r dal.Repository) {
+	user := insertUser(t, r, "alice")
+	other := insertUser(t, r, "bob")
+
+	now := time.Now()
+	past, future := now.Add(-time.Hour), now.Add(time.Hour)
+	earlier := now.Add(-2 * time.Hour)
+
+	due := entity.Todo{Title: "due", UserID: user.ID, NotifyAt: &past}
+	dueEarlier := entity.Todo{Title: "due earlier", UserID: other.ID, NotifyAt: &earlier}
+	later := entity.Todo{Title: "later", UserID: user.ID, NotifyAt: &future}
+	done := entity.Todo{Title: "done", UserID: user.ID, NotifyAt: &past, Done: true}
+	notified := entity.Todo{Title: "notified", UserID: user.ID, NotifyAt: &past, Notified: true}
+	for _, todo := range []*entity.Todo{&due, &dueEarlier, &later, &done, &notified} {
+		must(t, r.InsertTodo(todo))
+	}
+
+	todos, err := r.SelectNotNotifiedTodos(user.ID)
+	must(t, err)
+	expectTodosInOrder(t, "not notified todos", todos, due, done, later)
+
+	todos, err = r.SelectDueReminders(now, 10)
+	must(t, err)
+	expectTodosInOrder(t, "due reminders", todos, dueEarlier, due)
+
+	todos, err = r.SelectDueReminders(now, 1)
+	must(t, err)
+	expectTodosInOrder(t, "limited due reminders", todos, dueEarlier)
+
+	claimed, err := r.ClaimReminder(due.ID, *todos[0].NotifyAt)
+	must(t, err)
+	expectBool(t, "claim with rescheduled time", claimed, false)
+
+	got, err := r.SelectTodo(due.ID)
+	must(t, err)
+	claimed, err = r.ClaimReminder(due.ID, *got.NotifyAt)
+	must(t, err)
+	expectBool(t, "claim", claimed, true)
+
+	claimed, err = r.ClaimReminder(due.ID, *got.NotifyAt)
+	must(t, err)
+	expectBool(t, "claim twice", claimed, false)
+
+	got, err = r.SelectTodo(due.ID)
+	must(t, err)
+	if !got.Notified || got.Version != due.Version+1 {
+		t.Errorf("claimed todo should be notified and version bumped, got %+v", got)
+	}
+
+	todos, err = r.SelectDueReminders(now, 10)
+	must(t, err)
+	expectTodosInOrder(t, "due reminders after claim", todos, dueEarlier)
+}
+
+func testTodoFile(t *testing.T, r dal.Repository) {
+	user := insertUser(t, r, "alice")
+	todo := entity.Todo{Title: "todo", UserID: user.ID}
+	must(t, r.InsertTodo(&todo))
+
+	files, err := r.SelectTodoFiles(todo.ID)
+	must(t, err)
+	if len(files) != 0 {
+		t.Errorf("expected no files, got %v", len(files))
+	}
+
+	file := entity.File{FileName: "a.txt", AccessType: int8(entity.FileTypeTodo), RelatedID: todo.ID}
+	must(t, r.InsertFile(&file))
+	must(t, r.InsertTodoFile(todo.ID, file.ID))
+
+	files, err = r.SelectTodoFiles(todo.ID)
+	must(t, err)
+	if len(files) != 1 || files[0].ID != file.ID {
+		t.Errorf("expected file %v, got %+v", file.ID, files)
+	}
+
+	got, err := r.SelectTodo(todo.ID)
+	must(t, err)
+	if len(got.Files) != 1 || got.Files[0].ID != file.ID {
+		t.Errorf("files should be preloaded, got %+v", got.Files)
+	}
+}
+
+func testTodoStep(t *testing.T, r dal.Repository) {
+	user := insertUser(t, r, "alice")
+	todo := entity.Todo{Title: "todo", UserID: user.ID}
+	must(t, r.InsertTodo(&todo))
+
+	first := entity.TodoStep{Name: "first", TodoID: todo.ID}
+	second := entity.TodoStep{Name: "second", TodoID: todo.ID}
+	must(t, r.InsertTodoStep(&first))
+	must(t, r.InsertTodoStep(&second))
+
+	got, err := r.SelectTodoStep(first.ID)
+	must(t, err)
+	if got.Name != "first" || got.TodoID != todo.ID {
+		t.Errorf("unexpected step: %+v", got)
+	}
+
+	steps, err := r.SelectTodoSteps(todo.ID)
+	must(t, err)
+	if ids := stepIDs(steps); !equalIDs(ids, []int64{first.ID, second.ID}) {
+		t.Errorf("expected steps %v, got %v", []int64{first.ID, second.ID}, ids)
+	}
+
+	first.Done = true
+	must(t, r.SaveTodoStep(&first))
+	got, err = r.SelectTodoStep(first.ID)
+	must(t, err)
+	expectBool(t, "step done", got.Done, true)
+
+	must(t, r.DeleteTodoStep(second.ID))
+	_, err = r.SelectTodoStep(second.ID)
+	mustNotFound(t, err)
+
+	todoWithSteps, err := r.SelectTodo(todo.ID)
+	must(t, err)
+	if ids := stepIDs(todoWithSteps.Steps); !equalIDs(ids, []int64{first.ID}) {
+		t.Errorf("steps should be preloaded, got %v", ids)
+	}
+}
+
+func testTodoRepeatPlan(t *testing.T, r dal.Repository) {
+	plan := entity.TodoRepeatPlan{Type: string(entity.TodoRepeatPlanTypeDay), Interval: 1}
+	must(t, r.InsertTodoRepeatPlan(&plan))
+
+	got, err := r.SelectTodoRepeatPlan(plan.ID)
+	must(t, err)
+	if got.Type != string(entity.TodoRepeatPlanTypeDay) || got.Interval != 1 {
+		t.Errorf("unexpected plan: %+v", got)
+	}
+
+	plan.Interval = 2
+	plan.RRule = "DTSTART:20220101T000000Z\nRRULE:FREQ=MONTHLY;BYDAY=-1FR"
+	plan.RepeatFrom = string(entity.TodoRepeatFromCompletion)
+	must(t, r.SaveTodoRepeatPlan(&plan))
+	got, err = r.SelectTodoRepeatPlan(plan.ID)
+	must(t, err)
+	if got.Interval != 2 || got.RRule != plan.RRule || got.RepeatFrom != plan.RepeatFrom {
+		t.Errorf("plan should be updated, got %+v", got)
+	}
+
+	user := insertUser(t, r, "alice")
+	todo := entity.Todo{Title: "todo", UserID: user.ID, TodoRepeatPlanID: plan.ID}
+	must(t, r.InsertTodo(&todo))
+	todoWithPlan, err := r.SelectTodo(todo.ID)
+	must(t, err)
+	if todoWithPlan.TodoRepeatPlan.ID != plan.ID {
+		t.Errorf("repeat plan should be preloaded, got %v", todoWithPlan.TodoRepeatPlan.ID)
+	}
+
+	must(t, r.DeleteTodoRepeatPlan(plan.ID))
+	_, err = r.SelectTodoRepeatPlan(plan.ID)
+	mustNotFound(t, err)
+}
+
+func testTodoMove(t *testing.T, r dal.Repository) {
+	user := insertUser(t, r, "alice")
+	from := insertTodoList(t, r, user.ID, "from")
+	to := insertTodoList(t, r, user.ID, "to")
+	other := insertTodoList(t, r, user.ID, "other")
+	todo := entity.Todo{Title: "todo", UserID: user.ID, TodoListID: to.ID}
+	must(t, r.InsertTodo(&todo))
+
+	first := entity.TodoMove{Title: "todo", TodoID: todo.ID, FromTodoListID: from.ID, ToTodoListID: to.ID, UserID: user.ID}
+	second := entity.TodoMove{Title: "todo", TodoID: todo.ID, FromTodoListID: to.ID, ToTodoListID: other.ID, UserID: user.ID}
+	for _, move := range []*entity.TodoMove{&first, &second} {
+		must(t, r.InsertTodoMove(move))
+	}
+
+	moves, err := r.SelectTodoMoves(to.ID)
+	must(t, err)
+	if len(moves) != 2 || moves[0].ID != second.ID || moves[1].ID != first.ID {
+		t.Errorf("expected moves from and to todo list newest first, got %+v", moves)
+	}
+
+	moves, err = r.SelectTodoMoves(from.ID)
+	must(t, err)
+	if len(moves) != 1 || moves[0].ID != first.ID || moves[0].TodoID != todo.ID {
+		t.Errorf("expected moves from todo list, got %+v", moves)
+	}
+
+	moves, err = r.SelectTodoMoves(0)
+	must(t, err)
+	if len(moves) != 0 {
+		t.Errorf("expected no moves, got %+v", moves)
+	}
+}
+
+func testDailyTodo(t *testing.T, r dal.Repository) {
+	user := insertUser(t, r, "alice")
+	first := entity.Todo{Title: "first", UserID: user.ID}
+	second := entity.Todo{Title: "second", UserID: user.ID}
+	must(t, r.InsertTodo(&first))
+	must(t, r.InsertTodo(&second))
+
+	const today, yesterday = "2022-02-02", "2022-02-01"
+	must(t, r.InsertDailyTodo(&entity.DailyTodo{UserID: user.ID, TodoID: second.ID, Date: today}))
+	time.Sleep(10 * time.Millisecond) // keep created at different
+	must(t, r.InsertDailyTodo(&entity.DailyTodo{UserID: user.ID, TodoID: first.ID, Date: today}))
+	must(t, r.InsertDailyTodo(&entity.DailyTodo{UserID: user.ID, TodoID: first.ID, Date: yesterday}))
+
+	todos, err := r.SelectDailyTodos(user.ID, today)
+	must(t, err)
+	expectTodosInOrder(t, "daily todos", todos, second, first)
+
+	exist, err := r.ExistDailyTodo(user.ID, first.ID, today)
+	must(t, err)
+	expectBool(t, "daily todo exists", exist, true)
+
+	count, err := r.DeleteDailyTodo(user.ID, first.ID, today)
+	must(t, err)
+	if count != 1 {
+		t.Errorf("expected 1 daily todo deleted, got %v", count)
+	}
+
+	exist, err = r.ExistDailyTodo(user.ID, first.ID, today)
+	must(t, err)
+	expectBool(t, "deleted daily todo exists", exist, false)
+
+	todos, err = r.SelectDailyTodos(user.ID, yesterday)
+	must(t, err)
+	expectTodosInOrder(t, "daily todos of yesterday", todos, first)
+}
+
+/**
+ * Todo List
+ */
+
+func testTodoList(t *testing.T, r dal.Repository) {
+	user := insertUser(t, r, "alice")
+	folder := entity.TodoListFolder{Name: "folder", UserID: user.ID}
+	must(t, r.InsertTodoListFolder(&folder))
+
+	basic := entity.TodoList{Name: "basic", UserID: user.ID, IsBasic: true}
+	must(t, r.InsertTodoList(&basic))
+	list := insertTodoList(t, r, user.ID, "list")
+	inFolder := entity.TodoList{Name: "in folder", UserID: user.ID, TodoListFolderID: folder.ID}
+	must(t, r.InsertTodoList(&inFolder))
+
+	got, err := r.SelectTodoList(list.ID)
+	must(t, err)
+	if got.Name != "list" || got.UserID != user.ID {
+		t.Errorf("unexpected todo list: %+v", got)
+	}
+
+	lists, err := r.SelectTodoLists(user.ID)
+	must(t, err)
+	if ids := todoListIDs(lists); !equalIDs(ids, []int64{basic.ID, list.ID, inFolder.ID}) {
+		t.Errorf("expected todo lists %v, got %v", []int64{basic.ID, list.ID, inFolder.ID}, ids)
+	}
+
+	for i := 0; i < 2; i++ {
+		must(t, r.InsertTodo(&entity.Todo{Title: "todo", UserID: user.ID, TodoListID: list.ID}))
+	}
+	deleted := entity.Todo{Title: "deleted", UserID: user.ID, TodoListID: list.ID}
+	must(t, r.InsertTodo(&deleted))
+	must(t, r.DeleteTodo(deleted.ID, deleted.Version))
+
+	menu, err := r.SelectTodoListsWithMenuFormat(user.ID)
+	must(t, err)
+	counts := make(map[int64]int)
+	for _, item := range menu {
+		counts[item.ID] = item.Count
+		if item.ID == inFolder.ID && item.TodoListFolderID != folder.ID {
+			t.Errorf("expected folder %v, got %v", folder.ID, item.TodoListFolderID)
+		}
+	}
+	if len(menu) != 2 || counts[list.ID] != 2 || counts[inFolder.ID] != 0 {
+		t.Errorf("unexpected menu: %+v", menu)
+	}
+
+	other := insertUser(t, r, "bob")
+	list.Name = "renamed"
+	list.TransferUserID = other.ID
+	must(t, r.SaveTodoList(&list))
+	got, err = r.SelectTodoList(list.ID)
+	must(t, err)
+	if got.Name != "renamed" || got.TransferUserID != other.ID {
+		t.Errorf("name and transfer user should be updated, got %+v", got)
+	}
+
+	exist, err := r.ExistTodoList(list.ID)
+	must(t, err)
+	expectBool(t, "todo list exists", exist, true)
+
+	must(t, r.DeleteTodoList(list.ID, list.Version))
+	_, err = r.SelectTodoList(list.ID)
+	mustNotFound(t, err)
+
+	exist, err = r.ExistTodoList(list.ID)
+	must(t, err)
+	expectBool(t, "deleted todo list exists", exist, false)
+
+	count, err := r.DeleteTodoListsByFolder(folder.ID)
+	must(t, err)
+	if count != 1 {
+		t.Errorf("expected 1 todo list deleted, got %v", count)
+	}
+
+	lists, err = r.SelectTodoLists(user.ID)
+	must(t, err)
+	if ids := todoListIDs(lists); !equalIDs(ids, []int64{basic.ID}) {
+		t.Errorf("expected todo lists %v, got %v", []int64{basic.ID}, ids)
+	}
+}
+
+func testTodoListSharing(t *testing.T, r dal.Repository) {
+	owner := insertUser(t, r, "alice")
+	user := insertUser(t, r, "bob")
+	list := insertTodoList(t, r, owner.ID, "list")
+
+	exist, err := r.ExistTodoListSharing(user.ID, list.ID)
+	must(t, err)
+	expectBool(t, "sharing exists", exist, false)
+
+	must(t, r.InsertTodoListSharedUser(user.ID, list.ID, entity.TodoListRoleViewer))
+	exist, err = r.ExistTodoListSharing(user.ID, list.ID)
+	must(t, err)
+	expectBool(t, "sharing exists", exist, true)
+
+	lists, err := r.SelectSharedTodoLists(user.ID)
+	must(t, err)
+	if ids := todoListIDs(lists); !equalIDs(ids, []int64{list.ID}) {
+		t.Errorf("expected shared todo lists %v, got %v", []int64{list.ID}, ids)
+	}
+
+	users, err := r.SelectTodoListSharedUsers(list.ID)
+	must(t, err)
+	if len(users) != 1 || users[0].ID != user.ID {
+		t.Errorf("expected shared user %v, got %+v", user.ID, users)
+	}
+
+	role, err := r.SelectTodoListSharedUserRole(user.ID, list.ID)
+	must(t, err)
+	if role != entity.TodoListRoleViewer {
+		t.Errorf("expected role %v, got %v", entity.TodoListRoleViewer, role)
+	}
+
+	must(t, r.UpdateTodoListSharedUserRole(user.ID, list.ID, entity.TodoListRoleManager))
+	roles, err := r.SelectTodoListSharedUserRoles(list.ID)
+	must(t, err)
+	if len(roles) != 1 || roles[user.ID] != entity.TodoListRoleManager {
+		t.Errorf("role should be updated, got %v", roles)
+	}
+
+	_, err = r.SelectTodoListSharedUserRole(owner.ID, list.ID)
+	mustNotFound(t, err)
+
+	must(t, r.DeleteTodoListSharedUser(user.ID, list.ID))
+	exist, err = r.ExistTodoListSharing(user.ID, list.ID)
+	must(t, err)
+	expectBool(t, "deleted sharing exists", exist, false)
+
+	lists, err = r.SelectSharedTodoLists(user.ID)
+	must(t, err)
+	if len(lists) != 0 {
+		t.Errorf("expected no shared todo lists, got %v", todoListIDs(lists))
+	}
+
+	_, err = r.SelectTodoListSharedUserRole(user.ID, list.ID)
+	mustNotFound(t, err)
+}
+
+func testTodoListInvitation(t *testing.T, r dal.Repository) {
+	alice := insertUser(t, r, "alice")
+	bob := insertUser(t, r, "bob")
+	list := insertTodoList(t, r, alice.ID, "list")
+	other := insertTodoList(t, r, alice.ID, "other")
+
+	invited := entity.TodoListInvitation{Role: entity.TodoListRoleViewer, InviteeID: bob.ID, TodoListID: list.ID, UserID: alice.ID}
+	held := entity.TodoListInvitation{Role: entity.TodoListRoleEditor, Email: "carol@example.com", TodoListID: list.ID, UserID: alice.ID}
+	deleted := entity.TodoListInvitation{Role: entity.TodoListRoleEditor, InviteeID: bob.ID, TodoListID: other.ID, UserID: alice.ID}
+	for _, invitation := range []*entity.TodoListInvitation{&invited, &held, &deleted} {
+		must(t, r.InsertTodoListInvitation(invitation))
+	}
+
+	got, err := r.SelectTodoListInvitation(invited.ID)
+	must(t, err)
+	if got.InviteeID != bob.ID || got.TodoListID != list.ID || got.Role != entity.TodoListRoleViewer {
+		t.Errorf("unexpected todo list invitation: %+v", got)
+	}
+
+	invitations, err := r.SelectTodoListInvitations(list.ID)
+	must(t, err)
+	if len(invitations) != 2 || invitations[0].ID != invited.ID || invitations[0].Invitee.Name != "bob" || invitations[0].User.Name != "alice" || invitations[1].ID != held.ID {
+		t.Errorf("expected invitations of todo list, got %+v", invitations)
+	}
+
+	must(t, r.DeleteTodoList(other.ID, other.Version))
+	invitations, err = r.SelectUserTodoListInvitations(bob.ID)
+	must(t, err)
+	if len(invitations) != 2 || invitations[0].TodoList.Name != "list" || invitations[0].User.Name != "alice" || invitations[1].TodoList.ID != 0 {
+		t.Errorf("expected invitations to user, got %+v", invitations)
+	}
+
+	carol := insertUser(t, r, "carol")
+	count, err := r.UpdateTodoListInvitationsInvitee("carol@example.com", carol.ID)
+	must(t, err)
+	if count != 1 {
+		t.Errorf("expected 1 invitation handed over, got %v", count)
+	}
+
+	invitations, err = r.SelectUserTodoListInvitations(carol.ID)
+	must(t, err)
+	if len(invitations) != 1 || invitations[0].ID != held.ID {
+		t.Errorf("expected held invitation, got %+v", invitations)
+	}
+
+	must(t, r.DeleteTodoListInvitation(invited.ID))
+	_, err = r.SelectTodoListInvitation(invited.ID)
+	mustNotFound(t, err)
+}
+
+func testTodoListFolder(t *testing.T, r dal.Repository) {
+	user := insertUser(t, r, "alice")
+	first := entity.TodoListFolder{Name: "first", UserID: user.ID}
+	second := entity.TodoListFolder{Name: "second", UserID: user.ID}
+	must(t, r.InsertTodoListFolder(&first))
+	must(t, r.InsertTodoListFolder(&second))
+
+	got, err := r.SelectTodoListFolder(first.ID)
+	must(t, err)
+	if got.Name != "first" || got.UserID != user.ID {
+		t.Errorf("unexpected folder: %+v", got)
+	}
+
+	folders, err := r.SelectTodoListFolders(user.ID)
+	must(t, err)
+	if len(folders) != 2 {
+		t.Errorf("expected 2 folders, got %v", len(folders))
+	}
+
+	exist, err := r.ExistTodoListFolder(first.ID)
+	must(t, err)
+	expectBool(t, "folder exists", exist, true)
+
+	must(t, r.DeleteTodoListFolder(first.ID))
+	_, err = r.SelectTodoListFolder(first.ID)
+	mustNotFound(t, err)
+
+	exist, err = r.ExistTodoListFolder(first.ID)
+	must(t, err)
+	expectBool(t, "deleted folder exists", exist, false)
+
+	second.Name = "renamed"
+	must(t, r.SaveTodoListFolder(&second))
+	got, err = r.SelectTodoListFolder(second.ID)
+	must(t, err)
+	if got.Name != "renamed" || got.UserID != user.ID {
+		t.Errorf("name should be updated, got %+v", got)
+	}
+
+	inFolder := entity.TodoList{Name: "in folder", UserID: user.ID, TodoListFolderID: second.ID}
+	must(t, r.InsertTodoList(&inFolder))
+	list := insertTodoList(t, r, user.ID, "list")
+
+	count, err := r.UngroupTodoListsByFolder(second.ID)
+	must(t, err)
+	if count != 1 {
+		t.Errorf("expected 1 todo list ungrouped, got %v", count)
+	}
+
+	gotList, err := r.SelectTodoList(inFolder.ID)
+	must(t, err)
+	if gotList.TodoListFolderID != 0 || gotList.Version != inFolder.Version+1 {
+		t.Errorf("todo list should be moved to root with version bumped, got %+v", gotList)
+	}
+
+	gotList, err = r.SelectTodoList(list.ID)
+	must(t, err)
+	if gotList.Version != list.Version {
+		t.Errorf("todo list not in folder should not be touched, got version %v", gotList.Version)
+	}
+}
+
+/**
+ * Sharing
+ */
+
+func testTodoListFolderSharing(t *testing.T, r dal.Repository) {
+	owner := insertUser(t, r, "alice")
+	user := insertUser(t, r, "bob")
+	folder := entity.TodoListFolder{Name: "folder", UserID: owner.ID}
+	other := entity.TodoListFolder{Name: "other", UserID: owner.ID}
+	must(t, r.InsertTodoListFolder(&folder))
+	must(t, r.InsertTodoListFolder(&other))
+
+	_, err := r.SelectTodoListFolderSharedUserRole(user.ID, folder.ID)
+	mustNotFound(t, err)
+
+	must(t, r.InsertTodoListFolderSharedUser(user.ID, folder.ID, entity.TodoListRoleEditor))
+	role, err := r.SelectTodoListFolderSharedUserRole(user.ID, folder.ID)
+	must(t, err)
+	if role != entity.TodoListRoleEditor {
+		t.Errorf("expected role %v, got %v", entity.TodoListRoleEditor, role)
+	}
+
+	folders, err := r.SelectSharedTodoListFolders(user.ID)
+	must(t, err)
+	if len(folders) != 1 || folders[0].ID != folder.ID {
+		t.Errorf("expected shared folder %v, got %+v", folder.ID, folders)
+	}
+
+	sharedUsers, err := r.SelectTodoListFolderSharedUsers(folder.ID)
+	must(t, err)
+	if len(sharedUsers) != 1 || sharedUsers[0].UserID != user.ID || sharedUsers[0].User.Name != "bob" || sharedUsers[0].Role != entity.TodoListRoleEditor {
+		t.Errorf("expected shared user %v, got %+v", user.ID, sharedUsers)
+	}
+
+	// Todo lists in folders, including lists added later
+	list := entity.TodoList{Name: "list", UserID: owner.ID, TodoListFolderID: folder.ID}
+	otherList := entity.TodoList{Name: "other list", UserID: owner.ID, TodoListFolderID: other.ID}
+	must(t, r.InsertTodoList(&list))
+	must(t, r.InsertTodoList(&otherList))
+	insertTodoList(t, r, owner.ID, "root")
+	must(t, r.InsertTodo(&entity.Todo{Title: "todo", UserID: owner.ID, TodoListID: list.ID}))
+
+	lists, err := r.SelectTodoListsByFolders([]int64{folder.ID})
+	must(t, err)
+	if ids := todoListIDs(lists); !equalIDs(ids, []int64{list.ID}) {
+		t.Errorf("expected todo lists %v, got %v", []int64{list.ID}, ids)
+	}
+
+	lists, err = r.SelectTodoListsByFolders([]int64{})
+	must(t, err)
+	if len(lists) != 0 {
+		t.Errorf("expected no todo lists, got %v", todoListIDs(lists))
+	}
+
+	items, err := r.SelectTodoListsWithMenuFormatByFolders([]int64{folder.ID, other.ID})
+	must(t, err)
+	if len(items) != 2 || items[0].ID != list.ID || items[0].Count != 1 || items[0].TodoListFolderID != folder.ID || items[1].ID != otherList.ID || items[1].Count != 0 {
+		t.Errorf("unexpected menu items: %+v", items)
+	}
+
+	must(t, r.DeleteTodoListFolderSharedUser(user.ID, folder.ID))
+	_, err = r.SelectTodoListFolderSharedUserRole(user.ID, folder.ID)
+	mustNotFound(t, err)
+
+	folders, err = r.SelectSharedTodoListFolders(user.ID)
+	must(t, err)
+	if len(folders) != 0 {
+		t.Errorf("expected no shared folders, got %+v", folders)
+	}
+}
+
+func testTodoSharing(t *testing.T, r dal.Repository) {
+	owner := insertUser(t, r, "alice")
+	user := insertUser(t, r, "bob")
+	list := insertTodoList(t, r, owner.ID, "list")
+	a := entity.Todo{Title: "a", UserID: owner.ID, TodoListID: list.ID}
+	b := entity.Todo{Title: "b", UserID: owner.ID, TodoListID: list.ID}
+	must(t, r.InsertTodo(&a))
+	must(t, r.InsertTodo(&b))
+
+	_, err := r.SelectTodoSharedUserRole(user.ID, a.ID)
+	mustNotFound(t, err)
+
+	must(t, r.InsertTodoSharedUser(user.ID, b.ID, entity.TodoListRoleViewer))
+	must(t, r.InsertTodoSharedUser(user.ID, a.ID, entity.TodoListRoleEditor))
+	role, err := r.SelectTodoSharedUserRole(user.ID, a.ID)
+	must(t, err)
+	if role != entity.TodoListRoleEditor {
+		t.Errorf("expected role %v, got %v", entity.TodoListRoleEditor, role)
+	}
+
+	ids, err := r.SelectSharedTodoIDs(user.ID)
+	must(t, err)
+	if !equalIDs(ids, []int64{a.ID, b.ID}) {
+		t.Errorf("expected shared todos %v, got %v", []int64{a.ID, b.ID}, ids)
+	}
+
+	sharedUsers, err := r.SelectTodoSharedUsers(a.ID)
+	must(t, err)
+	if len(sharedUsers) != 1 || sharedUsers[0].UserID != user.ID || sharedUsers[0].User.Name != "bob" || sharedUsers[0].Role != entity.TodoListRoleEditor {
+		t.Errorf("expected shared user %v, got %+v", user.ID, sharedUsers)
+	}
+
+	must(t, r.DeleteTodoSharedUser(user.ID, a.ID))
+	_, err = r.SelectTodoSharedUserRole(user.ID, a.ID)
+	mustNotFound(t, err)
+
+	ids, err = r.SelectSharedTodoIDs(user.ID)
+	must(t, err)
+	if !equalIDs(ids, []int64{b.ID}) {
+		t.Errorf("expected shared todos %v, got %v", []int64{b.ID}, ids)
+	}
+
+	// Purged with todo
+	must(t, r.DeleteTodo(b.ID, b.Version))
+	_, err = r.PurgeTodos(time.Now().Add(time.Hour))
+	must(t, err)
+	ids, err = r.SelectSharedTodoIDs(user.ID)
+	must(t, err)
+	if len(ids) != 0 {
+		t.Errorf("shared users should be purged, got %v", ids)
+	}
+}
+
+func testSharing(t *testing.T, r dal.Repository) {
+	user := insertUser(t, r, "alice")
+	list := insertTodoList(t, r, user.ID, "list")
+
+	exist, err := r.ExistActiveSharing(user.ID, entity.SharingTypeTodoList)
+	must(t, err)
+	expectBool(t, "active sharing exists", exist, false)
+
+	first := entity.Sharing{Token: "first", Active: true, Type: entity.SharingTypeTodoList, RelatedID: list.ID, Role: entity.TodoListRoleViewer, PublishMemo: true, UserID: user.ID}
+	second := entity.Sharing{Token: "second", Active: false, Type: entity.SharingTypeTodoList, RelatedID: list.ID, UserID: user.ID}
+	must(t, r.InsertSharing(&first))
+	must(t, r.InsertSharing(&second))
+
+	got, err := r.SelectSharing("first")
+	must(t, err)
+	if got.ID != first.ID || got.RelatedID != list.ID || got.Role != entity.TodoListRoleViewer || !got.PublishMemo || got.PublishFiles {
+		t.Errorf("unexpected sharing: %+v", got)
+	}
+
+	_, err = r.SelectSharing("third")
+	mustNotFound(t, err)
+
+	sharings, err := r.SelectSharings(user.ID, entity.SharingTypeTodoList)
+	must(t, err)
+	if len(sharings) != 2 {
+		t.Errorf("expected 2 sharings, got %v", len(sharings))
+	}
+
+	sharings, err = r.SelectActiveSharings(user.ID, entity.SharingTypeTodoList)
+	must(t, err)
+	if len(sharings) != 1 || sharings[0].ID != first.ID {
+		t.Errorf("expected active sharing %v, got %+v", first.ID, sharings)
+	}
+
+	exist, err = r.ExistActiveSharing(user.ID, entity.SharingTypeTodoList)
+	must(t, err)
+	expectBool(t, "active sharing exists", exist, true)
+
+	second.Active = true
+	must(t, r.SaveSharing(&second))
+	sharings, err = r.SelectActiveSharings(user.ID, entity.SharingTypeTodoList)
+	must(t, err)
+	if len(sharings) != 2 {
+		t.Errorf("expected 2 active sharings, got %v", len(sharings))
+	}
+
+	other := entity.Sharing{Token: "other", Active: true, Type: entity.SharingTypeTodoList, RelatedID: list.ID, UserID: insertUser(t, r, "bob").ID}
+	must(t, r.InsertSharing(&other))
+	sharings, err = r.SelectActiveRelatedSharings(entity.SharingTypeTodoList, list.ID)
+	must(t, err)
+	if len(sharings) != 3 || sharings[0].ID != first.ID || sharings[2].ID != other.ID {
+		t.Errorf("expected 3 active sharings of todo list, got %+v", sharings)
+	}
+
+	redeemer := insertUser(t, r, "carol")
+	must(t, r.InsertSharingRedemption(&entity.SharingRedemption{SharingID: first.ID, UserID: redeemer.ID}))
+	must(t, r.InsertSharingRedemption(&entity.SharingRedemption{SharingID: first.ID, UserID: other.UserID}))
+	must(t, r.InsertSharingRedemption(&entity.SharingRedemption{SharingID: second.ID, UserID: redeemer.ID}))
+
+	redemptions, err := r.SelectSharingRedemptions(first.ID)
+	must(t, err)
+	if len(redemptions) != 2 || redemptions[0].UserID != redeemer.ID || redemptions[0].User.Name != "carol" {
+		t.Errorf("unexpected sharing redemptions: %+v", redemptions)
+	}
+
+	count, err := r.CountSharingRedemptions(first.ID)
+	must(t, err)
+	if count != 2 {
+		t.Errorf("expected 2 sharing redemptions, got %v", count)
+	}
+
+	count, err = r.DeleteSharings(user.ID, entity.SharingTypeTodoList)
+	must(t, err)
+	if count != 2 {
+		t.Errorf("expected 2 sharings deleted, got %v", count)
+	}
+
+	exist, err = r.ExistActiveSharing(user.ID, entity.SharingTypeTodoList)
+	must(t, err)
+	expectBool(t, "active sharing exists after delete", exist, false)
+}
+
+/**
+ * Tag
+ */
+
+func testTag(t *testing.T, r dal.Repository) {
+	user := insertUser(t, r, "alice")
+	todo := entity.Todo{Title: "todo", UserID: user.ID}
+	must(t, r.InsertTodo(&todo))
+
+	exist, err := r.ExistTag(user.ID, "work")
+	must(t, err)
+	expectBool(t, "tag exists", exist, false)
+
+	work := entity.Tag{Name: "work", UserID: user.ID}
+	must(t, r.InsertTag(&work))
+	must(t, r.InsertTag(&entity.Tag{Name: "home", UserID: user.ID}))
+
+	exist, err = r.ExistTag(user.ID, "work")
+	must(t, err)
+	expectBool(t, "tag exists", exist, true)
+
+	got, err := r.SelectTag(user.ID, "work")
+	must(t, err)
+	if got.ID != work.ID {
+		t.Errorf("expected tag %v, got %v", work.ID, got.ID)
+	}
+
+	_, err = r.SelectTag(user.ID+1, "work")
+	mustNotFound(t, err)
+
+	tags, err := r.SelectTags(user.ID)
+	must(t, err)
+	if len(tags) != 2 {
+		t.Errorf("expected 2 tags, got %v", len(tags))
+	}
+
+	must(t, r.InsertTagTodo(user.ID, todo.ID, "work"))
+	must(t, r.DeleteTagTodo(user.ID, todo.ID, "work"))
+	mustNotFound(t, r.InsertTagTodo(user.ID, todo.ID, "study"))
+
+	work.Name = "job"
+	work.Color = "#ff8800"
+	must(t, r.SaveTag(&work))
+	got, err = r.SelectTag(user.ID, "job")
+	must(t, err)
+	if got.ID != work.ID || got.Color != "#ff8800" {
+		t.Errorf("tag should be updated, got %+v", got)
+	}
+
+	list := insertTodoList(t, r, user.ID, "list")
+	listed := entity.Todo{Title: "listed", UserID: user.ID, TodoListID: list.ID}
+	deleted := entity.Todo{Title: "deleted", UserID: user.ID, TodoListID: list.ID}
+	must(t, r.InsertTodo(&listed))
+	must(t, r.InsertTodo(&deleted))
+	for _, id := range []int64{todo.ID, listed.ID, deleted.ID} {
+		must(t, r.InsertTagTodo(user.ID, id, "job"))
+	}
+	must(t, r.DeleteTodo(deleted.ID, deleted.Version))
+
+	// each shared user has their own tags
+	bob := insertUser(t, r, "bob")
+	must(t, r.InsertTag(&entity.Tag{Name: "job", UserID: bob.ID}))
+	must(t, r.InsertTag(&entity.Tag{Name: "home", UserID: bob.ID}))
+	must(t, r.InsertTagTodo(bob.ID, listed.ID, "job"))
+	must(t, r.InsertTagTodo(bob.ID, listed.ID, "home"))
+	tagged, err := r.SelectTodo(listed.ID)
+	must(t, err)
+	if strings.Join(tagged.Tags, ",") != "home,job" {
+		t.Errorf("expected deduplicated tags, got %v", tagged.Tags)
+	}
+
+	counts, err := r.CountTagTodos(user.ID, []int64{list.ID})
+	must(t, err)
+	if len(counts) != 1 || counts[work.ID] != 1 {
+		t.Errorf("expected 1 todo of tag in list, got %v", counts)
+	}
+
+	must(t, r.DeleteTag(work.ID))
+	_, err = r.SelectTag(user.ID, "job")
+	mustNotFound(t, err)
+
+	counts, err = r.CountTagTodos(user.ID, []int64{list.ID})
+	must(t, err)
+	if len(counts) != 0 {
+		t.Errorf("associations should be deleted, got %v", counts)
+	}
+
+	// name is reusable after deleted
+	must(t, r.InsertTag(&entity.Tag{Name: "job", UserID: user.ID}))
+}
+
+/**
+ * Notification
+ */
+
+func testNotification(t *testing.T, r dal.Repository) {
+	user := insertUser(t, r, "alice")
+	other := insertUser(t, r, "bob")
+
+	first := entity.Notification{Title: "first", UserID: user.ID, NotifyAt: time.Now()}
+	second := entity.Notification{Title: "second", UserID: user.ID, NotifyAt: time.Now()}
+	others := entity.Notification{Title: "others", UserID: other.ID, NotifyAt: time.Now()}
+	for _, n := range []*entity.Notification{&first, &second, &others} {
+		must(t, r.InsertNotification(n))
+	}
+
+	notifications, err := r.SelectNotifications(user.ID)
+	must(t, err)
+	if len(notifications) != 2 || notifications[0].ID != second.ID || notifications[1].ID != first.ID {
+		t.Errorf("expected notifications newest first, got %+v", notifications)
+	}
+
+	must(t, r.ReadNotification(first.ID))
+	got, err := r.SelectNotification(first.ID)
+	must(t, err)
+	if !got.Read || got.ReadAt == nil {
+		t.Errorf("notification should be read, got %+v", got)
+	}
+
+	count, err := r.ReadAllNotifications(user.ID)
+	must(t, err)
+	if count != 1 {
+		t.Errorf("expected 1 notification read, got %v", count)
+	}
+
+	got, err = r.SelectNotification(others.ID)
+	must(t, err)
+	expectBool(t, "notification of others read", got.Read, false)
+
+	_, err = r.SelectNotification(0)
+	mustNotFound(t, err)
+}
+
+/**
+ * Rank
+ */
+
+func testRank(t *testing.T, r dal.Repository) {
+	user := insertUser(t, r, "alice")
+	other := insertUser(t, r, "bob")
+	list := insertTodoList(t, r, user.ID, "list")
+	folder := entity.TodoListFolder{Name: "folder", UserID: user.ID}
+	must(t, r.InsertTodoListFolder(&folder))
+
+	rank := entity.Rank{UserID: user.ID, Type: entity.RankTypeTodoList, RelatedID: list.ID, Key: "m"}
+	must(t, r.SaveRank(&rank))
+	if rank.ID == 0 {
+		t.Fatal("id should be assigned after save")
+	}
+	must(t, r.SaveRank(&entity.Rank{UserID: other.ID, Type: entity.RankTypeTodoList, RelatedID: list.ID, Key: "g"}))
+	must(t, r.SaveRank(&entity.Rank{UserID: user.ID, Type: entity.RankTypeTodoListFolder, RelatedID: folder.ID, Key: "a"}))
+
+	keys, err := r.SelectRanks(user.ID, entity.RankTypeTodoList, []int64{list.ID, folder.ID})
+	must(t, err)
+	if len(keys) != 1 || keys[list.ID] != "m" {
+		t.Errorf("unexpected ranks: %v", keys)
+	}
+
+	// update by user, type and related id
+	update := entity.Rank{UserID: user.ID, Type: entity.RankTypeTodoList, RelatedID: list.ID, Key: "n"}
+	must(t, r.SaveRank(&update))
+	if update.ID != rank.ID {
+		t.Errorf("rank should be updated in place, got id %v, want %v", update.ID, rank.ID)
+	}
+
+	keys, err = r.SelectRanks(user.ID, entity.RankTypeTodoList, []int64{list.ID})
+	must(t, err)
+	if keys[list.ID] != "n" {
+		t.Errorf("rank should be updated, got %v", keys)
+	}
+
+	keys, err = r.SelectRanks(other.ID, entity.RankTypeTodoList, []int64{list.ID})
+	must(t, err)
+	if keys[list.ID] != "g" {
+		t.Errorf("ranks of others should not be changed, got %v", keys)
+	}
+
+	keys, err = r.SelectRanks(user.ID, entity.RankTypeTodoList, []int64{})
+	must(t, err)
+	if len(keys) != 0 {
+		t.Errorf("expected no ranks, got %v", keys)
+	}
+}
+
+/**
+ * Version
+ */
+
+func testVersion(t *testing.T, r dal.Repository) {
+	user := insertUser(t, r, "alice")
+	list := insertTodoList(t, r, user.ID, "list")
+	todo := entity.Todo{Title: "todo", UserID: user.ID, TodoListID: list.ID}
+	must(t, r.InsertTodo(&todo))
+	step := entity.TodoStep{Name: "step", TodoID: todo.ID}
+	must(t, r.InsertTodoStep(&step))
+
+	if list.Version != 1 || todo.Version != 1 || step.Version != 1 {
+		t.Fatalf("version should be 1 after insert, got %v, %v, %v", list.Version, todo.Version, step.Version)
+	}
+
+	stale := todo
+	todo.Title = "first"
+	must(t, r.SaveTodo(&todo))
+	if todo.Version != 2 {
+		t.Errorf("todo version should be bumped, got %v", todo.Version)
+	}
+
+	stale.Title = "second"
+	mustPreconditionFailed(t, r.SaveTodo(&stale))
+	got, err := r.SelectTodo(todo.ID)
+	must(t, err)
+	if got.Title != "first" || got.Version != 2 {
+		t.Errorf("stale save should not be applied, got %v in version %v", got.Title, got.Version)
+	}
+
+	staleStep := step
+	must(t, r.SaveTodoStep(&step))
+	mustPreconditionFailed(t, r.SaveTodoStep(&staleStep))
+	gotStep, err := r.SelectTodoStep(step.ID)
+	must(t, err)
+	if gotStep.Version != 2 {
+		t.Errorf("todo step version should be bumped, got %v", gotStep.Version)
+	}
+
+	staleList := list
+	must(t, r.SaveTodoList(&list))
+	mustPreconditionFailed(t, r.SaveTodoList(&staleList))
+	gotList, err := r.SelectTodoList(list.ID)
+	must(t, err)
+	if gotList.Version != 2 {
+		t.Errorf("todo list version should be bumped, got %v", gotList.Version)
+	}
+
+	// Stale records can not be deleted
+	mustPreconditionFailed(t, r.DeleteTodo(stale.ID, stale.Version))
+	mustPreconditionFailed(t, r.DeleteTodoList(staleList.ID, staleList.Version))
+	_, err = r.SelectTodo(todo.ID)
+	must(t, err)
+	_, err = r.SelectTodoList(list.ID)
+	must(t, err)
+
+	// Deleted records can not be saved, restore bumps version
+	must(t, r.DeleteTodo(todo.ID, todo.Version))
+	mustPreconditionFailed(t, r.SaveTodo(&todo))
+	must(t, r.RestoreTodo(todo.ID, list.ID))
+	got, err = r.SelectTodo(todo.ID)
+	must(t, err)
+	if got.Version != 3 {
+		t.Errorf("todo version should be bumped by restore, got %v", got.Version)
+	}
+}
+
+/**
+ * Trash
+ */
+
+func testTrash(t *testing.T, r dal.Repository) {
+	user := insertUser(t, r, "alice")
+	folder := entity.TodoListFolder{Name: "folder", UserID: user.ID}
+	must(t, r.InsertTodoListFolder(&folder))
+	list := entity.TodoList{Name: "list", UserID: user.ID, TodoListFolderID: folder.ID}
+	must(t, r.InsertTodoList(&list))
+
+	before := entity.Todo{Title: "deleted before", UserID: user.ID, TodoListID: list.ID}
+	cascaded := entity.Todo{Title: "cascaded", UserID: user.ID, TodoListID: list.ID}
+	must(t, r.InsertTodo(&before))
+	must(t, r.InsertTodo(&cascaded))
+	must(t, r.InsertTodoStep(&entity.TodoStep{Name: "step", TodoID: cascaded.ID}))
+
+	must(t, r.DeleteTodo(before.ID, before.Version))
+	time.Sleep(10 * time.Millisecond) // keep deleted at different
+	must(t, r.DeleteTodoListFolder(folder.ID))
+	must(t, r.DeleteTodoList(list.ID, list.Version))
+	_, err := r.DeleteTodos(list.ID)
+	must(t, err)
+
+	// Todos of collaborators are visible to owner of todo list
+	bob := insertUser(t, r, "bob")
+	shared := insertTodoList(t, r, user.ID, "shared")
+	collaborated := entity.Todo{Title: "collaborated", UserID: bob.ID, TodoListID: shared.ID}
+	must(t, r.InsertTodo(&collaborated))
+	must(t, r.DeleteTodo(collaborated.ID, collaborated.Version))
+
+	todos, err := r.SelectDeletedTodos(user.ID)
+	must(t, err)
+	expectTodos(t, "deleted todos", todos, before, cascaded, collaborated)
+
+	todos, err = r.SelectDeletedTodos(bob.ID)
+	must(t, err)
+	expectTodos(t, "deleted todos of creator", todos, collaborated)
+
+	carol := insertUser(t, r, "carol")
+	shared.UserID = carol.ID
+	must(t, r.SaveTodoList(&shared))
+	todos, err = r.SelectDeletedTodos(carol.ID)
+	must(t, err)
+	expectTodos(t, "deleted todos of new owner", todos, collaborated)
+
+	todos, err = r.SelectDeletedTodos(user.ID)
+	must(t, err)
+	expectTodos(t, "deleted todos of previous owner", todos, before, cascaded)
+
+	lists, err := r.SelectDeletedTodoLists(user.ID)
+	must(t, err)
+	if ids := todoListIDs(lists); !equalIDs(ids, []int64{list.ID}) {
+		t.Errorf("expected deleted todo lists %v, got %v", []int64{list.ID}, ids)
+	}
+
+	folders, err := r.SelectDeletedTodoListFolders(user.ID)
+	must(t, err)
+	if len(folders) != 1 || folders[0].ID != folder.ID {
+		t.Errorf("expected deleted folder %v, got %+v", folder.ID, folders)
+	}
+
+	_, err = r.SelectDeletedTodo(before.ID)
+	must(t, err)
+	_, err = r.SelectDeletedTodoListFolder(folder.ID)
+	must(t, err)
+
+	// Restore todo list with todos deleted together
+	deletedList, err := r.SelectDeletedTodoList(list.ID)
+	must(t, err)
+	must(t, r.RestoreTodoList(list.ID, 0))
+	count, err := r.RestoreTodos(list.ID, deletedList.DeletedAt.Time)
+	must(t, err)
+	if count != 1 {
+		t.Errorf("expected 1 todo restored, got %v", count)
+	}
+
+	got, err := r.SelectTodoList(list.ID)
+	must(t, err)
+	if got.TodoListFolderID != 0 {
+		t.Errorf("todo list should be re-attached to root, got %v", got.TodoListFolderID)
+	}
+
+	todos, err = r.SelectTodos(list.ID)
+	must(t, err)
+	expectTodos(t, "restored todos", todos, cascaded)
+
+	_, err = r.SelectDeletedTodoList(list.ID)
+	mustNotFound(t, err)
+
+	// Restore single todo into another list
+	other := insertTodoList(t, r, user.ID, "other")
+	must(t, r.RestoreTodo(before.ID, other.ID))
+	todo, err := r.SelectTodo(before.ID)
+	must(t, err)
+	if todo.TodoListID != other.ID {
+		t.Errorf("todo should be re-attached to %v, got %v", other.ID, todo.TodoListID)
+	}
+
+	must(t, r.RestoreTodoListFolder(folder.ID))
+	_, err = r.SelectTodoListFolder(folder.ID)
+	must(t, err)
+
+	// Purge with dependents, so that no orphans are left
+	steps, err := r.SelectTodoSteps(cascaded.ID)
+	must(t, err)
+	inOther := entity.Todo{Title: "in other", UserID: user.ID, TodoListID: other.ID}
+	must(t, r.InsertTodo(&inOther))
+	must(t, r.InsertTodoMove(&entity.TodoMove{Title: "cascaded", TodoID: cascaded.ID, FromTodoListID: other.ID, ToTodoListID: list.ID, UserID: user.ID}))
+
+	sharings := []entity.Sharing{
+		{Token: "todo", Active: true, Type: entity.SharingTypeTodo, RelatedID: cascaded.ID, UserID: user.ID},
+		{Token: "todo list", Active: true, Type: entity.SharingTypeTodoList, RelatedID: other.ID, UserID: user.ID},
+		{Token: "published", Active: true, Type: entity.SharingTypeTodoListPublish, RelatedID: other.ID, UserID: user.ID},
+		{Token: "folder", Active: true, Type: entity.SharingTypeTodoListFolder, RelatedID: folder.ID, UserID: user.ID},
+	}
+	for i := range sharings {
+		must(t, r.InsertSharing(&sharings[i]))
+		must(t, r.InsertSharingRedemption(&entity.SharingRedemption{SharingID: sharings[i].ID, UserID: bob.ID}))
+	}
+
+	invitation := entity.TodoListInvitation{Role: entity.TodoListRoleViewer, InviteeID: bob.ID, TodoListID: other.ID, UserID: user.ID}
+	must(t, r.InsertTodoListInvitation(&invitation))
+
+	ranks := []entity.Rank{
+		{UserID: bob.ID, Type: entity.RankTypeTodo, RelatedID: cascaded.ID, Key: "0"},
+		{UserID: bob.ID, Type: entity.RankTypeTodoStep, RelatedID: steps[0].ID, Key: "0"},
+		{UserID: bob.ID, Type: entity.RankTypeTodoList, RelatedID: other.ID, Key: "0"},
+		{UserID: bob.ID, Type: entity.RankTypeTodoListFolder, RelatedID: folder.ID, Key: "0"},
+	}
+	for i := range ranks {
+		must(t, r.SaveRank(&ranks[i]))
+	}
+
+	cascaded, err = r.SelectTodo(cascaded.ID) // restored
+	must(t, err)
+	must(t, r.DeleteTodo(cascaded.ID, cascaded.Version))
+	must(t, r.DeleteTodoList(other.ID, other.Version))
+	must(t, r.DeleteTodoListFolder(folder.ID))
+
+	count, err = r.PurgeTodos(time.Now().Add(-time.Hour))
+	must(t, err)
+	if count != 0 {
+		t.Errorf("expected no todos purged, got %v", count)
+	}
+
+	future := time.Now().Add(time.Hour)
+	count, err = r.PurgeTodos(future)
+	must(t, err)
+	if count != 2 {
+		t.Errorf("expected 2 todos purged, got %v", count)
+	}
+
+	count, err = r.PurgeTodoLists(future)
+	must(t, err)
+	if count != 1 {
+		t.Errorf("expected 1 todo list purged, got %v", count)
+	}
+
+	count, err = r.PurgeTodoListFolders(future)
+	must(t, err)
+	if count != 1 {
+		t.Errorf("expected 1 folder purged, got %v", count)
+	}
+
+	_, err = r.SelectDeletedTodo(cascaded.ID)
+	mustNotFound(t, err)
+
+	steps, err = r.SelectTodoSteps(cascaded.ID)
+	must(t, err)
+	if len(steps) != 0 {
+		t.Errorf("steps should be purged, got %v", len(steps))
+	}
+
+	_, err = r.SelectTodo(inOther.ID)
+	mustNotFound(t, err)
+
+	for _, sharing := range sharings {
+		_, err = r.SelectSharing(sharing.Token)
+		mustNotFound(t, err)
+		count, err = r.CountSharingRedemptions(sharing.ID)
+		must(t, err)
+		if count != 0 {
+			t.Errorf("redemptions of sharing %q should be purged, got %v", sharing.Token, count)
+		}
+	}
+
+	_, err = r.SelectTodoListInvitation(invitation.ID)
+	mustNotFound(t, err)
+
+	for _, rank := range ranks {
+		keys, err := r.SelectRanks(bob.ID, rank.Type, []int64{rank.RelatedID})
+		must(t, err)
+		if len(keys) != 0 {
+			t.Errorf("rank of type %v should be purged, got %v", rank.Type, keys)
+		}
+	}
+
+	moves, err := r.SelectTodoMoves(list.ID)
+	must(t, err)
+	if len(moves) != 0 {
+		t.Errorf("todo moves should be purged, got %v", len(moves))
+	}
+
+	todos, err = r.SelectDeletedTodos(user.ID)
+	must(t, err)
+	expectTodos(t, "deleted todos after purge", todos)
+}
+
+/**
+ * Transaction
+ */
+
+func testTransaction(t *testing.T, r dal.Repository) {
+	errRollback := errors.New("rollback")
+
+	var committed entity.User
+	must(t, r.Transaction(func(r dal.Repository) error {
+		committed = insertUser(t, r, "alice")
+		insertTodoList(t, r, committed.ID, "list")
+		return nil
+	}))
+
+	if _, err := r.SelectUser(committed.ID); err != nil {
+		t.Errorf("user should be committed: %v", err)
+	}
+
+	var rolledBack entity.User
+	err := r.Transaction(func(r dal.Repository) error {
+		rolledBack = insertUser(t, r, "bob")
+		committed.Nickname = "changed"
+		must(t, r.SaveUser(&committed))
+		return errRollback
+	})
+	if !errors.Is(err, errRollback) {
+		t.Errorf("expected error of fn, got %v", err)
+	}
+
+	_, err = r.SelectUser(rolledBack.ID)
+	mustNotFound(t, err)
+
+	got, err := r.SelectUser(committed.ID)
+	must(t, err)
+	if got.Nickname != "alice" {
+		t.Errorf("update should be rolled back, got %v", got.Nickname)
+	}
+
+	// Nested transaction rollbacks itself only
+	var outer, inner entity.User
+	must(t, r.Transaction(func(r dal.Repository) error {
+		outer = insertUser(t, r, "carol")
+		err := r.Transaction(func(r dal.Repository) error {
+			inner = insertUser(t, r, "dave")
+			return errRollback
+		})
+		if !errors.Is(err, errRollback) {
+			t.Errorf("expected error of nested fn, got %v", err)
+		}
+
+		return nil
+	}))
+
+	if _, err := r.SelectUser(outer.ID); err != nil {
+		t.Errorf("outer user should be committed: %v", err)
+	}
+
+	_, err = r.SelectUser(inner.ID)
+	mustNotFound(t, err)
+}
+
+/**
+ * Helpers
+ */
+
+func must(t *testing.T, err error) {
+	t.Helper()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func mustNotFound(t *testing.T, err error) {
+	t.Helper()
+	var e *otodo.Error
+	if !errors.As(err, &e) || e.Code != otodo.ErrNotFound {
+		t.Fatalf("expected not found error, got %v", err)
+	}
+}
+
+func mustPreconditionFailed(t *testing.T, err error) {
+	t.Helper()
+	var e *otodo.Error
+	if !errors.As(err, &e) || e.Code != otodo.ErrPreconditionFailed {
+		t.Fatalf("expected precondition failed error, got %v", err)
+	}
+}
+
+func expectBool(t *testing.T, name string, got, expected bool) {
+	t.Helper()
+	if got != expected {
+		t.Errorf("%v: expected %v, got %v", name, expected, got)
+	}
+}
+
+func insertUser(t *testing.T, r dal.Repository, name string) entity.User {
+	t.Helper()
+	user := entity.User{Name: name, Nickname: name}
+	must(t, r.InsertUser(&user))
+	return user
+}
+
+func insertTodoList(t *testing.T, r dal.Repository, userID int64, name string) entity.TodoList {
+	t.Helper()
+	list := entity.TodoList{Name: name, UserID: userID}
+	must(t, r.InsertTodoList(&list))
+	return list
+}
+
+func expectTodos(t *testing.T, name string, got []entity.Todo, expected ...entity.Todo) {
+	t.Helper()
+	gotIDs, expectedIDs := todoIDs(got), todoIDs(expected)
+	sortIDs(gotIDs)
+	sortIDs(expectedIDs)
+	if !equalIDs(gotIDs, expectedIDs) {
+		t.Errorf("%v: expected %v, got %v", name, expectedIDs, gotIDs)
+	}
+}
+
+func expectTodosInOrder(t *testing.T, name string, got []entity.Todo, expected ...entity.Todo) {
+	t.Helper()
+	gotIDs, expectedIDs := todoIDs(got), todoIDs(expected)
+	if !equalIDs(gotIDs, expectedIDs) {
+		t.Errorf("%v: expected %v, got %v", name, expectedIDs, gotIDs)
+	}
+}
+
+func todoIDs(todos []entity.Todo) []int64 {
+	ids := make([]int64, 0, len(todos))
+	for i := range todos {
+		ids = append(ids, todos[i].ID)
+	}
+	return ids
+}
+
+func todoListIDs(lists []entity.TodoList) []int64 {
+	ids := make([]int64, 0, len(lists))
+	for i := range lists {
+		ids = append(ids, lists[i].ID)
+	}
+	sortIDs(ids)
+	return ids
+}
+
+func stepIDs(steps []entity.TodoStep) []int64 {
+	ids := make([]int64, 0, len(steps))
+	for i := range steps {
+		ids = append(ids, steps[i].ID)
+	}
+	sortIDs(ids)
+	return ids
+}
+
+func sortIDs(ids []int64) {
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+}
+
+func equalIDs(a, b []int64) bool {
+	if len(a) != len(b) {
+		return false
+	}
+
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+
+	return true
+}
@@ -7,6 +7,8 @@ import (
 	"github.com/yzx9/otodo/otodo"
 	"github.com/yzx9/otodo/util"
 	"gorm.io/driver/mysql"
+	"gorm.io/driver/postgres"
+	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 )
 
@@ -18,41 +20,43 @@ func initDatabase() error {
 		return util.NewError(otodo.ErrDatabaseConnectFailed, "fails to connect database: %w", err)
 	}
 
-	// See https://github.com/go-sql-driver/mysql#dsn-data-source-name for details
-	c := otodo.Conf.Database
-	dsn := fmt.Sprintf("%v:%v@%v(%v:%v)/%v?charset=utf8mb4&parseTime=True&loc=Local", c.UserName, c.Password, c.Protocol, c.Host, c.Port, c.DatabaseName)
-	db, err = gorm.Open(mysql.Open(dsn), &gorm.Config{
+	dialector, err := newDialector(otodo.Conf.Database)
+	if err != nil {
+		return write(err)
+	}
+
+	db, err = gorm.Open(dialector, &gorm.Config{
 		DisableForeignKeyConstraintWhenMigrating: true,
 	})
 	if err != nil {
 		return write(err)
 	}
 
-	if err = autoMigrate(); err != nil {
+	// Other tables are managed by versioned migrations, see dal/migrations
+	if err = db.AutoMigrate(&entity.SchemaMigration{}); err != nil {
 		return write(err)
 	}
 
 	return nil
 }
 
-func autoMigrate() error {
-	return db.AutoMigrate(
-		&entity.File{},
+func newDialector(c otodo.ConfigDatabase) (gorm.Dialector, error) {
+	switch c.Driver {
+	case otodo.DatabaseDriverMySQL, "":
+		// See https://github.com/go-sql-driver/mysql#dsn-data-source-name for details
+		dsn := fmt.Sprintf("%v:%v@%v(%v:%v)/%v?charset=utf8mb4&parseTime=True&loc=Local", c.UserName, c.Password, c.Protocol, c.Host, c.Port, c.DatabaseName)
+		return mysql.Open(dsn), nil
 
-		&entity.User{},
-		&entity.UserInvalidRefreshToken{},
+	case otodo.DatabaseDriverPostgres:
+		// See https://pkg.go.dev/github.com/jackc/pgconn#ParseConfig for details
+		dsn := fmt.Sprintf("host=%v port=%v user=%v password=%v dbname=%v", c.Host, c.Port, c.UserName, c.Password, c.DatabaseName)
+		return postgres.Open(dsn), nil
 
-		&entity.Todo{},
-		&entity.TodoStep{},
-		&entity.TodoRepeatPlan{},
+	case otodo.DatabaseDriverSQLite:
+		// Database name is the path of database file, or `:memory:`
+		return sqlite.Open(c.DatabaseName), nil
 
-		&entity.TodoList{},
-		&entity.TodoListFolder{},
-
-		&entity.Tag{},
-
-		&entity.Sharing{},
-
-		&entity.ThirdPartyOAuthToken{},
-	)
+	default:
+		return nil, fmt.Errorf("unsupported database driver: %v", c.Driver)
+	}
 }
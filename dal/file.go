@@ -3,21 +3,39 @@ package dal
 import (
 	"github.com/yzx9/otodo/model/entity"
 	"github.com/yzx9/otodo/util"
+	"gorm.io/gorm"
 )
 
-func InsertFile(file *entity.File) error {
-	re := db.Create(file)
+type FileRepository interface {
+	InsertFile(file *entity.File) error
+	SelectFile(id int64) (*entity.File, error)
+	SaveFile(file *entity.File) error
+}
+
+func (r *gormRepository) InsertFile(file *entity.File) error {
+	re := r.db.Create(file)
 	return util.WrapGormErr(re.Error, "file")
 }
 
-func SelectFile(id int64) (*entity.File, error) {
+func (r *gormRepository) SelectFile(id int64) (*entity.File, error) {
 	var file entity.File
 	where := entity.File{Entity: entity.Entity{ID: id}}
-	re := db.Where(&where).First(&file)
+	re := r.db.Where(&where).First(&file)
 	return &file, util.WrapGormErr(re.Error, "file")
 }
 
-func SaveFile(file *entity.File) error {
-	re := db.Save(file)
-	return util.WrapGormErr(re.Error, "file")
+func (r *gormRepository) SaveFile(file *entity.File) error {
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Save(file).Error; err != nil {
+			return err
+		}
+
+		var todoIDs []int64
+		if err := tx.Table("todo_files").Where("file_id = ?", file.ID).Pluck("todo_id", &todoIDs).Error; err != nil {
+			return err
+		}
+
+		return indexTodoSearch(tx, todoIDs...)
+	})
+	return util.WrapGormErr(err, "file")
 }
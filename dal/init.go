@@ -1,5 +1,21 @@
 package dal
 
-func Init() error {
+import "github.com/yzx9/otodo/otodo"
+
+// Init database and returns repository bound to it
+func Init() (Repository, error) {
+	if err := initDatabase(); err != nil {
+		return nil, err
+	}
+
+	if err := checkSchemaVersion(otodo.Conf.Database.AutoMigrate); err != nil {
+		return nil, err
+	}
+
+	return NewGormRepository(db), nil
+}
+
+// Init database without checking schema version, for migration commands
+func InitWithoutMigration() error {
 	return initDatabase()
 }
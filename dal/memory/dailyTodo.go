@@ -0,0 +1,80 @@
+package memory
+
+import (
+	"sort"
+
+	"github.com/yzx9/otodo/model/entity"
+)
+
+func (r *Repository) InsertDailyTodo(daily *entity.DailyTodo) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	create(&daily.Entity)
+	r.dailyTodos[daily.ID] = stripDailyTodo(*daily)
+	return nil
+}
+
+func (r *Repository) SelectDailyTodos(userID int64, date string) ([]entity.Todo, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	dailies := make([]entity.DailyTodo, 0)
+	for _, daily := range r.dailyTodos {
+		if alive(daily.Entity) && daily.UserID == userID && daily.Date == date {
+			dailies = append(dailies, daily)
+		}
+	}
+
+	sort.Slice(dailies, func(i, j int) bool {
+		if !dailies[i].CreatedAt.Equal(dailies[j].CreatedAt) {
+			return dailies[i].CreatedAt.Before(dailies[j].CreatedAt)
+		}
+
+		return dailies[i].ID < dailies[j].ID
+	})
+
+	todos := make([]entity.Todo, 0)
+	for i := range dailies {
+		if todo, ok := r.todos[dailies[i].TodoID]; ok && alive(todo.Entity) {
+			todos = append(todos, r.preloadTodo(todo))
+		}
+	}
+
+	return todos, nil
+}
+
+func (r *Repository) DeleteDailyTodo(userID, todoID int64, date string) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var count int64
+	for id, daily := range r.dailyTodos {
+		if alive(daily.Entity) && daily.UserID == userID && daily.TodoID == todoID && daily.Date == date {
+			softDelete(&daily.Entity)
+			r.dailyTodos[id] = daily
+			count++
+		}
+	}
+
+	return count, nil
+}
+
+func (r *Repository) ExistDailyTodo(userID, todoID int64, date string) (bool, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, daily := range r.dailyTodos {
+		if alive(daily.Entity) && daily.UserID == userID && daily.TodoID == todoID && daily.Date == date {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
+
+func stripDailyTodo(daily entity.DailyTodo) entity.DailyTodo {
+	daily.User = entity.User{}
+	daily.Todo = entity.Todo{}
+	return daily
+}
@@ -0,0 +1,39 @@
+package memory
+
+import (
+	"github.com/yzx9/otodo/model/entity"
+)
+
+func (r *Repository) InsertFile(file *entity.File) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	create(&file.Entity)
+	r.files[file.ID] = *file
+	return nil
+}
+
+func (r *Repository) SelectFile(id int64) (*entity.File, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	file, ok := r.files[id]
+	if !ok || !alive(file.Entity) {
+		return &entity.File{}, notFound("file")
+	}
+
+	return &file, nil
+}
+
+func (r *Repository) SaveFile(file *entity.File) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	_, exist := r.files[file.ID]
+	save(&file.Entity, exist)
+	r.files[file.ID] = *file
+	for _, todoID := range r.todoFiles.lefts(file.ID) {
+		r.indexTodoSearch(todoID)
+	}
+	return nil
+}
@@ -0,0 +1,85 @@
+package memory
+
+import (
+	"sort"
+	"time"
+
+	"github.com/yzx9/otodo/model/entity"
+)
+
+func (r *Repository) InsertNotification(notification *entity.Notification) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	create(&notification.Entity)
+	r.notifications[notification.ID] = stripNotification(*notification)
+	return nil
+}
+
+func (r *Repository) SelectNotification(id int64) (entity.Notification, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	notification, ok := r.notifications[id]
+	if !ok || !alive(notification.Entity) {
+		return entity.Notification{}, notFound("notification")
+	}
+
+	return notification, nil
+}
+
+func (r *Repository) SelectNotifications(userID int64) ([]entity.Notification, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	notifications := make([]entity.Notification, 0)
+	for _, notification := range r.notifications {
+		if alive(notification.Entity) && notification.UserID == userID {
+			notifications = append(notifications, notification)
+		}
+	}
+
+	sort.Slice(notifications, func(i, j int) bool { return notifications[i].ID > notifications[j].ID })
+	return notifications, nil
+}
+
+func (r *Repository) ReadNotification(id int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if notification, ok := r.notifications[id]; ok && alive(notification.Entity) && !notification.Read {
+		readNotification(&notification)
+		r.notifications[id] = notification
+	}
+
+	return nil
+}
+
+func (r *Repository) ReadAllNotifications(userID int64) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var count int64
+	for id, notification := range r.notifications {
+		if alive(notification.Entity) && notification.UserID == userID && !notification.Read {
+			readNotification(&notification)
+			r.notifications[id] = notification
+			count++
+		}
+	}
+
+	return count, nil
+}
+
+func readNotification(notification *entity.Notification) {
+	now := time.Now()
+	notification.Read = true
+	notification.ReadAt = &now
+	save(&notification.Entity, true)
+}
+
+func stripNotification(notification entity.Notification) entity.Notification {
+	notification.User = entity.User{}
+	notification.Todo = entity.Todo{}
+	return notification
+}
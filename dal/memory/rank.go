@@ -0,0 +1,61 @@
+package memory
+
+import "github.com/yzx9/otodo/model/entity"
+
+func (r *Repository) SelectRanks(userID int64, rankType entity.RankType, relatedIDs []int64) (map[int64]string, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	ids := idSet(relatedIDs)
+	keys := make(map[int64]string)
+	for id, key := range r.rankKeys(userID, rankType) {
+		if ids[id] {
+			keys[id] = key
+		}
+	}
+
+	return keys, nil
+}
+
+func (r *Repository) SaveRank(rank *entity.Rank) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	exist := false
+	for _, old := range r.ranks {
+		if alive(old.Entity) && old.UserID == rank.UserID && old.Type == rank.Type && old.RelatedID == rank.RelatedID {
+			rank.Entity = old.Entity
+			exist = true
+			break
+		}
+	}
+
+	save(&rank.Entity, exist)
+	r.ranks[rank.ID] = stripRank(*rank)
+	return nil
+}
+
+// Rank keys of user, indexed by related id
+func (r *Repository) rankKeys(userID int64, rankType entity.RankType) map[int64]string {
+	keys := make(map[int64]string)
+	for _, rank := range r.ranks {
+		if alive(rank.Entity) && rank.UserID == userID && rank.Type == rankType {
+			keys[rank.RelatedID] = rank.Key
+		}
+	}
+	return keys
+}
+
+// Hard delete ranks of all users of item
+func (r *Repository) purgeRanks(rankType entity.RankType, relatedID int64) {
+	for id, rank := range r.ranks {
+		if rank.Type == rankType && rank.RelatedID == relatedID {
+			delete(r.ranks, id)
+		}
+	}
+}
+
+func stripRank(rank entity.Rank) entity.Rank {
+	rank.User = entity.User{}
+	return rank
+}
@@ -0,0 +1,322 @@
+package memory
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/yzx9/otodo/dal"
+	"github.com/yzx9/otodo/model/entity"
+	"github.com/yzx9/otodo/otodo"
+	"github.com/yzx9/otodo/util"
+	"gorm.io/gorm"
+)
+
+// Repository is an in-memory implementation of dal.Repository, which
+// follows the same semantics as the GORM one, e.g. soft delete. All data
+// are lost on exit, so it is used for testing and trying out.
+type Repository struct {
+	mu   sync.RWMutex
+	txMu sync.Mutex // serialize transactions
+
+	data
+}
+
+type data struct {
+	files                    map[int64]entity.File
+	users                    map[int64]entity.User
+	userInvalidRefreshTokens map[int64]entity.UserInvalidRefreshToken
+	thirdPartyOAuthTokens    map[int64]entity.ThirdPartyOAuthToken
+	todos                    map[int64]entity.Todo
+	todoSteps                map[int64]entity.TodoStep
+	todoRepeatPlans          map[int64]entity.TodoRepeatPlan
+	dailyTodos               map[int64]entity.DailyTodo
+	todoLists                map[int64]entity.TodoList
+	todoListInvitations      map[int64]entity.TodoListInvitation
+	todoListFolders          map[int64]entity.TodoListFolder
+	sharings                 map[int64]entity.Sharing
+	sharingRedemptions       map[int64]entity.SharingRedemption
+	tags                     map[int64]entity.Tag
+	notifications            map[int64]entity.Notification
+	ranks                    map[int64]entity.Rank
+	todoMoves                map[int64]entity.TodoMove
+
+	// many2many associations
+	todoFiles           joinTable // todo - file
+	tagTodos            joinTable // tag - todo
+	todoListSharedUsers joinTable // user - todo list
+
+	todoListRoles map[[2]int64]entity.TodoListRole // roles of todo list shared users
+
+	// shared users with roles, [user id, related id]
+	todoListFolderSharedUsers map[[2]int64]entity.TodoListRole
+	todoSharedUsers           map[[2]int64]entity.TodoListRole
+
+	todoSearchGrams searchIndex
+}
+
+var _ dal.Repository = (*Repository)(nil)
+
+func New() *Repository {
+	return &Repository{data: newData()}
+}
+
+func newData() data {
+	return data{
+		files:                    make(map[int64]entity.File),
+		users:                    make(map[int64]entity.User),
+		userInvalidRefreshTokens: make(map[int64]entity.UserInvalidRefreshToken),
+		thirdPartyOAuthTokens:    make(map[int64]entity.ThirdPartyOAuthToken),
+		todos:                    make(map[int64]entity.Todo),
+		todoSteps:                make(map[int64]entity.TodoStep),
+		todoRepeatPlans:          make(map[int64]entity.TodoRepeatPlan),
+		dailyTodos:               make(map[int64]entity.DailyTodo),
+		todoLists:                make(map[int64]entity.TodoList),
+		todoListInvitations:      make(map[int64]entity.TodoListInvitation),
+		todoListFolders:          make(map[int64]entity.TodoListFolder),
+		sharings:                 make(map[int64]entity.Sharing),
+		sharingRedemptions:       make(map[int64]entity.SharingRedemption),
+		tags:                     make(map[int64]entity.Tag),
+		notifications:            make(map[int64]entity.Notification),
+		ranks:                    make(map[int64]entity.Rank),
+		todoMoves:                make(map[int64]entity.TodoMove),
+
+		todoFiles:           make(joinTable),
+		tagTodos:            make(joinTable),
+		todoListSharedUsers: make(joinTable),
+
+		todoListRoles: make(map[[2]int64]entity.TodoListRole),
+
+		todoListFolderSharedUsers: make(map[[2]int64]entity.TodoListRole),
+		todoSharedUsers:           make(map[[2]int64]entity.TodoListRole),
+
+		todoSearchGrams: make(searchIndex),
+	}
+}
+
+/**
+ * Transaction
+ */
+
+// Transaction takes a snapshot and restores it if fn fails. Transactions
+// are serialized, but writes outside of transactions are not isolated and
+// will be lost on rollback.
+func (r *Repository) Transaction(fn func(r dal.Repository) error) error {
+	r.txMu.Lock()
+	defer r.txMu.Unlock()
+
+	return r.transaction(fn)
+}
+
+func (r *Repository) transaction(fn func(r dal.Repository) error) (err error) {
+	r.mu.RLock()
+	snapshot := r.data.clone()
+	r.mu.RUnlock()
+
+	rollback := func() {
+		r.mu.Lock()
+		r.data = snapshot
+		r.mu.Unlock()
+	}
+
+	defer func() {
+		if p := recover(); p != nil {
+			rollback()
+			panic(p)
+		}
+	}()
+
+	if err = fn(tx{r}); err != nil {
+		rollback()
+	}
+
+	return err
+}
+
+// tx is the repository within a transaction, nested transactions act as
+// savepoints
+type tx struct {
+	*Repository
+}
+
+func (t tx) Transaction(fn func(r dal.Repository) error) error {
+	return t.Repository.transaction(fn)
+}
+
+func (d data) clone() data {
+	c := newData()
+	for k, v := range d.files {
+		c.files[k] = v
+	}
+	for k, v := range d.users {
+		c.users[k] = v
+	}
+	for k, v := range d.userInvalidRefreshTokens {
+		c.userInvalidRefreshTokens[k] = v
+	}
+	for k, v := range d.thirdPartyOAuthTokens {
+		c.thirdPartyOAuthTokens[k] = v
+	}
+	for k, v := range d.todos {
+		c.todos[k] = v
+	}
+	for k, v := range d.todoSteps {
+		c.todoSteps[k] = v
+	}
+	for k, v := range d.todoRepeatPlans {
+		c.todoRepeatPlans[k] = v
+	}
+	for k, v := range d.dailyTodos {
+		c.dailyTodos[k] = v
+	}
+	for k, v := range d.todoLists {
+		c.todoLists[k] = v
+	}
+	for k, v := range d.todoListInvitations {
+		c.todoListInvitations[k] = v
+	}
+	for k, v := range d.todoListFolders {
+		c.todoListFolders[k] = v
+	}
+	for k, v := range d.sharings {
+		c.sharings[k] = v
+	}
+	for k, v := range d.sharingRedemptions {
+		c.sharingRedemptions[k] = v
+	}
+	for k, v := range d.tags {
+		c.tags[k] = v
+	}
+	for k, v := range d.notifications {
+		c.notifications[k] = v
+	}
+	for k, v := range d.ranks {
+		c.ranks[k] = v
+	}
+	for k, v := range d.todoMoves {
+		c.todoMoves[k] = v
+	}
+	for k, v := range d.todoFiles {
+		c.todoFiles[k] = v
+	}
+	for k, v := range d.tagTodos {
+		c.tagTodos[k] = v
+	}
+	for k, v := range d.todoListSharedUsers {
+		c.todoListSharedUsers[k] = v
+	}
+	for k, v := range d.todoListRoles {
+		c.todoListRoles[k] = v
+	}
+	for k, v := range d.todoListFolderSharedUsers {
+		c.todoListFolderSharedUsers[k] = v
+	}
+	for k, v := range d.todoSharedUsers {
+		c.todoSharedUsers[k] = v
+	}
+	c.todoSearchGrams = d.todoSearchGrams.clone()
+	return c
+}
+
+/**
+ * Helpers
+ */
+
+// Same as gorm create, keep id and timestamps if exists
+func create(e *entity.Entity) {
+	if e.ID == 0 {
+		e.ID = otodo.NewID()
+	}
+
+	now := time.Now()
+	if e.CreatedAt.IsZero() {
+		e.CreatedAt = now
+	}
+
+	if e.UpdatedAt.IsZero() {
+		e.UpdatedAt = now
+	}
+}
+
+// Same as gorm save, create if not exists
+func save(e *entity.Entity, exist bool) {
+	if e.ID == 0 || !exist {
+		create(e)
+		return
+	}
+
+	e.UpdatedAt = time.Now()
+}
+
+// Same as default value of version column
+func createVersion(version *int64) {
+	if *version == 0 {
+		*version = 1
+	}
+}
+
+func versionConflict(resource string) error {
+	return util.NewErrorWithPreconditionFailed("%v has been modified by others", resource)
+}
+
+func softDelete(e *entity.Entity) {
+	e.DeletedAt = gorm.DeletedAt{Time: time.Now(), Valid: true}
+}
+
+func restore(e *entity.Entity) {
+	e.DeletedAt = gorm.DeletedAt{}
+	e.UpdatedAt = time.Now()
+}
+
+func alive(e entity.Entity) bool {
+	return !e.DeletedAt.Valid
+}
+
+// Ordered by deleted at desc
+func deletedLater(a, b entity.Entity) bool {
+	return a.DeletedAt.Time.After(b.DeletedAt.Time)
+}
+
+func notFound(resource string) error {
+	return util.NewErrorWithNotFound("%v not found", resource)
+}
+
+func sortedIDs(ids []int64) []int64 {
+	sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+	return ids
+}
+
+// Records of many2many association, [left id, right id]
+type joinTable map[[2]int64]bool
+
+func (t joinTable) add(left, right int64) {
+	t[[2]int64{left, right}] = true
+}
+
+func (t joinTable) remove(left, right int64) {
+	delete(t, [2]int64{left, right})
+}
+
+func (t joinTable) has(left, right int64) bool {
+	return t[[2]int64{left, right}]
+}
+
+func (t joinTable) rights(left int64) []int64 {
+	ids := make([]int64, 0)
+	for k := range t {
+		if k[0] == left {
+			ids = append(ids, k[1])
+		}
+	}
+	return sortedIDs(ids)
+}
+
+func (t joinTable) lefts(right int64) []int64 {
+	ids := make([]int64, 0)
+	for k := range t {
+		if k[1] == right {
+			ids = append(ids, k[0])
+		}
+	}
+	return sortedIDs(ids)
+}
@@ -0,0 +1,15 @@
+package memory_test
+
+import (
+	"testing"
+
+	"github.com/yzx9/otodo/dal"
+	"github.com/yzx9/otodo/dal/daltest"
+	"github.com/yzx9/otodo/dal/memory"
+)
+
+func TestRepository(t *testing.T) {
+	daltest.Run(t, func(t *testing.T) dal.Repository {
+		return memory.New()
+	})
+}
@@ -0,0 +1,160 @@
+package memory
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/yzx9/otodo/model/dto"
+	"github.com/yzx9/otodo/model/entity"
+)
+
+// Search by the built-in index and scanning candidates, same as SQLite
+// implementation
+func (r *Repository) SearchTodos(search dto.TodoSearch) ([]entity.Todo, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	lists := idSet(search.TodoListIDs)
+	shared := idSet(search.SharedIDs)
+	match := func(todo entity.Todo) bool {
+		if !(lists[todo.TodoListID] || shared[todo.ID]) ||
+			(search.Done != nil && todo.Done != *search.Done) ||
+			(search.Importance != nil && todo.Importance != *search.Importance) ||
+			(search.DeadlineFrom != nil && (todo.Deadline == nil || todo.Deadline.Before(*search.DeadlineFrom))) ||
+			(search.DeadlineTo != nil && (todo.Deadline == nil || !todo.Deadline.Before(*search.DeadlineTo))) {
+			return false
+		}
+
+		for _, tag := range search.Tags {
+			if !r.hasTag(todo.ID, search.TagUserID, tag) {
+				return false
+			}
+		}
+
+		todo = r.preloadTodo(todo)
+		for _, term := range search.Terms {
+			if !matchTodoTerm(todo, strings.ToLower(term)) {
+				return false
+			}
+		}
+
+		return true
+	}
+
+	var todos []entity.Todo
+	if candidates := r.todoSearchGrams.findTerms(search.Terms); candidates == nil {
+		todos = r.findTodos(match)
+	} else {
+		todos = make([]entity.Todo, 0)
+		for id := range candidates {
+			if todo, ok := r.todos[id]; ok && alive(todo.Entity) && match(todo) {
+				todos = append(todos, r.preloadTodo(todo))
+			}
+		}
+	}
+
+	sort.Slice(todos, func(i, j int) bool { return todos[i].ID > todos[j].ID })
+	if search.Limit > 0 && len(todos) > search.Limit {
+		todos = todos[:search.Limit]
+	}
+
+	return todos, nil
+}
+
+func matchTodoTerm(todo entity.Todo, term string) bool {
+	contains := func(s string) bool { return strings.Contains(strings.ToLower(s), term) }
+	if contains(todo.Title) || contains(todo.Memo) {
+		return true
+	}
+
+	for _, step := range todo.Steps {
+		if contains(step.Name) {
+			return true
+		}
+	}
+
+	for _, file := range todo.Files {
+		if contains(file.FileName) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Index searchable text of todo, should be called after the text changed
+func (r *Repository) indexTodoSearch(todoID int64) {
+	todo, ok := r.todos[todoID]
+	if !ok {
+		return
+	}
+
+	todo = r.preloadTodo(todo)
+	texts := []string{todo.Title, todo.Memo}
+	for _, step := range todo.Steps {
+		texts = append(texts, step.Name)
+	}
+	for _, file := range todo.Files {
+		texts = append(texts, file.FileName)
+	}
+
+	r.todoSearchGrams.add(todoID, entity.SearchGrams(texts...))
+}
+
+// Built-in search index of grams to todo ids, same as todo_search_grams in
+// SQLite. Grams of removed text are kept until todo is purged.
+type searchIndex map[string]map[int64]bool
+
+func (idx searchIndex) add(todoID int64, grams []string) {
+	for _, gram := range grams {
+		if idx[gram] == nil {
+			idx[gram] = make(map[int64]bool)
+		}
+		idx[gram][todoID] = true
+	}
+}
+
+func (idx searchIndex) remove(todoID int64) {
+	for gram, ids := range idx {
+		delete(ids, todoID)
+		if len(ids) == 0 {
+			delete(idx, gram)
+		}
+	}
+}
+
+// Candidate todos having all grams of terms, nil if none of terms can be
+// matched by grams
+func (idx searchIndex) findTerms(terms []string) map[int64]bool {
+	var candidates map[int64]bool
+	for _, term := range terms {
+		for _, gram := range entity.SearchGrams(term) {
+			if candidates == nil {
+				candidates = make(map[int64]bool)
+				for id := range idx[gram] {
+					candidates[id] = true
+				}
+				continue
+			}
+
+			for id := range candidates {
+				if !idx[gram][id] {
+					delete(candidates, id)
+				}
+			}
+		}
+	}
+
+	return candidates
+}
+
+func (idx searchIndex) clone() searchIndex {
+	c := make(searchIndex)
+	for gram, ids := range idx {
+		c[gram] = make(map[int64]bool)
+		for id := range ids {
+			c[gram][id] = true
+		}
+	}
+	return c
+}
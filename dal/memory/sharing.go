@@ -0,0 +1,187 @@
+package memory
+
+import (
+	"sort"
+
+	"github.com/yzx9/otodo/model/entity"
+)
+
+func (r *Repository) InsertSharing(sharing *entity.Sharing) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	create(&sharing.Entity)
+	r.sharings[sharing.ID] = stripSharing(*sharing)
+	return nil
+}
+
+func (r *Repository) SelectSharing(token string) (entity.Sharing, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, sharing := range r.sharings {
+		if alive(sharing.Entity) && sharing.Token == token {
+			return sharing, nil
+		}
+	}
+
+	return entity.Sharing{}, notFound("sharing")
+}
+
+func (r *Repository) SelectSharings(userID int64, sharingType entity.SharingType) ([]entity.Sharing, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.findSharings(func(sharing entity.Sharing) bool {
+		return sharing.UserID == userID && sharing.Type == sharingType
+	}), nil
+}
+
+func (r *Repository) SelectActiveSharings(userID int64, sharingType entity.SharingType) ([]entity.Sharing, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.findSharings(func(sharing entity.Sharing) bool {
+		return sharing.UserID == userID && sharing.Type == sharingType && sharing.Active
+	}), nil
+}
+
+func (r *Repository) SaveSharing(sharing *entity.Sharing) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	_, exist := r.sharings[sharing.ID]
+	save(&sharing.Entity, exist)
+	r.sharings[sharing.ID] = stripSharing(*sharing)
+	return nil
+}
+
+func (r *Repository) ExistActiveSharing(userID int64, sharingType entity.SharingType) (bool, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	sharings := r.findSharings(func(sharing entity.Sharing) bool {
+		return sharing.UserID == userID && sharing.Type == sharingType && sharing.Active
+	})
+	return len(sharings) != 0, nil
+}
+
+func (r *Repository) DeleteSharings(userID int64, sharingType entity.SharingType) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	// Here we inactive sharing instead of not delete
+	var count int64
+	for id, sharing := range r.sharings {
+		if alive(sharing.Entity) && sharing.UserID == userID && sharing.Type == sharingType && sharing.Active {
+			sharing.Active = false
+			save(&sharing.Entity, true)
+			r.sharings[id] = sharing
+			count++
+		}
+	}
+
+	return count, nil
+}
+
+func (r *Repository) SelectActiveRelatedSharings(sharingType entity.SharingType, relatedID int64) ([]entity.Sharing, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.findSharings(func(sharing entity.Sharing) bool {
+		return sharing.Type == sharingType && sharing.RelatedID == relatedID && sharing.Active
+	}), nil
+}
+
+// Find sharings, ordered by id
+func (r *Repository) findSharings(match func(entity.Sharing) bool) []entity.Sharing {
+	sharings := make([]entity.Sharing, 0)
+	for _, sharing := range r.sharings {
+		if alive(sharing.Entity) && match(sharing) {
+			sharings = append(sharings, sharing)
+		}
+	}
+
+	sort.Slice(sharings, func(i, j int) bool { return sharings[i].ID < sharings[j].ID })
+	return sharings
+}
+
+// Hard delete sharings of item and their redemptions
+func (r *Repository) purgeSharings(relatedID int64, types ...entity.SharingType) {
+	for id, sharing := range r.sharings {
+		if sharing.RelatedID != relatedID || !containsSharingType(types, sharing.Type) {
+			continue
+		}
+
+		for redemptionID, redemption := range r.sharingRedemptions {
+			if redemption.SharingID == id {
+				delete(r.sharingRedemptions, redemptionID)
+			}
+		}
+
+		delete(r.sharings, id)
+	}
+}
+
+func containsSharingType(types []entity.SharingType, sharingType entity.SharingType) bool {
+	for _, t := range types {
+		if t == sharingType {
+			return true
+		}
+	}
+	return false
+}
+
+func stripSharing(sharing entity.Sharing) entity.Sharing {
+	sharing.User = entity.User{}
+	return sharing
+}
+
+/**
+ * Redemption
+ */
+
+func (r *Repository) InsertSharingRedemption(redemption *entity.SharingRedemption) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	create(&redemption.Entity)
+	r.sharingRedemptions[redemption.ID] = stripSharingRedemption(*redemption)
+	return nil
+}
+
+func (r *Repository) SelectSharingRedemptions(sharingID int64) ([]entity.SharingRedemption, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	redemptions := make([]entity.SharingRedemption, 0)
+	for _, redemption := range r.sharingRedemptions {
+		if alive(redemption.Entity) && redemption.SharingID == sharingID {
+			redemption.User = r.users[redemption.UserID]
+			redemptions = append(redemptions, redemption)
+		}
+	}
+
+	sort.Slice(redemptions, func(i, j int) bool { return redemptions[i].ID < redemptions[j].ID })
+	return redemptions, nil
+}
+
+func (r *Repository) CountSharingRedemptions(sharingID int64) (int64, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var count int64
+	for _, redemption := range r.sharingRedemptions {
+		if alive(redemption.Entity) && redemption.SharingID == sharingID {
+			count++
+		}
+	}
+
+	return count, nil
+}
+
+func stripSharingRedemption(redemption entity.SharingRedemption) entity.SharingRedemption {
+	redemption.Sharing = entity.Sharing{}
+	redemption.User = entity.User{}
+	return redemption
+}
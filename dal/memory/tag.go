@@ -0,0 +1,132 @@
+package memory
+
+import (
+	"sort"
+
+	"github.com/yzx9/otodo/model/entity"
+)
+
+func (r *Repository) InsertTag(tag *entity.Tag) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	create(&tag.Entity)
+	r.tags[tag.ID] = stripTag(*tag)
+	return nil
+}
+
+func (r *Repository) SelectTag(userID int64, tagName string) (entity.Tag, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.selectTag(userID, tagName)
+}
+
+func (r *Repository) SelectTags(userID int64) ([]entity.Tag, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	tags := make([]entity.Tag, 0)
+	for _, tag := range r.tags {
+		if alive(tag.Entity) && tag.UserID == userID {
+			tags = append(tags, tag)
+		}
+	}
+
+	sort.Slice(tags, func(i, j int) bool { return tags[i].ID < tags[j].ID })
+	return tags, nil
+}
+
+func (r *Repository) SaveTag(tag *entity.Tag) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	_, exist := r.tags[tag.ID]
+	save(&tag.Entity, exist)
+	r.tags[tag.ID] = stripTag(*tag)
+	return nil
+}
+
+func (r *Repository) DeleteTag(id int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, todoID := range r.tagTodos.rights(id) {
+		r.tagTodos.remove(id, todoID)
+	}
+	delete(r.tags, id)
+	return nil
+}
+
+func (r *Repository) CountTagTodos(userID int64, todoListIDs []int64) (map[int64]int64, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	lists := make(map[int64]bool)
+	for _, id := range todoListIDs {
+		lists[id] = true
+	}
+
+	counts := make(map[int64]int64)
+	for k := range r.tagTodos {
+		tag, ok := r.tags[k[0]]
+		if !ok || !alive(tag.Entity) || tag.UserID != userID {
+			continue
+		}
+
+		if todo, ok := r.todos[k[1]]; ok && alive(todo.Entity) && lists[todo.TodoListID] {
+			counts[tag.ID]++
+		}
+	}
+	return counts, nil
+}
+
+func (r *Repository) InsertTagTodo(userID, todoID int64, tagName string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	tag, err := r.selectTag(userID, tagName)
+	if err != nil {
+		return err
+	}
+
+	r.tagTodos.add(tag.ID, todoID)
+	return nil
+}
+
+func (r *Repository) DeleteTagTodo(userID, todoID int64, tagName string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	tag, err := r.selectTag(userID, tagName)
+	if err != nil {
+		return err
+	}
+
+	r.tagTodos.remove(tag.ID, todoID)
+	return nil
+}
+
+func (r *Repository) ExistTag(userID int64, tagName string) (bool, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	_, err := r.selectTag(userID, tagName)
+	return err == nil, nil
+}
+
+func (r *Repository) selectTag(userID int64, tagName string) (entity.Tag, error) {
+	for _, tag := range r.tags {
+		if alive(tag.Entity) && tag.UserID == userID && tag.Name == tagName {
+			return tag, nil
+		}
+	}
+
+	return entity.Tag{}, notFound("tag")
+}
+
+func stripTag(tag entity.Tag) entity.Tag {
+	tag.User = entity.User{}
+	tag.Todos = nil
+	return tag
+}
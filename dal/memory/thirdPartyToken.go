@@ -0,0 +1,46 @@
+package memory
+
+import (
+	"github.com/yzx9/otodo/model/entity"
+)
+
+func (r *Repository) InsertThirdPartyOAuthToken(token *entity.ThirdPartyOAuthToken) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	create(&token.Entity)
+	stripped := *token
+	stripped.User = entity.User{}
+	r.thirdPartyOAuthTokens[token.ID] = stripped
+	return nil
+}
+
+func (r *Repository) UpdateThirdPartyOAuthToken(new *entity.ThirdPartyOAuthToken) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for id, token := range r.thirdPartyOAuthTokens {
+		if alive(token.Entity) && token.UserID == new.UserID && token.Type == new.Type {
+			token.Active = new.Active
+			token.Token = new.Token
+			token.Scope = new.Scope
+			save(&token.Entity, true)
+			r.thirdPartyOAuthTokens[id] = token
+		}
+	}
+
+	return nil
+}
+
+func (r *Repository) ExistActiveThirdPartyOAuthToken(userID int64, tokenType entity.ThirdPartyTokenType) (bool, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, token := range r.thirdPartyOAuthTokens {
+		if alive(token.Entity) && token.UserID == userID && token.Type == int8(tokenType) && token.Active {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
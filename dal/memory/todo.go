@@ -0,0 +1,468 @@
+package memory
+
+import (
+	"sort"
+	"time"
+
+	"github.com/yzx9/otodo/model/entity"
+)
+
+func (r *Repository) InsertTodo(todo *entity.Todo) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	create(&todo.Entity)
+	createVersion(&todo.Version)
+	r.todos[todo.ID] = stripTodo(*todo)
+	r.indexTodoSearch(todo.ID)
+	return nil
+}
+
+func (r *Repository) SelectTodo(id int64) (entity.Todo, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	todo, ok := r.todos[id]
+	if !ok || !alive(todo.Entity) {
+		return entity.Todo{}, notFound("todo")
+	}
+
+	return r.preloadTodo(todo), nil
+}
+
+func (r *Repository) SelectTodos(todoListID int64) ([]entity.Todo, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.findTodos(func(todo entity.Todo) bool {
+		return todo.TodoListID == todoListID
+	}), nil
+}
+
+func (r *Repository) SelectAllTodos(userID int64) ([]entity.Todo, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.findTodos(func(todo entity.Todo) bool {
+		return todo.UserID == userID
+	}), nil
+}
+
+func (r *Repository) SelectImportantTodos(userID int64) ([]entity.Todo, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.findTodos(func(todo entity.Todo) bool {
+		return todo.UserID == userID && todo.Importance
+	}), nil
+}
+
+func (r *Repository) SelectPlanedTodos(userID int64) ([]entity.Todo, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	todos := r.findTodos(func(todo entity.Todo) bool {
+		return todo.UserID == userID && todo.Deadline != nil
+	})
+	sortTodosByTime(todos, func(todo entity.Todo) *time.Time { return todo.Deadline })
+	return todos, nil
+}
+
+func (r *Repository) SelectNotNotifiedTodos(userID int64) ([]entity.Todo, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	todos := r.findTodos(func(todo entity.Todo) bool {
+		return todo.UserID == userID && !todo.Notified
+	})
+	sortTodosByTime(todos, func(todo entity.Todo) *time.Time { return todo.NotifyAt })
+	return todos, nil
+}
+
+func (r *Repository) SelectOverdueTodos(userID int64, before time.Time) ([]entity.Todo, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	todos := r.findTodos(func(todo entity.Todo) bool {
+		return todo.UserID == userID && !todo.Done && todo.Deadline != nil && todo.Deadline.Before(before)
+	})
+	sortTodosByTime(todos, func(todo entity.Todo) *time.Time { return todo.Deadline })
+	return todos, nil
+}
+
+func (r *Repository) SelectDueReminders(now time.Time, limit int) ([]entity.Todo, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	todos := make([]entity.Todo, 0)
+	for _, todo := range r.todos {
+		if alive(todo.Entity) && !todo.Notified && !todo.Done && todo.NotifyAt != nil && !todo.NotifyAt.After(now) {
+			todos = append(todos, todo)
+		}
+	}
+
+	sort.Slice(todos, func(i, j int) bool { return todos[i].ID < todos[j].ID })
+	sortTodosByTime(todos, func(todo entity.Todo) *time.Time { return todo.NotifyAt })
+	if limit > 0 && len(todos) > limit {
+		todos = todos[:limit]
+	}
+
+	return todos, nil
+}
+
+func (r *Repository) ClaimReminder(id int64, notifyAt time.Time) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	todo, ok := r.todos[id]
+	if !ok || !alive(todo.Entity) || todo.Notified || todo.NotifyAt == nil || !todo.NotifyAt.Equal(notifyAt) {
+		return false, nil
+	}
+
+	todo.Notified = true
+	todo.Version++
+	save(&todo.Entity, true)
+	r.todos[id] = todo
+	return true, nil
+}
+
+func (r *Repository) SaveTodo(todo *entity.Todo) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	old, ok := r.todos[todo.ID]
+	if !ok || !alive(old.Entity) || old.Version != todo.Version {
+		return versionConflict("todo")
+	}
+
+	save(&todo.Entity, true)
+	todo.Version++
+	r.todos[todo.ID] = stripTodo(*todo)
+	r.indexTodoSearch(todo.ID)
+	return nil
+}
+
+func (r *Repository) DeleteTodo(id, version int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	todo, ok := r.todos[id]
+	if !ok || !alive(todo.Entity) || todo.Version != version {
+		return versionConflict("todo")
+	}
+
+	softDelete(&todo.Entity)
+	r.todos[id] = todo
+	return nil
+}
+
+func (r *Repository) DeleteTodos(todoListID int64) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var count int64
+	for id, todo := range r.todos {
+		if alive(todo.Entity) && todo.TodoListID == todoListID {
+			softDelete(&todo.Entity)
+			r.todos[id] = todo
+			count++
+		}
+	}
+
+	return count, nil
+}
+
+/**
+ * oTodo File
+ */
+
+func (r *Repository) InsertTodoFile(todoID, fileID int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.todoFiles.add(todoID, fileID)
+	r.indexTodoSearch(todoID)
+	return nil
+}
+
+func (r *Repository) SelectTodoFiles(todoID int64) ([]entity.File, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.selectTodoFiles(todoID), nil
+}
+
+/**
+ * Sharing
+ */
+
+func (r *Repository) InsertTodoSharedUser(userID, todoID int64, role entity.TodoListRole) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.todoSharedUsers[[2]int64{userID, todoID}] = role
+	return nil
+}
+
+func (r *Repository) SelectSharedTodoIDs(userID int64) ([]int64, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	ids := make([]int64, 0)
+	for key := range r.todoSharedUsers {
+		if key[0] == userID {
+			ids = append(ids, key[1])
+		}
+	}
+
+	return sortedIDs(ids), nil
+}
+
+func (r *Repository) SelectTodoSharedUsers(todoID int64) ([]entity.TodoSharedUser, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	sharedUsers := make([]entity.TodoSharedUser, 0)
+	for key, role := range r.todoSharedUsers {
+		if key[1] == todoID {
+			sharedUsers = append(sharedUsers, entity.TodoSharedUser{
+				UserID: key[0],
+				User:   r.users[key[0]],
+				TodoID: todoID,
+				Role:   role,
+			})
+		}
+	}
+
+	sort.Slice(sharedUsers, func(i, j int) bool { return sharedUsers[i].UserID < sharedUsers[j].UserID })
+	return sharedUsers, nil
+}
+
+func (r *Repository) SelectTodoSharedUserRole(userID, todoID int64) (entity.TodoListRole, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	role, ok := r.todoSharedUsers[[2]int64{userID, todoID}]
+	if !ok {
+		return 0, notFound("todo shared user")
+	}
+
+	return role, nil
+}
+
+func (r *Repository) DeleteTodoSharedUser(userID, todoID int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.todoSharedUsers, [2]int64{userID, todoID})
+	return nil
+}
+
+/**
+ * Trash
+ */
+
+func (r *Repository) SelectDeletedTodo(id int64) (entity.Todo, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	todo, ok := r.todos[id]
+	if !ok || alive(todo.Entity) {
+		return entity.Todo{}, notFound("deleted todo")
+	}
+
+	return todo, nil
+}
+
+func (r *Repository) SelectDeletedTodos(userID int64) ([]entity.Todo, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	todos := make([]entity.Todo, 0)
+	for _, todo := range r.todos {
+		if alive(todo.Entity) {
+			continue
+		}
+
+		list, ok := r.todoLists[todo.TodoListID]
+		if todo.UserID == userID || (ok && alive(list.Entity) && list.UserID == userID) {
+			todos = append(todos, todo)
+		}
+	}
+
+	sort.Slice(todos, func(i, j int) bool { return deletedLater(todos[i].Entity, todos[j].Entity) })
+	return todos, nil
+}
+
+func (r *Repository) RestoreTodo(id, todoListID int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if todo, ok := r.todos[id]; ok {
+		restore(&todo.Entity)
+		todo.TodoListID = todoListID
+		todo.Version++
+		r.todos[id] = todo
+	}
+
+	return nil
+}
+
+func (r *Repository) RestoreTodos(todoListID int64, deletedSince time.Time) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var count int64
+	for id, todo := range r.todos {
+		if !alive(todo.Entity) && todo.TodoListID == todoListID && !todo.DeletedAt.Time.Before(deletedSince) {
+			restore(&todo.Entity)
+			todo.Version++
+			r.todos[id] = todo
+			count++
+		}
+	}
+
+	return count, nil
+}
+
+func (r *Repository) PurgeTodos(deletedBefore time.Time) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var count int64
+	for id, todo := range r.todos {
+		if alive(todo.Entity) || !todo.DeletedAt.Time.Before(deletedBefore) {
+			continue
+		}
+
+		r.purgeTodo(id)
+		count++
+	}
+
+	return count, nil
+}
+
+// Hard delete todo and its steps, ranks, sharings and associations
+func (r *Repository) purgeTodo(id int64) {
+	for stepID, step := range r.todoSteps {
+		if step.TodoID == id {
+			r.purgeRanks(entity.RankTypeTodoStep, stepID)
+			delete(r.todoSteps, stepID)
+		}
+	}
+
+	for dailyID, daily := range r.dailyTodos {
+		if daily.TodoID == id {
+			delete(r.dailyTodos, dailyID)
+		}
+	}
+
+	for _, fileID := range r.todoFiles.rights(id) {
+		r.todoFiles.remove(id, fileID)
+	}
+
+	for _, tagID := range r.tagTodos.lefts(id) {
+		r.tagTodos.remove(tagID, id)
+	}
+
+	for notificationID, notification := range r.notifications {
+		if notification.TodoID == id {
+			delete(r.notifications, notificationID)
+		}
+	}
+
+	for key := range r.todoSharedUsers {
+		if key[1] == id {
+			delete(r.todoSharedUsers, key)
+		}
+	}
+
+	for moveID, move := range r.todoMoves {
+		if move.TodoID == id {
+			delete(r.todoMoves, moveID)
+		}
+	}
+
+	r.purgeRanks(entity.RankTypeTodo, id)
+	r.purgeSharings(id, entity.SharingTypeTodo)
+	r.todoSearchGrams.remove(id)
+	delete(r.todos, id)
+}
+
+/**
+ * Helpers
+ */
+
+// Find todos with preload, ordered by id
+func (r *Repository) findTodos(match func(entity.Todo) bool) []entity.Todo {
+	todos := make([]entity.Todo, 0)
+	for _, todo := range r.todos {
+		if alive(todo.Entity) && match(todo) {
+			todos = append(todos, r.preloadTodo(todo))
+		}
+	}
+
+	sort.Slice(todos, func(i, j int) bool { return todos[i].ID < todos[j].ID })
+	return todos
+}
+
+// Same as preload in GORM: files, steps, repeat plan and tags
+func (r *Repository) preloadTodo(todo entity.Todo) entity.Todo {
+	todo.Files = r.selectTodoFiles(todo.ID)
+
+	todo.Steps = make([]entity.TodoStep, 0)
+	for _, step := range r.todoSteps {
+		if alive(step.Entity) && step.TodoID == todo.ID {
+			todo.Steps = append(todo.Steps, step)
+		}
+	}
+	sort.Slice(todo.Steps, func(i, j int) bool { return todo.Steps[i].ID < todo.Steps[j].ID })
+
+	if plan, ok := r.todoRepeatPlans[todo.TodoRepeatPlanID]; ok && alive(plan.Entity) {
+		todo.TodoRepeatPlan = plan
+	}
+
+	todo.TagList = make([]entity.Tag, 0)
+	for _, id := range r.tagTodos.lefts(todo.ID) {
+		if tag, ok := r.tags[id]; ok && alive(tag.Entity) {
+			todo.TagList = append(todo.TagList, tag)
+		}
+	}
+	todo.Tags = entity.TagNames(todo.TagList)
+
+	return todo
+}
+
+func (r *Repository) selectTodoFiles(todoID int64) []entity.File {
+	files := make([]entity.File, 0)
+	for _, id := range r.todoFiles.rights(todoID) {
+		if file, ok := r.files[id]; ok && alive(file.Entity) {
+			files = append(files, file)
+		}
+	}
+	return files
+}
+
+// Stable sort by time, nil first which is same as SQL
+func sortTodosByTime(todos []entity.Todo, get func(entity.Todo) *time.Time) {
+	sort.SliceStable(todos, func(i, j int) bool {
+		a, b := get(todos[i]), get(todos[j])
+		if a == nil || b == nil {
+			return a == nil && b != nil
+		}
+
+		return a.Before(*b)
+	})
+}
+
+func stripTodo(todo entity.Todo) entity.Todo {
+	todo.User = entity.User{}
+	todo.TodoList = entity.TodoList{}
+	todo.Files = nil
+	todo.Steps = nil
+	todo.Tags = nil
+	todo.TagList = nil
+	todo.TodoRepeatPlan = entity.TodoRepeatPlan{}
+	todo.Next = nil
+	return todo
+}
@@ -0,0 +1,370 @@
+package memory
+
+import (
+	"sort"
+	"time"
+
+	"github.com/yzx9/otodo/model/dto"
+	"github.com/yzx9/otodo/model/entity"
+)
+
+func (r *Repository) InsertTodoList(todoList *entity.TodoList) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	create(&todoList.Entity)
+	createVersion(&todoList.Version)
+	r.todoLists[todoList.ID] = stripTodoList(*todoList)
+	return nil
+}
+
+func (r *Repository) SelectTodoList(id int64) (entity.TodoList, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	list, ok := r.todoLists[id]
+	if !ok || !alive(list.Entity) {
+		return entity.TodoList{}, notFound("todo list")
+	}
+
+	return list, nil
+}
+
+func (r *Repository) SelectTodoLists(userID int64) ([]entity.TodoList, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.findTodoLists(func(list entity.TodoList) bool {
+		return list.UserID == userID
+	}), nil
+}
+
+func (r *Repository) SelectTodoListsWithMenuFormat(userID int64) ([]dto.TodoListMenuItemRaw, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	lists := r.findTodoLists(func(list entity.TodoList) bool {
+		return list.UserID == userID && !list.IsBasic
+	})
+
+	return r.todoListMenuItems(lists), nil
+}
+
+func (r *Repository) SelectTodoListsByFolders(todoListFolderIDs []int64) ([]entity.TodoList, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	folderIDs := make(map[int64]bool)
+	for _, id := range todoListFolderIDs {
+		folderIDs[id] = true
+	}
+
+	return r.findTodoLists(func(list entity.TodoList) bool {
+		return folderIDs[list.TodoListFolderID]
+	}), nil
+}
+
+func (r *Repository) SelectTodoListsWithMenuFormatByFolders(todoListFolderIDs []int64) ([]dto.TodoListMenuItemRaw, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	folderIDs := make(map[int64]bool)
+	for _, id := range todoListFolderIDs {
+		folderIDs[id] = true
+	}
+
+	lists := r.findTodoLists(func(list entity.TodoList) bool {
+		return folderIDs[list.TodoListFolderID]
+	})
+
+	return r.todoListMenuItems(lists), nil
+}
+
+// Todo lists with count of todos
+func (r *Repository) todoListMenuItems(lists []entity.TodoList) []dto.TodoListMenuItemRaw {
+	items := make([]dto.TodoListMenuItemRaw, 0, len(lists))
+	for i := range lists {
+		count := 0
+		for _, todo := range r.todos {
+			if alive(todo.Entity) && todo.TodoListID == lists[i].ID {
+				count++
+			}
+		}
+
+		items = append(items, dto.TodoListMenuItemRaw{
+			ID:               lists[i].ID,
+			Name:             lists[i].Name,
+			Count:            count,
+			TodoListFolderID: lists[i].TodoListFolderID,
+		})
+	}
+
+	return items
+}
+
+func (r *Repository) SaveTodoList(todoList *entity.TodoList) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	old, ok := r.todoLists[todoList.ID]
+	if !ok || !alive(old.Entity) || old.Version != todoList.Version {
+		return versionConflict("todo list")
+	}
+
+	save(&todoList.Entity, true)
+	todoList.Version++
+	r.todoLists[todoList.ID] = stripTodoList(*todoList)
+	return nil
+}
+
+func (r *Repository) DeleteTodoList(id, version int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	list, ok := r.todoLists[id]
+	if !ok || !alive(list.Entity) || list.Version != version {
+		return versionConflict("todo list")
+	}
+
+	softDelete(&list.Entity)
+	r.todoLists[id] = list
+	return nil
+}
+
+func (r *Repository) DeleteTodoListsByFolder(todoListFolderID int64) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var count int64
+	for id, list := range r.todoLists {
+		if alive(list.Entity) && list.TodoListFolderID == todoListFolderID {
+			softDelete(&list.Entity)
+			r.todoLists[id] = list
+			count++
+		}
+	}
+
+	return count, nil
+}
+
+func (r *Repository) UngroupTodoListsByFolder(todoListFolderID int64) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var count int64
+	for id, list := range r.todoLists {
+		if alive(list.Entity) && list.TodoListFolderID == todoListFolderID {
+			save(&list.Entity, true)
+			list.TodoListFolderID = 0
+			list.Version++
+			r.todoLists[id] = list
+			count++
+		}
+	}
+
+	return count, nil
+}
+
+func (r *Repository) ExistTodoList(id int64) (bool, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	list, ok := r.todoLists[id]
+	return ok && alive(list.Entity), nil
+}
+
+/**
+ * Sharing
+ */
+
+func (r *Repository) InsertTodoListSharedUser(userID, todoListID int64, role entity.TodoListRole) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.todoListSharedUsers.add(userID, todoListID)
+	r.todoListRoles[[2]int64{userID, todoListID}] = role
+	return nil
+}
+
+func (r *Repository) SelectSharedTodoLists(userID int64) ([]entity.TodoList, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	lists := make([]entity.TodoList, 0)
+	for _, id := range r.todoListSharedUsers.rights(userID) {
+		if list, ok := r.todoLists[id]; ok && alive(list.Entity) {
+			lists = append(lists, list)
+		}
+	}
+
+	return lists, nil
+}
+
+func (r *Repository) SelectTodoListSharedUsers(todoListID int64) ([]entity.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	users := make([]entity.User, 0)
+	for _, id := range r.todoListSharedUsers.lefts(todoListID) {
+		if user, ok := r.users[id]; ok && alive(user.Entity) {
+			users = append(users, user)
+		}
+	}
+
+	return users, nil
+}
+
+func (r *Repository) SelectTodoListSharedUserRole(userID, todoListID int64) (entity.TodoListRole, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if !r.todoListSharedUsers.has(userID, todoListID) {
+		return 0, notFound("todo list shared user")
+	}
+
+	return r.todoListRoles[[2]int64{userID, todoListID}], nil
+}
+
+func (r *Repository) SelectTodoListSharedUserRoles(todoListID int64) (map[int64]entity.TodoListRole, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	roles := make(map[int64]entity.TodoListRole)
+	for _, userID := range r.todoListSharedUsers.lefts(todoListID) {
+		roles[userID] = r.todoListRoles[[2]int64{userID, todoListID}]
+	}
+
+	return roles, nil
+}
+
+func (r *Repository) UpdateTodoListSharedUserRole(userID, todoListID int64, role entity.TodoListRole) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.todoListSharedUsers.has(userID, todoListID) {
+		r.todoListRoles[[2]int64{userID, todoListID}] = role
+	}
+
+	return nil
+}
+
+func (r *Repository) DeleteTodoListSharedUser(userID, todoListID int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.todoListSharedUsers.remove(userID, todoListID)
+	delete(r.todoListRoles, [2]int64{userID, todoListID})
+	return nil
+}
+
+func (r *Repository) ExistTodoListSharing(userID, todoListID int64) (bool, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.todoListSharedUsers.has(userID, todoListID), nil
+}
+
+/**
+ * Trash
+ */
+
+func (r *Repository) SelectDeletedTodoList(id int64) (entity.TodoList, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	list, ok := r.todoLists[id]
+	if !ok || alive(list.Entity) {
+		return entity.TodoList{}, notFound("deleted todo list")
+	}
+
+	return list, nil
+}
+
+func (r *Repository) SelectDeletedTodoLists(userID int64) ([]entity.TodoList, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	lists := make([]entity.TodoList, 0)
+	for _, list := range r.todoLists {
+		if !alive(list.Entity) && list.UserID == userID {
+			lists = append(lists, list)
+		}
+	}
+
+	sort.Slice(lists, func(i, j int) bool { return deletedLater(lists[i].Entity, lists[j].Entity) })
+	return lists, nil
+}
+
+func (r *Repository) RestoreTodoList(id, todoListFolderID int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if list, ok := r.todoLists[id]; ok {
+		restore(&list.Entity)
+		list.TodoListFolderID = todoListFolderID
+		list.Version++
+		r.todoLists[id] = list
+	}
+
+	return nil
+}
+
+func (r *Repository) PurgeTodoLists(deletedBefore time.Time) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var count int64
+	for id, list := range r.todoLists {
+		if alive(list.Entity) || !list.DeletedAt.Time.Before(deletedBefore) {
+			continue
+		}
+
+		for todoID, todo := range r.todos {
+			if todo.TodoListID == id {
+				r.purgeTodo(todoID)
+			}
+		}
+
+		for _, userID := range r.todoListSharedUsers.lefts(id) {
+			r.todoListSharedUsers.remove(userID, id)
+			delete(r.todoListRoles, [2]int64{userID, id})
+		}
+
+		for invitationID, invitation := range r.todoListInvitations {
+			if invitation.TodoListID == id {
+				delete(r.todoListInvitations, invitationID)
+			}
+		}
+
+		r.purgeRanks(entity.RankTypeTodoList, id)
+		r.purgeSharings(id, entity.SharingTypeTodoList, entity.SharingTypeTodoListPublish)
+		delete(r.todoLists, id)
+		count++
+	}
+
+	return count, nil
+}
+
+/**
+ * Helpers
+ */
+
+// Find todo lists, ordered by id
+func (r *Repository) findTodoLists(match func(entity.TodoList) bool) []entity.TodoList {
+	lists := make([]entity.TodoList, 0)
+	for _, list := range r.todoLists {
+		if alive(list.Entity) && match(list) {
+			lists = append(lists, list)
+		}
+	}
+
+	sort.Slice(lists, func(i, j int) bool { return lists[i].ID < lists[j].ID })
+	return lists
+}
+
+func stripTodoList(list entity.TodoList) entity.TodoList {
+	list.User = entity.User{}
+	list.TodoListFolder = entity.TodoListFolder{}
+	list.SharedUsers = nil
+	return list
+}
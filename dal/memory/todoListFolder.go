@@ -0,0 +1,219 @@
+package memory
+
+import (
+	"sort"
+	"time"
+
+	"github.com/yzx9/otodo/model/entity"
+)
+
+func (r *Repository) InsertTodoListFolder(folder *entity.TodoListFolder) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	create(&folder.Entity)
+	r.todoListFolders[folder.ID] = stripTodoListFolder(*folder)
+	return nil
+}
+
+func (r *Repository) SelectTodoListFolder(id int64) (entity.TodoListFolder, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	folder, ok := r.todoListFolders[id]
+	if !ok || !alive(folder.Entity) {
+		return entity.TodoListFolder{}, notFound("todo list folder")
+	}
+
+	return folder, nil
+}
+
+func (r *Repository) SelectTodoListFolders(userID int64) ([]entity.TodoListFolder, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	folders := make([]entity.TodoListFolder, 0)
+	for _, folder := range r.todoListFolders {
+		if alive(folder.Entity) && folder.UserID == userID {
+			folders = append(folders, folder)
+		}
+	}
+
+	sort.Slice(folders, func(i, j int) bool { return folders[i].ID < folders[j].ID })
+	return folders, nil
+}
+
+func (r *Repository) SaveTodoListFolder(folder *entity.TodoListFolder) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	_, exist := r.todoListFolders[folder.ID]
+	save(&folder.Entity, exist)
+	r.todoListFolders[folder.ID] = stripTodoListFolder(*folder)
+	return nil
+}
+
+func (r *Repository) DeleteTodoListFolder(id int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if folder, ok := r.todoListFolders[id]; ok && alive(folder.Entity) {
+		softDelete(&folder.Entity)
+		r.todoListFolders[id] = folder
+	}
+
+	return nil
+}
+
+func (r *Repository) ExistTodoListFolder(id int64) (bool, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	folder, ok := r.todoListFolders[id]
+	return ok && alive(folder.Entity), nil
+}
+
+/**
+ * Sharing
+ */
+
+func (r *Repository) InsertTodoListFolderSharedUser(userID, todoListFolderID int64, role entity.TodoListRole) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.todoListFolderSharedUsers[[2]int64{userID, todoListFolderID}] = role
+	return nil
+}
+
+func (r *Repository) SelectSharedTodoListFolders(userID int64) ([]entity.TodoListFolder, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	folders := make([]entity.TodoListFolder, 0)
+	for key := range r.todoListFolderSharedUsers {
+		if key[0] != userID {
+			continue
+		}
+
+		if folder, ok := r.todoListFolders[key[1]]; ok && alive(folder.Entity) {
+			folders = append(folders, folder)
+		}
+	}
+
+	sort.Slice(folders, func(i, j int) bool { return folders[i].ID < folders[j].ID })
+	return folders, nil
+}
+
+func (r *Repository) SelectTodoListFolderSharedUsers(todoListFolderID int64) ([]entity.TodoListFolderSharedUser, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	sharedUsers := make([]entity.TodoListFolderSharedUser, 0)
+	for key, role := range r.todoListFolderSharedUsers {
+		if key[1] == todoListFolderID {
+			sharedUsers = append(sharedUsers, entity.TodoListFolderSharedUser{
+				UserID:           key[0],
+				User:             r.users[key[0]],
+				TodoListFolderID: todoListFolderID,
+				Role:             role,
+			})
+		}
+	}
+
+	sort.Slice(sharedUsers, func(i, j int) bool { return sharedUsers[i].UserID < sharedUsers[j].UserID })
+	return sharedUsers, nil
+}
+
+func (r *Repository) SelectTodoListFolderSharedUserRole(userID, todoListFolderID int64) (entity.TodoListRole, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	role, ok := r.todoListFolderSharedUsers[[2]int64{userID, todoListFolderID}]
+	if !ok {
+		return 0, notFound("todo list folder shared user")
+	}
+
+	return role, nil
+}
+
+func (r *Repository) DeleteTodoListFolderSharedUser(userID, todoListFolderID int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.todoListFolderSharedUsers, [2]int64{userID, todoListFolderID})
+	return nil
+}
+
+/**
+ * Trash
+ */
+
+func (r *Repository) SelectDeletedTodoListFolder(id int64) (entity.TodoListFolder, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	folder, ok := r.todoListFolders[id]
+	if !ok || alive(folder.Entity) {
+		return entity.TodoListFolder{}, notFound("deleted todo list folder")
+	}
+
+	return folder, nil
+}
+
+func (r *Repository) SelectDeletedTodoListFolders(userID int64) ([]entity.TodoListFolder, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	folders := make([]entity.TodoListFolder, 0)
+	for _, folder := range r.todoListFolders {
+		if !alive(folder.Entity) && folder.UserID == userID {
+			folders = append(folders, folder)
+		}
+	}
+
+	sort.Slice(folders, func(i, j int) bool { return deletedLater(folders[i].Entity, folders[j].Entity) })
+	return folders, nil
+}
+
+func (r *Repository) RestoreTodoListFolder(id int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if folder, ok := r.todoListFolders[id]; ok {
+		restore(&folder.Entity)
+		r.todoListFolders[id] = folder
+	}
+
+	return nil
+}
+
+func (r *Repository) PurgeTodoListFolders(deletedBefore time.Time) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var count int64
+	for id, folder := range r.todoListFolders {
+		if alive(folder.Entity) || !folder.DeletedAt.Time.Before(deletedBefore) {
+			continue
+		}
+
+		for key := range r.todoListFolderSharedUsers {
+			if key[1] == id {
+				delete(r.todoListFolderSharedUsers, key)
+			}
+		}
+
+		r.purgeRanks(entity.RankTypeTodoListFolder, id)
+		r.purgeSharings(id, entity.SharingTypeTodoListFolder)
+		delete(r.todoListFolders, id)
+		count++
+	}
+
+	return count, nil
+}
+
+func stripTodoListFolder(folder entity.TodoListFolder) entity.TodoListFolder {
+	folder.User = entity.User{}
+	folder.TodoLists = nil
+	return folder
+}
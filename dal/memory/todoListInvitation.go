@@ -0,0 +1,115 @@
+package memory
+
+import (
+	"sort"
+
+	"github.com/yzx9/otodo/model/entity"
+)
+
+func (r *Repository) InsertTodoListInvitation(invitation *entity.TodoListInvitation) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	create(&invitation.Entity)
+	r.todoListInvitations[invitation.ID] = stripTodoListInvitation(*invitation)
+	return nil
+}
+
+func (r *Repository) SelectTodoListInvitation(id int64) (entity.TodoListInvitation, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	invitation, ok := r.todoListInvitations[id]
+	if !ok || !alive(invitation.Entity) {
+		return entity.TodoListInvitation{}, notFound("todo list invitation")
+	}
+
+	return invitation, nil
+}
+
+func (r *Repository) SelectTodoListInvitations(todoListID int64) ([]entity.TodoListInvitation, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	invitations := r.findTodoListInvitations(func(invitation entity.TodoListInvitation) bool {
+		return invitation.TodoListID == todoListID
+	})
+	for i := range invitations {
+		if user, ok := r.users[invitations[i].InviteeID]; ok && alive(user.Entity) {
+			invitations[i].Invitee = user
+		}
+		if user, ok := r.users[invitations[i].UserID]; ok && alive(user.Entity) {
+			invitations[i].User = user
+		}
+	}
+
+	return invitations, nil
+}
+
+func (r *Repository) SelectUserTodoListInvitations(userID int64) ([]entity.TodoListInvitation, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	invitations := r.findTodoListInvitations(func(invitation entity.TodoListInvitation) bool {
+		return invitation.InviteeID == userID
+	})
+	for i := range invitations {
+		if list, ok := r.todoLists[invitations[i].TodoListID]; ok && alive(list.Entity) {
+			invitations[i].TodoList = list
+		}
+		if user, ok := r.users[invitations[i].UserID]; ok && alive(user.Entity) {
+			invitations[i].User = user
+		}
+	}
+
+	return invitations, nil
+}
+
+func (r *Repository) UpdateTodoListInvitationsInvitee(email string, inviteeID int64) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var count int64
+	for id, invitation := range r.todoListInvitations {
+		if alive(invitation.Entity) && invitation.Email == email && invitation.InviteeID == 0 {
+			invitation.InviteeID = inviteeID
+			save(&invitation.Entity, true)
+			r.todoListInvitations[id] = invitation
+			count++
+		}
+	}
+
+	return count, nil
+}
+
+func (r *Repository) DeleteTodoListInvitation(id int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if invitation, ok := r.todoListInvitations[id]; ok && alive(invitation.Entity) {
+		softDelete(&invitation.Entity)
+		r.todoListInvitations[id] = invitation
+	}
+
+	return nil
+}
+
+// Find invitations, ordered by id
+func (r *Repository) findTodoListInvitations(match func(entity.TodoListInvitation) bool) []entity.TodoListInvitation {
+	invitations := make([]entity.TodoListInvitation, 0)
+	for _, invitation := range r.todoListInvitations {
+		if alive(invitation.Entity) && match(invitation) {
+			invitations = append(invitations, invitation)
+		}
+	}
+
+	sort.Slice(invitations, func(i, j int) bool { return invitations[i].ID < invitations[j].ID })
+	return invitations
+}
+
+func stripTodoListInvitation(invitation entity.TodoListInvitation) entity.TodoListInvitation {
+	invitation.Invitee = entity.User{}
+	invitation.TodoList = entity.TodoList{}
+	invitation.User = entity.User{}
+	return invitation
+}
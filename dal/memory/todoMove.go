@@ -0,0 +1,37 @@
+package memory
+
+import (
+	"sort"
+
+	"github.com/yzx9/otodo/model/entity"
+)
+
+func (r *Repository) InsertTodoMove(move *entity.TodoMove) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	create(&move.Entity)
+	r.todoMoves[move.ID] = stripTodoMove(*move)
+	return nil
+}
+
+func (r *Repository) SelectTodoMoves(todoListID int64) ([]entity.TodoMove, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	moves := make([]entity.TodoMove, 0)
+	for _, move := range r.todoMoves {
+		if alive(move.Entity) && (move.FromTodoListID == todoListID || move.ToTodoListID == todoListID) {
+			moves = append(moves, move)
+		}
+	}
+
+	sort.Slice(moves, func(i, j int) bool { return moves[i].ID > moves[j].ID })
+	return moves, nil
+}
+
+func stripTodoMove(move entity.TodoMove) entity.TodoMove {
+	move.Todo = entity.Todo{}
+	move.User = entity.User{}
+	return move
+}
@@ -0,0 +1,173 @@
+package memory
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/yzx9/otodo/model/dto"
+	"github.com/yzx9/otodo/model/entity"
+)
+
+func (r *Repository) SelectTodosByQuery(query dto.TodoQuery) ([]entity.Todo, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	ids := idSet(query.IDs)
+	lists := idSet(query.TodoListIDs)
+	shared := idSet(query.SharedIDs)
+	todos := r.findTodos(func(todo entity.Todo) bool {
+		return (query.UserID == 0 || todo.UserID == query.UserID) &&
+			(query.TodoListID == 0 || todo.TodoListID == query.TodoListID) &&
+			(lists == nil || lists[todo.TodoListID] || shared[todo.ID]) &&
+			(ids == nil || ids[todo.ID]) &&
+			r.matchTodoQuery(todo, query)
+	})
+
+	// rank is not stored in todo, see also NewTodoCursor
+	var ranks map[int64]string
+	if query.Sort == dto.TodoSortRank {
+		ranks = r.rankKeys(query.RankUserID, entity.RankTypeTodo)
+	}
+	cursorOf := func(todo entity.Todo) dto.TodoCursor {
+		todo.Rank = ranks[todo.ID]
+		return dto.NewTodoCursor(todo, query.Sort, query.Desc)
+	}
+
+	sort.SliceStable(todos, func(i, j int) bool {
+		return compareTodoCursor(cursorOf(todos[i]), cursorOf(todos[j]), query.Desc) < 0
+	})
+
+	if query.After != nil {
+		i := sort.Search(len(todos), func(i int) bool {
+			return compareTodoCursor(cursorOf(todos[i]), *query.After, query.Desc) > 0
+		})
+		todos = todos[i:]
+	}
+
+	if query.Limit > 0 && len(todos) > query.Limit {
+		todos = todos[:query.Limit]
+	}
+
+	return todos, nil
+}
+
+/**
+ * Helpers
+ */
+
+func (r *Repository) matchTodoQuery(todo entity.Todo, query dto.TodoQuery) bool {
+	if query.Done != nil && todo.Done != *query.Done {
+		return false
+	}
+	if query.Importance != nil && todo.Importance != *query.Importance {
+		return false
+	}
+	if query.Notified != nil && todo.Notified != *query.Notified {
+		return false
+	}
+	if query.HasDeadline != nil && (todo.Deadline != nil) != *query.HasDeadline {
+		return false
+	}
+	if query.DeadlineFrom != nil && (todo.Deadline == nil || todo.Deadline.Before(*query.DeadlineFrom)) {
+		return false
+	}
+	if query.DeadlineTo != nil && (todo.Deadline == nil || !todo.Deadline.Before(*query.DeadlineTo)) {
+		return false
+	}
+	if query.Tag != "" && !r.hasTag(todo.ID, query.TagUserID, query.Tag) {
+		return false
+	}
+	if query.HasFiles != nil && (len(r.todoFiles.rights(todo.ID)) != 0) != *query.HasFiles {
+		return false
+	}
+	return true
+}
+
+// Set of ids, nil if ids is nil
+func idSet(ids []int64) map[int64]bool {
+	if ids == nil {
+		return nil
+	}
+
+	set := make(map[int64]bool)
+	for _, id := range ids {
+		set[id] = true
+	}
+	return set
+}
+
+func (r *Repository) hasTag(todoID, userID int64, tagName string) bool {
+	for _, tagID := range r.tagTodos.lefts(todoID) {
+		if tag, ok := r.tags[tagID]; ok && alive(tag.Entity) && tag.UserID == userID && tag.Name == tagName {
+			return true
+		}
+	}
+	return false
+}
+
+// Compare in order of query, same as GORM implementation
+func compareTodoCursor(a, b dto.TodoCursor, desc bool) int {
+	c := compareTodoSortValue(a, b)
+	if c == 0 {
+		c = compareInt64(a.ID, b.ID)
+	}
+	if desc {
+		c = -c
+	}
+	return c
+}
+
+func compareTodoSortValue(a, b dto.TodoCursor) int {
+	switch a.Sort {
+	case dto.TodoSortDeadline, dto.TodoSortCreatedAt, dto.TodoSortNotifyAt:
+		switch {
+		case a.Time == nil && b.Time == nil:
+			return 0
+		case a.Time == nil: // null is greater
+			return 1
+		case b.Time == nil:
+			return -1
+		case a.Time.Before(*b.Time):
+			return -1
+		case a.Time.After(*b.Time):
+			return 1
+		}
+		return 0
+
+	case dto.TodoSortTitle:
+		return strings.Compare(a.Title, b.Title)
+
+	case dto.TodoSortRank:
+		switch {
+		case a.Rank == b.Rank:
+			return 0
+		case a.Rank == "": // unranked is greater
+			return 1
+		case b.Rank == "":
+			return -1
+		}
+		return strings.Compare(a.Rank, b.Rank)
+
+	case dto.TodoSortImportance:
+		switch {
+		case a.Importance == b.Importance:
+			return 0
+		case b.Importance:
+			return -1
+		}
+		return 1
+
+	default:
+		return 0
+	}
+}
+
+func compareInt64(a, b int64) int {
+	switch {
+	case a < b:
+		return -1
+	case a > b:
+		return 1
+	}
+	return 0
+}
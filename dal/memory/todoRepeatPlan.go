@@ -0,0 +1,53 @@
+package memory
+
+import (
+	"github.com/yzx9/otodo/model/entity"
+)
+
+func (r *Repository) InsertTodoRepeatPlan(plan *entity.TodoRepeatPlan) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	create(&plan.Entity)
+	r.todoRepeatPlans[plan.ID] = stripTodoRepeatPlan(*plan)
+	return nil
+}
+
+func (r *Repository) SelectTodoRepeatPlan(id int64) (entity.TodoRepeatPlan, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	plan, ok := r.todoRepeatPlans[id]
+	if !ok || !alive(plan.Entity) {
+		return entity.TodoRepeatPlan{}, notFound("todo repeat plan")
+	}
+
+	return plan, nil
+}
+
+func (r *Repository) SaveTodoRepeatPlan(plan *entity.TodoRepeatPlan) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	_, exist := r.todoRepeatPlans[plan.ID]
+	save(&plan.Entity, exist)
+	r.todoRepeatPlans[plan.ID] = stripTodoRepeatPlan(*plan)
+	return nil
+}
+
+func (r *Repository) DeleteTodoRepeatPlan(id int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if plan, ok := r.todoRepeatPlans[id]; ok && alive(plan.Entity) {
+		softDelete(&plan.Entity)
+		r.todoRepeatPlans[id] = plan
+	}
+
+	return nil
+}
+
+func stripTodoRepeatPlan(plan entity.TodoRepeatPlan) entity.TodoRepeatPlan {
+	plan.Todos = nil
+	return plan
+}
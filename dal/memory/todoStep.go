@@ -0,0 +1,78 @@
+package memory
+
+import (
+	"sort"
+
+	"github.com/yzx9/otodo/model/entity"
+)
+
+func (r *Repository) InsertTodoStep(step *entity.TodoStep) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	create(&step.Entity)
+	createVersion(&step.Version)
+	r.todoSteps[step.ID] = stripTodoStep(*step)
+	r.indexTodoSearch(step.TodoID)
+	return nil
+}
+
+func (r *Repository) SelectTodoStep(id int64) (entity.TodoStep, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	step, ok := r.todoSteps[id]
+	if !ok || !alive(step.Entity) {
+		return entity.TodoStep{}, notFound("todo step")
+	}
+
+	return step, nil
+}
+
+func (r *Repository) SelectTodoSteps(todoID int64) ([]entity.TodoStep, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	steps := make([]entity.TodoStep, 0)
+	for _, step := range r.todoSteps {
+		if alive(step.Entity) && step.TodoID == todoID {
+			steps = append(steps, step)
+		}
+	}
+
+	sort.Slice(steps, func(i, j int) bool { return steps[i].ID < steps[j].ID })
+	return steps, nil
+}
+
+func (r *Repository) SaveTodoStep(step *entity.TodoStep) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	old, ok := r.todoSteps[step.ID]
+	if !ok || !alive(old.Entity) || old.Version != step.Version {
+		return versionConflict("todo step")
+	}
+
+	save(&step.Entity, true)
+	step.Version++
+	r.todoSteps[step.ID] = stripTodoStep(*step)
+	r.indexTodoSearch(step.TodoID)
+	return nil
+}
+
+func (r *Repository) DeleteTodoStep(id int64) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if step, ok := r.todoSteps[id]; ok && alive(step.Entity) {
+		softDelete(&step.Entity)
+		r.todoSteps[id] = step
+	}
+
+	return nil
+}
+
+func stripTodoStep(step entity.TodoStep) entity.TodoStep {
+	step.Todo = entity.Todo{}
+	return step
+}
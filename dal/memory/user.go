@@ -0,0 +1,113 @@
+package memory
+
+import (
+	"github.com/yzx9/otodo/model/entity"
+)
+
+func (r *Repository) InsertUser(user *entity.User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	create(&user.Entity)
+	r.users[user.ID] = stripUser(*user)
+	return nil
+}
+
+func (r *Repository) SelectUser(id int64) (entity.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.selectUser(id)
+}
+
+func (r *Repository) SelectUserByUserName(username string) (entity.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.findUser(func(user entity.User) bool { return user.Name == username })
+}
+
+func (r *Repository) SelectUserByGithubID(githubID int64) (entity.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.findUser(func(user entity.User) bool { return user.GithubID == githubID })
+}
+
+func (r *Repository) SelectUserByEmail(email string) (entity.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	return r.findUser(func(user entity.User) bool { return user.Email == email })
+}
+
+func (r *Repository) SelectUserByTodo(todoID int64) (entity.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	todo, ok := r.todos[todoID]
+	if !ok || !alive(todo.Entity) {
+		return entity.User{}, notFound("todo")
+	}
+
+	return r.selectUser(todo.UserID)
+}
+
+func (r *Repository) SaveUser(user *entity.User) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	_, exist := r.users[user.ID]
+	save(&user.Entity, exist)
+	r.users[user.ID] = stripUser(*user)
+	return nil
+}
+
+func (r *Repository) ExistUserByUserName(username string) (bool, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	_, err := r.findUser(func(user entity.User) bool { return user.Name == username })
+	return err == nil, nil
+}
+
+func (r *Repository) ExistUserByGithubID(githubID int64) (bool, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	_, err := r.findUser(func(user entity.User) bool { return user.GithubID == githubID })
+	return err == nil, nil
+}
+
+func (r *Repository) selectUser(id int64) (entity.User, error) {
+	user, ok := r.users[id]
+	if !ok || !alive(user.Entity) {
+		return entity.User{}, notFound("user")
+	}
+
+	return user, nil
+}
+
+// Find first matched user, ordered by id
+func (r *Repository) findUser(match func(entity.User) bool) (entity.User, error) {
+	var found *entity.User
+	for _, user := range r.users {
+		if alive(user.Entity) && match(user) && (found == nil || user.ID < found.ID) {
+			user := user
+			found = &user
+		}
+	}
+
+	if found == nil {
+		return entity.User{}, notFound("user")
+	}
+
+	return *found, nil
+}
+
+func stripUser(user entity.User) entity.User {
+	user.BasicTodoList = nil
+	user.TodoLists = nil
+	user.SharedTodoLists = nil
+	return user
+}
@@ -0,0 +1,29 @@
+package memory
+
+import (
+	"github.com/yzx9/otodo/model/entity"
+)
+
+func (r *Repository) InsertUserInvalidRefreshToken(token *entity.UserInvalidRefreshToken) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	create(&token.Entity)
+	stripped := *token
+	stripped.User = entity.User{}
+	r.userInvalidRefreshTokens[token.ID] = stripped
+	return nil
+}
+
+func (r *Repository) ExistUserInvalidRefreshToken(userID int64, tokenID string) (bool, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, token := range r.userInvalidRefreshTokens {
+		if alive(token.Entity) && token.UserID == userID && token.TokenID == tokenID {
+			return true, nil
+		}
+	}
+
+	return false, nil
+}
@@ -0,0 +1,177 @@
+package dal
+
+import (
+	"sort"
+	"time"
+
+	"github.com/yzx9/otodo/dal/migrations"
+	"github.com/yzx9/otodo/model/dto"
+	"github.com/yzx9/otodo/model/entity"
+	"github.com/yzx9/otodo/otodo"
+	"github.com/yzx9/otodo/util"
+	"gorm.io/gorm"
+)
+
+// Apply all pending migrations, returns count of applied migrations
+func MigrateUp() (int, error) {
+	applied, err := selectAppliedMigrations()
+	if err != nil {
+		return 0, err
+	}
+
+	if err := checkAppliedMigrations(applied); err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for _, m := range migrations.All {
+		if _, ok := applied[m.Version]; ok {
+			continue
+		}
+
+		m := m
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := m.Up(tx); err != nil {
+				return err
+			}
+
+			return tx.Create(&entity.SchemaMigration{
+				Version:   m.Version,
+				Name:      m.Name,
+				AppliedAt: time.Now(),
+			}).Error
+		})
+		if err != nil {
+			return count, util.NewErrorWithUnknown("fails to apply migration %v: %w", m.Version, err)
+		}
+
+		count++
+	}
+
+	return count, nil
+}
+
+// Rollback latest applied migrations, returns count of rolled back migrations
+func MigrateDown(steps int) (int, error) {
+	applied, err := selectAppliedMigrations()
+	if err != nil {
+		return 0, err
+	}
+
+	if err := checkAppliedMigrations(applied); err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for i := len(migrations.All) - 1; i >= 0 && count < steps; i-- {
+		m := migrations.All[i]
+		if _, ok := applied[m.Version]; !ok {
+			continue
+		}
+
+		err := db.Transaction(func(tx *gorm.DB) error {
+			if err := m.Down(tx); err != nil {
+				return err
+			}
+
+			return tx.Delete(&entity.SchemaMigration{Version: m.Version}).Error
+		})
+		if err != nil {
+			return count, util.NewErrorWithUnknown("fails to rollback migration %v: %w", m.Version, err)
+		}
+
+		count++
+	}
+
+	return count, nil
+}
+
+func SelectMigrationStatus() ([]dto.MigrationStatus, error) {
+	applied, err := selectAppliedMigrations()
+	if err != nil {
+		return nil, err
+	}
+
+	vec := make([]dto.MigrationStatus, 0, len(migrations.All))
+	for _, m := range migrations.All {
+		status := dto.MigrationStatus{
+			Version: m.Version,
+			Name:    m.Name,
+		}
+
+		if record, ok := applied[m.Version]; ok {
+			status.Applied = true
+			status.AppliedAt = &record.AppliedAt
+			delete(applied, m.Version)
+		}
+
+		vec = append(vec, status)
+	}
+
+	// Unknown migrations, applied by a newer binary
+	for _, record := range applied {
+		record := record
+		vec = append(vec, dto.MigrationStatus{
+			Version:   record.Version,
+			Name:      record.Name,
+			Applied:   true,
+			AppliedAt: &record.AppliedAt,
+		})
+	}
+
+	sort.Slice(vec, func(i, j int) bool { return vec[i].Version < vec[j].Version })
+	return vec, nil
+}
+
+// Check schema version on startup, refuse to start if database is ahead
+// of binary, or pending migrations exist and auto migrate is disabled.
+func checkSchemaVersion(autoMigrate bool) error {
+	applied, err := selectAppliedMigrations()
+	if err != nil {
+		return err
+	}
+
+	if err := checkAppliedMigrations(applied); err != nil {
+		return err
+	}
+
+	if len(applied) == len(migrations.All) {
+		return nil
+	}
+
+	if !autoMigrate {
+		return util.NewError(otodo.ErrDataInconsistency, "database schema is outdated, run `migrate up` first")
+	}
+
+	_, err = MigrateUp()
+	return err
+}
+
+func checkAppliedMigrations(applied map[int]entity.SchemaMigration) error {
+	known := make(map[int]bool)
+	for _, m := range migrations.All {
+		known[m.Version] = true
+	}
+
+	for version := range applied {
+		if !known[version] {
+			return util.NewError(otodo.ErrDataInconsistency, "database schema version %v is ahead of binary version %v", version, migrations.Latest())
+		}
+	}
+
+	return nil
+}
+
+func selectAppliedMigrations() (map[int]entity.SchemaMigration, error) {
+	var records []entity.SchemaMigration
+	if re := db.Find(&records); re.Error != nil {
+		return nil, util.WrapGormErr(re.Error, "schema migration")
+	}
+
+	applied := make(map[int]entity.SchemaMigration)
+	for i := range records {
+		applied[records[i].Version] = records[i]
+	}
+
+	return applied, nil
+}
@@ -0,0 +1,43 @@
+package migrations
+
+import "gorm.io/gorm"
+
+type Migration struct {
+	Version int
+	Name    string
+	Up      func(tx *gorm.DB) error
+	Down    func(tx *gorm.DB) error
+}
+
+// All migrations, sorted by version.
+// Released migrations MUST NOT be modified, add a new one instead.
+var All = []Migration{
+	{Version: 1, Name: "init", Up: v1Up, Down: v1Down},
+	{Version: 2, Name: "store todo step name", Up: v2Up, Down: v2Down},
+	{Version: 3, Name: "non-unique user github id", Up: v3Up, Down: v3Down},
+	{Version: 4, Name: "version for optimistic lock", Up: v4Up, Down: v4Down},
+	{Version: 5, Name: "full-text index for search", Up: v5Up, Down: v5Down},
+	{Version: 6, Name: "in-app notification", Up: v6Up, Down: v6Down},
+	{Version: 7, Name: "rrule of todo repeat plan", Up: v7Up, Down: v7Down},
+	{Version: 8, Name: "repeat from completion", Up: v8Up, Down: v8Down},
+	{Version: 9, Name: "tag color", Up: v9Up, Down: v9Down},
+	{Version: 10, Name: "user-defined rank", Up: v10Up, Down: v10Down},
+	{Version: 11, Name: "todo move history", Up: v11Up, Down: v11Down},
+	{Version: 12, Name: "role of todo list shared user", Up: v12Up, Down: v12Down},
+	{Version: 13, Name: "sharing limits and redemptions", Up: v13Up, Down: v13Down},
+	{Version: 14, Name: "todo list invitation", Up: v14Up, Down: v14Down},
+	{Version: 15, Name: "published todo list", Up: v15Up, Down: v15Down},
+	{Version: 16, Name: "sharing of todo list folder and todo", Up: v16Up, Down: v16Down},
+	{Version: 17, Name: "todo list transfer", Up: v17Up, Down: v17Down},
+	{Version: 18, Name: "ngram parser of full-text index", Up: v18Up, Down: v18Down},
+	{Version: 19, Name: "built-in search index", Up: v19Up, Down: v19Down},
+}
+
+// Latest version known by this binary
+func Latest() int {
+	if len(All) == 0 {
+		return 0
+	}
+
+	return All[len(All)-1].Version
+}
@@ -0,0 +1,22 @@
+package migrations
+
+import "gorm.io/gorm"
+
+type v10Rank struct {
+	Entity v1Entity `gorm:"embedded"`
+
+	Key       string `gorm:"column:rank_key;size:128"`
+	Type      int8   `gorm:"uniqueIndex:idx_ranks_item,priority:2"`
+	RelatedID int64  `gorm:"uniqueIndex:idx_ranks_item,priority:3"`
+	UserID    int64  `gorm:"uniqueIndex:idx_ranks_item,priority:1"`
+}
+
+func (v10Rank) TableName() string { return "ranks" }
+
+func v10Up(tx *gorm.DB) error {
+	return tx.Migrator().CreateTable(&v10Rank{})
+}
+
+func v10Down(tx *gorm.DB) error {
+	return tx.Migrator().DropTable(&v10Rank{})
+}
@@ -0,0 +1,23 @@
+package migrations
+
+import "gorm.io/gorm"
+
+type v11TodoMove struct {
+	Entity v1Entity `gorm:"embedded"`
+
+	Title          string `gorm:"size:128"`
+	TodoID         int64
+	FromTodoListID int64 `gorm:"index"`
+	ToTodoListID   int64 `gorm:"index"`
+	UserID         int64
+}
+
+func (v11TodoMove) TableName() string { return "todo_moves" }
+
+func v11Up(tx *gorm.DB) error {
+	return tx.Migrator().CreateTable(&v11TodoMove{})
+}
+
+func v11Down(tx *gorm.DB) error {
+	return tx.Migrator().DropTable(&v11TodoMove{})
+}
@@ -0,0 +1,33 @@
+package migrations
+
+import "gorm.io/gorm"
+
+// Existing shared users and sharing tokens are editors, which have the
+// same permissions as before
+type v12TodoListSharedUser struct {
+	Role int8 `gorm:"not null;default:11"`
+}
+
+func (v12TodoListSharedUser) TableName() string { return "todo_list_shared_users" }
+
+type v12Sharing struct {
+	Role int8 `gorm:"not null;default:11"`
+}
+
+func (v12Sharing) TableName() string { return "sharings" }
+
+func v12Up(tx *gorm.DB) error {
+	if err := tx.Migrator().AddColumn(&v12TodoListSharedUser{}, "Role"); err != nil {
+		return err
+	}
+
+	return tx.Migrator().AddColumn(&v12Sharing{}, "Role")
+}
+
+func v12Down(tx *gorm.DB) error {
+	if err := tx.Migrator().DropColumn(&v12Sharing{}, "Role"); err != nil {
+		return err
+	}
+
+	return tx.Migrator().DropColumn(&v12TodoListSharedUser{}, "Role")
+}
@@ -0,0 +1,48 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type v13Sharing struct {
+	Label          string `gorm:"size:64"`
+	ExpiresAt      *time.Time
+	MaxRedemptions int64
+}
+
+func (v13Sharing) TableName() string { return "sharings" }
+
+type v13SharingRedemption struct {
+	Entity v1Entity `gorm:"embedded"`
+
+	SharingID int64 `gorm:"index"`
+	UserID    int64
+}
+
+func (v13SharingRedemption) TableName() string { return "sharing_redemptions" }
+
+func v13Up(tx *gorm.DB) error {
+	for _, column := range []string{"Label", "ExpiresAt", "MaxRedemptions"} {
+		if err := tx.Migrator().AddColumn(&v13Sharing{}, column); err != nil {
+			return err
+		}
+	}
+
+	return tx.Migrator().CreateTable(&v13SharingRedemption{})
+}
+
+func v13Down(tx *gorm.DB) error {
+	if err := tx.Migrator().DropTable(&v13SharingRedemption{}); err != nil {
+		return err
+	}
+
+	for _, column := range []string{"Label", "ExpiresAt", "MaxRedemptions"} {
+		if err := tx.Migrator().DropColumn(&v13Sharing{}, column); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
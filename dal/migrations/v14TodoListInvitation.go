@@ -0,0 +1,23 @@
+package migrations
+
+import "gorm.io/gorm"
+
+type v14TodoListInvitation struct {
+	Entity v1Entity `gorm:"embedded"`
+
+	Role       int8   `gorm:"not null;default:11"`
+	Email      string `gorm:"size:32;index"`
+	InviteeID  int64  `gorm:"index"`
+	TodoListID int64  `gorm:"index"`
+	UserID     int64
+}
+
+func (v14TodoListInvitation) TableName() string { return "todo_list_invitations" }
+
+func v14Up(tx *gorm.DB) error {
+	return tx.Migrator().CreateTable(&v14TodoListInvitation{})
+}
+
+func v14Down(tx *gorm.DB) error {
+	return tx.Migrator().DropTable(&v14TodoListInvitation{})
+}
@@ -0,0 +1,30 @@
+package migrations
+
+import "gorm.io/gorm"
+
+type v15Sharing struct {
+	PublishMemo  bool
+	PublishFiles bool
+}
+
+func (v15Sharing) TableName() string { return "sharings" }
+
+func v15Up(tx *gorm.DB) error {
+	for _, column := range []string{"PublishMemo", "PublishFiles"} {
+		if err := tx.Migrator().AddColumn(&v15Sharing{}, column); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func v15Down(tx *gorm.DB) error {
+	for _, column := range []string{"PublishMemo", "PublishFiles"} {
+		if err := tx.Migrator().DropColumn(&v15Sharing{}, column); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,27 @@
+package migrations
+
+import "gorm.io/gorm"
+
+type v16TodoListFolderSharedUser struct {
+	UserID           int64 `gorm:"primaryKey"`
+	TodoListFolderID int64 `gorm:"primaryKey"`
+	Role             int8  `gorm:"not null;default:11"`
+}
+
+func (v16TodoListFolderSharedUser) TableName() string { return "todo_list_folder_shared_users" }
+
+type v16TodoSharedUser struct {
+	UserID int64 `gorm:"primaryKey"`
+	TodoID int64 `gorm:"primaryKey"`
+	Role   int8  `gorm:"not null;default:11"`
+}
+
+func (v16TodoSharedUser) TableName() string { return "todo_shared_users" }
+
+func v16Up(tx *gorm.DB) error {
+	return tx.Migrator().CreateTable(&v16TodoListFolderSharedUser{}, &v16TodoSharedUser{})
+}
+
+func v16Down(tx *gorm.DB) error {
+	return tx.Migrator().DropTable(&v16TodoListFolderSharedUser{}, &v16TodoSharedUser{})
+}
@@ -0,0 +1,17 @@
+package migrations
+
+import "gorm.io/gorm"
+
+type v17TodoList struct {
+	TransferUserID int64
+}
+
+func (v17TodoList) TableName() string { return "todo_lists" }
+
+func v17Up(tx *gorm.DB) error {
+	return tx.Migrator().AddColumn(&v17TodoList{}, "TransferUserID")
+}
+
+func v17Down(tx *gorm.DB) error {
+	return tx.Migrator().DropColumn(&v17TodoList{}, "TransferUserID")
+}
@@ -0,0 +1,42 @@
+package migrations
+
+import "gorm.io/gorm"
+
+// Full-text indexes of MySQL with ngram parser, so that CJK text and short
+// words are tokenized. The ngram parser excludes tokens containing any
+// stopword, e.g. "a", so stopwords are disabled for these indexes.
+var v18FullTextIndexes = map[string][]string{
+	"mysql": {
+		"SET SESSION innodb_ft_enable_stopword = OFF",
+		"CREATE FULLTEXT INDEX idx_todos_full_text ON todos (title, memo) WITH PARSER ngram",
+		"CREATE FULLTEXT INDEX idx_todo_steps_full_text ON todo_steps (name) WITH PARSER ngram",
+		"CREATE FULLTEXT INDEX idx_files_full_text ON files (file_name) WITH PARSER ngram",
+		"SET SESSION innodb_ft_enable_stopword = DEFAULT",
+	},
+}
+
+func v18Up(tx *gorm.DB) error {
+	name := tx.Dialector.Name()
+	if _, ok := v18FullTextIndexes[name]; !ok {
+		return nil
+	}
+
+	if err := v5Exec(tx, v5FullTextIndexDrops[name]); err != nil {
+		return err
+	}
+
+	return v5Exec(tx, v18FullTextIndexes[name])
+}
+
+func v18Down(tx *gorm.DB) error {
+	name := tx.Dialector.Name()
+	if _, ok := v18FullTextIndexes[name]; !ok {
+		return nil
+	}
+
+	if err := v5Exec(tx, v5FullTextIndexDrops[name]); err != nil {
+		return err
+	}
+
+	return v5Exec(tx, v5FullTextIndexes[name])
+}
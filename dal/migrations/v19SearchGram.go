@@ -0,0 +1,87 @@
+package migrations
+
+import (
+	"github.com/yzx9/otodo/model/entity"
+	"gorm.io/gorm"
+)
+
+// Built-in search index for databases without full-text index, see migration
+// v5 and v18
+type v19TodoSearchGram struct {
+	Gram   string `gorm:"primaryKey;size:8"`
+	TodoID int64  `gorm:"primaryKey;index"`
+}
+
+func (v19TodoSearchGram) TableName() string { return "todo_search_grams" }
+
+// Searchable texts of todos, [todo id, text]
+var v19SearchTexts = []string{
+	"SELECT id, COALESCE(title, '') FROM todos",
+	"SELECT id, COALESCE(memo, '') FROM todos",
+	"SELECT todo_id, COALESCE(name, '') FROM todo_steps WHERE deleted_at IS NULL",
+	"SELECT todo_files.todo_id, COALESCE(files.file_name, '') FROM todo_files JOIN files ON files.id = todo_files.file_id WHERE files.deleted_at IS NULL",
+}
+
+func v19Up(tx *gorm.DB) error {
+	if v19FullTextIndexed(tx) {
+		return nil
+	}
+
+	if err := tx.Migrator().CreateTable(&v19TodoSearchGram{}); err != nil {
+		return err
+	}
+
+	texts := make(map[int64][]string)
+	for _, sql := range v19SearchTexts {
+		rows, err := tx.Raw(sql).Rows()
+		if err != nil {
+			return err
+		}
+
+		for rows.Next() {
+			var id int64
+			var text string
+			if err := rows.Scan(&id, &text); err != nil {
+				rows.Close()
+				return err
+			}
+
+			texts[id] = append(texts[id], text)
+		}
+
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+	}
+
+	for id, t := range texts {
+		grams := make([]v19TodoSearchGram, 0)
+		for _, gram := range entity.SearchGrams(t...) {
+			grams = append(grams, v19TodoSearchGram{Gram: gram, TodoID: id})
+		}
+
+		if len(grams) == 0 {
+			continue
+		}
+
+		if err := tx.CreateInBatches(grams, 100).Error; err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func v19Down(tx *gorm.DB) error {
+	if v19FullTextIndexed(tx) {
+		return nil
+	}
+
+	return tx.Migrator().DropTable(&v19TodoSearchGram{})
+}
+
+func v19FullTextIndexed(tx *gorm.DB) bool {
+	_, ok := v5FullTextIndexes[tx.Dialector.Name()]
+	return ok
+}
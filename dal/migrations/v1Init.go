@@ -0,0 +1,204 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// Snapshots of entities, which are equivalent to the schema created by
+// AutoMigrate before, so that it is safe to apply on existing database.
+
+type v1Entity struct {
+	ID        int64     `gorm:"primaryKey"`
+	CreatedAt time.Time `gorm:"not null"`
+	UpdatedAt time.Time
+	DeletedAt gorm.DeletedAt `gorm:"index"`
+}
+
+type v1File struct {
+	Entity v1Entity `gorm:"embedded"`
+
+	FileName     string
+	FileServerID string `gorm:"size:15"`
+	FilePath     string `gorm:"size:128"`
+	AccessType   int8
+	RelatedID    int64
+}
+
+type v1User struct {
+	Entity v1Entity `gorm:"embedded"`
+
+	Name            string `gorm:"size:128;index:,unique,priority:11;"`
+	Nickname        string `gorm:"size:128"`
+	Password        []byte `gorm:"size:32;"`
+	Email           string `gorm:"size:32;"`
+	Telephone       string `gorm:"size:16;"`
+	Avatar          string
+	GithubID        int64  `gorm:"index:,unique,priority:12"`
+	Timezone        string `gorm:"size:64"`
+	BasicTodoListID int64
+}
+
+type v1UserInvalidRefreshToken struct {
+	Entity v1Entity `gorm:"embedded"`
+
+	UserID  int64
+	TokenID string `gorm:"size:36"`
+}
+
+type v1Todo struct {
+	Entity v1Entity `gorm:"embedded"`
+
+	Title            string `gorm:"size:128"`
+	Memo             string
+	Importance       bool
+	Deadline         *time.Time
+	Notified         bool
+	NotifyAt         *time.Time
+	Done             bool
+	DoneAt           *time.Time
+	UserID           int64
+	TodoListID       int64
+	TodoRepeatPlanID int64
+	NextID           *int64
+}
+
+type v1TodoStep struct {
+	Entity v1Entity `gorm:"embedded"`
+
+	Done   bool
+	DoneAt *time.Time
+	TodoID int64
+}
+
+type v1TodoRepeatPlan struct {
+	Entity v1Entity `gorm:"embedded"`
+
+	Type     string `gorm:"size:8"`
+	Interval int
+	Before   *time.Time
+	Weekday  int8
+}
+
+type v1DailyTodo struct {
+	Entity v1Entity `gorm:"embedded"`
+
+	Date   string `gorm:"size:10;index:idx_daily_todos_user_date"`
+	UserID int64  `gorm:"index:idx_daily_todos_user_date"`
+	TodoID int64
+}
+
+type v1TodoList struct {
+	Entity v1Entity `gorm:"embedded"`
+
+	Name             string `gorm:"size:128"`
+	IsBasic          bool
+	IsSharing        bool
+	UserID           int64
+	TodoListFolderID int64
+}
+
+type v1TodoListFolder struct {
+	Entity v1Entity `gorm:"embedded"`
+
+	Name   string `gorm:"size:128"`
+	UserID int64
+}
+
+type v1Tag struct {
+	Entity v1Entity `gorm:"embedded"`
+
+	Name   string `gorm:"size:32;index:idx_tags_user,unique"`
+	UserID int64  `gorm:"index:idx_tags_user,unique"`
+}
+
+type v1Sharing struct {
+	Entity v1Entity `gorm:"embedded"`
+
+	Token     string `gorm:"size:128;uniqueIndex"`
+	Active    bool
+	Type      int8
+	RelatedID int64
+	UserID    int64
+}
+
+type v1ThirdPartyOAuthToken struct {
+	Entity v1Entity `gorm:"embedded"`
+
+	Active bool
+	Type   int8   `gorm:"index:idx_third_party_oauth_tokens_user,unique"`
+	Token  string `gorm:"size:128"`
+	Scope  string `gorm:"size:32"`
+	UserID int64  `gorm:"index:idx_third_party_oauth_tokens_user,unique"`
+}
+
+// Join tables of many2many associations
+
+type v1TagTodo struct {
+	TagID  int64 `gorm:"primaryKey"`
+	TodoID int64 `gorm:"primaryKey"`
+}
+
+type v1TodoFile struct {
+	TodoID int64 `gorm:"primaryKey"`
+	FileID int64 `gorm:"primaryKey"`
+}
+
+type v1TodoListSharedUser struct {
+	UserID     int64 `gorm:"primaryKey"`
+	TodoListID int64 `gorm:"primaryKey"`
+}
+
+func (v1File) TableName() string                    { return "files" }
+func (v1User) TableName() string                    { return "users" }
+func (v1UserInvalidRefreshToken) TableName() string { return "user_invalid_refresh_tokens" }
+func (v1Todo) TableName() string                    { return "todos" }
+func (v1TodoStep) TableName() string                { return "todo_steps" }
+func (v1TodoRepeatPlan) TableName() string          { return "todo_repeat_plans" }
+func (v1DailyTodo) TableName() string               { return "daily_todos" }
+func (v1TodoList) TableName() string                { return "todo_lists" }
+func (v1TodoListFolder) TableName() string          { return "todo_list_folders" }
+func (v1Tag) TableName() string                     { return "tags" }
+func (v1Sharing) TableName() string                 { return "sharings" }
+func (v1ThirdPartyOAuthToken) TableName() string    { return "third_party_oauth_tokens" }
+func (v1TagTodo) TableName() string                 { return "tag_todos" }
+func (v1TodoFile) TableName() string                { return "todo_files" }
+func (v1TodoListSharedUser) TableName() string      { return "todo_list_shared_users" }
+
+func v1Tables() []interface{} {
+	return []interface{}{
+		&v1File{},
+
+		&v1User{},
+		&v1UserInvalidRefreshToken{},
+
+		&v1Todo{},
+		&v1TodoStep{},
+		&v1TodoRepeatPlan{},
+		&v1DailyTodo{},
+
+		&v1TodoList{},
+		&v1TodoListFolder{},
+
+		&v1Tag{},
+
+		&v1Sharing{},
+
+		&v1ThirdPartyOAuthToken{},
+
+		&v1TagTodo{},
+		&v1TodoFile{},
+		&v1TodoListSharedUser{},
+	}
+}
+
+// Use AutoMigrate instead of CreateTable, as tables may have been
+// created before versioned migrations were introduced
+func v1Up(tx *gorm.DB) error {
+	return tx.AutoMigrate(v1Tables()...)
+}
+
+func v1Down(tx *gorm.DB) error {
+	return tx.Migrator().DropTable(v1Tables()...)
+}
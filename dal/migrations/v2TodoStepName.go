@@ -0,0 +1,17 @@
+package migrations
+
+import "gorm.io/gorm"
+
+type v2TodoStep struct {
+	Name string `gorm:"size:128"`
+}
+
+func (v2TodoStep) TableName() string { return "todo_steps" }
+
+func v2Up(tx *gorm.DB) error {
+	return tx.Migrator().AddColumn(&v2TodoStep{}, "Name")
+}
+
+func v2Down(tx *gorm.DB) error {
+	return tx.Migrator().DropColumn(&v2TodoStep{}, "Name")
+}
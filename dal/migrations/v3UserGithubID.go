@@ -0,0 +1,31 @@
+package migrations
+
+import "gorm.io/gorm"
+
+// Github ID is 0 for users registered by password, so it can not be unique
+type v3User struct {
+	GithubID int64 `gorm:"index"`
+}
+
+type v3UserUnique struct {
+	GithubID int64 `gorm:"index:,unique"`
+}
+
+func (v3User) TableName() string       { return "users" }
+func (v3UserUnique) TableName() string { return "users" }
+
+func v3Up(tx *gorm.DB) error {
+	if err := tx.Migrator().DropIndex(&v3UserUnique{}, "idx_users_github_id"); err != nil {
+		return err
+	}
+
+	return tx.Migrator().CreateIndex(&v3User{}, "GithubID")
+}
+
+func v3Down(tx *gorm.DB) error {
+	if err := tx.Migrator().DropIndex(&v3User{}, "idx_users_github_id"); err != nil {
+		return err
+	}
+
+	return tx.Migrator().CreateIndex(&v3UserUnique{}, "GithubID")
+}
@@ -0,0 +1,39 @@
+package migrations
+
+import "gorm.io/gorm"
+
+type v4Todo struct {
+	Version int64 `gorm:"not null;default:1"`
+}
+
+type v4TodoStep struct {
+	Version int64 `gorm:"not null;default:1"`
+}
+
+type v4TodoList struct {
+	Version int64 `gorm:"not null;default:1"`
+}
+
+func (v4Todo) TableName() string     { return "todos" }
+func (v4TodoStep) TableName() string { return "todo_steps" }
+func (v4TodoList) TableName() string { return "todo_lists" }
+
+func v4Up(tx *gorm.DB) error {
+	for _, model := range []interface{}{&v4Todo{}, &v4TodoStep{}, &v4TodoList{}} {
+		if err := tx.Migrator().AddColumn(model, "Version"); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func v4Down(tx *gorm.DB) error {
+	for _, model := range []interface{}{&v4Todo{}, &v4TodoStep{}, &v4TodoList{}} {
+		if err := tx.Migrator().DropColumn(model, "Version"); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,48 @@
+package migrations
+
+import "gorm.io/gorm"
+
+// Full-text indexes for searching, SQLite falls back to scanning
+var v5FullTextIndexes = map[string][]string{
+	"mysql": {
+		"CREATE FULLTEXT INDEX idx_todos_full_text ON todos (title, memo)",
+		"CREATE FULLTEXT INDEX idx_todo_steps_full_text ON todo_steps (name)",
+		"CREATE FULLTEXT INDEX idx_files_full_text ON files (file_name)",
+	},
+	"postgres": {
+		"CREATE INDEX idx_todos_full_text ON todos USING GIN (to_tsvector('simple', coalesce(title, '') || ' ' || coalesce(memo, '')))",
+		"CREATE INDEX idx_todo_steps_full_text ON todo_steps USING GIN (to_tsvector('simple', coalesce(name, '')))",
+		"CREATE INDEX idx_files_full_text ON files USING GIN (to_tsvector('simple', coalesce(file_name, '')))",
+	},
+}
+
+var v5FullTextIndexDrops = map[string][]string{
+	"mysql": {
+		"DROP INDEX idx_todos_full_text ON todos",
+		"DROP INDEX idx_todo_steps_full_text ON todo_steps",
+		"DROP INDEX idx_files_full_text ON files",
+	},
+	"postgres": {
+		"DROP INDEX idx_todos_full_text",
+		"DROP INDEX idx_todo_steps_full_text",
+		"DROP INDEX idx_files_full_text",
+	},
+}
+
+func v5Up(tx *gorm.DB) error {
+	return v5Exec(tx, v5FullTextIndexes[tx.Dialector.Name()])
+}
+
+func v5Down(tx *gorm.DB) error {
+	return v5Exec(tx, v5FullTextIndexDrops[tx.Dialector.Name()])
+}
+
+func v5Exec(tx *gorm.DB, statements []string) error {
+	for _, sql := range statements {
+		if err := tx.Exec(sql).Error; err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,28 @@
+package migrations
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type v6Notification struct {
+	Entity v1Entity `gorm:"embedded"`
+
+	Title    string `gorm:"size:128"`
+	NotifyAt time.Time
+	Read     bool `gorm:"column:is_read"`
+	ReadAt   *time.Time
+	UserID   int64 `gorm:"index"`
+	TodoID   int64
+}
+
+func (v6Notification) TableName() string { return "notifications" }
+
+func v6Up(tx *gorm.DB) error {
+	return tx.Migrator().CreateTable(&v6Notification{})
+}
+
+func v6Down(tx *gorm.DB) error {
+	return tx.Migrator().DropTable(&v6Notification{})
+}
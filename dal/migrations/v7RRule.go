@@ -0,0 +1,17 @@
+package migrations
+
+import "gorm.io/gorm"
+
+type v7TodoRepeatPlan struct {
+	RRule string `gorm:"column:rrule;size:1024"`
+}
+
+func (v7TodoRepeatPlan) TableName() string { return "todo_repeat_plans" }
+
+func v7Up(tx *gorm.DB) error {
+	return tx.Migrator().AddColumn(&v7TodoRepeatPlan{}, "RRule")
+}
+
+func v7Down(tx *gorm.DB) error {
+	return tx.Migrator().DropColumn(&v7TodoRepeatPlan{}, "RRule")
+}
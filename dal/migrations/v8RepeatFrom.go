@@ -0,0 +1,17 @@
+package migrations
+
+import "gorm.io/gorm"
+
+type v8TodoRepeatPlan struct {
+	RepeatFrom string `gorm:"size:16"`
+}
+
+func (v8TodoRepeatPlan) TableName() string { return "todo_repeat_plans" }
+
+func v8Up(tx *gorm.DB) error {
+	return tx.Migrator().AddColumn(&v8TodoRepeatPlan{}, "RepeatFrom")
+}
+
+func v8Down(tx *gorm.DB) error {
+	return tx.Migrator().DropColumn(&v8TodoRepeatPlan{}, "RepeatFrom")
+}
@@ -0,0 +1,17 @@
+package migrations
+
+import "gorm.io/gorm"
+
+type v9Tag struct {
+	Color string `gorm:"size:16"`
+}
+
+func (v9Tag) TableName() string { return "tags" }
+
+func v9Up(tx *gorm.DB) error {
+	return tx.Migrator().AddColumn(&v9Tag{}, "Color")
+}
+
+func v9Down(tx *gorm.DB) error {
+	return tx.Migrator().DropColumn(&v9Tag{}, "Color")
+}
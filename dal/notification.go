@@ -0,0 +1,53 @@
+package dal
+
+import (
+	"time"
+
+	"github.com/yzx9/otodo/model/entity"
+	"github.com/yzx9/otodo/util"
+)
+
+type NotificationRepository interface {
+	InsertNotification(notification *entity.Notification) error
+	SelectNotification(id int64) (entity.Notification, error)
+	SelectNotifications(userID int64) ([]entity.Notification, error)
+	ReadNotification(id int64) error
+	ReadAllNotifications(userID int64) (int64, error)
+}
+
+func (r *gormRepository) InsertNotification(notification *entity.Notification) error {
+	re := r.db.Create(notification)
+	return util.WrapGormErr(re.Error, "notification")
+}
+
+func (r *gormRepository) SelectNotification(id int64) (entity.Notification, error) {
+	var notification entity.Notification
+	re := r.db.Where("id = ?", id).First(&notification)
+	return notification, util.WrapGormErr(re.Error, "notification")
+}
+
+// Select notifications of user, newest first
+func (r *gormRepository) SelectNotifications(userID int64) ([]entity.Notification, error) {
+	var notifications []entity.Notification
+	re := r.db.
+		Where(entity.Notification{UserID: userID}).
+		Order("id DESC").
+		Find(&notifications)
+	return notifications, util.WrapGormErr(re.Error, "notifications")
+}
+
+func (r *gormRepository) ReadNotification(id int64) error {
+	re := r.db.
+		Model(&entity.Notification{Entity: entity.Entity{ID: id}}).
+		Where("is_read = ?", false).
+		Updates(map[string]interface{}{"is_read": true, "read_at": time.Now()})
+	return util.WrapGormErr(re.Error, "notification")
+}
+
+func (r *gormRepository) ReadAllNotifications(userID int64) (int64, error) {
+	re := r.db.
+		Model(&entity.Notification{}).
+		Where("user_id = ? AND is_read = ?", userID, false).
+		Updates(map[string]interface{}{"is_read": true, "read_at": time.Now()})
+	return re.RowsAffected, util.WrapGormErr(re.Error, "notifications")
+}
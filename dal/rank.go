@@ -0,0 +1,46 @@
+package dal
+
+import (
+	"github.com/yzx9/otodo/model/entity"
+	"github.com/yzx9/otodo/util"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type RankRepository interface {
+	SelectRanks(userID int64, rankType entity.RankType, relatedIDs []int64) (map[int64]string, error)
+	SaveRank(rank *entity.Rank) error
+}
+
+// Rank keys of items, indexed by related id. Unranked items are absent
+func (r *gormRepository) SelectRanks(userID int64, rankType entity.RankType, relatedIDs []int64) (map[int64]string, error) {
+	keys := make(map[int64]string)
+	if len(relatedIDs) == 0 {
+		return keys, nil
+	}
+
+	var ranks []entity.Rank
+	re := r.db.
+		Where("user_id = ? AND type = ? AND related_id IN ?", userID, rankType, relatedIDs).
+		Find(&ranks)
+	for _, rank := range ranks {
+		keys[rank.RelatedID] = rank.Key
+	}
+	return keys, util.WrapGormErr(re.Error, "ranks")
+}
+
+// Create or update rank of item
+func (r *gormRepository) SaveRank(rank *entity.Rank) error {
+	where := entity.Rank{UserID: rank.UserID, Type: rank.Type, RelatedID: rank.RelatedID}
+	re := r.db.
+		Omit(clause.Associations).
+		Where(&where).
+		Assign(entity.Rank{Key: rank.Key}).
+		FirstOrCreate(rank)
+	return util.WrapGormErr(re.Error, "rank")
+}
+
+// Hard delete ranks of all users of items, relatedIDs is a subquery
+func purgeRanks(tx *gorm.DB, rankType entity.RankType, relatedIDs *gorm.DB) error {
+	return tx.Exec("DELETE FROM ranks WHERE type = ? AND related_id IN (?)", rankType, relatedIDs).Error
+}
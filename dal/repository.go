@@ -0,0 +1,49 @@
+package dal
+
+import (
+	"github.com/yzx9/otodo/util"
+	"gorm.io/gorm"
+)
+
+// Repository is the whole data access layer used by bll, there are two
+// implementations: GORM in this package, and memory in dal/memory.
+type Repository interface {
+	FileRepository
+	UserRepository
+	UserInvalidRefreshTokenRepository
+	ThirdPartyOAuthTokenRepository
+	TodoRepository
+	TodoMoveRepository
+	TodoStepRepository
+	TodoRepeatPlanRepository
+	DailyTodoRepository
+	TodoListRepository
+	TodoListInvitationRepository
+	TodoListFolderRepository
+	SharingRepository
+	TagRepository
+	NotificationRepository
+	RankRepository
+
+	// Transaction runs fn within a transaction, which is committed if fn
+	// returns nil, otherwise rolled back. Nested calls use savepoints.
+	Transaction(fn func(r Repository) error) error
+}
+
+type gormRepository struct {
+	db *gorm.DB
+}
+
+func NewGormRepository(db *gorm.DB) Repository {
+	return &gormRepository{db: db}
+}
+
+func (r *gormRepository) Transaction(fn func(r Repository) error) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		return fn(&gormRepository{db: tx})
+	})
+}
+
+func versionConflict(resource string) error {
+	return util.NewErrorWithPreconditionFailed("%v has been modified by others", resource)
+}
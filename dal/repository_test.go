@@ -0,0 +1,31 @@
+package dal_test
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/yzx9/otodo/dal"
+	"github.com/yzx9/otodo/dal/daltest"
+	"github.com/yzx9/otodo/otodo"
+)
+
+func TestGormRepository(t *testing.T) {
+	daltest.Run(t, func(t *testing.T) dal.Repository {
+		// Shared cache is required since gorm uses a connection pool, and
+		// each test gets a fresh database by its unique name
+		name := strings.ReplaceAll(t.Name(), "/", "_")
+		otodo.Conf.Database = otodo.ConfigDatabase{
+			Driver:       otodo.DatabaseDriverSQLite,
+			DatabaseName: fmt.Sprintf("file:%v?mode=memory&cache=shared", name),
+			AutoMigrate:  true,
+		}
+
+		r, err := dal.Init()
+		if err != nil {
+			t.Fatalf("fails to init database: %v", err)
+		}
+
+		return r
+	})
+}
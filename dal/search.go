@@ -0,0 +1,218 @@
+package dal
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/yzx9/otodo/model/dto"
+	"github.com/yzx9/otodo/model/entity"
+	"github.com/yzx9/otodo/util"
+	"gorm.io/gorm"
+)
+
+// Search todos by terms and operators, newest first. Full-text index is
+// used on MySQL and PostgreSQL, and the built-in index of grams on others.
+// Terms which the index cannot match fall back to scanning.
+func (r *gormRepository) SearchTodos(search dto.TodoSearch) ([]entity.Todo, error) {
+	todos := make([]entity.Todo, 0)
+	if len(search.TodoListIDs) == 0 && len(search.SharedIDs) == 0 {
+		return todos, nil
+	}
+
+	db := r.db.
+		Scopes(todoPreload).
+		Where(todoInListsOrShared(search.TodoListIDs, search.SharedIDs))
+
+	for _, term := range search.Terms {
+		cond := todoSearchTerm(r.db.Dialector.Name(), term)
+		db = db.Where(cond.sql, cond.vars...)
+	}
+
+	for _, tag := range search.Tags {
+		db = db.Where("id IN (?)", todoTagged(db, search.TagUserID, tag))
+	}
+
+	if search.Done != nil {
+		db = db.Where("done = ?", *search.Done)
+	}
+	if search.Importance != nil {
+		db = db.Where("importance = ?", *search.Importance)
+	}
+	if search.DeadlineFrom != nil {
+		db = db.Where("deadline >= ?", *search.DeadlineFrom)
+	}
+	if search.DeadlineTo != nil {
+		db = db.Where("deadline < ?", *search.DeadlineTo)
+	}
+	if search.Limit > 0 {
+		db = db.Limit(search.Limit)
+	}
+
+	re := db.Order("id DESC").Find(&todos)
+	return todos, util.WrapGormErr(re.Error, "todos")
+}
+
+/**
+ * Helpers
+ */
+
+// Condition of a term matching title, memo, steps or files of todo
+func todoSearchTerm(dialect, term string) condition {
+	match := func(columns ...string) condition {
+		return todoSearchMatch(dialect, term, columns...)
+	}
+
+	title := match("todos.title", "todos.memo")
+	step := match("todo_steps.name")
+	file := match("files.file_name")
+
+	sql := fmt.Sprintf("(%v OR EXISTS (%v) OR EXISTS (%v))",
+		title.sql,
+		"SELECT 1 FROM todo_steps WHERE todo_steps.todo_id = todos.id AND todo_steps.deleted_at IS NULL AND "+step.sql,
+		"SELECT 1 FROM todo_files JOIN files ON files.id = todo_files.file_id WHERE todo_files.todo_id = todos.id AND files.deleted_at IS NULL AND "+file.sql,
+	)
+
+	vars := append(append(title.vars, step.vars...), file.vars...)
+
+	// candidates of built-in index, which are checked by scanning
+	if grams := entity.SearchGrams(term); gramIndexed(dialect) && len(grams) > 0 {
+		sql = "todos.id IN (SELECT todo_id FROM todo_search_grams WHERE gram IN ? GROUP BY todo_id HAVING COUNT(*) = ?) AND " + sql
+		vars = append([]interface{}{grams, len(grams)}, vars...)
+	}
+
+	return condition{sql, vars}
+}
+
+// The expressions must be same as indexes, see migration v5 and v18. Terms
+// which the index cannot match fall back to scanning with LIKE.
+func todoSearchMatch(dialect, term string, columns ...string) condition {
+	if !fullTextMatchable(dialect, term) {
+		return todoSearchLike(dialect, term, columns...)
+	}
+
+	switch dialect {
+	case "mysql":
+		// quote as phrase, so that operators of boolean mode are ignored
+		phrase := `"` + strings.ReplaceAll(term, `"`, " ") + `"`
+		return condition{
+			fmt.Sprintf("MATCH (%v) AGAINST (? IN BOOLEAN MODE)", strings.Join(columns, ", ")),
+			[]interface{}{phrase},
+		}
+
+	default: // postgres
+		exprs := make([]string, 0, len(columns))
+		for _, column := range columns {
+			exprs = append(exprs, fmt.Sprintf("coalesce(%v, '')", column))
+		}
+		return condition{
+			fmt.Sprintf("to_tsvector('simple', %v) @@ plainto_tsquery('simple', ?)", strings.Join(exprs, " || ' ' || ")),
+			[]interface{}{term},
+		}
+	}
+}
+
+func todoSearchLike(dialect, term string, columns ...string) condition {
+	// backslash is an escape character in string literals of MySQL
+	escape := `'\'`
+	if dialect == "mysql" {
+		escape = `'\\'`
+	}
+
+	pattern := "%" + escapeLike(strings.ToLower(term)) + "%"
+	conds := make([]string, 0, len(columns))
+	vars := make([]interface{}, 0, len(columns))
+	for _, column := range columns {
+		conds = append(conds, fmt.Sprintf("LOWER(%v) LIKE ? ESCAPE %v", column, escape))
+		vars = append(vars, pattern)
+	}
+	return condition{"(" + strings.Join(conds, " OR ") + ")", vars}
+}
+
+// Whether the full-text index can match the term. The ngram parser of MySQL
+// skips terms shorter than its token size, and PostgreSQL never splits CJK
+// text into words.
+func fullTextMatchable(dialect, term string) bool {
+	switch dialect {
+	case "mysql":
+		return utf8.RuneCountInString(term) >= mysqlNgramTokenSize
+
+	case "postgres":
+		for _, r := range term {
+			if unicode.In(r, unicode.Han, unicode.Hiragana, unicode.Katakana, unicode.Hangul) {
+				return false
+			}
+		}
+		return true
+
+	default:
+		return false
+	}
+}
+
+// Index searchable text of todos into the built-in index, on databases
+// without full-text index. Should be called in transaction after the text
+// changed, see migration v19.
+func indexTodoSearch(tx *gorm.DB, todoIDs ...int64) error {
+	if !gramIndexed(tx.Dialector.Name()) {
+		return nil
+	}
+
+	for _, id := range todoIDs {
+		var todo entity.Todo
+		if err := tx.Unscoped().Select("title", "memo").Where("id = ?", id).Take(&todo).Error; err != nil {
+			return err
+		}
+
+		var steps, files []string
+		if err := tx.Model(&entity.TodoStep{}).Where("todo_id = ?", id).Pluck("name", &steps).Error; err != nil {
+			return err
+		}
+
+		err := tx.Model(&entity.File{}).
+			Joins("JOIN todo_files ON todo_files.file_id = files.id").
+			Where("todo_files.todo_id = ?", id).
+			Pluck("file_name", &files).
+			Error
+		if err != nil {
+			return err
+		}
+
+		if err := tx.Where("todo_id = ?", id).Delete(&entity.TodoSearchGram{}).Error; err != nil {
+			return err
+		}
+
+		grams := make([]entity.TodoSearchGram, 0)
+		for _, gram := range entity.SearchGrams(append(append([]string{todo.Title, todo.Memo}, steps...), files...)...) {
+			grams = append(grams, entity.TodoSearchGram{Gram: gram, TodoID: id})
+		}
+
+		if len(grams) == 0 {
+			continue
+		}
+
+		if err := tx.CreateInBatches(grams, 100).Error; err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// Whether the built-in index is used instead of full-text index
+func gramIndexed(dialect string) bool {
+	return dialect != "mysql" && dialect != "postgres"
+}
+
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
+
+// Default ngram_token_size of MySQL
+const mysqlNgramTokenSize = 2
+
+type condition struct {
+	sql  string
+	vars []interface{}
+}
@@ -0,0 +1,34 @@
+package dal
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestTodoSearchMatch(t *testing.T) {
+	tests := []struct {
+		dialect  string
+		term     string
+		fullText bool
+	}{
+		{"mysql", "milk", true},
+		{"mysql", "牛奶", true},
+		{"mysql", "牛", false},
+		{"mysql", "x", false},
+		{"postgres", "tv", true},
+		{"postgres", "牛奶", false},
+		{"postgres", "にんじん", false},
+		{"sqlite", "milk", false},
+	}
+
+	for _, tt := range tests {
+		cond := todoSearchMatch(tt.dialect, tt.term, "todos.title")
+		if fullText := !strings.Contains(cond.sql, "LIKE"); fullText != tt.fullText {
+			t.Errorf("todoSearchMatch(%v, %v) = %v, want full-text %v", tt.dialect, tt.term, cond.sql, tt.fullText)
+		}
+	}
+
+	if cond := todoSearchLike("mysql", "x", "todos.title"); !strings.HasSuffix(cond.sql, `ESCAPE '\\')`) {
+		t.Errorf("escape of MySQL = %v, want escaped backslash", cond.sql)
+	}
+}
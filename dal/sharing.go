@@ -3,31 +3,48 @@ package dal
 import (
 	"github.com/yzx9/otodo/model/entity"
 	"github.com/yzx9/otodo/util"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
-func InsertSharing(sharing *entity.Sharing) error {
-	re := db.Create(sharing)
+type SharingRepository interface {
+	InsertSharing(sharing *entity.Sharing) error
+	SelectSharing(token string) (entity.Sharing, error)
+	SelectSharings(userID int64, sharingType entity.SharingType) ([]entity.Sharing, error)
+	SelectActiveSharings(userID int64, sharingType entity.SharingType) ([]entity.Sharing, error)
+	SaveSharing(sharing *entity.Sharing) error
+	ExistActiveSharing(userID int64, sharingType entity.SharingType) (bool, error)
+	DeleteSharings(userID int64, sharingType entity.SharingType) (int64, error)
+	SelectActiveRelatedSharings(sharingType entity.SharingType, relatedID int64) ([]entity.Sharing, error)
+
+	InsertSharingRedemption(redemption *entity.SharingRedemption) error
+	SelectSharingRedemptions(sharingID int64) ([]entity.SharingRedemption, error)
+	CountSharingRedemptions(sharingID int64) (int64, error)
+}
+
+func (r *gormRepository) InsertSharing(sharing *entity.Sharing) error {
+	re := r.db.Omit(clause.Associations).Create(sharing)
 	return util.WrapGormErr(re.Error, "sharing")
 }
 
-func SelectSharing(token string) (entity.Sharing, error) {
+func (r *gormRepository) SelectSharing(token string) (entity.Sharing, error) {
 	var sharing entity.Sharing
-	re := db.Where(&entity.Sharing{Token: token}).First(&sharing)
+	re := r.db.Where(&entity.Sharing{Token: token}).First(&sharing)
 	return sharing, util.WrapGormErr(re.Error, "sharing")
 }
 
-func SelectSharings(userID int64, sharingType entity.SharingType) ([]entity.Sharing, error) {
+func (r *gormRepository) SelectSharings(userID int64, sharingType entity.SharingType) ([]entity.Sharing, error) {
 	var sharings []entity.Sharing
-	re := db.Where(&entity.Sharing{
+	re := r.db.Where(&entity.Sharing{
 		UserID: userID,
 		Type:   sharingType,
 	}).Find(&sharings)
 	return sharings, util.WrapGormErr(re.Error, "sharing")
 }
 
-func SelectActiveSharings(userID int64, sharingType entity.SharingType) ([]entity.Sharing, error) {
+func (r *gormRepository) SelectActiveSharings(userID int64, sharingType entity.SharingType) ([]entity.Sharing, error) {
 	var sharings []entity.Sharing
-	re := db.Where(&entity.Sharing{
+	re := r.db.Where(&entity.Sharing{
 		UserID: userID,
 		Type:   sharingType,
 		Active: true,
@@ -35,27 +52,91 @@ func SelectActiveSharings(userID int64, sharingType entity.SharingType) ([]entit
 	return sharings, util.WrapGormErr(re.Error, "sharing")
 }
 
-func SaveSharing(sharing *entity.Sharing) error {
-	re := db.Save(&sharing)
+func (r *gormRepository) SaveSharing(sharing *entity.Sharing) error {
+	re := r.db.Omit(clause.Associations).Save(sharing)
 	return util.WrapGormErr(re.Error, "sharing")
 }
 
-func ExistActiveSharing(userID int64, sharingType entity.SharingType) (bool, error) {
+func (r *gormRepository) ExistActiveSharing(userID int64, sharingType entity.SharingType) (bool, error) {
 	var count int64
-	re := db.Where(&entity.Sharing{
-		UserID: userID,
-		Type:   sharingType,
-		Active: true,
-	}).Count(&count)
+	re := r.db.
+		Model(&entity.Sharing{}).
+		Where(&entity.Sharing{
+			UserID: userID,
+			Type:   sharingType,
+			Active: true,
+		}).
+		Count(&count)
 	return count != 0, util.WrapGormErr(re.Error, "sharing")
 }
 
-func DeleteSharings(userID int64, sharingType entity.SharingType) (int64, error) {
+func (r *gormRepository) DeleteSharings(userID int64, sharingType entity.SharingType) (int64, error) {
 	// Here we inactive sharing instead of not delete
-	re := db.Where(entity.Sharing{
-		UserID: userID,
-		Type:   sharingType,
-		Active: true,
-	}).Update("active", false)
+	re := r.db.
+		Model(&entity.Sharing{}).
+		Where(entity.Sharing{
+			UserID: userID,
+			Type:   sharingType,
+			Active: true,
+		}).
+		Update("active", false)
 	return re.RowsAffected, util.WrapGormErr(re.Error, "sharing")
 }
+
+// Select active sharings of resource, e.g. todo list, created by anyone
+func (r *gormRepository) SelectActiveRelatedSharings(sharingType entity.SharingType, relatedID int64) ([]entity.Sharing, error) {
+	var sharings []entity.Sharing
+	re := r.db.
+		Where(&entity.Sharing{
+			Type:      sharingType,
+			RelatedID: relatedID,
+			Active:    true,
+		}).
+		Order("id").
+		Find(&sharings)
+	return sharings, util.WrapGormErr(re.Error, "sharing")
+}
+
+/**
+ * Redemption
+ */
+
+func (r *gormRepository) InsertSharingRedemption(redemption *entity.SharingRedemption) error {
+	re := r.db.Omit(clause.Associations).Create(redemption)
+	return util.WrapGormErr(re.Error, "sharing redemption")
+}
+
+// Select redemptions of sharing with users, in order of redemption
+func (r *gormRepository) SelectSharingRedemptions(sharingID int64) ([]entity.SharingRedemption, error) {
+	var redemptions []entity.SharingRedemption
+	re := r.db.
+		Preload("User").
+		Where(entity.SharingRedemption{SharingID: sharingID}).
+		Order("id").
+		Find(&redemptions)
+	return redemptions, util.WrapGormErr(re.Error, "sharing redemptions")
+}
+
+func (r *gormRepository) CountSharingRedemptions(sharingID int64) (int64, error) {
+	var count int64
+	re := r.db.
+		Model(&entity.SharingRedemption{}).
+		Where(entity.SharingRedemption{SharingID: sharingID}).
+		Count(&count)
+	return count, util.WrapGormErr(re.Error, "sharing redemptions")
+}
+
+/**
+ * Helpers
+ */
+
+// Hard delete sharings of items and their redemptions, relatedIDs is a
+// subquery
+func purgeSharings(tx *gorm.DB, relatedIDs *gorm.DB, types ...entity.SharingType) error {
+	ids := tx.Unscoped().Model(&entity.Sharing{}).Select("id").Where("type IN ? AND related_id IN (?)", types, relatedIDs)
+	if err := tx.Exec("DELETE FROM sharing_redemptions WHERE sharing_id IN (?)", ids).Error; err != nil {
+		return err
+	}
+
+	return tx.Exec("DELETE FROM sharings WHERE type IN ? AND related_id IN (?)", types, relatedIDs).Error
+}
@@ -4,46 +4,116 @@ import (
 	"github.com/yzx9/otodo/model/entity"
 	"github.com/yzx9/otodo/util"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
-func InsertTag(tag *entity.Tag) error {
-	re := db.Create(tag)
+type TagRepository interface {
+	InsertTag(tag *entity.Tag) error
+	SelectTag(userID int64, tagName string) (entity.Tag, error)
+	SelectTags(userID int64) ([]entity.Tag, error)
+	SaveTag(tag *entity.Tag) error
+	DeleteTag(id int64) error
+	CountTagTodos(userID int64, todoListIDs []int64) (map[int64]int64, error)
+	InsertTagTodo(userID, todoID int64, tagName string) error
+	DeleteTagTodo(userID, todoID int64, tagName string) error
+	ExistTag(userID int64, tagName string) (bool, error)
+}
+
+func (r *gormRepository) InsertTag(tag *entity.Tag) error {
+	re := r.db.Omit(clause.Associations).Create(tag)
 	return util.WrapGormErr(re.Error, "tag")
 }
 
-func SelectTag(userID int64, tagName string) (entity.Tag, error) {
+func (r *gormRepository) SelectTag(userID int64, tagName string) (entity.Tag, error) {
 	var tag entity.Tag
-	re := db.Scopes(tagScope(userID, tagName)).First(&tag)
+	re := r.db.Scopes(tagScope(userID, tagName)).First(&tag)
 	return tag, util.WrapGormErr(re.Error, "tag")
 }
 
-func SelectTags(userID int64) ([]entity.Tag, error) {
+func (r *gormRepository) SelectTags(userID int64) ([]entity.Tag, error) {
 	var tags []entity.Tag
-	re := db.Where(entity.Tag{UserID: userID}).Find(&tags)
+	re := r.db.Where(entity.Tag{UserID: userID}).Find(&tags)
 	return tags, util.WrapGormErr(re.Error, "tag")
 }
 
-func InsertTagTodo(userID, todoID int64, tagName string) error {
-	err := db.
-		Scopes(tagScope(userID, tagName)).
+func (r *gormRepository) SaveTag(tag *entity.Tag) error {
+	re := r.db.Omit(clause.Associations).Save(tag)
+	return util.WrapGormErr(re.Error, "tag")
+}
+
+// Hard delete tag and its associations, so that the name can be reused
+func (r *gormRepository) DeleteTag(id int64) error {
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec("DELETE FROM tag_todos WHERE tag_id = ?", id).Error; err != nil {
+			return err
+		}
+
+		return tx.Unscoped().Delete(&entity.Tag{Entity: entity.Entity{ID: id}}).Error
+	})
+	return util.WrapGormErr(err, "tag")
+}
+
+// Count alive todos in lists of each tag of user, keyed by tag id
+func (r *gormRepository) CountTagTodos(userID int64, todoListIDs []int64) (map[int64]int64, error) {
+	counts := make(map[int64]int64)
+	if len(todoListIDs) == 0 {
+		return counts, nil
+	}
+
+	var rows []struct {
+		TagID int64
+		Count int64
+	}
+	re := r.db.
+		Table("tag_todos").
+		Select("tag_todos.tag_id, COUNT(*) AS count").
+		Joins("JOIN tags ON tags.id = tag_todos.tag_id").
+		Joins("JOIN todos ON todos.id = tag_todos.todo_id").
+		Where("tags.user_id = ? AND tags.deleted_at IS NULL", userID).
+		Where("todos.todo_list_id IN ? AND todos.deleted_at IS NULL", todoListIDs).
+		Group("tag_todos.tag_id").
+		Scan(&rows)
+	if re.Error != nil {
+		return nil, util.WrapGormErr(re.Error, "tag todos")
+	}
+
+	for _, row := range rows {
+		counts[row.TagID] = row.Count
+	}
+	return counts, nil
+}
+
+func (r *gormRepository) InsertTagTodo(userID, todoID int64, tagName string) error {
+	tag, err := r.SelectTag(userID, tagName)
+	if err != nil {
+		return err
+	}
+
+	err = r.db.
+		Model(&tag).
 		Association("Todos").
 		Append(&entity.Todo{Entity: entity.Entity{ID: todoID}})
 
 	return util.WrapGormErr(err, "tag todos")
 }
 
-func DeleteTagTodo(userID, todoID int64, tagName string) error {
-	err := db.
-		Scopes(tagScope(userID, tagName)).
+func (r *gormRepository) DeleteTagTodo(userID, todoID int64, tagName string) error {
+	tag, err := r.SelectTag(userID, tagName)
+	if err != nil {
+		return err
+	}
+
+	err = r.db.
+		Model(&tag).
 		Association("Todos").
 		Delete(&entity.Todo{Entity: entity.Entity{ID: todoID}})
 
 	return util.WrapGormErr(err, "tag todos")
 }
 
-func ExistTag(userID int64, tagName string) (bool, error) {
+func (r *gormRepository) ExistTag(userID int64, tagName string) (bool, error) {
 	var count int64
-	re := db.Scopes(tagScope(userID, tagName)).Count(&count)
+	re := r.db.Scopes(tagScope(userID, tagName)).Count(&count)
 	if re.Error != nil {
 		return false, util.WrapGormErr(re.Error, "tag")
 	}
@@ -5,25 +5,33 @@ import (
 	"github.com/yzx9/otodo/util"
 )
 
-func InsertThirdPartyOAuthToken(entity *entity.ThirdPartyOAuthToken) error {
-	re := db.Create(entity)
+type ThirdPartyOAuthTokenRepository interface {
+	InsertThirdPartyOAuthToken(entity *entity.ThirdPartyOAuthToken) error
+	UpdateThirdPartyOAuthToken(new *entity.ThirdPartyOAuthToken) error
+	ExistActiveThirdPartyOAuthToken(userID int64, tokenType entity.ThirdPartyTokenType) (bool, error)
+}
+
+func (r *gormRepository) InsertThirdPartyOAuthToken(entity *entity.ThirdPartyOAuthToken) error {
+	re := r.db.Create(entity)
 	return util.WrapGormErr(re.Error, "third party token")
 }
 
-func UpdateThirdPartyOAuthToken(new *entity.ThirdPartyOAuthToken) error {
-	re := db.
+func (r *gormRepository) UpdateThirdPartyOAuthToken(new *entity.ThirdPartyOAuthToken) error {
+	re := r.db.
+		Model(&entity.ThirdPartyOAuthToken{}).
 		Where(&entity.ThirdPartyOAuthToken{
 			UserID: new.UserID,
 			Type:   new.Type,
 		}).
-		Save(new)
+		Select("Active", "Token", "Scope").
+		Updates(new)
 
 	return util.WrapGormErr(re.Error, "third party token")
 }
 
-func ExistActiveThirdPartyOAuthToken(userID int64, tokenType entity.ThirdPartyTokenType) (bool, error) {
+func (r *gormRepository) ExistActiveThirdPartyOAuthToken(userID int64, tokenType entity.ThirdPartyTokenType) (bool, error) {
 	var count int64
-	re := db.
+	re := r.db.
 		Model(entity.ThirdPartyOAuthToken{}).
 		Where(entity.ThirdPartyOAuthToken{
 			UserID: userID,
@@ -1,65 +1,168 @@
 package dal
 
 import (
+	"time"
+
+	"github.com/yzx9/otodo/model/dto"
 	"github.com/yzx9/otodo/model/entity"
 	"github.com/yzx9/otodo/util"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
-func InsertTodo(todo *entity.Todo) error {
-	re := db.Create(todo)
-	return util.WrapGormErr(re.Error, "todo")
+type TodoRepository interface {
+	InsertTodo(todo *entity.Todo) error
+	SelectTodo(id int64) (entity.Todo, error)
+	SelectTodos(todoListID int64) ([]entity.Todo, error)
+	SelectAllTodos(userID int64) ([]entity.Todo, error)
+	SelectImportantTodos(userID int64) ([]entity.Todo, error)
+	SelectPlanedTodos(userID int64) ([]entity.Todo, error)
+	SelectNotNotifiedTodos(userID int64) ([]entity.Todo, error)
+	SelectOverdueTodos(userID int64, before time.Time) ([]entity.Todo, error)
+	SelectTodosByQuery(query dto.TodoQuery) ([]entity.Todo, error)
+	SearchTodos(search dto.TodoSearch) ([]entity.Todo, error)
+	SelectDueReminders(now time.Time, limit int) ([]entity.Todo, error)
+	ClaimReminder(id int64, notifyAt time.Time) (bool, error)
+	SaveTodo(todo *entity.Todo) error
+	DeleteTodo(id, version int64) error // conflicts if modified
+	DeleteTodos(todoListID int64) (int64, error)
+
+	InsertTodoFile(todoID, fileID int64) error
+	SelectTodoFiles(todoID int64) ([]entity.File, error)
+
+	InsertTodoSharedUser(userID, todoID int64, role entity.TodoListRole) error
+	SelectSharedTodoIDs(userID int64) ([]int64, error)
+	SelectTodoSharedUsers(todoID int64) ([]entity.TodoSharedUser, error)
+	SelectTodoSharedUserRole(userID, todoID int64) (entity.TodoListRole, error)
+	DeleteTodoSharedUser(userID, todoID int64) error
+
+	SelectDeletedTodo(id int64) (entity.Todo, error)
+	SelectDeletedTodos(userID int64) ([]entity.Todo, error)
+	RestoreTodo(id, todoListID int64) error
+	RestoreTodos(todoListID int64, deletedSince time.Time) (int64, error)
+	PurgeTodos(deletedBefore time.Time) (int64, error)
 }
 
-func SelectTodo(id int64) (entity.Todo, error) {
+func (r *gormRepository) InsertTodo(todo *entity.Todo) error {
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit(clause.Associations).Create(todo).Error; err != nil {
+			return err
+		}
+
+		return indexTodoSearch(tx, todo.ID)
+	})
+	return util.WrapGormErr(err, "todo")
+}
+
+func (r *gormRepository) SelectTodo(id int64) (entity.Todo, error) {
 	var todo entity.Todo
 	where := entity.Todo{Entity: entity.Entity{ID: id}}
-	re := db.Scopes(todoPreload).Where(&where).First(&todo)
+	re := r.db.Scopes(todoPreload).Where(&where).First(&todo)
 	return todo, util.WrapGormErr(re.Error, "todo")
 }
 
-func SelectTodos(todoListID int64) ([]entity.Todo, error) {
+func (r *gormRepository) SelectTodos(todoListID int64) ([]entity.Todo, error) {
 	var todos []entity.Todo
-	re := db.Scopes(todoPreload).Where(entity.Todo{TodoListID: todoListID}).Find(&todos)
+	re := r.db.Scopes(todoPreload).Where(entity.Todo{TodoListID: todoListID}).Find(&todos)
 	return todos, util.WrapGormErr(re.Error, "todos")
 }
 
-func SelectAllTodos(userID int64) ([]entity.Todo, error) {
+func (r *gormRepository) SelectAllTodos(userID int64) ([]entity.Todo, error) {
 	var todos []entity.Todo
-	re := db.Scopes(todoUser(userID)).Find(&todos)
+	re := r.db.Scopes(todoUser(userID)).Find(&todos)
 	return todos, util.WrapGormErr(re.Error, "all todos")
 }
 
-func SelectImportantTodos(userID int64) ([]entity.Todo, error) {
+func (r *gormRepository) SelectImportantTodos(userID int64) ([]entity.Todo, error) {
 	var todos []entity.Todo
-	re := db.Scopes(todoUser(userID)).Where("Importance", true).Find(&todos)
+	re := r.db.Scopes(todoUser(userID)).Where("importance", true).Find(&todos)
 	return todos, util.WrapGormErr(re.Error, "important todos")
 }
 
-func SelectPlanedTodos(userID int64) ([]entity.Todo, error) {
+func (r *gormRepository) SelectPlanedTodos(userID int64) ([]entity.Todo, error) {
 	var todos []entity.Todo
-	re := db.Scopes(todoUser(userID)).Not("deadline", nil).Order("deadline").Find(&todos)
+	re := r.db.Scopes(todoUser(userID)).Not("deadline", nil).Order("deadline").Find(&todos)
 	return todos, util.WrapGormErr(re.Error, "planed todos")
 }
 
-func SelectNotNotifiedTodos(userID int64) ([]entity.Todo, error) {
+func (r *gormRepository) SelectNotNotifiedTodos(userID int64) ([]entity.Todo, error) {
 	var todos []entity.Todo
-	re := db.Scopes(todoUser(userID)).Not("notified", false).Order("notify_at").Find(&todos)
+	re := r.db.Scopes(todoUser(userID)).Where("notified = ?", false).Order("notify_at").Find(&todos)
 	return todos, util.WrapGormErr(re.Error, "not notified todos")
 }
 
-func SaveTodo(todo *entity.Todo) error {
-	re := db.Save(&todo)
-	return util.WrapGormErr(re.Error, "todo")
+func (r *gormRepository) SelectOverdueTodos(userID int64, before time.Time) ([]entity.Todo, error) {
+	var todos []entity.Todo
+	re := r.db.
+		Scopes(todoUser(userID)).
+		Where("done = ? AND deadline < ?", false, before).
+		Order("deadline").
+		Find(&todos)
+	return todos, util.WrapGormErr(re.Error, "overdue todos")
+}
+
+// Select todos whose reminder is due but not notified yet, across all users
+func (r *gormRepository) SelectDueReminders(now time.Time, limit int) ([]entity.Todo, error) {
+	var todos []entity.Todo
+	re := r.db.
+		Where("notified = ? AND done = ? AND notify_at <= ?", false, false, now).
+		Order("notify_at").
+		Limit(limit).
+		Find(&todos)
+	return todos, util.WrapGormErr(re.Error, "due reminders")
 }
 
-func DeleteTodo(id int64) error {
-	re := db.Delete(&entity.Todo{Entity: entity.Entity{ID: id}})
+// Mark reminder as notified, returns false if it has been claimed by
+// others or rescheduled, so that each reminder is notified exactly once
+// even with multiple server instances
+func (r *gormRepository) ClaimReminder(id int64, notifyAt time.Time) (bool, error) {
+	re := r.db.
+		Model(&entity.Todo{}).
+		Where("id = ? AND notified = ? AND notify_at = ?", id, false, notifyAt).
+		Updates(map[string]interface{}{"notified": true, "version": gorm.Expr("version + 1")})
+	return re.RowsAffected == 1, util.WrapGormErr(re.Error, "todo")
+}
+
+// Save todo if version matched, and bump version
+func (r *gormRepository) SaveTodo(todo *entity.Todo) error {
+	version := todo.Version
+	todo.Version++
+	conflict := false
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		re := tx.
+			Model(todo).
+			Select("*").
+			Omit(clause.Associations).
+			Where("version = ?", version).
+			Updates(todo)
+		if re.Error != nil || re.RowsAffected == 0 {
+			conflict = re.Error == nil
+			return re.Error
+		}
+
+		return indexTodoSearch(tx, todo.ID)
+	})
+	if conflict {
+		todo.Version = version
+		return versionConflict("todo")
+	}
+
+	return util.WrapGormErr(err, "todo")
+}
+
+func (r *gormRepository) DeleteTodo(id, version int64) error {
+	re := r.db.
+		Where("version = ?", version).
+		Delete(&entity.Todo{Entity: entity.Entity{ID: id}})
+	if re.Error == nil && re.RowsAffected == 0 {
+		return versionConflict("todo")
+	}
+
 	return util.WrapGormErr(re.Error, "todo")
 }
 
-func DeleteTodos(todoListID int64) (int64, error) {
-	re := db.Where(entity.Todo{TodoListID: todoListID}).Delete(entity.Todo{})
+func (r *gormRepository) DeleteTodos(todoListID int64) (int64, error) {
+	re := r.db.Where(entity.Todo{TodoListID: todoListID}).Delete(&entity.Todo{})
 	return re.RowsAffected, util.WrapGormErr(re.Error, "todo")
 }
 
@@ -67,24 +170,165 @@ func DeleteTodos(todoListID int64) (int64, error) {
  * oTodo File
  */
 
-func InsertTodoFile(todoID, fileID int64) error {
-	err := db.
-		Where(entity.Todo{Entity: entity.Entity{ID: todoID}}).
-		Association("Files").
-		Append(&entity.File{Entity: entity.Entity{ID: fileID}})
+func (r *gormRepository) InsertTodoFile(todoID, fileID int64) error {
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		err := tx.
+			Model(&entity.Todo{Entity: entity.Entity{ID: todoID}}).
+			Association("Files").
+			Append(&entity.File{Entity: entity.Entity{ID: fileID}})
+		if err != nil {
+			return err
+		}
+
+		return indexTodoSearch(tx, todoID)
+	})
 	return util.WrapGormErr(err, "todo file")
 }
 
-func SelectTodoFiles(todoID int64) ([]entity.File, error) {
+func (r *gormRepository) SelectTodoFiles(todoID int64) ([]entity.File, error) {
 	var files []entity.File
-	re := db.Where(entity.Todo{Entity: entity.Entity{ID: todoID}}).Find(&files)
-	return files, util.WrapGormErr(re.Error, "todo file")
+	err := r.db.
+		Model(&entity.Todo{Entity: entity.Entity{ID: todoID}}).
+		Association("Files").
+		Find(&files)
+	return files, util.WrapGormErr(err, "todo file")
+}
+
+/**
+ * Sharing
+ */
+
+func (r *gormRepository) InsertTodoSharedUser(userID, todoID int64, role entity.TodoListRole) error {
+	re := r.db.Omit(clause.Associations).Create(&entity.TodoSharedUser{UserID: userID, TodoID: todoID, Role: role})
+	return util.WrapGormErr(re.Error, "todo shared user")
+}
+
+// Select ids of todos shared with user, deleted todos are included
+func (r *gormRepository) SelectSharedTodoIDs(userID int64) ([]int64, error) {
+	var ids []int64
+	re := r.db.
+		Model(&entity.TodoSharedUser{}).
+		Where("user_id = ?", userID).
+		Order("todo_id").
+		Pluck("todo_id", &ids)
+	return ids, util.WrapGormErr(re.Error, "user shared todos")
+}
+
+// Select shared users of todo with roles, ordered by user id
+func (r *gormRepository) SelectTodoSharedUsers(todoID int64) ([]entity.TodoSharedUser, error) {
+	var sharedUsers []entity.TodoSharedUser
+	re := r.db.
+		Preload("User").
+		Where("todo_id = ?", todoID).
+		Order("user_id").
+		Find(&sharedUsers)
+	return sharedUsers, util.WrapGormErr(re.Error, "todo shared users")
+}
+
+func (r *gormRepository) SelectTodoSharedUserRole(userID, todoID int64) (entity.TodoListRole, error) {
+	var sharedUser entity.TodoSharedUser
+	re := r.db.
+		Where("user_id = ? AND todo_id = ?", userID, todoID).
+		First(&sharedUser)
+	return sharedUser.Role, util.WrapGormErr(re.Error, "todo shared user")
+}
+
+func (r *gormRepository) DeleteTodoSharedUser(userID, todoID int64) error {
+	re := r.db.
+		Where("user_id = ? AND todo_id = ?", userID, todoID).
+		Delete(&entity.TodoSharedUser{})
+	return util.WrapGormErr(re.Error, "todo shared user")
+}
+
+/**
+ * Trash
+ */
+
+func (r *gormRepository) SelectDeletedTodo(id int64) (entity.Todo, error) {
+	var todo entity.Todo
+	re := r.db.Unscoped().Where("id = ? AND deleted_at IS NOT NULL", id).First(&todo)
+	return todo, util.WrapGormErr(re.Error, "deleted todo")
+}
+
+// Deleted todos created by user, or in todo lists owned by user
+func (r *gormRepository) SelectDeletedTodos(userID int64) ([]entity.Todo, error) {
+	var todos []entity.Todo
+	owned := r.db.Model(&entity.TodoList{}).Select("id").Where("user_id = ?", userID)
+	re := r.db.
+		Unscoped().
+		Where("deleted_at IS NOT NULL AND (user_id = ? OR todo_list_id IN (?))", userID, owned).
+		Order("deleted_at DESC").
+		Find(&todos)
+	return todos, util.WrapGormErr(re.Error, "deleted todos")
+}
+
+func (r *gormRepository) RestoreTodo(id, todoListID int64) error {
+	re := r.db.
+		Unscoped().
+		Model(&entity.Todo{Entity: entity.Entity{ID: id}}).
+		Updates(map[string]interface{}{
+			"deleted_at":   nil,
+			"todo_list_id": todoListID,
+			"version":      gorm.Expr("version + 1"),
+		})
+	return util.WrapGormErr(re.Error, "todo")
+}
+
+func (r *gormRepository) RestoreTodos(todoListID int64, deletedSince time.Time) (int64, error) {
+	re := r.db.
+		Unscoped().
+		Model(&entity.Todo{}).
+		Where("todo_list_id = ? AND deleted_at >= ?", todoListID, deletedSince).
+		Updates(map[string]interface{}{"deleted_at": nil, "version": gorm.Expr("version + 1")})
+	return re.RowsAffected, util.WrapGormErr(re.Error, "todos")
+}
+
+// Hard delete todos and their steps and associations
+func (r *gormRepository) PurgeTodos(deletedBefore time.Time) (int64, error) {
+	var count int64
+	err := r.db.Transaction(func(tx *gorm.DB) (err error) {
+		count, err = purgeTodos(tx, "deleted_at < ?", deletedBefore)
+		return err
+	})
+	return count, util.WrapGormErr(err, "todos")
 }
 
 /**
  * Helpers
  */
 
+// Hard delete todos matching condition, and their steps, ranks, sharings
+// and associations, should be called in transaction
+func purgeTodos(tx *gorm.DB, query string, args ...interface{}) (int64, error) {
+	ids := tx.Unscoped().Model(&entity.Todo{}).Select("id").Where(query, args...)
+	steps := tx.Unscoped().Model(&entity.TodoStep{}).Select("id").Where("todo_id IN (?)", ids)
+	if err := purgeRanks(tx, entity.RankTypeTodoStep, steps); err != nil {
+		return 0, err
+	}
+
+	if err := purgeRanks(tx, entity.RankTypeTodo, ids); err != nil {
+		return 0, err
+	}
+
+	if err := purgeSharings(tx, ids, entity.SharingTypeTodo); err != nil {
+		return 0, err
+	}
+
+	tables := []string{"todo_steps", "daily_todos", "todo_files", "tag_todos", "notifications", "todo_shared_users", "todo_moves"}
+	if gramIndexed(tx.Dialector.Name()) {
+		tables = append(tables, "todo_search_grams")
+	}
+
+	for _, table := range tables {
+		if err := tx.Exec("DELETE FROM "+table+" WHERE todo_id IN (?)", ids).Error; err != nil {
+			return 0, err
+		}
+	}
+
+	re := tx.Unscoped().Where(query, args...).Delete(&entity.Todo{})
+	return re.RowsAffected, re.Error
+}
+
 func todoUser(userID int64) func(db *gorm.DB) *gorm.DB {
 	return func(db *gorm.DB) *gorm.DB {
 		return db.Where(entity.Todo{UserID: userID}).Scopes(todoPreload)
@@ -92,5 +336,5 @@ func todoUser(userID int64) func(db *gorm.DB) *gorm.DB {
 }
 
 func todoPreload(db *gorm.DB) *gorm.DB {
-	return db.Preload("Files").Preload("Steps").Preload("TodoRepeatPlan")
+	return db.Preload("Files").Preload("Steps").Preload("TodoRepeatPlan").Preload("TagList")
 }
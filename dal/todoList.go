@@ -1,59 +1,154 @@
 package dal
 
 import (
+	"time"
+
 	"github.com/yzx9/otodo/model/dto"
 	"github.com/yzx9/otodo/model/entity"
 	"github.com/yzx9/otodo/util"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
-func InsertTodoList(todoList *entity.TodoList) error {
-	re := db.Create(todoList)
+type TodoListRepository interface {
+	InsertTodoList(todoList *entity.TodoList) error
+	SelectTodoList(id int64) (entity.TodoList, error)
+	SelectTodoLists(userId int64) ([]entity.TodoList, error)
+	SelectTodoListsWithMenuFormat(userID int64) ([]dto.TodoListMenuItemRaw, error)
+	SelectTodoListsByFolders(todoListFolderIDs []int64) ([]entity.TodoList, error)
+	SelectTodoListsWithMenuFormatByFolders(todoListFolderIDs []int64) ([]dto.TodoListMenuItemRaw, error)
+	SaveTodoList(todoList *entity.TodoList) error
+	DeleteTodoList(id, version int64) error // conflicts if modified
+	DeleteTodoListsByFolder(todoListFolderID int64) (int64, error)
+	UngroupTodoListsByFolder(todoListFolderID int64) (int64, error)
+	ExistTodoList(id int64) (bool, error)
+
+	InsertTodoListSharedUser(userID, todoListID int64, role entity.TodoListRole) error
+	SelectSharedTodoLists(userID int64) ([]entity.TodoList, error)
+	SelectTodoListSharedUsers(todoListID int64) ([]entity.User, error)
+	SelectTodoListSharedUserRole(userID, todoListID int64) (entity.TodoListRole, error)
+	SelectTodoListSharedUserRoles(todoListID int64) (map[int64]entity.TodoListRole, error)
+	UpdateTodoListSharedUserRole(userID, todoListID int64, role entity.TodoListRole) error
+	DeleteTodoListSharedUser(userID, todoListID int64) error
+	ExistTodoListSharing(userID, todoListID int64) (bool, error)
+
+	SelectDeletedTodoList(id int64) (entity.TodoList, error)
+	SelectDeletedTodoLists(userID int64) ([]entity.TodoList, error)
+	RestoreTodoList(id, todoListFolderID int64) error
+	PurgeTodoLists(deletedBefore time.Time) (int64, error)
+}
+
+func (r *gormRepository) InsertTodoList(todoList *entity.TodoList) error {
+	re := r.db.Omit(clause.Associations).Create(todoList)
 	return util.WrapGormErr(re.Error, "todo list")
 }
 
-func SelectTodoList(id int64) (entity.TodoList, error) {
+func (r *gormRepository) SelectTodoList(id int64) (entity.TodoList, error) {
 	var list entity.TodoList
 	where := entity.TodoList{Entity: entity.Entity{ID: id}}
-	re := db.Where(&where).First(&list)
+	re := r.db.Where(&where).First(&list)
 	return list, util.WrapGormErr(re.Error, "todo list")
 }
 
-func SelectTodoLists(userId int64) ([]entity.TodoList, error) {
+func (r *gormRepository) SelectTodoLists(userId int64) ([]entity.TodoList, error) {
 	var lists []entity.TodoList
-	re := db.Where(entity.TodoList{UserID: userId}).Find(&lists)
+	re := r.db.Where(entity.TodoList{UserID: userId}).Find(&lists)
 	return lists, util.WrapGormErr(re.Error, "todo list")
 }
 
-func SelectTodoListsWithMenuFormat(userID int64) ([]dto.TodoListMenuItemRaw, error) {
+func (r *gormRepository) SelectTodoListsWithMenuFormat(userID int64) ([]dto.TodoListMenuItemRaw, error) {
 	var lists []dto.TodoListMenuItemRaw
-	re := db.
+	count := r.db.
+		Model(&entity.Todo{}).
+		Select("count(*)").
+		Where("todos.todo_list_id = todo_lists.id")
+	re := r.db.
 		Model(entity.TodoList{}).
 		Where(entity.TodoList{UserID: userID}).
 		Not(entity.TodoList{IsBasic: true}). // Skip basic todo list
-		Select("id", "name", "todo_list_folder_id", "(SELECT count(todos.id) FROM todos WHERE todos.todo_list_id = todo_lists.id) as count").
+		Select("id, name, todo_list_folder_id, (?) AS count", count).
 		Find(&lists)
 	return lists, util.WrapGormErr(re.Error, "todo list")
 }
 
-func SaveTodoList(todoList *entity.TodoList) error {
-	re := db.Save(&todoList)
+func (r *gormRepository) SelectTodoListsByFolders(todoListFolderIDs []int64) ([]entity.TodoList, error) {
+	var lists []entity.TodoList
+	if len(todoListFolderIDs) == 0 {
+		return lists, nil
+	}
+
+	re := r.db.Where("todo_list_folder_id IN ?", todoListFolderIDs).Find(&lists)
+	return lists, util.WrapGormErr(re.Error, "todo list")
+}
+
+func (r *gormRepository) SelectTodoListsWithMenuFormatByFolders(todoListFolderIDs []int64) ([]dto.TodoListMenuItemRaw, error) {
+	var lists []dto.TodoListMenuItemRaw
+	if len(todoListFolderIDs) == 0 {
+		return lists, nil
+	}
+
+	count := r.db.
+		Model(&entity.Todo{}).
+		Select("count(*)").
+		Where("todos.todo_list_id = todo_lists.id")
+	re := r.db.
+		Model(entity.TodoList{}).
+		Where("todo_list_folder_id IN ?", todoListFolderIDs).
+		Select("id, name, todo_list_folder_id, (?) AS count", count).
+		Find(&lists)
+	return lists, util.WrapGormErr(re.Error, "todo list")
+}
+
+// Save todo list if version matched, and bump version
+func (r *gormRepository) SaveTodoList(todoList *entity.TodoList) error {
+	version := todoList.Version
+	todoList.Version++
+	re := r.db.
+		Model(todoList).
+		Select("*").
+		Omit(clause.Associations).
+		Where("version = ?", version).
+		Updates(todoList)
+	if re.Error == nil && re.RowsAffected == 0 {
+		todoList.Version = version
+		return versionConflict("todo list")
+	}
+
 	return util.WrapGormErr(re.Error, "todo list")
 }
 
-func DeleteTodoList(id int64) error {
-	re := db.Delete(&entity.Todo{Entity: entity.Entity{ID: id}})
+func (r *gormRepository) DeleteTodoList(id, version int64) error {
+	re := r.db.
+		Where("version = ?", version).
+		Delete(&entity.TodoList{Entity: entity.Entity{ID: id}})
+	if re.Error == nil && re.RowsAffected == 0 {
+		return versionConflict("todo list")
+	}
+
 	return util.WrapGormErr(re.Error, "todo list")
 }
 
-func DeleteTodoListsByFolder(todoListFolderID int64) (int64, error) {
-	re := db.Where(entity.TodoList{TodoListFolderID: todoListFolderID}).Delete(entity.TodoList{})
+func (r *gormRepository) DeleteTodoListsByFolder(todoListFolderID int64) (int64, error) {
+	re := r.db.Where(entity.TodoList{TodoListFolderID: todoListFolderID}).Delete(&entity.TodoList{})
+	return re.RowsAffected, util.WrapGormErr(re.Error, "todo list")
+}
+
+// Move todo lists in folder to root, and bump version
+func (r *gormRepository) UngroupTodoListsByFolder(todoListFolderID int64) (int64, error) {
+	re := r.db.
+		Model(&entity.TodoList{}).
+		Where(entity.TodoList{TodoListFolderID: todoListFolderID}).
+		Updates(map[string]interface{}{
+			"todo_list_folder_id": 0,
+			"version":             gorm.Expr("version + 1"),
+		})
 	return re.RowsAffected, util.WrapGormErr(re.Error, "todo list")
 }
 
-func ExistTodoList(id int64) (bool, error) {
+func (r *gormRepository) ExistTodoList(id int64) (bool, error) {
 	var count int64
 	where := entity.TodoList{Entity: entity.Entity{ID: id}}
-	re := db.Model(&entity.TodoList{}).Where(&where).Count(&count)
+	re := r.db.Model(&entity.TodoList{}).Where(&where).Count(&count)
 	return count != 0, util.WrapGormErr(re.Error, "todo list")
 }
 
@@ -61,42 +156,128 @@ func ExistTodoList(id int64) (bool, error) {
  * Sharing
  */
 
-func InsertTodoListSharedUser(userID, todoListID int64) error {
-	user := entity.User{Entity: entity.Entity{ID: userID}}
-	list := entity.TodoList{Entity: entity.Entity{ID: todoListID}}
-	err := db.Model(&user).Association("SharedTodoLists").Append(&list)
-	return util.WrapGormErr(err, "todo list shared user")
+func (r *gormRepository) InsertTodoListSharedUser(userID, todoListID int64, role entity.TodoListRole) error {
+	re := r.db.Create(&entity.TodoListSharedUser{UserID: userID, TodoListID: todoListID, Role: role})
+	return util.WrapGormErr(re.Error, "todo list shared user")
 }
 
-func SelectSharedTodoLists(userID int64) ([]entity.TodoList, error) {
+func (r *gormRepository) SelectSharedTodoLists(userID int64) ([]entity.TodoList, error) {
 	user := entity.User{Entity: entity.Entity{ID: userID}}
 	var lists []entity.TodoList
-	err := db.Model(&user).Association("SharedTodoLists").Find(&lists)
+	err := r.db.Model(&user).Association("SharedTodoLists").Find(&lists)
 	return lists, util.WrapGormErr(err, "user shared todo list")
 }
 
-func SelectTodoListSharedUsers(todoListID int64) ([]entity.User, error) {
+func (r *gormRepository) SelectTodoListSharedUsers(todoListID int64) ([]entity.User, error) {
 	list := entity.TodoList{Entity: entity.Entity{ID: todoListID}}
 	var users []entity.User
-	err := db.Model(&list).Association("SharedUsers").Find(&users)
+	err := r.db.Model(&list).Association("SharedUsers").Find(&users)
 	return users, util.WrapGormErr(err, "todo list shared users")
 }
 
-func DeleteTodoListSharedUser(userID, todoListID int64) error {
+func (r *gormRepository) SelectTodoListSharedUserRole(userID, todoListID int64) (entity.TodoListRole, error) {
+	var sharedUser entity.TodoListSharedUser
+	re := r.db.
+		Where("user_id = ? AND todo_list_id = ?", userID, todoListID).
+		First(&sharedUser)
+	return sharedUser.Role, util.WrapGormErr(re.Error, "todo list shared user")
+}
+
+// Roles of shared users, keyed by user id
+func (r *gormRepository) SelectTodoListSharedUserRoles(todoListID int64) (map[int64]entity.TodoListRole, error) {
+	var sharedUsers []entity.TodoListSharedUser
+	re := r.db.Where("todo_list_id = ?", todoListID).Find(&sharedUsers)
+
+	roles := make(map[int64]entity.TodoListRole, len(sharedUsers))
+	for _, sharedUser := range sharedUsers {
+		roles[sharedUser.UserID] = sharedUser.Role
+	}
+	return roles, util.WrapGormErr(re.Error, "todo list shared users")
+}
+
+func (r *gormRepository) UpdateTodoListSharedUserRole(userID, todoListID int64, role entity.TodoListRole) error {
+	re := r.db.
+		Model(&entity.TodoListSharedUser{}).
+		Where("user_id = ? AND todo_list_id = ?", userID, todoListID).
+		Update("role", role)
+	return util.WrapGormErr(re.Error, "todo list shared user")
+}
+
+func (r *gormRepository) DeleteTodoListSharedUser(userID, todoListID int64) error {
 	user := entity.User{Entity: entity.Entity{ID: userID}}
 	list := entity.TodoList{Entity: entity.Entity{ID: todoListID}}
-	err := db.Model(&list).Association("SharedUsers").Delete(&user)
+	err := r.db.Model(&list).Association("SharedUsers").Delete(&user)
 	return util.WrapGormErr(err, "todo list shared users")
 }
 
-func ExistTodoListSharing(userID, todoListID int64) (bool, error) {
-	// TODO[pref]: count in db
-	user := entity.User{Entity: entity.Entity{ID: userID}}
-	list := entity.TodoList{Entity: entity.Entity{ID: todoListID}}
+func (r *gormRepository) ExistTodoListSharing(userID, todoListID int64) (bool, error) {
+	var count int64
+	re := r.db.
+		Table("todo_list_shared_users").
+		Where("user_id = ? AND todo_list_id = ?", userID, todoListID).
+		Count(&count)
+	return count != 0, util.WrapGormErr(re.Error, "todo list sharing")
+}
+
+/**
+ * Trash
+ */
+
+func (r *gormRepository) SelectDeletedTodoList(id int64) (entity.TodoList, error) {
+	var list entity.TodoList
+	re := r.db.Unscoped().Where("id = ? AND deleted_at IS NOT NULL", id).First(&list)
+	return list, util.WrapGormErr(re.Error, "deleted todo list")
+}
+
+func (r *gormRepository) SelectDeletedTodoLists(userID int64) ([]entity.TodoList, error) {
 	var lists []entity.TodoList
-	if err := db.Model(&user).Association("SharedTodoLists").Find(&lists, &list); err != nil {
-		return false, util.WrapGormErr(err, "todo list sharing")
-	}
+	re := r.db.
+		Unscoped().
+		Where("user_id = ? AND deleted_at IS NOT NULL", userID).
+		Order("deleted_at DESC").
+		Find(&lists)
+	return lists, util.WrapGormErr(re.Error, "deleted todo lists")
+}
+
+func (r *gormRepository) RestoreTodoList(id, todoListFolderID int64) error {
+	re := r.db.
+		Unscoped().
+		Model(&entity.TodoList{Entity: entity.Entity{ID: id}}).
+		Updates(map[string]interface{}{
+			"deleted_at":          nil,
+			"todo_list_folder_id": todoListFolderID,
+			"version":             gorm.Expr("version + 1"),
+		})
+	return util.WrapGormErr(re.Error, "todo list")
+}
+
+// Hard delete todo lists and their todos, shared users, invitations,
+// sharings and ranks
+func (r *gormRepository) PurgeTodoLists(deletedBefore time.Time) (int64, error) {
+	var count int64
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		ids := tx.Unscoped().Model(&entity.TodoList{}).Select("id").Where("deleted_at < ?", deletedBefore)
+		if _, err := purgeTodos(tx, "todo_list_id IN (?)", ids); err != nil {
+			return err
+		}
+
+		if err := purgeSharings(tx, ids, entity.SharingTypeTodoList, entity.SharingTypeTodoListPublish); err != nil {
+			return err
+		}
+
+		if err := purgeRanks(tx, entity.RankTypeTodoList, ids); err != nil {
+			return err
+		}
+
+		for _, table := range []string{"todo_list_shared_users", "todo_list_invitations"} {
+			if err := tx.Exec("DELETE FROM "+table+" WHERE todo_list_id IN (?)", ids).Error; err != nil {
+				return err
+			}
+		}
 
-	return len(lists) != 0, nil
+		re := tx.Unscoped().Where("deleted_at < ?", deletedBefore).Delete(&entity.TodoList{})
+		count = re.RowsAffected
+		return re.Error
+	})
+	return count, util.WrapGormErr(err, "todo lists")
 }
@@ -1,30 +1,59 @@
 package dal
 
 import (
+	"time"
+
 	"github.com/yzx9/otodo/model/entity"
 	"github.com/yzx9/otodo/util"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
-func InsertTodoListFolder(todoListFolder *entity.TodoListFolder) error {
-	re := db.Create(todoListFolder)
+type TodoListFolderRepository interface {
+	InsertTodoListFolder(todoListFolder *entity.TodoListFolder) error
+	SelectTodoListFolder(id int64) (entity.TodoListFolder, error)
+	SelectTodoListFolders(userId int64) ([]entity.TodoListFolder, error)
+	SaveTodoListFolder(todoListFolder *entity.TodoListFolder) error
+	DeleteTodoListFolder(id int64) error
+	ExistTodoListFolder(id int64) (bool, error)
+
+	InsertTodoListFolderSharedUser(userID, todoListFolderID int64, role entity.TodoListRole) error
+	SelectSharedTodoListFolders(userID int64) ([]entity.TodoListFolder, error)
+	SelectTodoListFolderSharedUsers(todoListFolderID int64) ([]entity.TodoListFolderSharedUser, error)
+	SelectTodoListFolderSharedUserRole(userID, todoListFolderID int64) (entity.TodoListRole, error)
+	DeleteTodoListFolderSharedUser(userID, todoListFolderID int64) error
+
+	SelectDeletedTodoListFolder(id int64) (entity.TodoListFolder, error)
+	SelectDeletedTodoListFolders(userID int64) ([]entity.TodoListFolder, error)
+	RestoreTodoListFolder(id int64) error
+	PurgeTodoListFolders(deletedBefore time.Time) (int64, error)
+}
+
+func (r *gormRepository) InsertTodoListFolder(todoListFolder *entity.TodoListFolder) error {
+	re := r.db.Create(todoListFolder)
 	return util.WrapGormErr(re.Error, "todo list folder")
 }
 
-func SelectTodoListFolder(id int64) (entity.TodoListFolder, error) {
+func (r *gormRepository) SelectTodoListFolder(id int64) (entity.TodoListFolder, error) {
 	var folder entity.TodoListFolder
 	where := entity.TodoListFolder{Entity: entity.Entity{ID: id}}
-	re := db.Where(&where).First(&folder)
+	re := r.db.Where(&where).First(&folder)
 	return folder, util.WrapGormErr(re.Error, "todo list folder")
 }
 
-func SelectTodoListFolders(userId int64) ([]entity.TodoListFolder, error) {
+func (r *gormRepository) SelectTodoListFolders(userId int64) ([]entity.TodoListFolder, error) {
 	var folders []entity.TodoListFolder
-	re := db.Where(entity.TodoListFolder{UserID: userId}).Find(&folders)
+	re := r.db.Where(entity.TodoListFolder{UserID: userId}).Find(&folders)
 	return folders, util.WrapGormErr(re.Error, "todo list folder")
 }
 
-func DeleteTodoListFolder(id int64) error {
-	re := db.Delete(&entity.TodoListFolder{
+func (r *gormRepository) SaveTodoListFolder(todoListFolder *entity.TodoListFolder) error {
+	re := r.db.Omit(clause.Associations).Save(todoListFolder)
+	return util.WrapGormErr(re.Error, "todo list folder")
+}
+
+func (r *gormRepository) DeleteTodoListFolder(id int64) error {
+	re := r.db.Delete(&entity.TodoListFolder{
 		Entity: entity.Entity{
 			ID: id,
 		},
@@ -32,9 +61,104 @@ func DeleteTodoListFolder(id int64) error {
 	return util.WrapGormErr(re.Error, "todo list folder")
 }
 
-func ExistTodoListFolder(id int64) (bool, error) {
+func (r *gormRepository) ExistTodoListFolder(id int64) (bool, error) {
 	var count int64
 	folder := entity.TodoListFolder{Entity: entity.Entity{ID: id}}
-	re := db.Model(&entity.TodoListFolder{}).Where(&folder).Count(&count)
-	return count != 0, util.WrapGormErr(re.Error, "tag")
+	re := r.db.Model(&entity.TodoListFolder{}).Where(&folder).Count(&count)
+	return count != 0, util.WrapGormErr(re.Error, "todo list folder")
+}
+
+/**
+ * Sharing
+ */
+
+func (r *gormRepository) InsertTodoListFolderSharedUser(userID, todoListFolderID int64, role entity.TodoListRole) error {
+	re := r.db.Omit(clause.Associations).Create(&entity.TodoListFolderSharedUser{UserID: userID, TodoListFolderID: todoListFolderID, Role: role})
+	return util.WrapGormErr(re.Error, "todo list folder shared user")
+}
+
+func (r *gormRepository) SelectSharedTodoListFolders(userID int64) ([]entity.TodoListFolder, error) {
+	var folders []entity.TodoListFolder
+	re := r.db.
+		Where("id IN (?)", r.db.Model(&entity.TodoListFolderSharedUser{}).Select("todo_list_folder_id").Where("user_id = ?", userID)).
+		Find(&folders)
+	return folders, util.WrapGormErr(re.Error, "user shared todo list folders")
+}
+
+// Select shared users of todo list folder with roles, ordered by user id
+func (r *gormRepository) SelectTodoListFolderSharedUsers(todoListFolderID int64) ([]entity.TodoListFolderSharedUser, error) {
+	var sharedUsers []entity.TodoListFolderSharedUser
+	re := r.db.
+		Preload("User").
+		Where("todo_list_folder_id = ?", todoListFolderID).
+		Order("user_id").
+		Find(&sharedUsers)
+	return sharedUsers, util.WrapGormErr(re.Error, "todo list folder shared users")
+}
+
+func (r *gormRepository) SelectTodoListFolderSharedUserRole(userID, todoListFolderID int64) (entity.TodoListRole, error) {
+	var sharedUser entity.TodoListFolderSharedUser
+	re := r.db.
+		Where("user_id = ? AND todo_list_folder_id = ?", userID, todoListFolderID).
+		First(&sharedUser)
+	return sharedUser.Role, util.WrapGormErr(re.Error, "todo list folder shared user")
+}
+
+func (r *gormRepository) DeleteTodoListFolderSharedUser(userID, todoListFolderID int64) error {
+	re := r.db.
+		Where("user_id = ? AND todo_list_folder_id = ?", userID, todoListFolderID).
+		Delete(&entity.TodoListFolderSharedUser{})
+	return util.WrapGormErr(re.Error, "todo list folder shared user")
+}
+
+/**
+ * Trash
+ */
+
+func (r *gormRepository) SelectDeletedTodoListFolder(id int64) (entity.TodoListFolder, error) {
+	var folder entity.TodoListFolder
+	re := r.db.Unscoped().Where("id = ? AND deleted_at IS NOT NULL", id).First(&folder)
+	return folder, util.WrapGormErr(re.Error, "deleted todo list folder")
+}
+
+func (r *gormRepository) SelectDeletedTodoListFolders(userID int64) ([]entity.TodoListFolder, error) {
+	var folders []entity.TodoListFolder
+	re := r.db.
+		Unscoped().
+		Where("user_id = ? AND deleted_at IS NOT NULL", userID).
+		Order("deleted_at DESC").
+		Find(&folders)
+	return folders, util.WrapGormErr(re.Error, "deleted todo list folders")
+}
+
+func (r *gormRepository) RestoreTodoListFolder(id int64) error {
+	re := r.db.
+		Unscoped().
+		Model(&entity.TodoListFolder{Entity: entity.Entity{ID: id}}).
+		Update("deleted_at", nil)
+	return util.WrapGormErr(re.Error, "todo list folder")
+}
+
+// Hard delete todo list folders and their shared users, sharings and ranks
+func (r *gormRepository) PurgeTodoListFolders(deletedBefore time.Time) (int64, error) {
+	var count int64
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		ids := tx.Unscoped().Model(&entity.TodoListFolder{}).Select("id").Where("deleted_at < ?", deletedBefore)
+		if err := purgeSharings(tx, ids, entity.SharingTypeTodoListFolder); err != nil {
+			return err
+		}
+
+		if err := purgeRanks(tx, entity.RankTypeTodoListFolder, ids); err != nil {
+			return err
+		}
+
+		if err := tx.Exec("DELETE FROM todo_list_folder_shared_users WHERE todo_list_folder_id IN (?)", ids).Error; err != nil {
+			return err
+		}
+
+		re := tx.Unscoped().Where("deleted_at < ?", deletedBefore).Delete(&entity.TodoListFolder{})
+		count = re.RowsAffected
+		return re.Error
+	})
+	return count, util.WrapGormErr(err, "todo list folders")
 }
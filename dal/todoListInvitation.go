@@ -0,0 +1,67 @@
+package dal
+
+import (
+	"github.com/yzx9/otodo/model/entity"
+	"github.com/yzx9/otodo/util"
+	"gorm.io/gorm/clause"
+)
+
+type TodoListInvitationRepository interface {
+	InsertTodoListInvitation(invitation *entity.TodoListInvitation) error
+	SelectTodoListInvitation(id int64) (entity.TodoListInvitation, error)
+	SelectTodoListInvitations(todoListID int64) ([]entity.TodoListInvitation, error)
+	SelectUserTodoListInvitations(userID int64) ([]entity.TodoListInvitation, error)
+	UpdateTodoListInvitationsInvitee(email string, inviteeID int64) (int64, error)
+	DeleteTodoListInvitation(id int64) error
+}
+
+func (r *gormRepository) InsertTodoListInvitation(invitation *entity.TodoListInvitation) error {
+	re := r.db.Omit(clause.Associations).Create(invitation)
+	return util.WrapGormErr(re.Error, "todo list invitation")
+}
+
+func (r *gormRepository) SelectTodoListInvitation(id int64) (entity.TodoListInvitation, error) {
+	var invitation entity.TodoListInvitation
+	where := entity.TodoListInvitation{Entity: entity.Entity{ID: id}}
+	re := r.db.Where(&where).First(&invitation)
+	return invitation, util.WrapGormErr(re.Error, "todo list invitation")
+}
+
+// Select invitations of todo list with invitees and inviters, oldest first
+func (r *gormRepository) SelectTodoListInvitations(todoListID int64) ([]entity.TodoListInvitation, error) {
+	var invitations []entity.TodoListInvitation
+	re := r.db.
+		Preload("Invitee").
+		Preload("User").
+		Where(entity.TodoListInvitation{TodoListID: todoListID}).
+		Order("id").
+		Find(&invitations)
+	return invitations, util.WrapGormErr(re.Error, "todo list invitations")
+}
+
+// Select invitations to user with todo lists and inviters, oldest first.
+// Todo list is empty if deleted
+func (r *gormRepository) SelectUserTodoListInvitations(userID int64) ([]entity.TodoListInvitation, error) {
+	var invitations []entity.TodoListInvitation
+	re := r.db.
+		Preload("TodoList").
+		Preload("User").
+		Where(entity.TodoListInvitation{InviteeID: userID}).
+		Order("id").
+		Find(&invitations)
+	return invitations, util.WrapGormErr(re.Error, "todo list invitations")
+}
+
+// Hand invitations held for email over to the registered invitee
+func (r *gormRepository) UpdateTodoListInvitationsInvitee(email string, inviteeID int64) (int64, error) {
+	re := r.db.
+		Model(&entity.TodoListInvitation{}).
+		Where("email = ? AND invitee_id = ?", email, 0).
+		Update("invitee_id", inviteeID)
+	return re.RowsAffected, util.WrapGormErr(re.Error, "todo list invitations")
+}
+
+func (r *gormRepository) DeleteTodoListInvitation(id int64) error {
+	re := r.db.Delete(&entity.TodoListInvitation{Entity: entity.Entity{ID: id}})
+	return util.WrapGormErr(re.Error, "todo list invitation")
+}
@@ -0,0 +1,27 @@
+package dal
+
+import (
+	"github.com/yzx9/otodo/model/entity"
+	"github.com/yzx9/otodo/util"
+	"gorm.io/gorm/clause"
+)
+
+type TodoMoveRepository interface {
+	InsertTodoMove(move *entity.TodoMove) error
+	SelectTodoMoves(todoListID int64) ([]entity.TodoMove, error)
+}
+
+func (r *gormRepository) InsertTodoMove(move *entity.TodoMove) error {
+	re := r.db.Omit(clause.Associations).Create(move)
+	return util.WrapGormErr(re.Error, "todo move")
+}
+
+// Select moves from or to todo list, newest first
+func (r *gormRepository) SelectTodoMoves(todoListID int64) ([]entity.TodoMove, error) {
+	var moves []entity.TodoMove
+	re := r.db.
+		Where("from_todo_list_id = ? OR to_todo_list_id = ?", todoListID, todoListID).
+		Order("id DESC").
+		Find(&moves)
+	return moves, util.WrapGormErr(re.Error, "todo moves")
+}
@@ -0,0 +1,216 @@
+package dal
+
+import (
+	"fmt"
+
+	"github.com/yzx9/otodo/model/dto"
+	"github.com/yzx9/otodo/model/entity"
+	"github.com/yzx9/otodo/util"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+func (r *gormRepository) SelectTodosByQuery(query dto.TodoQuery) ([]entity.Todo, error) {
+	todos := make([]entity.Todo, 0)
+	if (query.IDs != nil && len(query.IDs) == 0) ||
+		(query.TodoListIDs != nil && len(query.TodoListIDs) == 0 && len(query.SharedIDs) == 0) {
+		return todos, nil
+	}
+
+	db := r.db.Scopes(
+		todoPreload,
+		todoQueryScope(query),
+		todoQueryFilter(query),
+		todoQueryOrder(query),
+		todoQueryAfter(query),
+	)
+	if query.Limit > 0 {
+		db = db.Limit(query.Limit)
+	}
+
+	re := db.Find(&todos)
+	return todos, util.WrapGormErr(re.Error, "todos")
+}
+
+/**
+ * Helpers
+ */
+
+type todoSortColumn struct {
+	name     string
+	nullable bool
+}
+
+var todoSortColumns = map[dto.TodoSort]todoSortColumn{
+	dto.TodoSortDeadline:   {"deadline", true},
+	dto.TodoSortCreatedAt:  {"created_at", false},
+	dto.TodoSortTitle:      {"title", false},
+	dto.TodoSortImportance: {"importance", false},
+	dto.TodoSortNotifyAt:   {"notify_at", true},
+}
+
+// Sort column of query, rank is a subquery since it depends on user
+func todoQuerySortColumn(query dto.TodoQuery) (todoSortColumn, bool) {
+	if query.Sort == dto.TodoSortRank {
+		rank := fmt.Sprintf("(SELECT ranks.rank_key FROM ranks WHERE ranks.user_id = %d AND "+
+			"ranks.type = %d AND ranks.related_id = todos.id AND ranks.deleted_at IS NULL)",
+			query.RankUserID, entity.RankTypeTodo)
+		return todoSortColumn{rank, true}, true
+	}
+
+	column, ok := todoSortColumns[query.Sort]
+	return column, ok
+}
+
+func todoQueryScope(query dto.TodoQuery) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if query.UserID != 0 {
+			db = db.Where("user_id = ?", query.UserID)
+		}
+		if query.TodoListID != 0 {
+			db = db.Where("todo_list_id = ?", query.TodoListID)
+		}
+		if query.TodoListIDs != nil {
+			db = db.Where(todoInListsOrShared(query.TodoListIDs, query.SharedIDs))
+		}
+		if query.IDs != nil {
+			db = db.Where("id IN ?", query.IDs)
+		}
+		return db
+	}
+}
+
+// Condition of todos in todo lists or shared directly, both may be empty
+func todoInListsOrShared(todoListIDs, sharedIDs []int64) clause.Expression {
+	var exprs []clause.Expression
+	if len(todoListIDs) != 0 {
+		exprs = append(exprs, clause.Expr{SQL: "todo_list_id IN ?", Vars: []interface{}{todoListIDs}})
+	}
+	if len(sharedIDs) != 0 {
+		exprs = append(exprs, clause.Expr{SQL: "id IN ?", Vars: []interface{}{sharedIDs}})
+	}
+	return clause.Or(exprs...)
+}
+
+func todoQueryFilter(query dto.TodoQuery) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		if query.Done != nil {
+			db = db.Where("done = ?", *query.Done)
+		}
+		if query.Importance != nil {
+			db = db.Where("importance = ?", *query.Importance)
+		}
+		if query.Notified != nil {
+			db = db.Where("notified = ?", *query.Notified)
+		}
+		if query.HasDeadline != nil {
+			db = db.Where(nullCondition("deadline", !*query.HasDeadline))
+		}
+		if query.DeadlineFrom != nil {
+			db = db.Where("deadline >= ?", *query.DeadlineFrom)
+		}
+		if query.DeadlineTo != nil {
+			db = db.Where("deadline < ?", *query.DeadlineTo)
+		}
+		if query.Tag != "" {
+			db = db.Where("id IN (?)", todoTagged(db, query.TagUserID, query.Tag))
+		}
+		if query.HasFiles != nil {
+			exists := "EXISTS (SELECT 1 FROM todo_files WHERE todo_files.todo_id = todos.id)"
+			if !*query.HasFiles {
+				exists = "NOT " + exists
+			}
+			db = db.Where(exists)
+		}
+		return db
+	}
+}
+
+// Order by sort column and id, null values are greater than any others
+// which is not the default behavior of all databases
+func todoQueryOrder(query dto.TodoQuery) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		dir := "ASC"
+		if query.Desc {
+			dir = "DESC"
+		}
+
+		if column, ok := todoQuerySortColumn(query); ok {
+			if column.nullable {
+				db = db.Order(fmt.Sprintf("CASE WHEN %v IS NULL THEN 1 ELSE 0 END %v", column.name, dir))
+			}
+			db = db.Order(column.name + " " + dir)
+		}
+
+		return db.Order("id " + dir)
+	}
+}
+
+// Keyset pagination, todos after cursor in order of todoQueryOrder
+func todoQueryAfter(query dto.TodoQuery) func(db *gorm.DB) *gorm.DB {
+	return func(db *gorm.DB) *gorm.DB {
+		after := query.After
+		if after == nil {
+			return db
+		}
+
+		op := ">"
+		if query.Desc {
+			op = "<"
+		}
+
+		column, ok := todoQuerySortColumn(query)
+		if !ok {
+			return db.Where("id "+op+" ?", after.ID)
+		}
+
+		var value interface{}
+		switch query.Sort {
+		case dto.TodoSortTitle:
+			value = after.Title
+		case dto.TodoSortImportance:
+			value = after.Importance
+		case dto.TodoSortRank:
+			if after.Rank != "" {
+				value = after.Rank
+			}
+		default:
+			if after.Time != nil {
+				value = *after.Time
+			}
+		}
+
+		col := column.name
+		switch {
+		case value == nil && !query.Desc:
+			return db.Where(fmt.Sprintf("%v IS NULL AND id > ?", col), after.ID)
+
+		case value == nil && query.Desc:
+			return db.Where(fmt.Sprintf("(%v IS NOT NULL OR id < ?)", col), after.ID)
+
+		case column.nullable && !query.Desc:
+			cond := fmt.Sprintf("(%[1]v IS NULL OR %[1]v > ? OR (%[1]v = ? AND id > ?))", col)
+			return db.Where(cond, value, value, after.ID)
+
+		default:
+			cond := fmt.Sprintf("(%[1]v %[2]v ? OR (%[1]v = ? AND id %[2]v ?))", col, op)
+			return db.Where(cond, value, value, after.ID)
+		}
+	}
+}
+
+// Subquery of todo ids with tag of user
+func todoTagged(db *gorm.DB, userID int64, tag string) *gorm.DB {
+	return db.Session(&gorm.Session{NewDB: true}).
+		Table("tag_todos").
+		Select("tag_todos.todo_id").
+		Joins("JOIN tags ON tags.id = tag_todos.tag_id").
+		Where("tags.user_id = ? AND tags.name = ? AND tags.deleted_at IS NULL", userID, tag)
+}
+
+func nullCondition(column string, null bool) string {
+	if null {
+		return column + " IS NULL"
+	}
+	return column + " IS NOT NULL"
+}
@@ -5,25 +5,32 @@ import (
 	"github.com/yzx9/otodo/util"
 )
 
-func InsertTodoRepeatPlan(plan *entity.TodoRepeatPlan) error {
-	re := db.Create(plan)
+type TodoRepeatPlanRepository interface {
+	InsertTodoRepeatPlan(plan *entity.TodoRepeatPlan) error
+	SelectTodoRepeatPlan(id int64) (entity.TodoRepeatPlan, error)
+	SaveTodoRepeatPlan(todoRepeatPlan *entity.TodoRepeatPlan) error
+	DeleteTodoRepeatPlan(id int64) error
+}
+
+func (r *gormRepository) InsertTodoRepeatPlan(plan *entity.TodoRepeatPlan) error {
+	re := r.db.Create(plan)
 	return util.WrapGormErr(re.Error, "todo repeat plan")
 }
 
-func SelectTodoRepeatPlan(id int64) (entity.TodoRepeatPlan, error) {
+func (r *gormRepository) SelectTodoRepeatPlan(id int64) (entity.TodoRepeatPlan, error) {
 	var plan entity.TodoRepeatPlan
 	where := entity.TodoRepeatPlan{Entity: entity.Entity{ID: id}}
-	re := db.Where(&where).First(&plan)
+	re := r.db.Where(&where).First(&plan)
 	return plan, util.WrapGormErr(re.Error, "todo repeat plan")
 }
 
-func SaveTodoRepeatPlan(todoRepeatPlan *entity.TodoRepeatPlan) error {
-	re := db.Save(&todoRepeatPlan)
+func (r *gormRepository) SaveTodoRepeatPlan(todoRepeatPlan *entity.TodoRepeatPlan) error {
+	re := r.db.Save(todoRepeatPlan)
 	return util.WrapGormErr(re.Error, "todo repeat plan")
 }
 
-func DeleteTodoRepeatPlan(id int64) error {
-	re := db.Delete(&entity.TodoRepeatPlan{
+func (r *gormRepository) DeleteTodoRepeatPlan(id int64) error {
+	re := r.db.Delete(&entity.TodoRepeatPlan{
 		Entity: entity.Entity{
 			ID: id,
 		},
@@ -3,33 +3,71 @@ package dal
 import (
 	"github.com/yzx9/otodo/model/entity"
 	"github.com/yzx9/otodo/util"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
-func InsertTodoStep(step *entity.TodoStep) error {
-	re := db.Create(step)
-	return util.WrapGormErr(re.Error, "todo step")
+type TodoStepRepository interface {
+	InsertTodoStep(step *entity.TodoStep) error
+	SelectTodoStep(id int64) (entity.TodoStep, error)
+	SelectTodoSteps(todoID int64) ([]entity.TodoStep, error)
+	SaveTodoStep(todoStep *entity.TodoStep) error
+	DeleteTodoStep(id int64) error
+}
+
+func (r *gormRepository) InsertTodoStep(step *entity.TodoStep) error {
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Omit(clause.Associations).Create(step).Error; err != nil {
+			return err
+		}
+
+		return indexTodoSearch(tx, step.TodoID)
+	})
+	return util.WrapGormErr(err, "todo step")
 }
 
-func SelectTodoStep(id int64) (entity.TodoStep, error) {
+func (r *gormRepository) SelectTodoStep(id int64) (entity.TodoStep, error) {
 	var step entity.TodoStep
 	where := entity.TodoStep{Entity: entity.Entity{ID: id}}
-	re := db.Where(&where).First(&step)
+	re := r.db.Where(&where).First(&step)
 	return step, util.WrapGormErr(re.Error, "todo step")
 }
 
-func SelectTodoSteps(todoID int64) ([]entity.TodoStep, error) {
+func (r *gormRepository) SelectTodoSteps(todoID int64) ([]entity.TodoStep, error) {
 	var steps []entity.TodoStep
-	re := db.Where(entity.TodoStep{TodoID: todoID}).Find(&steps)
+	re := r.db.Where(entity.TodoStep{TodoID: todoID}).Find(&steps)
 	return steps, util.WrapGormErr(re.Error, "todo step")
 }
 
-func SaveTodoStep(todoStep *entity.TodoStep) error {
-	re := db.Save(&todoStep)
-	return util.WrapGormErr(re.Error, "todo step")
+// Save todo step if version matched, and bump version
+func (r *gormRepository) SaveTodoStep(todoStep *entity.TodoStep) error {
+	version := todoStep.Version
+	todoStep.Version++
+	conflict := false
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		re := tx.
+			Model(todoStep).
+			Select("*").
+			Omit(clause.Associations).
+			Where("version = ?", version).
+			Updates(todoStep)
+		if re.Error != nil || re.RowsAffected == 0 {
+			conflict = re.Error == nil
+			return re.Error
+		}
+
+		return indexTodoSearch(tx, todoStep.TodoID)
+	})
+	if conflict {
+		todoStep.Version = version
+		return versionConflict("todo step")
+	}
+
+	return util.WrapGormErr(err, "todo step")
 }
 
-func DeleteTodoStep(id int64) error {
-	re := db.Delete(&entity.TodoStep{
+func (r *gormRepository) DeleteTodoStep(id int64) error {
+	re := r.db.Delete(&entity.TodoStep{
 		Entity: entity.Entity{
 			ID: id,
 		},
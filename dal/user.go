@@ -3,56 +3,76 @@ package dal
 import (
 	"github.com/yzx9/otodo/model/entity"
 	"github.com/yzx9/otodo/util"
+	"gorm.io/gorm/clause"
 )
 
-func InsertUser(user *entity.User) error {
-	re := db.Create(user)
+type UserRepository interface {
+	InsertUser(user *entity.User) error
+	SelectUser(id int64) (entity.User, error)
+	SelectUserByUserName(username string) (entity.User, error)
+	SelectUserByGithubID(githubID int64) (entity.User, error)
+	SelectUserByEmail(email string) (entity.User, error)
+	SelectUserByTodo(todoID int64) (entity.User, error)
+	SaveUser(user *entity.User) error
+	ExistUserByUserName(username string) (bool, error)
+	ExistUserByGithubID(githubID int64) (bool, error)
+}
+
+func (r *gormRepository) InsertUser(user *entity.User) error {
+	re := r.db.Omit(clause.Associations).Create(user)
 	return util.WrapGormErr(re.Error, "user")
 }
 
-func SelectUser(id int64) (entity.User, error) {
+func (r *gormRepository) SelectUser(id int64) (entity.User, error) {
 	var user entity.User
 	where := entity.User{Entity: entity.Entity{ID: id}}
-	re := db.Where(&where).First(&user)
+	re := r.db.Where(&where).First(&user)
+	return user, util.WrapGormErr(re.Error, "user")
+}
+
+func (r *gormRepository) SelectUserByUserName(username string) (entity.User, error) {
+	var user entity.User
+	re := r.db.Where(entity.User{Name: username}).First(&user)
 	return user, util.WrapGormErr(re.Error, "user")
 }
 
-func SelectUserByUserName(username string) (entity.User, error) {
+func (r *gormRepository) SelectUserByGithubID(githubID int64) (entity.User, error) {
 	var user entity.User
-	re := db.Where(entity.User{Name: username}).First(&user)
+	re := r.db.Where(entity.User{GithubID: githubID}).First(&user)
 	return user, util.WrapGormErr(re.Error, "user")
 }
 
-func SelectUserByGithubID(githubID int64) (entity.User, error) {
+// Select first user of email, emails are not unique
+func (r *gormRepository) SelectUserByEmail(email string) (entity.User, error) {
 	var user entity.User
-	re := db.Where(entity.User{GithubID: githubID}).First(&user)
+	re := r.db.Where(entity.User{Email: email}).Order("id").First(&user)
 	return user, util.WrapGormErr(re.Error, "user")
 }
 
-func SelectUserByTodo(todoID int64) (entity.User, error) {
+func (r *gormRepository) SelectUserByTodo(todoID int64) (entity.User, error) {
 	var todo entity.Todo
 	where := entity.Todo{Entity: entity.Entity{ID: todoID}}
-	re := db.Where(&where).Select("UserID").First(&todo)
+	re := r.db.Where(&where).Select("user_id").First(&todo)
 	if re.Error != nil {
 		return entity.User{}, util.WrapGormErr(re.Error, "todo")
 	}
 
-	return SelectUser(todo.UserID)
+	return r.SelectUser(todo.UserID)
 }
 
-func SaveUser(user *entity.User) error {
-	re := db.Save(&user)
+func (r *gormRepository) SaveUser(user *entity.User) error {
+	re := r.db.Omit(clause.Associations).Save(user)
 	return util.WrapGormErr(re.Error, "user")
 }
 
-func ExistUserByUserName(username string) (bool, error) {
+func (r *gormRepository) ExistUserByUserName(username string) (bool, error) {
 	var count int64
-	re := db.Model(&entity.User{}).Where(entity.User{Name: username}).Count(&count)
+	re := r.db.Model(&entity.User{}).Where(entity.User{Name: username}).Count(&count)
 	return count != 0, util.WrapGormErr(re.Error, "user")
 }
 
-func ExistUserByGithubID(githubID int64) (bool, error) {
+func (r *gormRepository) ExistUserByGithubID(githubID int64) (bool, error) {
 	var count int64
-	re := db.Model(&entity.User{}).Where(entity.User{GithubID: githubID}).Count(&count)
+	re := r.db.Model(&entity.User{}).Where(entity.User{GithubID: githubID}).Count(&count)
 	return count != 0, util.WrapGormErr(re.Error, "user")
 }
@@ -5,8 +5,13 @@ import (
 	"github.com/yzx9/otodo/util"
 )
 
-func InsertUserInvalidRefreshToken(entity *entity.UserInvalidRefreshToken) error {
-	re := db.Create(entity)
+type UserInvalidRefreshTokenRepository interface {
+	InsertUserInvalidRefreshToken(entity *entity.UserInvalidRefreshToken) error
+	ExistUserInvalidRefreshToken(userID int64, tokenID string) (bool, error)
+}
+
+func (r *gormRepository) InsertUserInvalidRefreshToken(entity *entity.UserInvalidRefreshToken) error {
+	re := r.db.Create(entity)
 	if re.Error != nil {
 		return util.WrapGormErr(re.Error, "user invalid refresh token")
 	}
@@ -14,12 +19,15 @@ func InsertUserInvalidRefreshToken(entity *entity.UserInvalidRefreshToken) error
 	return nil
 }
 
-func ExistUserInvalidRefreshToken(userID int64, tokenID string) (bool, error) {
+func (r *gormRepository) ExistUserInvalidRefreshToken(userID int64, tokenID string) (bool, error) {
 	var count int64
-	re := db.Where(&entity.UserInvalidRefreshToken{
-		UserID:  userID,
-		TokenID: tokenID,
-	}).Count(&count)
+	re := r.db.
+		Model(&entity.UserInvalidRefreshToken{}).
+		Where(&entity.UserInvalidRefreshToken{
+			UserID:  userID,
+			TokenID: tokenID,
+		}).
+		Count(&count)
 	if re.Error != nil {
 		return false, util.WrapGormErr(re.Error, "user invalid refresh token")
 	}
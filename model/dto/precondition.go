@@ -0,0 +1,8 @@
+package dto
+
+// IfMatch is parsed from If-Match header
+type IfMatch struct {
+	Present  bool
+	Any      bool    // If-Match: *
+	Versions []int64 // versions in entity tags
+}
@@ -0,0 +1,5 @@
+package dto
+
+type ReorderDTO struct {
+	After int64 `json:"after"` // id of previous item, 0 to move to the first
+}
@@ -0,0 +1,19 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/yzx9/otodo/model/entity"
+)
+
+type TodoSnoozeDTO struct {
+	Until   *time.Time `json:"until"`   // remind at
+	Minutes int        `json:"minutes"` // or remind after minutes
+}
+
+// Payload posted to webhook
+type ReminderWebhookPayload struct {
+	Event  string      `json:"event"` // always "reminder"
+	UserID int64       `json:"userID"`
+	Todo   entity.Todo `json:"todo"`
+}
@@ -0,0 +1,47 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/yzx9/otodo/model/entity"
+)
+
+// Search of todos, parsed from query such as `milk #shopping is:done due:<2022-01-01`
+type TodoSearch struct {
+	TodoListIDs []int64 // scope, accessible todo lists
+	SharedIDs   []int64 // scope, todos shared directly besides todo lists
+	TagUserID   int64   // tags of whom to filter by, set by server
+
+	// Text terms, all are required. A term matches title, memo, name of
+	// steps or name of files
+	Terms []string
+
+	// Operators
+	Tags         []string // #tag, all are required
+	Done         *bool    // is:done, is:undone
+	Importance   *bool    // is:important
+	DeadlineFrom *time.Time
+	DeadlineTo   *time.Time
+
+	Limit int // no limit if zero
+}
+
+type TodoSearchResult struct {
+	Todo       entity.Todo           `json:"todo"`
+	Score      float64               `json:"score"`
+	Highlights []TodoSearchHighlight `json:"highlights"`
+}
+
+type TodoSearchField string
+
+const (
+	TodoSearchFieldTitle TodoSearchField = "title"
+	TodoSearchFieldMemo  TodoSearchField = "memo"
+	TodoSearchFieldStep  TodoSearchField = "step"
+	TodoSearchFieldFile  TodoSearchField = "file"
+)
+
+type TodoSearchHighlight struct {
+	Field   TodoSearchField `json:"field"`
+	Snippet string          `json:"snippet"` // html escaped, matched terms are wrapped in <mark>
+}
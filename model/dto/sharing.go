@@ -1,14 +1,138 @@
 package dto
 
-import "time"
+import (
+	"time"
+
+	"github.com/yzx9/otodo/model/entity"
+)
+
+type SharingDTO struct {
+	Type           int8       `json:"type"`           // SharingType, todo list if empty
+	Role           int8       `json:"role"`           // TodoListRole of users joined by token, editor if empty
+	Label          string     `json:"label"`          // optional
+	ExpiresAt      *time.Time `json:"expiresAt"`      // never expires if empty
+	MaxRedemptions int64      `json:"maxRedemptions"` // unlimited if empty
+	PublishMemo    bool       `json:"publishMemo"`    // published todo list only
+	PublishFiles   bool       `json:"publishFiles"`   // published todo list only
+}
 
 type SharingToken struct {
-	Token     string    `json:"token"`
-	Type      int8      `json:"type"`
-	CreatedAt time.Time `json:"createdAt"`
+	Token          string     `json:"token"`
+	Type           int8       `json:"type"`
+	Role           int8       `json:"role"`
+	Label          string     `json:"label"`
+	ExpiresAt      *time.Time `json:"expiresAt"`
+	MaxRedemptions int64      `json:"maxRedemptions"`
+	PublishMemo    bool       `json:"publishMemo"`
+	PublishFiles   bool       `json:"publishFiles"`
+	CreatedAt      time.Time  `json:"createdAt"`
+}
+
+func NewSharingToken(sharing entity.Sharing) SharingToken {
+	return SharingToken{
+		Token:          sharing.Token,
+		Type:           sharing.Type,
+		Role:           sharing.Role,
+		Label:          sharing.Label,
+		ExpiresAt:      sharing.ExpiresAt,
+		MaxRedemptions: sharing.MaxRedemptions,
+		PublishMemo:    sharing.PublishMemo,
+		PublishFiles:   sharing.PublishFiles,
+		CreatedAt:      sharing.CreatedAt,
+	}
+}
+
+// Sharing token with usage
+type SharingTokenStats struct {
+	SharingToken
+	UserID          int64               `json:"userID"` // creator
+	RedemptionCount int64               `json:"redemptionCount"`
+	Redemptions     []SharingRedemption `json:"redemptions"`
+}
+
+type SharingRedemption struct {
+	UserID       int64     `json:"userID"`
+	UserNickname string    `json:"userNickname"`
+	RedeemedAt   time.Time `json:"redeemedAt"`
 }
 
 type SharingTodoList struct {
 	TodoListName string `json:"todoListName"`
 	UserNickname string `json:"userNickname"`
 }
+
+// Read-only todo list for anyone with token, memos and files are empty
+// unless published
+type PublishedTodoList struct {
+	TodoListName string          `json:"todoListName"`
+	UserNickname string          `json:"userNickname"`
+	Todos        []PublishedTodo `json:"todos"`
+}
+
+type PublishedTodo struct {
+	Title      string              `json:"title"`
+	Memo       string              `json:"memo,omitempty"`
+	Importance bool                `json:"importance"`
+	Deadline   *time.Time          `json:"deadline"`
+	Done       bool                `json:"done"`
+	DoneAt     *time.Time          `json:"doneAt"`
+	Steps      []PublishedTodoStep `json:"steps"`
+	Files      []PublishedFile     `json:"files,omitempty"`
+}
+
+type PublishedTodoStep struct {
+	Name string `json:"name"`
+	Done bool   `json:"done"`
+}
+
+type PublishedFile struct {
+	FileID   string `json:"fileID"` // presigned
+	FileName string `json:"fileName"`
+}
+
+type TodoListSharedUser struct {
+	entity.User
+	Role int8 `json:"role"` // TodoListRole
+}
+
+type TodoListSharedUserRoleDTO struct {
+	Role int8 `json:"role" binding:"required"`
+}
+
+type TodoListTransferDTO struct {
+	UserID int64 `json:"userID" binding:"required"` // shared user of todo list
+}
+
+type TodoListInvitationDTO struct {
+	UserName string `json:"userName"` // invite by either user name or email
+	Email    string `json:"email"`
+	Role     int8   `json:"role"` // TodoListRole of invitee, editor if empty
+}
+
+type TodoListInvitation struct {
+	ID              int64     `json:"id"`
+	Role            int8      `json:"role"`
+	TodoListID      int64     `json:"todoListID"`
+	TodoListName    string    `json:"todoListName"`
+	InviterID       int64     `json:"inviterID"`
+	InviterNickname string    `json:"inviterNickname"`
+	InviteeID       int64     `json:"inviteeID"` // 0 if not registered
+	InviteeNickname string    `json:"inviteeNickname"`
+	Email           string    `json:"email"` // invitee email if not registered
+	CreatedAt       time.Time `json:"createdAt"`
+}
+
+func NewTodoListInvitation(invitation entity.TodoListInvitation) TodoListInvitation {
+	return TodoListInvitation{
+		ID:              invitation.ID,
+		Role:            invitation.Role,
+		TodoListID:      invitation.TodoListID,
+		TodoListName:    invitation.TodoList.Name,
+		InviterID:       invitation.UserID,
+		InviterNickname: invitation.User.Nickname,
+		InviteeID:       invitation.InviteeID,
+		InviteeNickname: invitation.Invitee.Nickname,
+		Email:           invitation.Email,
+		CreatedAt:       invitation.CreatedAt,
+	}
+}
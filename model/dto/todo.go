@@ -1,5 +1,78 @@
 package dto
 
+import (
+	"time"
+
+	"github.com/yzx9/otodo/model/entity"
+)
+
 type TodoStepDTO struct {
 	Name string `json:"name"`
 }
+
+type DailyTodoSuggestions struct {
+	Unfinished []entity.Todo `json:"unfinished"` // unfinished daily todos of yesterday
+	Overdue    []entity.Todo `json:"overdue"`
+}
+
+type TodoCompletion struct {
+	Todo entity.Todo  `json:"todo"`
+	Next *entity.Todo `json:"next"` // successor of recurring todo
+}
+
+type TagSummary struct {
+	entity.Tag
+	TodoCount int64 `json:"todoCount"` // in owned and shared todo lists
+}
+
+type TagMergeDTO struct {
+	Into string `json:"into" binding:"required"`
+}
+
+type TodoMoveDTO struct {
+	TodoListID int64 `json:"todoListID" binding:"required"` // target todo list
+}
+
+type TodosMoveDTO struct {
+	IDs        []int64 `json:"ids" binding:"required"`
+	TodoListID int64   `json:"todoListID" binding:"required"` // target todo list
+}
+
+type TodoBatchOp string
+
+const (
+	TodoBatchOpComplete      TodoBatchOp = "complete"
+	TodoBatchOpUncomplete    TodoBatchOp = "uncomplete"
+	TodoBatchOpDelete        TodoBatchOp = "delete"
+	TodoBatchOpMove          TodoBatchOp = "move"
+	TodoBatchOpSetImportance TodoBatchOp = "setImportance"
+	TodoBatchOpSetDeadline   TodoBatchOp = "setDeadline"
+	TodoBatchOpAddTag        TodoBatchOp = "addTag"
+	TodoBatchOpRemoveTag     TodoBatchOp = "removeTag"
+)
+
+type TodoBatchDTO struct {
+	Operations []TodoBatchOperation `json:"operations" binding:"required,dive"`
+}
+
+type TodoBatchOperation struct {
+	Op         TodoBatchOp `json:"op" binding:"required"`
+	ID         int64       `json:"id" binding:"required"`
+	TodoListID int64       `json:"todoListID"` // target todo list of move
+	Importance bool        `json:"importance"` // of setImportance
+	Deadline   *time.Time  `json:"deadline"`   // of setDeadline, null to clear
+	Tag        string      `json:"tag"`        // of addTag and removeTag
+	Version    *int64      `json:"version"`    // expected version of todo, required if shared
+}
+
+type TodoBatchResult struct {
+	Committed bool                  `json:"committed"` // nothing is changed if any operation fails
+	Results   []TodoBatchItemResult `json:"results"`   // in order of operations
+}
+
+type TodoBatchItemResult struct {
+	ID    int64        `json:"id"`
+	Op    TodoBatchOp  `json:"op"`
+	Todo  *entity.Todo `json:"todo,omitempty"`  // todo after operation if committed, deleted todo is also returned
+	Error *ErrorDTO    `json:"error,omitempty"` // empty if succeeded or not executed
+}
@@ -4,6 +4,7 @@ type TodoListMenuItemRaw struct {
 	ID               int64  `json:"id"`
 	Name             string `json:"name"`
 	Count            int    `json:"count"`
+	Rank             string `json:"rank,omitempty" gorm:"-"`
 	TodoListFolderID int64  `json:"-"`
 }
 
@@ -0,0 +1,92 @@
+package dto
+
+import (
+	"time"
+
+	"github.com/yzx9/otodo/model/entity"
+)
+
+type TodoSort string
+
+const (
+	TodoSortID         TodoSort = "" // default, snowflake id is in order of creation
+	TodoSortDeadline   TodoSort = "deadline"
+	TodoSortCreatedAt  TodoSort = "createdAt"
+	TodoSortTitle      TodoSort = "title"
+	TodoSortImportance TodoSort = "importance"
+	TodoSortNotifyAt   TodoSort = "notifyAt"
+	TodoSortRank       TodoSort = "rank" // user-defined order, see RankUserID
+)
+
+// Query of todo collections: scope, filters, sorting and cursor pagination.
+// Nil filters are not applied.
+type TodoQuery struct {
+	// Scope, set by server
+	UserID      int64
+	TodoListID  int64
+	TodoListIDs []int64 // only todos in lists if not nil
+	SharedIDs   []int64 // todos shared directly besides TodoListIDs
+	IDs         []int64 // only todos in ids if not nil
+
+	// Filters
+	Done         *bool
+	Importance   *bool
+	Notified     *bool
+	HasDeadline  *bool
+	DeadlineFrom *time.Time // inclusive
+	DeadlineTo   *time.Time // exclusive
+	Tag          string
+	TagUserID    int64 // tags of whom to filter by, set by server
+	HasFiles     *bool
+
+	// Sorting, ties are broken by id. Null values are greater than any others
+	Sort       TodoSort
+	Desc       bool
+	RankUserID int64 // ranks of whom to sort by, set by server
+
+	// Pagination
+	Limit int         // no limit if zero
+	After *TodoCursor // todos after cursor only
+}
+
+// Position of todo in sorted todos
+type TodoCursor struct {
+	Sort       TodoSort   `json:"s,omitempty"`
+	Desc       bool       `json:"d,omitempty"`
+	ID         int64      `json:"i"`
+	Time       *time.Time `json:"t,omitempty"` // deadline, createdAt or notifyAt
+	Title      string     `json:"n,omitempty"`
+	Importance bool       `json:"m,omitempty"`
+	Rank       string     `json:"r,omitempty"` // empty if unranked
+}
+
+func NewTodoCursor(todo entity.Todo, sort TodoSort, desc bool) TodoCursor {
+	cursor := TodoCursor{Sort: sort, Desc: desc, ID: todo.ID}
+	switch sort {
+	case TodoSortDeadline:
+		cursor.Time = todo.Deadline
+
+	case TodoSortCreatedAt:
+		createdAt := todo.CreatedAt
+		cursor.Time = &createdAt
+
+	case TodoSortNotifyAt:
+		cursor.Time = todo.NotifyAt
+
+	case TodoSortTitle:
+		cursor.Title = todo.Title
+
+	case TodoSortImportance:
+		cursor.Importance = todo.Importance
+
+	case TodoSortRank:
+		cursor.Rank = todo.Rank
+	}
+
+	return cursor
+}
+
+type TodoPage struct {
+	Todos []entity.Todo
+	Next  *TodoCursor // nil if no more todos
+}
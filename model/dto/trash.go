@@ -0,0 +1,19 @@
+package dto
+
+import "time"
+
+type TrashItemType = string
+
+const (
+	TrashItemTypeTodo           TrashItemType = "todo"
+	TrashItemTypeTodoList       TrashItemType = "todo-list"
+	TrashItemTypeTodoListFolder TrashItemType = "todo-list-folder"
+)
+
+type TrashItem struct {
+	Type      TrashItemType `json:"type"`
+	ID        int64         `json:"id"`
+	Name      string        `json:"name"`     // title of todo, or name of todo list / folder
+	ParentID  int64         `json:"parentID"` // todo list of todo, todo list folder of todo list
+	DeletedAt time.Time     `json:"deletedAt"`
+}
@@ -5,3 +5,7 @@ type CreateUserDTO struct {
 	Password string `json:"password"`
 	Nickname string `json:"nickname"`
 }
+
+type UserTimezoneDTO struct {
+	Timezone string `json:"timezone"`
+}
@@ -0,0 +1,14 @@
+package entity
+
+// DailyTodo marks a todo as selected into user's "My Day" on a date
+type DailyTodo struct {
+	Entity
+
+	Date string `json:"date" gorm:"size:10;index:idx_daily_todos_user_date"` // 2006-01-02, in user's location
+
+	UserID int64 `json:"userID" gorm:"index:idx_daily_todos_user_date"`
+	User   User  `json:"-"`
+
+	TodoID int64 `json:"todoID"`
+	Todo   Todo  `json:"-"`
+}
@@ -15,6 +15,9 @@ type Entity struct {
 }
 
 func (e *Entity) BeforeCreate(tx *gorm.DB) (err error) {
-	e.ID = otodo.NewID()
+	// keep id if exists, association mode creates records with id
+	if e.ID == 0 {
+		e.ID = otodo.NewID()
+	}
 	return
 }
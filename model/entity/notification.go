@@ -0,0 +1,19 @@
+package entity
+
+import "time"
+
+// Notification is an in-app reminder of todo
+type Notification struct {
+	Entity
+
+	Title    string     `json:"title" gorm:"size:128"` // title of todo when notified
+	NotifyAt time.Time  `json:"notifyAt"`
+	Read     bool       `json:"read" gorm:"column:is_read"` // read is reserved in MySQL
+	ReadAt   *time.Time `json:"readAt"`
+
+	UserID int64 `json:"userID" gorm:"index"`
+	User   User  `json:"-"`
+
+	TodoID int64 `json:"todoID"`
+	Todo   Todo  `json:"-"`
+}
@@ -0,0 +1,24 @@
+package entity
+
+type RankType = int8
+
+const (
+	RankTypeTodoListFolder RankType = 10*iota + 1 // Set RelatedID to todo list folder id
+	RankTypeTodoList                              // Set RelatedID to todo list id
+	RankTypeTodo                                  // Set RelatedID to todo id
+	RankTypeTodoStep                              // Set RelatedID to todo step id
+)
+
+// Rank is user-defined position of item, items are sorted by key in
+// lexicographic order. Each user has their own ranks, so that ordering of
+// shared todo lists does not affect others
+type Rank struct {
+	Entity
+
+	Key       string `json:"key" gorm:"column:rank_key;size:128"`               // key is reserved in MySQL
+	Type      int8   `json:"type" gorm:"uniqueIndex:idx_ranks_item,priority:2"` // RankType
+	RelatedID int64  `json:"relatedID" gorm:"uniqueIndex:idx_ranks_item,priority:3"`
+
+	UserID int64 `json:"userID" gorm:"uniqueIndex:idx_ranks_item,priority:1"`
+	User   User  `json:"-"`
+}
@@ -0,0 +1,10 @@
+package entity
+
+import "time"
+
+// Applied schema migrations, see dal/migrations
+type SchemaMigration struct {
+	Version   int       `json:"version" gorm:"primaryKey;autoIncrement:false"`
+	Name      string    `json:"name" gorm:"size:128"`
+	AppliedAt time.Time `json:"appliedAt"`
+}
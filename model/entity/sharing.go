@@ -1,9 +1,14 @@
 package entity
 
+import "time"
+
 type SharingType = int8
 
 const (
-	SharingTypeTodoList SharingType = 10*iota + 1 // Set RelatedID to todo list id
+	SharingTypeTodoList        SharingType = 10*iota + 1 // Set RelatedID to todo list id
+	SharingTypeTodoListPublish                           // Set RelatedID to todo list id, read-only for anyone
+	SharingTypeTodoListFolder                            // Set RelatedID to todo list folder id
+	SharingTypeTodo                                      // Set RelatedID to todo id
 )
 
 type Sharing struct {
@@ -11,9 +16,29 @@ type Sharing struct {
 
 	Token     string `json:"-" gorm:"size:128;uniqueIndex"`
 	Active    bool   `json:"active"`
-	Type      int8   `json:"type"` // SharingType
-	RelatedID int64  `json:"-"`    // Depends on Type
+	Type      int8   `json:"type"`                            // SharingType
+	RelatedID int64  `json:"-"`                               // Depends on Type
+	Role      int8   `json:"role" gorm:"not null;default:11"` // TodoListRole of users joined by token
+
+	Label          string     `json:"label" gorm:"size:64"`
+	ExpiresAt      *time.Time `json:"expiresAt"`      // never expires if nil
+	MaxRedemptions int64      `json:"maxRedemptions"` // unlimited if zero
+
+	// Hidden in published todo list unless opted in
+	PublishMemo  bool `json:"publishMemo"`
+	PublishFiles bool `json:"publishFiles"`
 
 	UserID int64 `json:"-"`
 	User   User  `json:"-"`
 }
+
+// SharingRedemption is a record of user joined by sharing token
+type SharingRedemption struct {
+	Entity
+
+	SharingID int64   `json:"sharingID" gorm:"index"`
+	Sharing   Sharing `json:"-"`
+
+	UserID int64 `json:"userID"`
+	User   User  `json:"-"`
+}
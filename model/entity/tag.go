@@ -3,7 +3,8 @@ package entity
 type Tag struct {
 	Entity
 
-	Name string `json:"name" gorm:"size:32;index:idx_tags_user,unique"`
+	Name  string `json:"name" gorm:"size:32;index:idx_tags_user,unique"`
+	Color string `json:"color" gorm:"size:16"` // e.g. #ff8800, empty for default
 
 	UserID int64 `json:"userID" gorm:"index:idx_tags_user,unique"`
 	User   User  `json:"-"`
@@ -1,7 +1,10 @@
 package entity
 
 import (
+	"sort"
 	"time"
+
+	"gorm.io/gorm"
 )
 
 type Todo struct {
@@ -15,7 +18,12 @@ type Todo struct {
 	NotifyAt   *time.Time `json:"notifyAt"`
 	Done       bool       `json:"done"`
 	DoneAt     *time.Time `json:"doneAt"`
+	Version    int64      `json:"version" gorm:"not null;default:1"` // optimistic lock
+	Rank       string     `json:"rank,omitempty" gorm:"-"`           // rank of current user
 
+	// Creator of todo, who is reminded and able to restore it from trash.
+	// Todo belongs to todo list rather than creator, so creator is kept for
+	// todos created by shared users or when todo list is transferred
 	UserID int64 `json:"userID"`
 	User   User  `json:"-"`
 
@@ -26,9 +34,43 @@ type Todo struct {
 
 	Steps []TodoStep `json:"steps"`
 
+	// Names of tags, including inline tags in title and memo. Each shared
+	// user has their own tags, so names are deduplicated from tag list
+	Tags    []string `json:"tags" gorm:"-"`
+	TagList []Tag    `json:"-" gorm:"many2many:tag_todos"`
+
 	TodoRepeatPlanID int64          `json:"-"`
 	TodoRepeatPlan   TodoRepeatPlan `json:"todoRepeatPlan"`
 
 	NextID *int64 `json:"nextID"` // next todo id if repeat
 	Next   *Todo  `json:"-"`
 }
+
+// TodoSharedUser is the join table of todo and shared users, who have role
+// in the todo only
+type TodoSharedUser struct {
+	UserID int64 `gorm:"primaryKey"`
+	User   User  `json:"-"`
+	TodoID int64 `gorm:"primaryKey"`
+	Role   int8  `gorm:"not null;default:11"` // TodoListRole
+}
+
+func (todo *Todo) AfterFind(tx *gorm.DB) (err error) {
+	todo.Tags = TagNames(todo.TagList)
+	return
+}
+
+// Deduplicated and sorted names of tags
+func TagNames(tags []Tag) []string {
+	seen := make(map[string]bool)
+	names := make([]string, 0, len(tags))
+	for _, tag := range tags {
+		if !seen[tag.Name] {
+			seen[tag.Name] = true
+			names = append(names, tag.Name)
+		}
+	}
+
+	sort.Strings(names)
+	return names
+}
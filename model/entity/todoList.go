@@ -6,12 +6,35 @@ type TodoList struct {
 	Name      string `json:"name" gorm:"size:128"`
 	IsBasic   bool   `json:"-"`
 	IsSharing bool   `json:"isSharing"`
+	Version   int64  `json:"version" gorm:"not null;default:1"` // optimistic lock
+	Rank      string `json:"rank,omitempty" gorm:"-"`           // rank of current user
 
-	UserID int64 `json:"userID"`
+	UserID int64 `json:"userID"` // owner
 	User   User  `json:"-"`
 
+	// Shared user whom ownership is being transferred to, 0 if none. Owner
+	// is changed after the user accepts
+	TransferUserID int64 `json:"transferUserID"`
+
 	TodoListFolderID int64          `json:"todoListFolderID"`
 	TodoListFolder   TodoListFolder `json:"-"`
 
 	SharedUsers []*User `json:"-" gorm:"many2many:todo_list_shared_users"`
 }
+
+type TodoListRole = int8
+
+// Roles are ordered, each role has permissions of roles before it
+const (
+	TodoListRoleViewer  TodoListRole = 10*iota + 1 // read todos
+	TodoListRoleEditor                             // edit todos, steps, files and repeat plans
+	TodoListRoleManager                            // edit todo list, invite and remove members
+	TodoListRoleOwner                              // owner of todo list, never stored
+)
+
+// TodoListSharedUser is the join table of todo list and shared users
+type TodoListSharedUser struct {
+	UserID     int64 `gorm:"primaryKey"`
+	TodoListID int64 `gorm:"primaryKey"`
+	Role       int8  `gorm:"not null;default:11"` // TodoListRole
+}
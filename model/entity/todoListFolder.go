@@ -4,9 +4,19 @@ type TodoListFolder struct {
 	Entity
 
 	Name string `json:"name" gorm:"size:128"`
+	Rank string `json:"rank,omitempty" gorm:"-"` // rank of current user
 
 	UserID int64 `json:"userID"`
 	User   User  `json:"-"`
 
 	TodoLists []TodoList `json:"-"`
 }
+
+// TodoListFolderSharedUser is the join table of todo list folder and shared
+// users, who have role in all todo lists of folder
+type TodoListFolderSharedUser struct {
+	UserID           int64 `gorm:"primaryKey"`
+	User             User  `json:"-"`
+	TodoListFolderID int64 `gorm:"primaryKey"`
+	Role             int8  `gorm:"not null;default:11"` // TodoListRole
+}
@@ -0,0 +1,20 @@
+package entity
+
+// TodoListInvitation is a pending invitation to join todo list, which is
+// deleted once accepted or declined. Invitation for email not registered
+// yet is held until the email registers
+type TodoListInvitation struct {
+	Entity
+
+	Role  int8   `json:"role" gorm:"not null;default:11"` // TodoListRole
+	Email string `json:"email" gorm:"size:32;index"`      // invitee email if not registered
+
+	InviteeID int64 `json:"inviteeID" gorm:"index"` // 0 if not registered
+	Invitee   User  `json:"-"`
+
+	TodoListID int64    `json:"todoListID" gorm:"index"`
+	TodoList   TodoList `json:"-"`
+
+	UserID int64 `json:"userID"` // who invited
+	User   User  `json:"-"`
+}
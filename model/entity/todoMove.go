@@ -0,0 +1,18 @@
+package entity
+
+// TodoMove is a record of todo moved between todo lists, so that
+// participants of both todo lists can find where it went
+type TodoMove struct {
+	Entity
+
+	Title string `json:"title" gorm:"size:128"` // title of todo when moved
+
+	TodoID int64 `json:"todoID"`
+	Todo   Todo  `json:"-"`
+
+	FromTodoListID int64 `json:"fromTodoListID" gorm:"index"`
+	ToTodoListID   int64 `json:"toTodoListID" gorm:"index"`
+
+	UserID int64 `json:"userID"` // who moved the todo
+	User   User  `json:"-"`
+}
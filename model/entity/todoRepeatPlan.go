@@ -13,6 +13,13 @@ const (
 	TodoRepeatPlanTypeYear  TodoRepeatPlanType = "year"
 )
 
+type TodoRepeatFrom string
+
+const (
+	TodoRepeatFromDeadline   TodoRepeatFrom = "deadline"   // default
+	TodoRepeatFromCompletion TodoRepeatFrom = "completion" // e.g. 3 days after I last did it
+)
+
 type TodoRepeatPlan struct {
 	Entity
 
@@ -21,5 +28,12 @@ type TodoRepeatPlan struct {
 	Before   *time.Time `json:"before"`
 	Weekday  int8       `json:"weekday"` // BitBools, [0..6]=[Sunday,Monday,Tuesday,Wednesday,Thursday,Friday,Saturday]
 
+	// Next deadline is computed from deadline or completion date
+	RepeatFrom string `json:"repeatFrom" gorm:"size:16"`
+
+	// RFC 5545 recurrence, e.g. `FREQ=MONTHLY;BYDAY=-1FR`, may contain
+	// DTSTART and EXDATE lines. Simple fields above are ignored if exists
+	RRule string `json:"rrule" gorm:"column:rrule;size:1024"`
+
 	Todos []Todo `json:"-"`
 }
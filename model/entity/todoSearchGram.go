@@ -0,0 +1,31 @@
+package entity
+
+import "strings"
+
+// TodoSearchGram is a gram of title, memo, step names or file names of todo,
+// which is the built-in search index for databases without full-text index,
+// e.g. SQLite. Grams of removed text may be kept, so that candidates found by
+// grams must be checked by scanning.
+type TodoSearchGram struct {
+	Gram   string `gorm:"primaryKey;size:8"`
+	TodoID int64  `gorm:"primaryKey;index"`
+}
+
+// Deduplicated bigrams of lowercased texts, a text contains a term only if
+// it has all grams of the term. Texts shorter than 2 runes have no grams.
+func SearchGrams(texts ...string) []string {
+	seen := make(map[string]bool)
+	grams := make([]string, 0)
+	for _, text := range texts {
+		runes := []rune(strings.ToLower(text))
+		for i := 0; i+1 < len(runes); i++ {
+			gram := string(runes[i : i+2])
+			if !seen[gram] {
+				seen[gram] = true
+				grams = append(grams, gram)
+			}
+		}
+	}
+
+	return grams
+}
@@ -5,9 +5,11 @@ import "time"
 type TodoStep struct {
 	Entity
 
-	Name   string     `json:"name" gorm:"-"`
-	Done   bool       `json:"done"`
-	DoneAt *time.Time `json:"doneAt"`
+	Name    string     `json:"name" gorm:"size:128"`
+	Done    bool       `json:"done"`
+	DoneAt  *time.Time `json:"doneAt"`
+	Version int64      `json:"version" gorm:"not null;default:1"` // optimistic lock
+	Rank    string     `json:"rank,omitempty" gorm:"-"`           // rank of current user
 
 	TodoID int64 `json:"todoID"`
 	Todo   Todo  `json:"-"`
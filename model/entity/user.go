@@ -9,7 +9,8 @@ type User struct {
 	Email     string `json:"email" gorm:"size:32;"`
 	Telephone string `json:"telephone" gorm:"size:16;"`
 	Avatar    string `json:"avatar"`
-	GithubID  int64  `json:"githubID" gorm:"index:,unique,priority:12"`
+	GithubID  int64  `json:"githubID" gorm:"index"`
+	Timezone  string `json:"timezone" gorm:"size:64"` // IANA time zone, e.g. Asia/Shanghai
 
 	BasicTodoListID int64     `json:"basicTodoListID"`
 	BasicTodoList   *TodoList `json:"-"`
@@ -6,5 +6,5 @@ type UserInvalidRefreshToken struct {
 	UserID int64 `json:"userID"`
 	User   User  `json:"-"`
 
-	TokenID string `json:"tokenID" gorm:"type:char(36);"`
+	TokenID string `json:"tokenID" gorm:"size:36"`
 }
@@ -2,14 +2,20 @@ package main
 
 import (
 	"log"
+	"os"
 
 	"github.com/yzx9/otodo/api"
 )
 
 func main() {
-	s := api.NewServer().
-		LoadAndWatchConfig(".").
-		Run()
+	s := api.NewServer()
+
+	// Usage: otodo migrate [up | down [steps] | status]
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		s.LoadConfig(".").Migrate(os.Args[2:]...)
+	} else {
+		s.LoadAndWatchConfig(".").Run()
+	}
 
 	if s.Error != nil {
 		log.Fatal(s.Error)
@@ -8,6 +8,8 @@ type Config struct {
 	Session  ConfigSession
 	Secret   ConfigSecret
 	Github   ConfigGithub
+	Trash    ConfigTrash
+	Reminder ConfigReminder
 }
 
 type ConfigServer struct {
@@ -18,13 +20,21 @@ type ConfigServer struct {
 	FilePathTemplate         string // Support :id, :ext, :name, :path, :date
 }
 
+const (
+	DatabaseDriverMySQL    = "mysql"
+	DatabaseDriverPostgres = "postgres"
+	DatabaseDriverSQLite   = "sqlite"
+)
+
 type ConfigDatabase struct {
+	Driver       string // mysql, postgres or sqlite, default to mysql
 	Host         string
 	Port         int
 	UserName     string
 	Password     string
 	Protocol     string
 	DatabaseName string
+	AutoMigrate  bool // apply pending migrations on startup
 }
 
 type ConfigSession struct {
@@ -45,3 +55,30 @@ type ConfigGithub struct {
 	OAuthRedirectURI    string
 	OAuthStateExpiresIn int
 }
+
+type ConfigTrash struct {
+	Retention     int // seconds to keep deleted items, keep forever if 0
+	PurgeInterval int // seconds
+}
+
+type ConfigReminder struct {
+	Interval int // seconds to check due reminders
+	SMTP     ConfigSMTP
+	Webhook  ConfigWebhook
+}
+
+// Send reminders by email, disabled if host is empty
+type ConfigSMTP struct {
+	Host     string
+	Port     int
+	UserName string
+	Password string
+	From     string
+}
+
+// Post reminders to webhook, disabled if url is empty. Payload is signed by
+// HMAC-SHA256 with secret if exists
+type ConfigWebhook struct {
+	URL    string
+	Secret string
+}
@@ -1,6 +1,7 @@
 package util
 
 import (
+	"errors"
 	"fmt"
 
 	"github.com/yzx9/otodo/otodo"
@@ -30,3 +31,9 @@ func NewErrorWithPreconditionFailed(format string, values ...interface{}) *otodo
 func NewErrorWithUnknown(format string, values ...interface{}) *otodo.Error {
 	return NewError(otodo.ErrUnknown, format, values...)
 }
+
+// Whether err is or wraps an error with code
+func IsErrorCode(err error, code otodo.ErrCode) bool {
+	var e *otodo.Error
+	return errors.As(err, &e) && e.Code == code
+}
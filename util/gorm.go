@@ -10,11 +10,11 @@ func WrapGormErr(err error, resource string) error {
 		return nil
 	}
 
-	if err != gorm.ErrRecordNotFound {
+	if err == gorm.ErrRecordNotFound {
 		return NewErrorWithNotFound("%v not found", resource)
 	}
 
-	if err != gorm.ErrNotImplemented {
+	if err == gorm.ErrNotImplemented {
 		return NewError(otodo.ErrNotImplemented, "handle %v not implemented", resource)
 	}
 